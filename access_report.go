@@ -0,0 +1,180 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// AccessRecord is a person listed in an AccessReport. PolicyAgreed is nil
+// unless policies were checked.
+type AccessRecord struct {
+	Person
+	PolicyAgreed *bool
+}
+
+// AccessReport lists people who have never signed in, or have not signed in
+// recently, for following up on accounts that are not being used. Suspended
+// accounts are excluded from every list except People.
+type AccessReport struct {
+	// People is everyone in the report, sorted by name
+	People []AccessRecord
+
+	NeverLoggedIn []AccessRecord
+
+	// Inactive have signed in, but not within the InactiveDays
+	Inactive []AccessRecord
+
+	// PolicyNotAgreed have not accepted every compulsory site policy
+	PolicyNotAgreed []AccessRecord
+}
+
+// AccessReportOptions control an AccessReport. Zero values use the
+// defaults noted.
+type AccessReportOptions struct {
+	// InactiveDays is how long since a person last signed in before they
+	// are inactive. Defaults to 30 days.
+	InactiveDays int
+
+	// CheckPolicies reads each person's acceptance of the site policies,
+	// which requires the tool_policy web service functions and one call per
+	// person.
+	CheckPolicies bool
+
+	// Now is the time the report is produced. Defaults to the current time.
+	Now time.Time
+}
+
+// GetCourseAccessReport reports on the sign in activity of the people
+// enrolled in a course.
+func (m *MoodleApi) GetCourseAccessReport(courseId CourseID, options *AccessReportOptions) (*AccessReport, error) {
+	body, err := m.call("core_enrol_get_enrolled_users", url.Values{
+		"courseid":          {fmt.Sprint(courseId)},
+		"options[0][name]":  {"userfields"},
+		"options[0][value]": {"id"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Id UserID `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	ids := make([]UserID, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Id)
+	}
+	return m.GetAccessReport(ids, options)
+}
+
+// GetCohortAccessReport reports on the sign in activity of the members of a
+// cohort.
+func (m *MoodleApi) GetCohortAccessReport(cohortId int64, options *AccessReportOptions) (*AccessReport, error) {
+	ids, err := m.GetCohortMembers(cohortId)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetAccessReport(ids, options)
+}
+
+// GetAccessReport reports on the sign in activity of people
+func (m *MoodleApi) GetAccessReport(userIds []UserID, options *AccessReportOptions) (*AccessReport, error) {
+	o := AccessReportOptions{}
+	if options != nil {
+		o = *options
+	}
+	if o.InactiveDays <= 0 {
+		o.InactiveDays = 30
+	}
+	if o.Now.IsZero() {
+		o.Now = time.Now()
+	}
+	cutoff := o.Now.AddDate(0, 0, -o.InactiveDays)
+
+	people, err := m.getPeopleById(userIds)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(people, func(i, j int) bool {
+		if people[i].LastName != people[j].LastName {
+			return people[i].LastName < people[j].LastName
+		}
+		return people[i].FirstName < people[j].FirstName
+	})
+
+	report := &AccessReport{}
+	for _, p := range people {
+		record := AccessRecord{Person: p}
+		if o.CheckPolicies && !p.Suspended {
+			agreed, err := m.policiesAgreed(p.MoodleId)
+			if err != nil {
+				return nil, err
+			}
+			record.PolicyAgreed = &agreed
+		}
+
+		report.People = append(report.People, record)
+		if p.Suspended {
+			continue
+		}
+		if p.LastAccess == nil {
+			report.NeverLoggedIn = append(report.NeverLoggedIn, record)
+		} else if p.LastAccess.Before(cutoff) {
+			report.Inactive = append(report.Inactive, record)
+		}
+		if record.PolicyAgreed != nil && !*record.PolicyAgreed {
+			report.PolicyNotAgreed = append(report.PolicyNotAgreed, record)
+		}
+	}
+	return report, nil
+}
+
+// getPeopleById fetches accounts including their sign in times
+func (m *MoodleApi) getPeopleById(userIds []UserID) ([]Person, error) {
+	values := make([]string, len(userIds))
+	for i, id := range userIds {
+		values[i] = fmt.Sprint(id)
+	}
+	return m.getPeopleByField("id", values)
+}
+
+// policiesAgreed reports whether a person has accepted every compulsory
+// site policy
+func (m *MoodleApi) policiesAgreed(userId UserID) (bool, error) {
+	body, err := m.call("tool_policy_get_user_acceptances", url.Values{
+		"userid": {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	type Acceptance struct {
+		Status int `json:"status"`
+	}
+	type Policy struct {
+		Optional   int         `json:"optional"`
+		Acceptance *Acceptance `json:"acceptance"`
+	}
+	type Result struct {
+		Policies []Policy `json:"policies"`
+	}
+
+	var result Result
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return false, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	for _, p := range result.Policies {
+		if p.Optional == 0 && (p.Acceptance == nil || p.Acceptance.Status != 1) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
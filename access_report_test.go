@@ -0,0 +1,73 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// policyLookupUrl returns the policy acceptances of the requested person
+type policyLookupUrl struct {
+	*testLookupUrl
+	acceptances map[string]string
+}
+
+func (p *policyLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	p.responses["tool_policy_get_user_acceptances"] = p.acceptances[form.Get("userid")]
+	return p.testLookupUrl.Do(method, u, form, header)
+}
+
+func TestAccessReport(t *testing.T) {
+
+	now := time.Unix(1600000000, 0)
+	recent := now.AddDate(0, 0, -2).Unix()
+	old := now.AddDate(0, 0, -60).Unix()
+	accepted := `{"policies":[{"versionid":1,"optional":0,"acceptance":{"status":1}},{"versionid":2,"optional":1,"acceptance":null}],"warnings":[]}`
+	declined := `{"policies":[{"versionid":1,"optional":0,"acceptance":null}],"warnings":[]}`
+	fetch := &policyLookupUrl{newTestLookupUrl(map[string]string{
+		"core_enrol_get_enrolled_users":  `[{"id":5},{"id":6},{"id":7},{"id":8}]`,
+		"core_cohort_get_cohort_members": `[{"cohortid":2,"userids":[5,6,7,8]}]`,
+		"core_user_get_users_by_field": `[` +
+			`{"id":5,"username":"ann","firstname":"Ann","lastname":"Lee","auth":"manual","firstaccess":` + itoa(old) + `,"lastaccess":` + itoa(recent) + `},` +
+			`{"id":6,"username":"bo","firstname":"Bo","lastname":"Ng","auth":"ldap","firstaccess":0,"lastaccess":0},` +
+			`{"id":7,"username":"cy","firstname":"Cy","lastname":"Ali","auth":"manual","firstaccess":` + itoa(old) + `,"lastaccess":` + itoa(old) + `},` +
+			`{"id":8,"username":"di","firstname":"Di","lastname":"Ho","auth":"manual","suspended":true,"firstaccess":0,"lastaccess":0}]`,
+	}), map[string]string{"5": accepted, "6": declined, "7": accepted}}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	report, err := api.GetCourseAccessReport(3, &AccessReportOptions{Now: now, CheckPolicies: true})
+	if err != nil {
+		t.Fatalf("GetCourseAccessReport failed: %v", err)
+	}
+	if len(report.People) != 4 || report.People[0].Username != "cy" {
+		t.Errorf("Expected everyone sorted by name, found %+v", report.People)
+	}
+	if len(report.NeverLoggedIn) != 1 || report.NeverLoggedIn[0].MoodleId != 6 || report.NeverLoggedIn[0].Auth != "ldap" {
+		t.Errorf("Expected only person 6 never to have signed in, found %+v", report.NeverLoggedIn)
+	}
+	if len(report.Inactive) != 1 || report.Inactive[0].MoodleId != 7 {
+		t.Errorf("Expected person 7 to be inactive, found %+v", report.Inactive)
+	}
+	if len(report.PolicyNotAgreed) != 1 || report.PolicyNotAgreed[0].MoodleId != 6 {
+		t.Errorf("Expected person 6 not to have agreed, found %+v", report.PolicyNotAgreed)
+	}
+
+	fetch.requests = nil
+	report, err = api.GetCohortAccessReport(2, &AccessReportOptions{Now: now, InactiveDays: 90})
+	if err != nil {
+		t.Fatalf("GetCohortAccessReport failed: %v", err)
+	}
+	if len(report.Inactive) != 0 || report.People[0].PolicyAgreed != nil || len(fetch.requests) != 2 {
+		t.Errorf("Expected no inactive people within 90 days and no policy checks, found %+v", report)
+	}
+	if r := fetch.last(); r.Get("values[3]") != "8" {
+		t.Errorf("Expected cohort members to be fetched together, found %v", r)
+	}
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,155 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NewUser describes an account to be created by AddUsers. If Password is
+// blank moodle generates a password and emails it to the person. Auth
+// defaults to "manual".
+type NewUser struct {
+	FirstName    string
+	LastName     string
+	Email        string
+	Username     string
+	Password     string
+	Auth         string
+	IdNumber     string
+	CustomFields map[string]string
+}
+
+// AddUserResult is the outcome of creating one account. Id is set if the
+// account was created, otherwise Err describes why it was not.
+type AddUserResult struct {
+	Username string
+	Id       UserID
+	Err      error
+}
+
+// addUsersBatchSize is the number of accounts created by each call
+const addUsersBatchSize = 100
+
+// AddUsers creates many accounts using one call for every hundred accounts.
+// Results are returned in the same order as users. Moodle rejects a whole
+// call if any account is invalid, for example because the username is
+// taken, in which case the accounts of that call are created one at a time
+// so that only the invalid accounts fail. The error is nil only if every
+// account was created.
+func (m *MoodleApi) AddUsers(users []NewUser) ([]AddUserResult, error) {
+	results := make([]AddUserResult, len(users))
+	var batch []int
+	for i, u := range users {
+		results[i].Username = u.Username
+		if strings.Index(u.Email, "@") < 0 {
+			results[i].Err = errors.New("Invalid email address")
+			continue
+		}
+		batch = append(batch, i)
+		if len(batch) == addUsersBatchSize {
+			m.addUsersBatch(users, batch, results)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		m.addUsersBatch(users, batch, results)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, errors.New(fmt.Sprintf("%d of %d accounts could not be created", failed, len(users)))
+	}
+	return results, nil
+}
+
+// addUsersBatch creates the users at the indexes, recording the outcome in
+// results
+func (m *MoodleApi) addUsersBatch(users []NewUser, indexes []int, results []AddUserResult) {
+	ids, err := m.createUsers(users, indexes)
+	if err != nil {
+		var merr *MoodleError
+		if len(indexes) > 1 && errors.As(err, &merr) && merr.Exception != "" {
+			for _, i := range indexes {
+				m.addUsersBatch(users, []int{i}, results)
+			}
+			return
+		}
+		for _, i := range indexes {
+			results[i].Err = err
+		}
+		return
+	}
+	for _, i := range indexes {
+		id, ok := ids[strings.ToLower(users[i].Username)]
+		if !ok {
+			results[i].Err = errors.New("Server returned unexpected response. ID is missing.")
+			continue
+		}
+		results[i].Id = id
+	}
+}
+
+// createUsers calls core_user_create_users, returning the new ids keyed by
+// lower case username
+func (m *MoodleApi) createUsers(users []NewUser, indexes []int) (map[string]UserID, error) {
+	params := url.Values{}
+	for n, i := range indexes {
+		u := users[i]
+		prefix := fmt.Sprintf("users[%d]", n)
+		params.Set(prefix+"[firstname]", u.FirstName)
+		params.Set(prefix+"[lastname]", u.LastName)
+		params.Set(prefix+"[email]", u.Email)
+		params.Set(prefix+"[username]", u.Username)
+		if u.Password == "" {
+			params.Set(prefix+"[createpassword]", "1")
+		} else {
+			params.Set(prefix+"[password]", u.Password)
+		}
+		if u.Auth != "" {
+			params.Set(prefix+"[auth]", u.Auth)
+		}
+		if u.IdNumber != "" {
+			params.Set(prefix+"[idnumber]", u.IdNumber)
+		}
+		names := make([]string, 0, len(u.CustomFields))
+		for name := range u.CustomFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for f, name := range names {
+			params.Set(fmt.Sprintf("%s[customfields][%d][type]", prefix, f), name)
+			params.Set(fmt.Sprintf("%s[customfields][%d][value]", prefix, f), u.CustomFields[name])
+		}
+	}
+
+	body, err := m.call("core_user_create_users", params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Id       UserID `json:"id"`
+		Username string `json:"username"`
+	}
+
+	var data []Result
+
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	ids := make(map[string]UserID, len(data))
+	for _, r := range data {
+		ids[strings.ToLower(r.Username)] = r.Id
+	}
+	return ids, nil
+}
@@ -0,0 +1,76 @@
+package moodle
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// createUsersLookupUrl creates accounts, rejecting any call that includes a
+// username that is already taken
+type createUsersLookupUrl struct {
+	*testLookupUrl
+	next int
+}
+
+func (c *createUsersLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	c.testLookupUrl.Do(method, u, form, header)
+	var created []string
+	for i := 0; form.Get(fmt.Sprintf("users[%d][username]", i)) != ""; i++ {
+		username := form.Get(fmt.Sprintf("users[%d][username]", i))
+		if username == "taken" {
+			return `{"exception":"invalid_parameter_exception","errorcode":"invalidparameter","message":"Invalid parameter value detected","debuginfo":"Username already exists: taken"}`, 200, "application/json", nil
+		}
+		c.next++
+		created = append(created, fmt.Sprintf(`{"id":%d,"username":"%s"}`, c.next, username))
+	}
+	return "[" + strings.Join(created, ",") + "]", 200, "application/json", nil
+}
+
+func TestAddUsers(t *testing.T) {
+
+	fetch := &createUsersLookupUrl{testLookupUrl: newTestLookupUrl(nil)}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	var users []NewUser
+	for i := 0; i < 150; i++ {
+		users = append(users, NewUser{FirstName: "Student", LastName: fmt.Sprint(i), Email: fmt.Sprintf("s%d@example.com", i), Username: fmt.Sprintf("s%d", i)})
+	}
+	users[0].CustomFields = map[string]string{"studentid": "S0", "campus": "North"}
+	users[0].Auth = "ldap"
+
+	results, err := api.AddUsers(users)
+	if err != nil {
+		t.Fatalf("AddUsers failed: %v", err)
+	}
+	if len(fetch.requests) != 2 {
+		t.Errorf("Expected two calls for 150 accounts, found %d", len(fetch.requests))
+	}
+	if results[149].Id != 150 || results[149].Username != "s149" {
+		t.Errorf("Expected results in order, found %+v", results[149])
+	}
+	r := fetch.requests[0]
+	if r.Get("users[0][customfields][0][type]") != "campus" || r.Get("users[0][customfields][1][value]") != "S0" || r.Get("users[0][auth]") != "ldap" || r.Get("users[1][createpassword]") != "1" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	fetch.requests = nil
+	results, err = api.AddUsers([]NewUser{
+		{FirstName: "A", LastName: "A", Email: "a@example.com", Username: "a"},
+		{FirstName: "T", LastName: "T", Email: "t@example.com", Username: "taken"},
+		{FirstName: "N", LastName: "N", Email: "none", Username: "noemail"},
+		{FirstName: "B", LastName: "B", Email: "b@example.com", Username: "b"},
+	})
+	if err == nil || err.Error() != "2 of 4 accounts could not be created" {
+		t.Errorf("Expected failures to be reported, found %v", err)
+	}
+	if results[0].Id == 0 || results[3].Id == 0 || results[1].Err == nil || results[2].Err == nil {
+		t.Errorf("Expected only the invalid accounts to fail, found %+v", results)
+	}
+	if len(fetch.requests) != 4 {
+		t.Errorf("Expected the rejected call to be repeated for each account, found %d calls", len(fetch.requests))
+	}
+}
@@ -0,0 +1,179 @@
+package moodle
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+//go:generate go run ./internal/mockgen -o moodlemock/mock.go api.go
+
+// Api is implemented by MoodleApi. Applications can depend on Api, or on
+// one of the smaller interfaces it is made from, so that tests can
+// substitute a mock such as moodlemock.Api.
+type Api interface {
+	PersonApi
+	CourseApi
+	GroupApi
+	GradeApi
+	ActivityApi
+	SiteApi
+}
+
+// PersonApi finds and updates moodle accounts
+type PersonApi interface {
+	GetPersonByUsername(username string) (*Person, error)
+	GetPersonByMoodleId(id UserID) (*Person, error)
+	GetPersonByEmail(email string) (*Person, error)
+	GetPeopleByUsernames(usernames []string) (map[string]Person, error)
+	GetPeopleByEmails(emails []string) (map[string]Person, error)
+	FindPeopleByName(firstname, lastname string) ([]Person, error)
+	FindPeopleByAttribute(attribute, value string) ([]Person, error)
+	FindPeople(attribute, value string, options *PeopleSearchOptions) ([]Person, error)
+	GetAccessReport(userIds []UserID, options *AccessReportOptions) (*AccessReport, error)
+	GetCourseAccessReport(courseId CourseID, options *AccessReportOptions) (*AccessReport, error)
+	GetCohortAccessReport(cohortId int64, options *AccessReportOptions) (*AccessReport, error)
+	AddUser(firstName, lastName, email, username, password string) (UserID, error)
+	AddUsers(users []NewUser) ([]AddUserResult, error)
+	UpdateUsers(users []UserUpdate) ([]UpdateUserResult, error)
+	SuspendUser(id UserID) error
+	ChangeUserEmail(userId UserID, newEmail string, notify bool) error
+	RenameUsername(userId UserID, newUsername string) error
+	UnsuspendUser(id UserID) error
+	UpdateUser(id UserID, firstName, lastName, email, username, password string) error
+	SetUserAttribute(personId UserID, attribute, value string) error
+	SetUserCustomField(personId UserID, attribute, value string) error
+	SetUserCustomFields(personId UserID, fields map[string]string) error
+	SetCustomFieldsForUsers(fields map[UserID]map[string]string) error
+	ResetPassword(moodleId UserID, password string) error
+	ResetPasswordWithEmail(email string) error
+	SetProfilePicture(userMoodleId UserID, r io.Reader) error
+	GetProfilePicture(userId UserID, size PictureSize) (io.ReadCloser, error)
+	RemoveProfilePicture(userId UserID) error
+	GetPersonLocation(userId UserID) (*time.Location, error)
+	GetUserDevices(userId UserID) ([]UserDevice, error)
+}
+
+// CourseApi finds courses, course modules, and the people enrolled in them
+type CourseApi interface {
+	GetCourses(value string) ([]Course, error)
+	GetCourseByField(field, value string) (*CourseRecord, error)
+	GetCourseByShortName(shortName string) (*CourseRecord, error)
+	SearchCoursesByCustomField(shortname, value string) ([]CourseRecord, error)
+	GetPersonCourseList(userId UserID) ([]Course, error)
+	GetCategories(categoryId int64, recursive bool) ([]CourseCategory, error)
+	GetCoursesInCategory(categoryId int64, recursive bool) ([]Course, error)
+	GetStarredCourses() ([]Course, error)
+	SetCourseStarred(courseId CourseID, starred bool) error
+	UpdateCourse(update CourseUpdate) error
+	DuplicateCourse(courseId CourseID, options DuplicateCourseOptions) (CourseID, error)
+	DeleteCourse(courseId CourseID) error
+	GetRecentItems(limit int) ([]RecentItem, error)
+	GetCourseRoles(courseId CourseID) ([]CoursePerson, error)
+	GetCourseEnrolmentCount(courseId CourseID) (int, error)
+	SearchCourseUsers(courseId CourseID, query string) ([]CoursePerson, error)
+	GetRolesForCourses(courseIds []CourseID, concurrency int) (map[CourseID][]CoursePerson, error)
+	StreamCourseRoles(courseId CourseID, fn func(CoursePerson) error) error
+	SetRole(personId UserID, roleId RoleID, courseId CourseID) error
+	GetUsersWithCapability(courseId CourseID, capability string) ([]UserID, error)
+	HasCapability(courseId CourseID, userId UserID, capability string) (bool, error)
+	RequireCapability(courseId CourseID, capability string) error
+	UnsetRole(personId UserID, roleId RoleID, courseId CourseID) error
+	GetCourseModule(cmid CmID) (*CourseModule, error)
+	GetCourseModules(courseId CourseID) ([]CourseModule, error)
+	GetCourseModulesByType(courseId CourseID, modname string) ([]CourseModule, error)
+	GetCourseContents(courseId CourseID) ([]CourseSection, error)
+	GetCourseModulesByIds(cmids []CmID, concurrency int) (map[CmID]*CourseModule, error)
+	ResolveAssignmentId(cmid CmID) (int64, error)
+	ResolveCmId(assignmentId int64) (CmID, error)
+	GetAssignmentCmIds(courseIds []CourseID) (map[int64]CmID, error)
+	SetAssignmentDueDate(assignmentId int64, due, cutoff time.Time) error
+	ShiftAssignmentDates(courseIds []CourseID, offset time.Duration) (int, error)
+	SetQuizDates(quizId int64, open, close time.Time) error
+	ShiftQuizDates(courseIds []CourseID, offset time.Duration) (int, error)
+	IsModuleAvailableTo(cmid CmID, userId UserID) (bool, error)
+	SetModuleAvailability(cmid CmID, r *Restriction) error
+	SetCourseModuleVisibility(cmid CmID, visible bool) error
+	DeleteCourseModule(cmid CmID) error
+}
+
+// GroupApi manages course groups, cohorts and their members, lists
+// groupings and contacts the members of a group
+type GroupApi interface {
+	GetCourseGroups(courseId CourseID) ([]CourseGroup, error)
+	GetPersonCourseGroups(courseId CourseID, userId UserID) ([]CourseGroup, error)
+	GetCourseGroupings(courseId CourseID) ([]CourseGrouping, error)
+	GetPersonCourseGroupings(courseId CourseID, userId UserID) ([]int64, error)
+	GetCourseGroupByName(courseId CourseID, name string) (*CourseGroup, error)
+	GetCourseGroupByIdNumber(courseId CourseID, idNumber string) (*CourseGroup, error)
+	GetGroupMembers(courseId CourseID, groupId GroupID) ([]CoursePerson, error)
+	MessageCourseGroup(courseId CourseID, groupName, text string) error
+	EmailCourseGroup(courseId CourseID, groupName string, t EmailTemplate, data interface{}) error
+	AddGroupToCourse(courseId CourseID, groupName, groupDescription string) (GroupID, error)
+	AddGroupToCourseWithIdNumber(courseId CourseID, groupName, groupDescription, idNumber string) (GroupID, error)
+	UpdateCourseGroup(group CourseGroup) error
+	AddPersonToCourseGroup(personId UserID, groupId GroupID) error
+	RemovePersonFromCourseGroup(personId UserID, groupId GroupID) error
+	CreateCohort(name, idNumber, description string) (int64, error)
+	GetCohorts(ids ...int64) ([]Cohort, error)
+	GetCohortMembers(cohortId int64) ([]UserID, error)
+	AddCohortMembers(cohortId int64, userIds []UserID) error
+	RemoveCohortMembers(cohortId int64, userIds []UserID) error
+}
+
+// GradeApi reads grades, ratings, competencies, activity completion and
+// analytics insights
+type GradeApi interface {
+	GetCourseGradebook(courseId CourseID) ([]GradebookEntry, error)
+	GetAssignmentGradeRecords(ids ...int64) ([]AssignmentRecord, error)
+	GetGradingBacklog(courseIds []CourseID) ([]GradingBacklog, error)
+	GetAssignmentStats(assignmentId int64) (*AssignmentStats, error)
+	GetActivitiesCompletion(courseId CourseID, userId UserID) (map[CmID]CompletionState, error)
+	GetItemRatings(area RatingArea, itemId int64) ([]Rating, error)
+	AddRating(area RatingArea, itemId int64, ratedUserId UserID, rating int64, aggregation RatingAggregation) (*RatingResult, error)
+	GetCompetencyFrameworks() ([]CompetencyFramework, error)
+	GetCompetencies(frameworkId int64) ([]Competency, error)
+	GetCourseCompetencies(courseId CourseID) ([]Competency, error)
+	GetUserCompetencyInCourse(userId UserID, competencyId int64, courseId CourseID) (*UserCompetency, error)
+	GetInsights(userId UserID) ([]Insight, error)
+}
+
+// ActivityApi reads assignments, quizzes and forums, and their submissions
+type ActivityApi interface {
+	GetAssignmentsForCourses(courseIds []CourseID) ([]AssignmentInfo, error)
+	GetQuizzesForCourses(courseIds []CourseID) ([]QuizInfo, error)
+	GetUserQuizAttempts(quizId int64, userId UserID) ([]QuizAttempt, error)
+	GetQuizAttemptReview(attemptId int64) (*QuizAttemptReview, error)
+	GetForumsForCourses(courseIds []CourseID) ([]ForumInfo, error)
+	GetForumDiscussions(forumId int) ([]ForumDiscussion, error)
+	GetForumDiscussionsForGroup(forumId int, groupId GroupID) ([]ForumDiscussion, error)
+	GetSubmissionsForAssignment(assignmentId int64) ([]AssignmentSubmission, error)
+	GetPlagiarismResults(assignmentId int64) (map[UserID][]PlagiarismResult, error)
+	GetAnnotatedFeedbackPdf(assignmentId int64, userId UserID) (*MoodleFile, error)
+	DownloadAnnotatedFeedbackPdf(assignmentId int64, userId UserID) (*MoodleFile, []byte, error)
+	DownloadFile(fileUrl string) ([]byte, error)
+	OpenFile(fileUrl string) (io.ReadCloser, error)
+	WriteSubmissionBundle(assignmentId int64, w io.Writer) (int, error)
+	NotifyFeedbackByEmail(t EmailTemplate) func(FeedbackReady) error
+	GetSubmissionComments(cmid CmID, submissionId int64) ([]Comment, error)
+	AddSubmissionComment(cmid CmID, submissionId int64, content string) (*Comment, error)
+	SetAssessmentExtensionDate(userId UserID, assessmentId int64, newDueDate time.Time) error
+}
+
+// SiteApi reads site wide information and custom reports, and calls
+// functions that are not otherwise wrapped
+type SiteApi interface {
+	GetSiteInfo() (string, string, string, int64, error)
+	GetSiteInfoStruct() (*SiteInfo, error)
+	Ping(ctx context.Context, required ...string) (*HealthStatus, error)
+	ValidateToken() (*SiteInfo, error)
+	Call(ctx context.Context, function string, params interface{}, result interface{}) error
+	GetPasswordPolicy() (*PasswordPolicy, error)
+	GetPublicConfig() (*PublicConfig, error)
+	GetMobileConfig(section string) (map[string]string, error)
+	GetCustomReports() ([]CustomReport, error)
+	GetCustomReport(reportId int64) (*ReportData, error)
+	GetCustomReportPage(reportId int64, page, perPage int) (*ReportData, error)
+}
+
+var _ Api = (*MoodleApi)(nil)
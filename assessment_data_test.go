@@ -41,7 +41,7 @@ func TestGetAssessmentInformation(t *testing.T) {
 	api := NewMoodleApi(requireEnv("MOODLE_URL", t), requireEnv("MOODLE_KEY", t))
 	api.SetLogger(&PrintMoodleLogger{})
 
-	r, err := api.GetAssignmentsWithCourseId([]int{3})
+	r, err := api.GetAssignmentsWithCourseId([]CourseID{3})
 	if err != nil {
 		t.Errorf("API call failed")
 		return
@@ -55,7 +55,7 @@ func TestGetAssessmentInformation(t *testing.T) {
 	}
 
 	fmt.Println("Check for Quizzes")
-	s, err := api.GetQuizzesWithCourseId([]int{3})
+	s, err := api.GetQuizzesWithCourseId([]CourseID{3})
 	if err != nil {
 		t.Errorf("API call failed: %s", err)
 		return
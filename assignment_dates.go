@@ -0,0 +1,71 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SetAssignmentDatesFunction sets the web service function used by
+// SetAssignmentDueDate. Moodle does not include a web service to change the
+// dates of an assignment, so this must be provided by a local plugin. The
+// function is called with the parameters "assignmentid", "duedate" and
+// "cutoffdate", the dates being unix times, or 0 to remove the date.
+func (m *MoodleApi) SetAssignmentDatesFunction(function string) {
+	m.assignmentDatesFunction = function
+}
+
+// SetAssignmentDueDate changes the due date and cut-off date of an
+// assignment for everyone. A zero time removes the date. The assignmentId is
+// the id from the mdl_assign table, see ResolveAssignmentId. Requires
+// SetAssignmentDatesFunction.
+func (m *MoodleApi) SetAssignmentDueDate(assignmentId int64, due, cutoff time.Time) error {
+	if m.assignmentDatesFunction == "" {
+		return errors.New("Setting assignment dates requires a web service function, see SetAssignmentDatesFunction")
+	}
+
+	_, err := m.call(m.assignmentDatesFunction, url.Values{
+		"assignmentid": {fmt.Sprint(assignmentId)},
+		"duedate":      {fmt.Sprint(unixOrZero(due))},
+		"cutoffdate":   {fmt.Sprint(unixOrZero(cutoff))},
+	})
+	return err
+}
+
+// ShiftAssignmentDates moves the due date and cut-off date of every
+// assignment in the courses by offset, such as when a term starts a week
+// later than planned. Dates that are not set are left unset. Returns the
+// number of assignments updated, stopping at the first failure.
+func (m *MoodleApi) ShiftAssignmentDates(courseIds []CourseID, offset time.Duration) (int, error) {
+	assignments, err := m.GetAssignmentsWithCourseId(courseIds)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, a := range assignments {
+		if a.DueDate == nil && a.CutoffDate == 0 {
+			continue
+		}
+		var due, cutoff time.Time
+		if a.DueDate != nil {
+			due = a.DueDate.Add(offset)
+		}
+		if a.CutoffDate != 0 {
+			cutoff = m.unix(a.CutoffDate).Add(offset)
+		}
+		if err := m.SetAssignmentDueDate(a.Id, due, cutoff); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
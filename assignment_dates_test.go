@@ -0,0 +1,44 @@
+package moodle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetAssignmentDueDate(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"local_dates_set_assign_dates": `null`,
+		"mod_assign_get_assignments":   `{"courses":[{"id":3,"shortname":"ACC101","assignments":[{"id":7,"cmid":101,"duedate":1600000000,"cutoffdate":1600086400},{"id":8,"cmid":102,"duedate":0},{"id":9,"cmid":103,"duedate":1600000000}]}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	due := time.Unix(1600000000, 0)
+	if err := api.SetAssignmentDueDate(7, due, time.Time{}); err == nil {
+		t.Errorf("Expected an error when no dates function has been set")
+	}
+
+	api.SetAssignmentDatesFunction("local_dates_set_assign_dates")
+	if err := api.SetAssignmentDueDate(7, due, time.Time{}); err != nil {
+		t.Fatalf("SetAssignmentDueDate failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("assignmentid") != "7" || r.Get("duedate") != "1600000000" || r.Get("cutoffdate") != "0" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	n, err := api.ShiftAssignmentDates([]CourseID{3}, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ShiftAssignmentDates failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected assignments without dates to be skipped, found %d updated", n)
+	}
+	calls := fetch.requests[len(fetch.requests)-2:]
+	if calls[0].Get("assignmentid") != "7" || calls[0].Get("duedate") != "1600604800" || calls[0].Get("cutoffdate") != "1600691200" {
+		t.Errorf("Expected both dates to move by a week, found %v", calls[0])
+	}
+	if calls[1].Get("assignmentid") != "9" || calls[1].Get("cutoffdate") != "0" {
+		t.Errorf("Expected unset cut-off date to remain unset, found %v", calls[1])
+	}
+}
@@ -0,0 +1,68 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ResolveAssignmentId returns the assignment id (the id from the mdl_assign
+// table) of the assignment with a course module id. The course module id is
+// the id shown in the url when viewing an assignment, the assignment id is
+// required by functions such as SetAssessmentExtensionDate.
+func (m *MoodleApi) ResolveAssignmentId(cmid CmID) (int64, error) {
+	cm, err := m.GetCourseModule(cmid)
+	if err != nil {
+		return 0, err
+	}
+	if cm.ModuleName != "assign" {
+		return 0, wrapError(fmt.Sprintf("Course module %d is not an assignment", cmid), ErrNotFound)
+	}
+	return cm.InstanceId, nil
+}
+
+// ResolveCmId returns the course module id of an assignment, the reverse of
+// ResolveAssignmentId.
+func (m *MoodleApi) ResolveCmId(assignmentId int64) (CmID, error) {
+	body, err := m.call("core_course_get_course_module_by_instance", url.Values{
+		"moodlewssettingraw": {"true"},
+		"module":             {"assign"},
+		"instance":           {fmt.Sprint(assignmentId)},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	type Result struct {
+		CM struct {
+			Id CmID `json:"id"`
+		} `json:"cm"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return 0, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if result.CM.Id == 0 {
+		return 0, wrapError(fmt.Sprintf("Assignment %d does not exist", assignmentId), ErrNotFound)
+	}
+	return result.CM.Id, nil
+}
+
+// GetAssignmentCmIds maps the assignment id of every assignment in the
+// courses to its course module id, using a single call to
+// mod_assign_get_assignments. Prefer this to ResolveCmId when mapping many
+// assignments.
+func (m *MoodleApi) GetAssignmentCmIds(courseIds []CourseID) (map[int64]CmID, error) {
+	assignments, err := m.GetAssignmentsWithCourseId(courseIds)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int64]CmID)
+	for _, a := range assignments {
+		ids[a.Id] = a.CmId
+	}
+	return ids, nil
+}
@@ -0,0 +1,41 @@
+package moodle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveAssignmentIds(t *testing.T) {
+
+	responses := map[string]string{
+		"core_course_get_course_module":             `{"cm":{"id":101,"course":3,"instance":7,"modname":"assign","visible":1},"warnings":[]}`,
+		"core_course_get_course_module_by_instance": `{"cm":{"id":101,"course":3,"instance":7,"modname":"assign","visible":1},"warnings":[]}`,
+		"mod_assign_get_assignments":                `{"courses":[{"id":3,"shortname":"ACC101","assignments":[{"id":7,"cmid":101},{"id":8,"cmid":105}]}],"warnings":[]}`,
+	}
+	fetch := newTestLookupUrl(responses)
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if id, err := api.ResolveAssignmentId(101); err != nil || id != 7 {
+		t.Errorf("Expected cmid 101 to resolve to assignment 7, found %v %v", id, err)
+	}
+	if cmid, err := api.ResolveCmId(7); err != nil || cmid != 101 {
+		t.Errorf("Expected assignment 7 to resolve to cmid 101, found %v %v", cmid, err)
+	}
+	if r := fetch.last(); r.Get("module") != "assign" || r.Get("instance") != "7" {
+		t.Errorf("Expected assignment instance to be requested, found %v", r)
+	}
+
+	ids, err := api.GetAssignmentCmIds([]CourseID{3})
+	if err != nil {
+		t.Fatalf("GetAssignmentCmIds failed: %v", err)
+	}
+	if len(ids) != 2 || ids[8] != 105 {
+		t.Errorf("Expected two assignments to be mapped, found %v", ids)
+	}
+
+	responses["core_course_get_course_module"] = `{"cm":{"id":102,"course":3,"instance":3,"modname":"quiz","visible":1},"warnings":[]}`
+	if _, err := api.ResolveAssignmentId(102); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a quiz not to resolve to an assignment, found %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package moodle
+
+import (
+	"time"
+)
+
+// AssignmentStats summarises the submissions to an assignment, for example
+// for a program dashboard
+type AssignmentStats struct {
+	AssignmentId int64
+	Submitted    int
+	Draft        int
+
+	// Ungraded counts submissions made, or changed, after they were last
+	// graded, see GetGradingBacklog
+	Ungraded   int
+	Graded     int
+	Extensions int
+
+	// AverageGrade is the mean of the latest grade given to each graded
+	// person, or zero if nobody has been graded
+	AverageGrade float64
+}
+
+// GetAssignmentStats counts the submitted, draft and ungraded submissions to
+// an assignment, the extensions granted, and the average grade.
+func (m *MoodleApi) GetAssignmentStats(assignmentId int64) (*AssignmentStats, error) {
+	submissions, err := m.GetAssignmentSubmissions(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	records, err := m.GetAssignmentGradeRecords(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	stats := assignmentStats(submissions, records, time.Now())
+	stats.AssignmentId = assignmentId
+	return stats, nil
+}
+
+func assignmentStats(submissions []*AssignmentSubmission, records []AssignmentRecord, now time.Time) *AssignmentStats {
+	stats := &AssignmentStats{}
+
+	extensions := make(map[UserID]bool)
+	for _, s := range submissions {
+		switch s.Status {
+		case "submitted":
+			stats.Submitted++
+		case "draft":
+			stats.Draft++
+		}
+		if s.Extension != nil {
+			extensions[s.UserId] = true
+		}
+	}
+	stats.Extensions = len(extensions)
+	stats.Ungraded = len(ungradedSubmissions(submissions, records, nil, now))
+
+	latest := make(map[UserID]GradeRecord)
+	for _, r := range records {
+		for _, g := range r.Grades {
+			if g.Grade < 0 {
+				continue
+			}
+			if l, ok := latest[g.UserId]; !ok || g.TimeModified >= l.TimeModified {
+				latest[g.UserId] = g
+			}
+		}
+	}
+	var total float64
+	for _, g := range latest {
+		total += g.Grade
+	}
+	stats.Graded = len(latest)
+	if stats.Graded > 0 {
+		stats.AverageGrade = total / float64(stats.Graded)
+	}
+	return stats
+}
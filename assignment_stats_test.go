@@ -0,0 +1,35 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestGetAssignmentStats(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"mod_assign_get_submissions": `{"assignments":[{"assignmentid":12,"submissions":[` +
+			`{"id":1,"userid":7,"status":"submitted","timemodified":1600000000},` +
+			`{"id":2,"userid":8,"status":"submitted","timemodified":1600000000},` +
+			`{"id":3,"userid":9,"status":"submitted","timemodified":1600005000},` +
+			`{"id":4,"userid":10,"status":"draft","timemodified":1600000000},` +
+			`{"id":5,"userid":11,"status":"new","timemodified":0}` +
+			`]}],"warnings":[]}`,
+		"mod_assign_get_user_flags": `{"assignments":[{"assignmentid":12,"userflags":[{"id":1,"userid":8,"extensionduedate":1600100000},{"id":2,"userid":11,"extensionduedate":1600100000}]}],"warnings":[]}`,
+		"mod_assign_get_grades": `{"assignments":[{"assignmentid":12,"grades":[` +
+			`{"id":1,"userid":7,"timemodified":1600001000,"grade":"60.00"},` +
+			`{"id":2,"userid":7,"timemodified":1600002000,"grade":"70.00"},` +
+			`{"id":3,"userid":9,"timemodified":1600001000,"grade":"90.00"},` +
+			`{"id":4,"userid":8,"timemodified":1600001000,"grade":"-1.00"}` +
+			`]}],"warnings":[]}`,
+	}))
+
+	stats, err := api.GetAssignmentStats(12)
+	if err != nil {
+		t.Fatalf("GetAssignmentStats failed: %v", err)
+	}
+	expected := AssignmentStats{AssignmentId: 12, Submitted: 3, Draft: 1, Ungraded: 2, Graded: 2, Extensions: 2, AverageGrade: 80}
+	if *stats != expected {
+		t.Errorf("Expected %+v, found %+v", expected, *stats)
+	}
+}
@@ -0,0 +1,44 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestGetAssignments(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_get_assignments": `{"courses":[
+			{"id":3,"shortname":"BIB101","assignments":[
+				{"id":11,"cmid":101,"name":"Essay","intro":"Write an essay","duedate":1600000000},
+				{"id":12,"cmid":102,"name":"Exam","duedate":0}]},
+			{"id":4,"shortname":"BIB102","assignments":[]}],"warnings":[]}`,
+		"gradereport_user_get_grade_items": `{"usergrades":[{"userid":5,"gradeitems":[
+			{"id":1,"itemtype":"mod","itemmodule":"assign","iteminstance":11,"weightraw":0.4},
+			{"id":2,"itemtype":"mod","itemmodule":"assign","iteminstance":12,"weightraw":0.6},
+			{"id":3,"itemtype":"course","weightraw":1}]}],"warnings":[]}`,
+	})
+	api.SetUrlFetcher(fetch)
+
+	courses := []Course{{MoodleId: 3, Code: "BIB101"}, {MoodleId: 4, Code: "BIB102"}}
+	if err := api.GetAssignments(courses); err != nil {
+		t.Fatalf("GetAssignments failed: %v", err)
+	}
+
+	if fetch.requests[0].Get("courseids[1]") != "4" {
+		t.Errorf("Expected both courses to be requested, found %v", fetch.requests[0])
+	}
+	if len(courses[0].Assignments) != 2 || len(courses[1].Assignments) != 0 {
+		t.Fatalf("Expected two assignments in the first course only, found %d and %d", len(courses[0].Assignments), len(courses[1].Assignments))
+	}
+	essay := courses[0].Assignments[0]
+	if essay.CmId != 101 || essay.Name != "Essay" || essay.Due == nil || essay.Due.Unix() != 1600000000 {
+		t.Errorf("Unexpected assignment %+v", essay)
+	}
+	if essay.Weight != 0.4 || courses[0].Assignments[1].Weight != 0.6 {
+		t.Errorf("Expected weights from the gradebook, found %v and %v", essay.Weight, courses[0].Assignments[1].Weight)
+	}
+	if courses[0].Assignments[1].Due != nil {
+		t.Errorf("Expected no due date when duedate is zero")
+	}
+}
@@ -0,0 +1,155 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// IsModuleAvailableTo reports whether a person can access a course module.
+// The module must be visible and its restrictions met. Groups, groupings,
+// profile fields, grades and activity completion are fetched from moodle only
+// when a restriction refers to them.
+func (m *MoodleApi) IsModuleAvailableTo(cmid CmID, userId UserID) (bool, error) {
+	cm, err := m.GetCourseModule(cmid)
+	if err != nil {
+		return false, err
+	}
+	if !cm.Visible {
+		return false, nil
+	}
+
+	types := make(map[string]bool)
+	restrictionTypes(cm.Availability.C, types)
+
+	ctx := &RestrictionContext{}
+	if types["group"] || types["grouping"] {
+		if ctx.Groups, err = m.GetPersonCourseGroups(cm.CourseId, userId); err != nil {
+			return false, err
+		}
+	}
+	if types["grouping"] && len(ctx.Groups) > 0 {
+		groupings, err := m.GetCourseGroupings(cm.CourseId)
+		if err != nil {
+			return false, err
+		}
+		ctx.Groupings = GroupingsOf(ctx.Groups, groupings)
+	}
+	if types["profile"] {
+		if ctx.Person, err = m.GetPersonByMoodleId(userId); err != nil {
+			return false, err
+		}
+	}
+	if types["grade"] {
+		if ctx.Grades, err = m.getPersonCourseGrades(cm.CourseId, userId); err != nil {
+			return false, err
+		}
+	}
+	if types["completion"] {
+		if ctx.Completion, err = m.GetActivitiesCompletion(cm.CourseId, userId); err != nil {
+			return false, err
+		}
+	}
+
+	return !cm.Availability.IsRestrictedFor(ctx), nil
+}
+
+// restrictionTypes records the condition types used in a restriction
+func restrictionTypes(conditions []RestrictionC, types map[string]bool) {
+	for _, c := range conditions {
+		types[c.Type] = true
+		restrictionTypes(c.C, types)
+	}
+}
+
+// GetPersonCourseGroups lists the groups a person belongs to in a course
+func (m *MoodleApi) GetPersonCourseGroups(courseId CourseID, userId UserID) ([]CourseGroup, error) {
+	body, err := m.call("core_group_get_course_user_groups", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+		"userid":             {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Groups []CourseGroup `json:"groups"`
+	}
+
+	var results Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return results.Groups, nil
+}
+
+// GetActivitiesCompletion fetches the completion state of each activity in a
+// course for a person. Activities without completion tracking are omitted.
+func (m *MoodleApi) GetActivitiesCompletion(courseId CourseID, userId UserID) (map[CmID]CompletionState, error) {
+	body, err := m.call("core_completion_get_activities_completion_status", url.Values{
+		"courseid": {fmt.Sprint(courseId)},
+		"userid":   {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Status struct {
+		CmId  CmID            `json:"cmid"`
+		State CompletionState `json:"state"`
+	}
+	type Result struct {
+		Statuses []Status `json:"statuses"`
+	}
+
+	var results Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	completion := make(map[CmID]CompletionState)
+	for _, s := range results.Statuses {
+		completion[s.CmId] = s.State
+	}
+	return completion, nil
+}
+
+// getPersonCourseGrades returns a person's grade for each graded item in a
+// course as a percentage, keyed by grade item id.
+func (m *MoodleApi) getPersonCourseGrades(courseId CourseID, userId UserID) (map[int64]float64, error) {
+	body, err := m.call("gradereport_user_get_grade_items", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+		"userid":             {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Results struct {
+		Usergrades []GradebookEntry `json:"usergrades"`
+	}
+	var results Results
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	grades := make(map[int64]float64)
+	for _, entry := range results.Usergrades {
+		if entry.UserId != userId {
+			continue
+		}
+		for _, item := range entry.Item {
+			if item.Graded() != nil || item.GradeRaw > 0 {
+				grades[item.Id] = item.InferGrade()
+			}
+		}
+	}
+	return grades, nil
+}
@@ -0,0 +1,83 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestIsModuleAvailableTo(t *testing.T) {
+
+	availability := `{\"op\":\"&\",\"c\":[{\"type\":\"group\",\"id\":10},{\"op\":\"|\",\"c\":[{\"type\":\"completion\",\"cm\":101,\"e\":1},{\"type\":\"grade\",\"id\":7,\"min\":50}]}],\"showc\":[true,true]}`
+	responses := map[string]string{
+		"core_course_get_course_module":                    `{"cm":{"id":100,"course":3,"visible":1,"availability":"` + availability + `"},"warnings":[]}`,
+		"core_group_get_course_user_groups":                `{"groups":[{"id":10,"name":"Audit"}],"warnings":[]}`,
+		"core_completion_get_activities_completion_status": `{"statuses":[{"cmid":101,"state":0}],"warnings":[]}`,
+		"gradereport_user_get_grade_items":                 `{"usergrades":[{"userid":5,"gradeitems":[{"id":7,"graderaw":8,"grademax":10,"gradedategraded":1600000000}]}],"warnings":[]}`,
+	}
+	fetch := newTestLookupUrl(responses)
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	ok, err := api.IsModuleAvailableTo(100, 5)
+	if err != nil {
+		t.Fatalf("IsModuleAvailableTo failed: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected module to be available with a grade of 80%%")
+	}
+	for _, r := range fetch.requests {
+		if r.Get("wsfunction") == "core_user_get_users_by_field" {
+			t.Errorf("Expected profile not to be fetched when there are no profile conditions")
+		}
+	}
+
+	responses["gradereport_user_get_grade_items"] = `{"usergrades":[{"userid":5,"gradeitems":[{"id":7,"graderaw":3,"grademax":10,"gradedategraded":1600000000}]}],"warnings":[]}`
+	if ok, err := api.IsModuleAvailableTo(100, 5); err != nil || ok {
+		t.Errorf("Expected module to be restricted with a grade of 30%%, found %v %v", ok, err)
+	}
+
+	responses["core_group_get_course_user_groups"] = `{"groups":[],"warnings":[]}`
+	responses["core_completion_get_activities_completion_status"] = `{"statuses":[{"cmid":101,"state":1}],"warnings":[]}`
+	if ok, err := api.IsModuleAvailableTo(100, 5); err != nil || ok {
+		t.Errorf("Expected module to be restricted to members of the group, found %v %v", ok, err)
+	}
+}
+
+func TestGroupingRestriction(t *testing.T) {
+
+	availability := `{\"op\":\"&\",\"c\":[{\"type\":\"grouping\",\"id\":4}],\"showc\":[true]}`
+	responses := map[string]string{
+		"core_course_get_course_module":     `{"cm":{"id":100,"course":3,"visible":1,"availability":"` + availability + `"},"warnings":[]}`,
+		"core_group_get_course_user_groups": `{"groups":[{"id":10,"name":"Audit"}],"warnings":[]}`,
+		"core_group_get_course_groupings":   `[{"id":4,"courseid":3,"name":"Semester 1"},{"id":5,"courseid":3,"name":"Semester 2"}]`,
+		"core_group_get_groupings":          `[{"id":4,"courseid":3,"name":"Semester 1","groups":[{"id":10,"name":"Audit"}]},{"id":5,"courseid":3,"name":"Semester 2","groups":[{"id":11,"name":"Tax"}]}]`,
+	}
+	fetch := newTestLookupUrl(responses)
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	groupings, err := api.GetPersonCourseGroupings(3, 5)
+	if err != nil {
+		t.Fatalf("GetPersonCourseGroupings failed: %v", err)
+	}
+	if len(groupings) != 1 || groupings[0] != 4 {
+		t.Errorf("Expected person to belong to grouping 4, found %v", groupings)
+	}
+	if r := fetch.last(); r.Get("groupingids[1]") != "5" || r.Get("returngroups") != "1" {
+		t.Errorf("Expected groups of each grouping to be requested, found %v", r)
+	}
+
+	if ok, err := api.IsModuleAvailableTo(100, 5); err != nil || !ok {
+		t.Errorf("Expected module to be available to members of the grouping, found %v %v", ok, err)
+	}
+
+	responses["core_group_get_course_user_groups"] = `{"groups":[{"id":11,"name":"Tax"}],"warnings":[]}`
+	if ok, err := api.IsModuleAvailableTo(100, 5); err != nil || ok {
+		t.Errorf("Expected module to be restricted to members of the grouping, found %v %v", ok, err)
+	}
+
+	r := &Restriction{OP: "&", C: []RestrictionC{{Type: "grouping", Id: 5}}}
+	all, _ := api.GetCourseGroupings(3)
+	if r.IsRestrictedForGroupings([]CourseGroup{{Id: 11}}, all) || !r.IsRestrictedForGroupings([]CourseGroup{{Id: 10}}, all) {
+		t.Errorf("Expected grouping condition to be met only by members of its groups")
+	}
+}
@@ -0,0 +1,187 @@
+package moodle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// CachedResponse is a response kept for a conditional request. Only
+// responses with an ETag or Last-Modified header are cached.
+type CachedResponse struct {
+	Body         string
+	ETag         string
+	LastModified string
+}
+
+// ResponseCache stores responses for conditional requests. Implementations
+// must be safe for concurrent use.
+type ResponseCache interface {
+	Get(key string) (CachedResponse, bool)
+	Set(key string, response CachedResponse)
+}
+
+// MemoryCache is a ResponseCache held in memory
+type MemoryCache struct {
+	mu        sync.Mutex
+	responses map[string]CachedResponse
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{responses: make(map[string]CachedResponse)}
+}
+
+func (c *MemoryCache) Get(key string) (CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.responses[key]
+	return r, ok
+}
+
+func (c *MemoryCache) Set(key string, response CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = response
+}
+
+// HeaderLookupUrl is implemented by a LookupUrl that can return the
+// response headers, which are needed to make conditional requests.
+type HeaderLookupUrl interface {
+	DoWithHeader(method, url string, form url.Values, header http.Header) (string, int, http.Header, error)
+}
+
+// SetCache enables conditional requests. Responses to functions that read
+// data, such as core_enrol_get_enrolled_users, are cached when the server
+// returns an ETag or Last-Modified header, and the same request is sent
+// with If-None-Match or If-Modified-Since. If the server replies 304 Not
+// Modified the cached response is used. Moodle does not send these headers
+// itself, but a caching proxy in front of moodle may.
+func (m *MoodleApi) SetCache(cache ResponseCache) {
+	m.cache = cache
+}
+
+// readOnlyFunctions lists the web service functions wrapped by this package
+// that only read data. Calls to them are cached, coalesced and retried, and
+// never queued. Calls to any other function are treated as changing data.
+var readOnlyFunctions = map[string]bool{
+	"block_recentlyaccesseditems_get_recent_items":                true,
+	"core_auth_get_signup_settings":                               true,
+	"core_cohort_get_cohort_members":                              true,
+	"core_cohort_get_cohorts":                                     true,
+	"core_comment_get_comments":                                   true,
+	"core_competency_list_competencies":                           true,
+	"core_competency_list_competency_frameworks":                  true,
+	"core_competency_list_course_competencies":                    true,
+	"core_completion_get_activities_completion_status":            true,
+	"core_course_get_categories":                                  true,
+	"core_course_get_contents":                                    true,
+	"core_course_get_course_module":                               true,
+	"core_course_get_course_module_by_instance":                   true,
+	"core_course_get_courses":                                     true,
+	"core_course_get_courses_by_field":                            true,
+	"core_course_get_enrolled_courses_by_timeline_classification": true,
+	"core_course_search_courses":                                  true,
+	"core_enrol_get_enrolled_users":                               true,
+	"core_enrol_get_enrolled_users_with_capability":               true,
+	"core_enrol_get_users_courses":                                true,
+	"core_enrol_search_users":                                     true,
+	"core_group_get_course_groupings":                             true,
+	"core_group_get_course_groups":                                true,
+	"core_group_get_course_user_groups":                           true,
+	"core_group_get_groupings":                                    true,
+	"core_rating_get_item_ratings":                                true,
+	"core_reportbuilder_list_reports":                             true,
+	"core_reportbuilder_retrieve_report":                          true,
+	"core_user_get_user_devices":                                  true,
+	"core_user_get_users":                                         true,
+	"core_user_get_users_by_field":                                true,
+	"core_webservice_get_site_info":                               true,
+	"gradereport_user_get_grade_items":                            true,
+	"mod_assign_get_assignments":                                  true,
+	"mod_assign_get_grades":                                       true,
+	"mod_assign_get_submission_status":                            true,
+	"mod_assign_get_submissions":                                  true,
+	"mod_assign_get_user_flags":                                   true,
+	"mod_assign_list_participants":                                true,
+	"mod_forum_get_forum_discussions":                             true,
+	"mod_forum_get_forums_by_courses":                             true,
+	"mod_quiz_get_attempt_review":                                 true,
+	"mod_quiz_get_quizzes_by_courses":                             true,
+	"mod_quiz_get_user_attempts":                                  true,
+	"tool_certification_get_certification_users":                  true,
+	"tool_certification_get_certifications":                       true,
+	"tool_lp_data_for_user_competency_summary_in_course":          true,
+	"tool_mobile_get_config":                                      true,
+	"tool_mobile_get_public_config":                               true,
+	"tool_policy_get_user_acceptances":                            true,
+	"tool_program_get_program_users":                              true,
+	"tool_program_get_programs":                                   true,
+	"tool_tenant_get_tenants":                                     true,
+}
+
+// readOnly reports whether a call to function only reads data
+func (m *MoodleApi) readOnly(function string) bool {
+	return readOnlyFunctions[function] || m.readOnlyFunctions[function]
+}
+
+// SetReadOnlyFunctions marks functions this package does not wrap, such as
+// those of local plugins invoked with Call, as only reading data. Their
+// responses may then be cached, identical calls in progress share a
+// response, failed calls are retried, and calls are never queued while moodle
+// is unreachable.
+func (m *MoodleApi) SetReadOnlyFunctions(functions ...string) {
+	readOnly := make(map[string]bool, len(m.readOnlyFunctions)+len(functions))
+	for f := range m.readOnlyFunctions {
+		readOnly[f] = true
+	}
+	for _, f := range functions {
+		readOnly[f] = true
+	}
+	m.readOnlyFunctions = readOnly
+}
+
+// cacheKey identifies a request by its function and parameters. The token
+// is excluded so that it is not held by the cache.
+func cacheKey(function string, params url.Values) string {
+	p := url.Values{}
+	for k, v := range params {
+		if k != "wstoken" {
+			p[k] = v
+		}
+	}
+	return function + "?" + p.Encode()
+}
+
+// scopedCacheKey identifies a request made to the site with the credentials
+// of the api, so that sites and users sharing a cache do not share
+// responses. The function comes first so that InvalidateCache can remove
+// every response to a function. Returns false if the user of the
+// credentials is not known, and the response must not be cached.
+func (m *MoodleApi) scopedCacheKey(function string, params url.Values) (string, bool) {
+	var identity string
+	switch c := m.credentials.(type) {
+	case TokenCredentials:
+		identity = "token:" + string(c)
+	case *LoginCredentials:
+		identity = "login:" + c.username
+	case *BearerCredentials:
+		identity = c.identity()
+	}
+	if identity == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(m.base + "\n" + identity))
+	return cacheKey(function, params) + "#" + hex.EncodeToString(sum[:8]), true
+}
+
+// conditionalHeaders adds the validators of a cached response to a request
+func conditionalHeaders(header http.Header, cached *CachedResponse) {
+	if cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		header.Set("If-Modified-Since", cached.LastModified)
+	}
+}
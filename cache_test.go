@@ -0,0 +1,59 @@
+package moodle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[{"id":7,"username":"jsmith"}]`))
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "token")
+	api.SetCache(NewMemoryCache())
+
+	for i := 0; i < 3; i++ {
+		people, err := api.GetCourseRoles(3)
+		if err != nil {
+			t.Fatalf("GetCourseRoles failed: %v", err)
+		}
+		if len(people) != 1 || people[0].Username != "jsmith" {
+			t.Errorf("Unexpected people: %+v", people)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("Expected two of three requests to be not modified, found %d of %d", notModified, requests)
+	}
+
+	// Different parameters are cached separately
+	api.GetCourseRoles(4)
+	if notModified != 2 {
+		t.Errorf("Expected a request for another course not to use the cache")
+	}
+
+	// Functions that change data are never conditional
+	api.SetRole(7, 5, 3)
+	api.SetRole(7, 5, 3)
+	if notModified != 2 {
+		t.Errorf("Expected SetRole not to be conditional")
+	}
+
+	// Responses seen with another token are not shared
+	api.WithToken("other").GetCourseRoles(3)
+	if notModified != 2 {
+		t.Errorf("Expected a request with another token not to use the cache")
+	}
+}
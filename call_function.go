@@ -0,0 +1,161 @@
+package moodle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Call invokes any moodle web service function, including functions this
+// package does not wrap. The token, response format, logging, caching and
+// error handling are the same as for the wrapped functions. Functions this
+// package does not wrap are treated as changing data unless they are passed
+// to SetReadOnlyFunctions.
+//
+// Params may be url.Values, which are sent unchanged, or any value that
+// encodes to a json object, such as a map or struct with json tags. Nested
+// objects and lists are encoded the way moodle expects, for example
+//
+//	map[string]interface{}{"users": []map[string]interface{}{{"id": 5, "suspended": true}}}
+//
+// is sent as users[0][id]=5&users[0][suspended]=1. The response is decoded
+// into result as json, unless result is a *string or *json.RawMessage which
+// receive the response unchanged. A nil result discards the response.
+//
+// If ctx is done before moodle responds Call returns ctx.Err() without
+// waiting for the call to finish.
+func (m *MoodleApi) Call(ctx context.Context, function string, params interface{}, result interface{}) error {
+	values, err := EncodeParams(params)
+	if err != nil {
+		return err
+	}
+	body, err := m.callContext(ctx, function, values)
+	if err != nil {
+		return err
+	}
+
+	switch r := result.(type) {
+	case nil:
+		return nil
+	case *string:
+		*r = body
+		return nil
+	case *json.RawMessage:
+		*r = json.RawMessage(body)
+		return nil
+	}
+	if err := json.Unmarshal([]byte(body), result); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+	return nil
+}
+
+// callContext is call, returning early if ctx is done before moodle responds
+func (m *MoodleApi) callContext(ctx context.Context, function string, params url.Values) (string, error) {
+	return withContext(ctx, func() (string, error) {
+		return m.call(function, params)
+	})
+}
+
+// withContext runs a call, returning early if ctx is done before it finishes
+func withContext(ctx context.Context, call func() (string, error)) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	type response struct {
+		body string
+		err  error
+	}
+	done := make(chan response, 1)
+	go func() {
+		body, err := call()
+		done <- response{body, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.body, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// EncodeParams converts web service parameters into the form moodle
+// expects, see Call.
+func EncodeParams(params interface{}) (url.Values, error) {
+	switch p := params.(type) {
+	case nil:
+		return url.Values{}, nil
+	case url.Values:
+		values := url.Values{}
+		for k, v := range p {
+			values[k] = append([]string(nil), v...)
+		}
+		return values, nil
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	fields, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Parameters must be an object, found %s", reflect.TypeOf(params))
+	}
+
+	values := url.Values{}
+	for _, name := range sortedKeys(fields) {
+		encodeParam(values, name, fields[name])
+	}
+	return values, nil
+}
+
+// encodeParam adds a decoded json value to the parameters, naming nested
+// values name[key] and name[index].
+func encodeParam(values url.Values, name string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		if v {
+			values.Set(name, "1")
+		} else {
+			values.Set(name, "0")
+		}
+	case json.Number:
+		values.Set(name, v.String())
+	case string:
+		values.Set(name, v)
+	case []interface{}:
+		for i, item := range v {
+			encodeParam(values, name+"["+strconv.Itoa(i)+"]", item)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			encodeParam(values, name+"["+key+"]", v[key])
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,67 @@
+package moodle
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_update_users":   `null`,
+		"core_course_get_courses":  `[{"id":3,"shortname":"ACC101"}]`,
+		"core_course_delete_thing": `{"exception":"webservice_access_exception","errorcode":"accessexception","message":"Access control exception"}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	type User struct {
+		Id        UserID `json:"id"`
+		Suspended bool   `json:"suspended"`
+		Auth      string `json:"auth,omitempty"`
+	}
+	err := api.Call(context.Background(), "core_user_update_users", map[string]interface{}{
+		"users": []User{{Id: 5, Suspended: true}, {Id: 6, Auth: "manual"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	r := fetch.last()
+	if r.Get("users[0][id]") != "5" || r.Get("users[0][suspended]") != "1" || r.Get("users[1][suspended]") != "0" || r.Get("users[1][auth]") != "manual" {
+		t.Errorf("Expected nested parameters to be encoded, found %v", r)
+	}
+	if _, ok := r["users[0][auth]"]; ok {
+		t.Errorf("Expected omitted fields not to be sent, found %v", r)
+	}
+	if r.Get("wstoken") != "token" || r.Get("moodlewsrestformat") != "json" {
+		t.Errorf("Expected token and format to be sent, found %v", r)
+	}
+
+	var courses []Course
+	if err := api.Call(nil, "core_course_get_courses", url.Values{"options[ids][0]": {"3"}}, &courses); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if len(courses) != 1 || courses[0].Code != "ACC101" || fetch.last().Get("options[ids][0]") != "3" {
+		t.Errorf("Expected response to be decoded, found %+v", courses)
+	}
+	var raw json.RawMessage
+	if err := api.Call(context.Background(), "core_course_get_courses", nil, &raw); err != nil || string(raw) != `[{"id":3,"shortname":"ACC101"}]` {
+		t.Errorf("Expected raw response, found %s %v", raw, err)
+	}
+
+	if err := api.Call(context.Background(), "core_course_delete_thing", nil, nil); err == nil {
+		t.Errorf("Expected moodle exception to be returned")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := api.Call(ctx, "core_course_get_courses", nil, nil); err != context.Canceled {
+		t.Errorf("Expected cancelled context to stop the call, found %v", err)
+	}
+
+	if _, err := EncodeParams([]int{1}); err == nil {
+		t.Errorf("Expected a list of parameters to be rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Moodle has no web service to read the capabilities of a role. Instead
+// these functions ask which people enrolled in a course have a capability,
+// such as "mod/assign:grade" or "moodle/grade:edit", which accounts for
+// role overrides in the course.
+
+// GetUsersWithCapability lists the people enrolled in a course who have a
+// capability in the course.
+func (m *MoodleApi) GetUsersWithCapability(courseId CourseID, capability string) ([]UserID, error) {
+	body, err := m.call("core_enrol_get_enrolled_users_with_capability", url.Values{
+		"coursecapabilities[0][courseid]":        {fmt.Sprint(courseId)},
+		"coursecapabilities[0][capabilities][0]": {capability},
+		"options[0][name]":                       {"userfields"},
+		"options[0][value]":                      {"id"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type User struct {
+		Id UserID `json:"id"`
+	}
+	type Result struct {
+		CourseId   CourseID `json:"courseid"`
+		Capability string   `json:"capability"`
+		Users      []User   `json:"users"`
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var ids []UserID
+	for _, r := range results {
+		if r.Capability != capability {
+			continue
+		}
+		for _, u := range r.Users {
+			ids = append(ids, u.Id)
+		}
+	}
+	return ids, nil
+}
+
+// HasCapability reports whether a person enrolled in a course has a
+// capability in the course.
+func (m *MoodleApi) HasCapability(courseId CourseID, userId UserID, capability string) (bool, error) {
+	ids, err := m.GetUsersWithCapability(courseId, capability)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if id == userId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RequireCapability checks that the web service user has a capability in a
+// course, so that an operation such as writing grades can be checked before
+// it is attempted. Site administrators have every capability. The error wraps
+// ErrPermissionDenied and names the missing capability.
+//
+// Only the capabilities of people enrolled in the course can be read. A web
+// service user given a role in the system or a category, but not enrolled in
+// the course, can not be checked, and no error is returned.
+func (m *MoodleApi) RequireCapability(courseId CourseID, capability string) error {
+	info, err := m.getSiteInfo()
+	if err != nil {
+		return err
+	}
+	if info.IsAdmin {
+		return nil
+	}
+	ok, err := m.HasCapability(courseId, info.UserId, capability)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	courses, err := m.GetPersonCourseList(info.UserId)
+	if err != nil {
+		return err
+	}
+	for _, c := range courses {
+		if c.MoodleId == courseId {
+			return wrapError(fmt.Sprintf("Web service user %s does not have the capability %s in course %d", info.Username, capability, courseId), ErrPermissionDenied)
+		}
+	}
+	m.debug("Unable to check the capability %s of web service user %s, who is not enrolled in course %d", capability, info.Username, courseId)
+	return nil
+}
@@ -0,0 +1,50 @@
+package moodle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+
+	responses := map[string]string{
+		"core_enrol_get_enrolled_users_with_capability": `[{"courseid":3,"capability":"mod/assign:grade","users":[{"id":2},{"id":9}]}]`,
+		"core_webservice_get_site_info":                 `{"sitename":"Test","username":"ws","userid":5,"userissiteadmin":false,"functions":[]}`,
+		"core_enrol_get_users_courses":                  `[{"id":3,"shortname":"ACC101"}]`,
+	}
+	fetch := newTestLookupUrl(responses)
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	ids, err := api.GetUsersWithCapability(3, "mod/assign:grade")
+	if err != nil {
+		t.Fatalf("GetUsersWithCapability failed: %v", err)
+	}
+	if len(ids) != 2 || ids[1] != 9 {
+		t.Errorf("Unexpected people: %v", ids)
+	}
+	if r := fetch.last(); r.Get("coursecapabilities[0][courseid]") != "3" || r.Get("coursecapabilities[0][capabilities][0]") != "mod/assign:grade" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	if ok, err := api.HasCapability(3, 9, "mod/assign:grade"); err != nil || !ok {
+		t.Errorf("Expected person 9 to have the capability, found %v %v", ok, err)
+	}
+
+	err = api.RequireCapability(3, "mod/assign:grade")
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected web service user to lack the capability, found %v", err)
+	}
+
+	// A service account with a system role is not enrolled in the course
+	responses["core_enrol_get_users_courses"] = `[{"id":4,"shortname":"ACC102"}]`
+	if err := api.RequireCapability(3, "mod/assign:grade"); err != nil {
+		t.Errorf("Expected the capability of a web service user not enrolled in the course to be unknown, found %v", err)
+	}
+
+	responses["core_webservice_get_site_info"] = `{"sitename":"Test","username":"admin","userid":2,"userissiteadmin":true,"functions":[]}`
+	fetch.requests = nil
+	if err := api.RequireCapability(3, "moodle/grade:edit"); err != nil || len(fetch.requests) != 1 {
+		t.Errorf("Expected site administrators to have every capability, found %v", err)
+	}
+}
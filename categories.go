@@ -0,0 +1,100 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// CourseCategory is a category in the course category tree. Path lists the
+// ids of the category and its parents, such as "/1/4/9".
+type CourseCategory struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	IdNumber    string `json:"idnumber"`
+	Parent      int64  `json:"parent"`
+	Path        string `json:"path"`
+	Depth       int    `json:"depth"`
+	CourseCount int    `json:"coursecount"`
+	Visible     bool   `json:"-"`
+}
+
+// GetCategories fetches a course category. If recursive is set every
+// category beneath it is also returned.
+func (m *MoodleApi) GetCategories(categoryId int64, recursive bool) ([]CourseCategory, error) {
+	add := "0"
+	if recursive {
+		add = "1"
+	}
+	body, err := m.call("core_course_get_categories", url.Values{
+		"moodlewssettingraw": {"true"},
+		"criteria[0][key]":   {"id"},
+		"criteria[0][value]": {fmt.Sprint(categoryId)},
+		"addsubcategories":   {add},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		CourseCategory
+		Visible int `json:"visible"`
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	categories := make([]CourseCategory, 0, len(results))
+	for _, r := range results {
+		c := r.CourseCategory
+		c.Visible = r.Visible == 1
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+// GetCoursesInCategory lists the courses in a category, sorted by course
+// code. If recursive is set courses in every category beneath it are also
+// returned.
+func (m *MoodleApi) GetCoursesInCategory(categoryId int64, recursive bool) ([]Course, error) {
+	ids := []int64{categoryId}
+	if recursive {
+		categories, err := m.GetCategories(categoryId, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range categories {
+			if c.Id != categoryId {
+				ids = append(ids, c.Id)
+			}
+		}
+	}
+
+	var courses []Course
+	for _, id := range ids {
+		c, err := m.getCoursesByCategory(id)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, c...)
+	}
+	sort.Sort(ByCourseCode(courses))
+	return courses, nil
+}
+
+func (m *MoodleApi) getCoursesByCategory(categoryId int64) ([]Course, error) {
+	records, err := m.getCoursesByField("category", fmt.Sprint(categoryId))
+	if err != nil {
+		return nil, err
+	}
+	courses := make([]Course, 0, len(records))
+	for i := range records {
+		courses = append(courses, records[i].Course())
+	}
+	return courses, nil
+}
@@ -0,0 +1,57 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// categoryLookupUrl returns the courses of the requested category
+type categoryLookupUrl struct {
+	*testLookupUrl
+	courses map[string]string
+}
+
+func (c *categoryLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	c.responses["core_course_get_courses_by_field"] = c.courses[form.Get("value")]
+	return c.testLookupUrl.Do(method, u, form, header)
+}
+
+func TestGetCoursesInCategory(t *testing.T) {
+
+	fetch := &categoryLookupUrl{newTestLookupUrl(map[string]string{
+		"core_course_get_categories": `[{"id":4,"name":"Business","parent":1,"path":"/1/4","depth":2,"coursecount":1,"visible":1},{"id":9,"name":"Accounting","parent":4,"path":"/1/4/9","depth":3,"coursecount":2,"visible":0}]`,
+	}), map[string]string{
+		"4": `{"courses":[{"id":3,"shortname":"BUS101","fullname":"Business","startdate":1600000000,"enddate":0}],"warnings":[]}`,
+		"9": `{"courses":[{"id":7,"shortname":"ACC201","fullname":"Audit"},{"id":8,"shortname":"ACC101","fullname":"Accounting"}],"warnings":[]}`,
+	}}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	categories, err := api.GetCategories(4, true)
+	if err != nil {
+		t.Fatalf("GetCategories failed: %v", err)
+	}
+	if len(categories) != 2 || !categories[0].Visible || categories[1].Visible || categories[1].Path != "/1/4/9" {
+		t.Errorf("Unexpected categories: %+v", categories)
+	}
+	if r := fetch.last(); r.Get("criteria[0][value]") != "4" || r.Get("addsubcategories") != "1" {
+		t.Errorf("Expected sub categories to be requested, found %v", r)
+	}
+
+	courses, err := api.GetCoursesInCategory(4, true)
+	if err != nil {
+		t.Fatalf("GetCoursesInCategory failed: %v", err)
+	}
+	if len(courses) != 3 || courses[0].Code != "ACC101" || courses[2].Code != "BUS101" {
+		t.Errorf("Expected courses of both categories sorted by code, found %+v", courses)
+	}
+	if courses[2].Start == nil || courses[2].End != nil {
+		t.Errorf("Expected start date only, found %v %v", courses[2].Start, courses[2].End)
+	}
+
+	courses, err = api.GetCoursesInCategory(4, false)
+	if err != nil || len(courses) != 1 {
+		t.Errorf("Expected only the courses directly in the category, found %+v %v", courses, err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zaddok/moodle"
+)
+
+// StudentRole is the id of the student role in a default moodle installation
+const StudentRole = 5
+
+func userCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Usage: moodle user <username|email|id>")
+	}
+	var p *moodle.Person
+	var err error
+	if id, perr := strconv.ParseInt(args[0], 10, 64); perr == nil {
+		p, err = api.GetPersonByMoodleId(moodle.UserID(id))
+	} else if strings.Contains(args[0], "@") {
+		p, err = api.GetPersonByEmail(args[0])
+	} else {
+		p, err = api.GetPersonByUsername(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return errors.New("Moodle account not found: " + args[0])
+	}
+	return out.write(p,
+		[]string{"id", "username", "email", "firstname", "lastname", "lang"},
+		[][]string{{fmt.Sprint(p.MoodleId), p.Username, p.Email, p.FirstName, p.LastName, p.Lang}})
+}
+
+func enrolCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	return roleCommand("enrol", args, func(user, role, course int64) error {
+		return api.SetRole(moodle.UserID(user), moodle.RoleID(role), moodle.CourseID(course))
+	})
+}
+
+func unenrolCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	return roleCommand("unenrol", args, func(user, role, course int64) error {
+		return api.UnsetRole(moodle.UserID(user), moodle.RoleID(role), moodle.CourseID(course))
+	})
+}
+
+func roleCommand(name string, args []string, apply func(user, role, course int64) error) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	user := flags.Int64("user", 0, "moodle id of the person")
+	course := flags.Int64("course", 0, "moodle id of the course")
+	role := flags.Int64("role", StudentRole, "moodle id of the role")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireIds(flags, map[string]*int64{"user": user, "course": course, "role": role}); err != nil {
+		return err
+	}
+	return apply(*user, *role, *course)
+}
+
+func peopleCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	course, err := courseFlag("people", args)
+	if err != nil {
+		return err
+	}
+	people, err := api.GetCourseRoles(moodle.CourseID(course))
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, p := range people {
+		var roles, groups []string
+		for _, r := range p.Roles {
+			roles = append(roles, r.ShortName)
+		}
+		for _, g := range p.Groups {
+			groups = append(groups, g.Name)
+		}
+		rows = append(rows, []string{fmt.Sprint(p.Id), p.Username, p.Email, p.FirstName, p.LastName, strings.Join(roles, ";"), strings.Join(groups, ";")})
+	}
+	return out.write(people, []string{"id", "username", "email", "firstname", "lastname", "roles", "groups"}, rows)
+}
+
+func groupsCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	course, err := courseFlag("groups", args)
+	if err != nil {
+		return err
+	}
+	groups, err := api.GetCourseGroups(moodle.CourseID(course))
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, g := range groups {
+		rows = append(rows, []string{fmt.Sprint(g.Id), g.Name, g.Description})
+	}
+	return out.write(groups, []string{"id", "name", "description"}, rows)
+}
+
+func groupCreateCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	flags := flag.NewFlagSet("group-create", flag.ContinueOnError)
+	course := flags.Int64("course", 0, "moodle id of the course")
+	name := flags.String("name", "", "name of the group")
+	description := flags.String("description", "", "description of the group")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireIds(flags, map[string]*int64{"course": course}); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("Missing required flag: -name")
+	}
+	id, err := api.AddGroupToCourse(moodle.CourseID(*course), *name, *description)
+	if err != nil {
+		return err
+	}
+	group := moodle.CourseGroup{Id: id, Name: *name, Description: *description}
+	return out.write(group, []string{"id", "name", "description"}, [][]string{{fmt.Sprint(id), *name, *description}})
+}
+
+func groupAddCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	return groupMemberCommand("group-add", args, func(user, group int64) error {
+		return api.AddPersonToCourseGroup(moodle.UserID(user), moodle.GroupID(group))
+	})
+}
+
+func groupRemoveCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	return groupMemberCommand("group-remove", args, func(user, group int64) error {
+		return api.RemovePersonFromCourseGroup(moodle.UserID(user), moodle.GroupID(group))
+	})
+}
+
+func groupMemberCommand(name string, args []string, apply func(user, group int64) error) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	user := flags.Int64("user", 0, "moodle id of the person")
+	group := flags.Int64("group", 0, "moodle id of the group")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireIds(flags, map[string]*int64{"user": user, "group": group}); err != nil {
+		return err
+	}
+	return apply(*user, *group)
+}
+
+func gradesCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	course, err := courseFlag("grades", args)
+	if err != nil {
+		return err
+	}
+	gradebook, err := api.GetCourseGradebook(moodle.CourseID(course))
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, entry := range gradebook {
+		for _, item := range entry.Item {
+			if item.ItemType == "course" || item.ItemType == "category" {
+				continue
+			}
+			rows = append(rows, []string{
+				fmt.Sprint(entry.UserId), entry.Name,
+				fmt.Sprint(item.Id), item.ItemName, item.ItemModule,
+				formatFloat(item.GradeRaw), formatFloat(item.GradeMax), item.PercentageFormatted,
+				formatTime(item.Graded()),
+			})
+		}
+	}
+	return out.write(gradebook, []string{"userid", "name", "itemid", "item", "module", "grade", "grademax", "percentage", "graded"}, rows)
+}
+
+func deadlinesCommand(api *moodle.MoodleApi, out *output, args []string) error {
+	flags := flag.NewFlagSet("deadlines", flag.ContinueOnError)
+	courses := flags.String("course", "", "comma separated moodle ids of the courses")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIds(*courses)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return errors.New("Missing required flag: -course")
+	}
+	var courseIds []moodle.CourseID
+	for _, id := range ids {
+		courseIds = append(courseIds, moodle.CourseID(id))
+	}
+
+	var deadlines []moodle.Deadline
+	assignments, err := api.GetAssignmentsForCourses(courseIds)
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		deadlines = append(deadlines, a.Deadline())
+	}
+	quizzes, err := api.GetQuizzesForCourses(courseIds)
+	if err != nil {
+		return err
+	}
+	for _, q := range quizzes {
+		deadlines = append(deadlines, q.Deadline())
+	}
+	forums, err := api.GetForumsForCourses(courseIds)
+	if err != nil {
+		return err
+	}
+	for _, f := range forums {
+		if f.DueDate != nil || f.CutoffDate != nil {
+			deadlines = append(deadlines, f.Deadline())
+		}
+	}
+
+	var rows [][]string
+	for _, d := range deadlines {
+		rows = append(rows, []string{
+			fmt.Sprint(d.CourseId), d.ActivityType, fmt.Sprint(d.InstanceId), fmt.Sprint(d.CmId), d.Name,
+			formatTime(d.Open), formatTime(d.Due), formatTime(d.Cutoff),
+		})
+	}
+	return out.write(deadlines, []string{"courseid", "type", "id", "cmid", "name", "open", "due", "cutoff"}, rows)
+}
+
+// courseFlag reads the -course flag of commands that act on one course
+func courseFlag(name string, args []string) (int64, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	course := flags.Int64("course", 0, "moodle id of the course")
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+	return *course, requireIds(flags, map[string]*int64{"course": course})
+}
@@ -0,0 +1,128 @@
+// Command moodle runs common administration tasks against a moodle site
+// using the moodle web service api.
+//
+//	export MOODLE_URL=https://moodle.example.com/
+//	export MOODLE_TOKEN=a0092ba9a9f5b45cdd2f01d049595bfe91
+//	moodle user jsmith
+//	moodle enrol -user 12 -course 3 -role 5
+//	moodle grades -course 3 -format csv > grades.csv
+//
+// Results are written as json, or as csv with -format csv.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zaddok/moodle"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(api *moodle.MoodleApi, out *output, args []string) error
+}
+
+var commands = map[string]command{
+	"user":         {"<username|email|id>", "Show a moodle account", userCommand},
+	"enrol":        {"-user id -course id [-role id]", "Enrol a person in a course", enrolCommand},
+	"unenrol":      {"-user id -course id [-role id]", "Remove a person from a course", unenrolCommand},
+	"people":       {"-course id", "List the people enrolled in a course", peopleCommand},
+	"groups":       {"-course id", "List the groups in a course", groupsCommand},
+	"group-create": {"-course id -name name [-description text]", "Create a group in a course", groupCreateCommand},
+	"group-add":    {"-user id -group id", "Add a person to a group", groupAddCommand},
+	"group-remove": {"-user id -group id", "Remove a person from a group", groupRemoveCommand},
+	"grades":       {"-course id", "Export the gradebook of a course", gradesCommand},
+	"deadlines":    {"-course id[,id...]", "List assignment, quiz and forum deadlines", deadlinesCommand},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	global := flag.NewFlagSet("moodle", flag.ContinueOnError)
+	base := global.String("url", os.Getenv("MOODLE_URL"), "moodle site url, defaults to $MOODLE_URL")
+	token := global.String("token", os.Getenv("MOODLE_TOKEN"), "web service token, defaults to $MOODLE_TOKEN")
+	format := global.String("format", "json", "output format, json or csv")
+	global.Usage = func() { usage(global) }
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	if global.NArg() == 0 {
+		usage(global)
+		return errors.New("No command specified")
+	}
+	name := global.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		usage(global)
+		return errors.New("Unknown command: " + name)
+	}
+	if *base == "" || *token == "" {
+		return errors.New("The moodle url and token must be set with -url and -token, or MOODLE_URL and MOODLE_TOKEN")
+	}
+	out, err := newOutput(w, *format)
+	if err != nil {
+		return err
+	}
+
+	api := moodle.NewMoodleApi(*base, *token)
+	api.SetUserAgent("moodle-cli/" + moodle.Version)
+	return cmd.run(api, out, global.Args()[1:])
+}
+
+func usage(global *flag.FlagSet) {
+	w := global.Output()
+	fmt.Fprintf(w, "Usage: moodle [-url url] [-token token] [-format json|csv] command [arguments]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-13s %s\n  %-13s   %s\n", name, commands[name].description, "", commands[name].usage)
+	}
+	fmt.Fprintf(w, "\nOptions:\n")
+	global.PrintDefaults()
+}
+
+// parseIds reads a comma separated list of ids
+func parseIds(value string) ([]int64, error) {
+	var ids []int64
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("Invalid id: " + v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// requireIds checks each named flag has been set
+func requireIds(flags *flag.FlagSet, ids map[string]*int64) error {
+	for name, id := range ids {
+		if *id <= 0 {
+			return errors.New("Missing required flag: -" + name)
+		}
+	}
+	if flags.NArg() > 0 {
+		return errors.New("Unexpected argument: " + flags.Arg(0))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+
+	responses := map[string]string{
+		"core_user_get_users_by_field":     `[{"id":7,"username":"jsmith","email":"jane@example.com","firstname":"Jane","lastname":"Smith","lang":"en"}]`,
+		"core_group_get_course_groups":     `[{"id":4,"name":"Tutorial A","description":""}]`,
+		"enrol_manual_enrol_users":         `null`,
+		"gradereport_user_get_grade_items": `{"usergrades":[{"userid":7,"userfullname":"Jane Smith","gradeitems":[{"id":1,"itemname":"Essay","itemtype":"mod","itemmodule":"assign","graderaw":8,"grademax":10,"percentageformatted":"80.00 %"},{"id":2,"itemtype":"course"}]}]}`,
+	}
+	var functions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		function := r.FormValue("wsfunction")
+		functions = append(functions, function)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(responses[function]))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-format", "csv", "user", "jsmith"}, "id,username,email,firstname,lastname,lang\n7,jsmith,jane@example.com,Jane,Smith,en\n"},
+		{[]string{"-format", "csv", "groups", "-course", "3"}, "id,name,description\n4,Tutorial A,\n"},
+		{[]string{"-format", "csv", "grades", "-course", "3"}, "userid,name,itemid,item,module,grade,grademax,percentage,graded\n7,Jane Smith,1,Essay,assign,8,10,80.00 %,\n"},
+		{[]string{"enrol", "-user", "7", "-course", "3"}, ""},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		args := append([]string{"-url", server.URL, "-token", "token"}, test.args...)
+		if err := run(args, &out); err != nil {
+			t.Errorf("%v failed: %v", test.args, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf("%v: expected %q, found %q", test.args, test.expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-url", server.URL, "-token", "token", "user", "jsmith"}, &out); err != nil || !strings.Contains(out.String(), `"Username": "jsmith"`) {
+		t.Errorf("Expected json output, found %q %v", out.String(), err)
+	}
+
+	if err := run([]string{"-url", server.URL, "-token", "token", "enrol", "-course", "3"}, &out); err == nil || !strings.Contains(err.Error(), "-user") {
+		t.Errorf("Expected missing -user to fail, found %v", err)
+	}
+	if err := run([]string{"-url", server.URL, "-token", "token", "unknown"}, &out); err == nil {
+		t.Errorf("Expected an unknown command to fail")
+	}
+}
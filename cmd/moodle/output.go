@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// output writes results as json or csv. Json output contains the values
+// returned by the api, csv output contains a header and a row per result.
+type output struct {
+	w   io.Writer
+	csv bool
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case "json", "":
+		return &output{w: w}, nil
+	case "csv":
+		return &output{w: w, csv: true}, nil
+	}
+	return nil, errors.New("Unknown output format: " + format)
+}
+
+// write outputs a value as json, or the header and rows as csv
+func (o *output) write(value interface{}, header []string, rows [][]string) error {
+	if !o.csv {
+		e := json.NewEncoder(o.w)
+		e.SetIndent("", "  ")
+		return e.Encode(value)
+	}
+	c := csv.NewWriter(o.w)
+	c.Write(header)
+	c.WriteAll(rows)
+	return c.Error()
+}
+
+// formatTime formats an optional time for csv output
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%g", f)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generator writes the go source for a set of functions
+type generator struct {
+	buf     bytes.Buffer
+	types   bytes.Buffer
+	usesFmt bool
+	vars    int
+}
+
+func generate(pkg string, functions []*wsFunction) ([]byte, error) {
+	g := &generator{}
+	for _, f := range functions {
+		g.function(f)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by wsgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	fmt.Fprintf(&out, "\t\"encoding/json\"\n\t\"errors\"\n")
+	if g.usesFmt {
+		fmt.Fprintf(&out, "\t\"fmt\"\n")
+	}
+	fmt.Fprintf(&out, "\t\"net/url\"\n)\n")
+	out.Write(g.types.Bytes())
+	out.Write(g.buf.Bytes())
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("Generated code is invalid: %w", err)
+	}
+	return code, nil
+}
+
+func (g *generator) function(f *wsFunction) {
+	name := goName(f.Name)
+
+	params := ""
+	if len(f.Args) > 0 {
+		params = "params *" + name + "Params"
+		g.structType(name+"Params", "are the parameters of "+f.Name, f.Args)
+	}
+
+	result := ""
+	if f.Response != nil {
+		result = g.goType(name+"Result", f.Response)
+	}
+
+	fmt.Fprintf(&g.buf, "\n// %s calls %s.", name, f.Name)
+	if f.Description != "" {
+		fmt.Fprintf(&g.buf, " %s", comment(f.Description))
+	}
+	fmt.Fprintf(&g.buf, "\n")
+	if result != "" {
+		fmt.Fprintf(&g.buf, "func (m *MoodleApi) %s(%s) (%s, error) {\n", name, params, result)
+	} else {
+		fmt.Fprintf(&g.buf, "func (m *MoodleApi) %s(%s) error {\n", name, params)
+	}
+
+	fmt.Fprintf(&g.buf, "\tvalues := url.Values{}\n")
+	for _, a := range f.Args {
+		g.encode(a.Type, "params."+goName(a.Name), fmt.Sprintf("%q", a.Name))
+	}
+
+	if result == "" {
+		fmt.Fprintf(&g.buf, "\t_, err := m.call(%q, values)\n\treturn err\n}\n", f.Name)
+		return
+	}
+	fmt.Fprintf(&g.buf, "\tvar result %s\n", result)
+	fmt.Fprintf(&g.buf, "\tbody, err := m.call(%q, values)\n", f.Name)
+	fmt.Fprintf(&g.buf, "\tif err != nil {\n\t\treturn result, err\n\t}\n")
+	fmt.Fprintf(&g.buf, "\tif err := json.Unmarshal([]byte(body), &result); err != nil {\n")
+	fmt.Fprintf(&g.buf, "\t\treturn result, errors.New(\"Server returned unexpected response. \" + err.Error())\n\t}\n")
+	fmt.Fprintf(&g.buf, "\treturn result, nil\n}\n")
+}
+
+// goType returns the go type of a structure, declaring any struct types
+// needed using the name given.
+func (g *generator) goType(name string, t *wsType) string {
+	switch t.Kind {
+	case "object":
+		g.structType(name, "", t.Fields)
+		return name
+	case "list":
+		return "[]" + g.goType(name, t.Elem)
+	}
+	switch t.Type {
+	case "int":
+		return "int64"
+	case "double", "float":
+		return "float64"
+	case "bool":
+		return "bool"
+	}
+	return "string"
+}
+
+func (g *generator) structType(name, description string, fields []wsField) {
+	var body bytes.Buffer
+	for i, f := range fields {
+		typ := g.goType(name+goName(f.Name), f.Type)
+		if f.Type.Description != "" {
+			if i > 0 {
+				body.WriteString("\n")
+			}
+			fmt.Fprintf(&body, "\t// %s\n", comment(f.Type.Description))
+		}
+		tag := f.Name
+		if f.Type.Optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&body, "\t%s %s `json:\"%s\"`\n", goName(f.Name), typ, tag)
+	}
+	fmt.Fprintf(&g.types, "\n")
+	if description != "" {
+		fmt.Fprintf(&g.types, "// %s %s\n", name, description)
+	}
+	fmt.Fprintf(&g.types, "type %s struct {\n%s}\n", name, body.String())
+}
+
+// encode writes the statements that add a value to the request parameters,
+// using moodle's key[0][field] form for lists and objects.
+func (g *generator) encode(t *wsType, expr, key string) {
+	switch t.Kind {
+	case "object":
+		for _, f := range t.Fields {
+			g.encode(f.Type, expr+"."+goName(f.Name), key+fmt.Sprintf(" + %q", "["+f.Name+"]"))
+		}
+	case "list":
+		g.vars++
+		i, v := fmt.Sprintf("i%d", g.vars), fmt.Sprintf("v%d", g.vars)
+		g.usesFmt = true
+		fmt.Fprintf(&g.buf, "\tfor %s, %s := range %s {\n", i, v, expr)
+		g.encode(t.Elem, v, key+` + "[" + fmt.Sprint(`+i+`) + "]"`)
+		fmt.Fprintf(&g.buf, "\t}\n")
+	default:
+		value := expr
+		zero := `""`
+		switch g.goType("", t) {
+		case "string":
+		case "bool":
+			// Moodle expects booleans as 1 or 0
+			if t.Optional {
+				fmt.Fprintf(&g.buf, "\tif %s {\n\t\tvalues.Set(%s, \"1\")\n\t}\n", expr, key)
+			} else {
+				fmt.Fprintf(&g.buf, "\tvalues.Set(%s, \"0\")\n\tif %s {\n\t\tvalues.Set(%s, \"1\")\n\t}\n", key, expr, key)
+			}
+			return
+		default:
+			zero = "0"
+			g.usesFmt = true
+			value = "fmt.Sprint(" + expr + ")"
+		}
+		if t.Optional {
+			fmt.Fprintf(&g.buf, "\tif %s != %s {\n\t", expr, zero)
+		}
+		fmt.Fprintf(&g.buf, "\tvalues.Set(%s, %s)\n", key, value)
+		if t.Optional {
+			fmt.Fprintf(&g.buf, "\t}\n")
+		}
+	}
+}
+
+// goName converts a moodle name such as core_user_get_users into a go name
+// such as CoreUserGetUsers.
+func goName(name string) string {
+	var s strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }) {
+		s.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if s.Len() == 0 {
+		return "X"
+	}
+	return s.String()
+}
+
+// comment flattens a description onto one line
+func comment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
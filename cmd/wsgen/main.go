@@ -0,0 +1,134 @@
+// Command wsgen generates typed wrappers for moodle web service functions.
+//
+// Moodle documents the parameters and response of each web service function
+// at Site administration > Server > Web services > API Documentation
+// (admin/webservice/documentation.php). Save that page, and optionally the
+// response of core_webservice_get_site_info, then generate wrappers for the
+// functions needed:
+//
+//	wsgen -doc documentation.html -siteinfo siteinfo.json \
+//		-functions core_user_get_users_by_field,core_group_get_course_groups \
+//		-o generated_wrappers.go
+//
+// If -functions is not set, every documented function enabled for the web
+// service user (as listed by -siteinfo) is generated. The output is a file
+// in package moodle containing a struct for each parameter and response,
+// and a MoodleApi method that calls the function.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+func main() {
+	doc := flag.String("doc", "", "saved moodle API documentation page")
+	siteInfo := flag.String("siteinfo", "", "saved core_webservice_get_site_info response, used to check functions are enabled")
+	functions := flag.String("functions", "", "comma separated functions to generate, defaults to all enabled functions")
+	pkg := flag.String("package", "moodle", "package name of the generated code")
+	out := flag.String("o", "", "output file, defaults to standard output")
+	flag.Parse()
+
+	if err := run(*doc, *siteInfo, *functions, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(docFile, siteInfoFile, functions, pkg, out string) error {
+	if docFile == "" {
+		return errors.New("The documentation page must be set with -doc")
+	}
+	data, err := ioutil.ReadFile(docFile)
+	if err != nil {
+		return err
+	}
+	documented, err := parseDocumentation(string(data))
+	if err != nil {
+		return err
+	}
+
+	var enabled map[string]bool
+	if siteInfoFile != "" {
+		data, err := ioutil.ReadFile(siteInfoFile)
+		if err != nil {
+			return err
+		}
+		if enabled, err = parseSiteInfo(data); err != nil {
+			return err
+		}
+	}
+
+	selected, err := selectFunctions(documented, enabled, functions)
+	if err != nil {
+		return err
+	}
+
+	code, err := generate(pkg, selected)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
+
+// parseSiteInfo returns the functions enabled for the web service user
+func parseSiteInfo(data []byte) (map[string]bool, error) {
+	type Function struct {
+		Name string `json:"name"`
+	}
+	type SiteInfo struct {
+		Functions []Function `json:"functions"`
+	}
+	var info SiteInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, errors.New("Site info is not valid json. " + err.Error())
+	}
+	enabled := make(map[string]bool)
+	for _, f := range info.Functions {
+		enabled[f.Name] = true
+	}
+	return enabled, nil
+}
+
+// selectFunctions picks the documented functions to generate. Named
+// functions must be documented, and enabled if the site info is known.
+func selectFunctions(documented []*wsFunction, enabled map[string]bool, names string) ([]*wsFunction, error) {
+	var selected []*wsFunction
+	if strings.TrimSpace(names) == "" {
+		for _, f := range documented {
+			if enabled == nil || enabled[f.Name] {
+				selected = append(selected, f)
+			}
+		}
+		return selected, nil
+	}
+
+	byName := make(map[string]*wsFunction)
+	for _, f := range documented {
+		byName[f.Name] = f
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		f, ok := byName[name]
+		if !ok {
+			return nil, errors.New("Function is not documented: " + name)
+		}
+		if enabled != nil && !enabled[name] {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not enabled for the web service user\n", name)
+		}
+		selected = append(selected, f)
+	}
+	return selected, nil
+}
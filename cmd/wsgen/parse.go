@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// wsFunction is a web service function described by the moodle
+// documentation.
+type wsFunction struct {
+	Name        string
+	Description string
+	Args        []wsField
+	Response    *wsType
+}
+
+type wsField struct {
+	Name string
+	Type *wsType
+}
+
+// wsType is a parameter or response structure. Kind is "value", "object" or
+// "list".
+type wsType struct {
+	Kind        string
+	Type        string
+	Optional    bool
+	Description string
+	Fields      []wsField
+	Elem        *wsType
+}
+
+var (
+	tagPattern       = regexp.MustCompile(`(?s)<[^>]*>`)
+	breakPattern     = regexp.MustCompile(`(?i)<br\s*/?>|</(div|p|pre|h\d|li|tr|dt|dd)>`)
+	functionPattern  = regexp.MustCompile(`^[a-z][a-z0-9]*_[a-z0-9_]+$`)
+	argumentPattern  = regexp.MustCompile(`^(\w+) \((Required|Optional|Default to .*)\)$`)
+	structureEndings = []string{"XML-RPC", "REST", "Response", "Arguments", "Error message", "Restricted to", "General structure"}
+)
+
+// parseDocumentation reads the functions described by the moodle API
+// documentation page. The page describes each parameter and response with
+// a "General structure" such as:
+//
+//	list of (
+//	object {
+//	id int   //ID of the user
+//	username string  Optional //The username
+//	} )
+func parseDocumentation(page string) ([]*wsFunction, error) {
+	text := breakPattern.ReplaceAllString(page, "\n")
+	text = html.UnescapeString(tagPattern.ReplaceAllString(text, ""))
+
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimRight(l, " \t\r"))
+	}
+
+	var functions []*wsFunction
+	var f *wsFunction
+	var arg string
+	inResponse := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case functionPattern.MatchString(line) && nextHeading(lines, i+1) == "Arguments":
+			f = &wsFunction{Name: line}
+			functions = append(functions, f)
+			arg, inResponse = "", false
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "Arguments" {
+				i++
+				if d := strings.TrimSpace(lines[i]); d != "" && f.Description == "" {
+					f.Description = d
+				}
+			}
+		case f == nil:
+		case line == "Arguments":
+			inResponse = false
+		case line == "Response":
+			inResponse = true
+		case !inResponse && argumentPattern.MatchString(line):
+			arg = argumentPattern.FindStringSubmatch(line)[1]
+		case line == "General structure":
+			var block []string
+			for i+1 < len(lines) && !isStructureEnd(lines[i+1]) {
+				i++
+				block = append(block, lines[i])
+			}
+			t, err := parseStructure(strings.Join(block, "\n"))
+			if err != nil {
+				return nil, errors.New(f.Name + ": " + err.Error())
+			}
+			if inResponse {
+				f.Response = t
+			} else if arg != "" {
+				f.Args = append(f.Args, wsField{Name: arg, Type: t})
+				arg = ""
+			}
+		}
+	}
+	if len(functions) == 0 {
+		return nil, errors.New("No functions found in the documentation")
+	}
+	return functions, nil
+}
+
+// nextHeading returns the next non-empty line within a few lines
+func nextHeading(lines []string, i int) string {
+	for n := 0; n < 4 && i+n < len(lines); n++ {
+		if l := strings.TrimSpace(lines[i+n]); l == "Arguments" {
+			return l
+		}
+	}
+	return ""
+}
+
+func isStructureEnd(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, e := range structureEndings {
+		if strings.HasPrefix(line, e) {
+			return true
+		}
+	}
+	return argumentPattern.MatchString(line)
+}
+
+// parseStructure parses a general structure description. An empty structure,
+// as documented for functions that return null, returns nil.
+func parseStructure(s string) (*wsType, error) {
+	p := &structureParser{tokens: tokenize(s)}
+	if len(p.tokens) == 0 {
+		return nil, nil
+	}
+	t, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type structureParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *structureParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *structureParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *structureParser) expect(token string) error {
+	if t := p.next(); t != token {
+		return errors.New("Expected " + token + " but found " + t)
+	}
+	return nil
+}
+
+// modifiers reads the optional and default markers, and the comment, that
+// may precede or follow a type.
+func (p *structureParser) modifiers(t *wsType) {
+	for {
+		switch tok := p.peek(); {
+		case tok == "Optional":
+			t.Optional = true
+			p.next()
+		case tok == "Required":
+			p.next()
+		case tok == "Default":
+			// Parameters with a default may be omitted
+			t.Optional = true
+			p.next()
+			if p.peek() == "to" {
+				p.next()
+				p.next()
+			}
+		case strings.HasPrefix(tok, "//"):
+			if t.Description == "" {
+				t.Description = strings.TrimSpace(tok[2:])
+			}
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *structureParser) parseType() (*wsType, error) {
+	t := &wsType{}
+	p.modifiers(t)
+	switch tok := p.next(); tok {
+	case "object":
+		t.Kind = "object"
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for p.peek() != "}" {
+			name := p.next()
+			if name == "" {
+				return nil, errors.New("Unterminated object")
+			}
+			field, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			t.Fields = append(t.Fields, wsField{Name: name, Type: field})
+		}
+		p.next()
+	case "list":
+		t.Kind = "list"
+		if err := p.expect("of"); err != nil {
+			return nil, err
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		t.Elem = elem
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	case "int", "double", "float", "string", "bool", "raw":
+		t.Kind = "value"
+		t.Type = tok
+	default:
+		return nil, errors.New("Unexpected " + tok)
+	}
+	p.modifiers(t)
+	return t, nil
+}
+
+// tokenize splits a structure into words, brackets, quoted strings and
+// comments.
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, s[i:i+end])
+			i += end
+		case c == '{' || c == '}' || c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				tokens = append(tokens, s[i:])
+				i = len(s)
+			} else {
+				tokens = append(tokens, s[i:i+end+2])
+				i += end + 2
+			}
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r{}()\"", rune(s[i])) && !strings.HasPrefix(s[i:], "//") {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseDocumentation(t *testing.T) {
+
+	data, err := ioutil.ReadFile("testdata/documentation.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := parseDocumentation(string(data))
+	if err != nil {
+		t.Fatalf("parseDocumentation failed: %v", err)
+	}
+	if len(functions) != 2 {
+		t.Fatalf("Expected 2 functions, found %d", len(functions))
+	}
+
+	f := functions[0]
+	if f.Name != "core_user_get_users_by_field" || f.Description != "Retrieve users' information for a specified unique field." {
+		t.Errorf("Unexpected function: %s %q", f.Name, f.Description)
+	}
+	if len(f.Args) != 2 || f.Args[0].Name != "field" || f.Args[1].Type.Kind != "list" {
+		t.Errorf("Unexpected arguments: %+v", f.Args)
+	}
+	user := f.Response.Elem
+	if f.Response.Kind != "list" || user.Kind != "object" || len(user.Fields) != 4 {
+		t.Fatalf("Unexpected response: %+v", f.Response)
+	}
+	if custom := user.Fields[3]; custom.Name != "customfields" || !custom.Type.Optional || custom.Type.Elem == nil || len(custom.Type.Elem.Fields) != 3 {
+		t.Errorf("Unexpected customfields: %+v", custom.Type)
+	}
+	if functions[1].Response != nil {
+		t.Errorf("Expected a function without a response to have a nil response")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+
+	data, err := ioutil.ReadFile("testdata/documentation.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented, err := parseDocumentation(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := parseSiteInfo([]byte(`{"sitename":"Test","functions":[{"name":"core_group_add_group_members","version":"2020061500"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, err := selectFunctions(documented, enabled, "")
+	if err != nil || len(selected) != 1 || selected[0].Name != "core_group_add_group_members" {
+		t.Errorf("Expected only enabled functions to be selected, found %v %v", selected, err)
+	}
+	if _, err := selectFunctions(documented, enabled, "core_course_get_courses"); err == nil {
+		t.Errorf("Expected an undocumented function to be rejected")
+	}
+
+	code, err := generate("moodle", documented)
+	if err != nil {
+		t.Fatalf("generate failed: %v\n%s", err, code)
+	}
+	for _, expected := range []string{
+		"func (m *MoodleApi) CoreUserGetUsersByField(params *CoreUserGetUsersByFieldParams) ([]CoreUserGetUsersByFieldResult, error) {",
+		"Customfields []CoreUserGetUsersByFieldResultCustomfields `json:\"customfields,omitempty\"`",
+		`values.Set("members"+"["+fmt.Sprint(i2)+"]"+"[userid]", fmt.Sprint(v2.Userid))`,
+		"func (m *MoodleApi) CoreGroupAddGroupMembers(params *CoreGroupAddGroupMembersParams) error {",
+	} {
+		if !strings.Contains(string(code), expected) {
+			t.Errorf("Expected generated code to contain %q\n%s", expected, code)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Cohort is a site wide set of people, such as the students of an intake,
+// that can be enrolled in courses together
+type Cohort struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	IdNumber    string `json:"idnumber"`
+	Description string `json:"description"`
+	Visible     bool   `json:"visible"`
+}
+
+// cohortMembersBatchSize is the number of members added or removed by each
+// call
+const cohortMembersBatchSize = 100
+
+// CreateCohort adds a visible cohort to the system context, returning the
+// id of the new cohort. The idNumber may be blank, or used to hold the id
+// of the cohort in another system.
+func (m *MoodleApi) CreateCohort(name, idNumber, description string) (int64, error) {
+	if strings.TrimSpace(name) == "" {
+		return 0, errors.New("CreateCohort() requires a name")
+	}
+
+	body, err := m.call("core_cohort_create_cohorts", url.Values{
+		"cohorts[0][categorytype][type]":  {"system"},
+		"cohorts[0][categorytype][value]": {""},
+		"cohorts[0][name]":                {name},
+		"cohorts[0][idnumber]":            {idNumber},
+		"cohorts[0][description]":         {description},
+		"cohorts[0][visible]":             {"1"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var results []Cohort
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return 0, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if len(results) != 1 || results[0].Id == 0 {
+		return 0, errors.New("Server returned unexpected response. ID is missing.")
+	}
+	return results[0].Id, nil
+}
+
+// GetCohorts fetches the cohorts with the ids, or every cohort if no ids are
+// given
+func (m *MoodleApi) GetCohorts(ids ...int64) ([]Cohort, error) {
+	params := url.Values{"moodlewssettingraw": {"true"}}
+	for i, id := range ids {
+		params.Set(fmt.Sprintf("cohortids[%d]", i), fmt.Sprint(id))
+	}
+	body, err := m.call("core_cohort_get_cohorts", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Cohort
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	return results, nil
+}
+
+// GetCohortMembers lists the ids of the members of a cohort
+func (m *MoodleApi) GetCohortMembers(cohortId int64) ([]UserID, error) {
+	body, err := m.call("core_cohort_get_cohort_members", url.Values{
+		"cohortids[0]": {fmt.Sprint(cohortId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		CohortId int64    `json:"cohortid"`
+		UserIds  []UserID `json:"userids"`
+	}
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var ids []UserID
+	for _, r := range results {
+		ids = append(ids, r.UserIds...)
+	}
+	return ids, nil
+}
+
+// AddCohortMembers adds people to a cohort, using one call for every hundred
+// people. People who are already members are skipped by moodle.
+func (m *MoodleApi) AddCohortMembers(cohortId int64, userIds []UserID) error {
+	failed := 0
+	var first string
+	for start := 0; start < len(userIds); start += cohortMembersBatchSize {
+		end := start + cohortMembersBatchSize
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+		params := url.Values{}
+		for i, id := range userIds[start:end] {
+			prefix := fmt.Sprintf("members[%d]", i)
+			params.Set(prefix+"[cohorttype][type]", "id")
+			params.Set(prefix+"[cohorttype][value]", fmt.Sprint(cohortId))
+			params.Set(prefix+"[usertype][type]", "id")
+			params.Set(prefix+"[usertype][value]", fmt.Sprint(id))
+		}
+		body, err := m.call("core_cohort_add_cohort_members", params)
+		if err != nil {
+			return err
+		}
+
+		type Warning struct {
+			Item        string `json:"item"`
+			ItemId      int64  `json:"itemid"`
+			WarningCode string `json:"warningcode"`
+			Message     string `json:"message"`
+		}
+		type Result struct {
+			Warnings []Warning `json:"warnings"`
+		}
+
+		var result Result
+
+		if err := json.Unmarshal([]byte(body), &result); err != nil {
+			return errors.New("Server returned unexpected response. " + err.Error())
+		}
+		for _, w := range result.Warnings {
+			if failed == 0 {
+				first = w.Message
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%d of %d people could not be added to the cohort. %s", failed, len(userIds), first))
+	}
+	return nil
+}
+
+// RemoveCohortMembers removes people from a cohort, using one call for every
+// hundred people. Removing a person also removes their cohort enrolments.
+func (m *MoodleApi) RemoveCohortMembers(cohortId int64, userIds []UserID) error {
+	for start := 0; start < len(userIds); start += cohortMembersBatchSize {
+		end := start + cohortMembersBatchSize
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+		params := url.Values{}
+		for i, id := range userIds[start:end] {
+			params.Set(fmt.Sprintf("members[%d][cohortid]", i), fmt.Sprint(cohortId))
+			params.Set(fmt.Sprintf("members[%d][userid]", i), fmt.Sprint(id))
+		}
+		if _, err := m.call("core_cohort_delete_cohort_members", params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
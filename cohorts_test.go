@@ -0,0 +1,69 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestCohorts(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_cohort_create_cohorts":        `[{"id":4,"name":"2024 Intake","idnumber":"SIS-2024","description":"","descriptionformat":1,"visible":true}]`,
+		"core_cohort_get_cohorts":           `[{"id":4,"name":"2024 Intake","idnumber":"SIS-2024","description":"","descriptionformat":1,"visible":true},{"id":5,"name":"Alumni","idnumber":"","description":"<p>Graduates</p>","descriptionformat":1,"visible":false}]`,
+		"core_cohort_get_cohort_members":    `[{"cohortid":4,"userids":[7,8]}]`,
+		"core_cohort_add_cohort_members":    `{"warnings":[]}`,
+		"core_cohort_delete_cohort_members": `null`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	id, err := api.CreateCohort("2024 Intake", "SIS-2024", "")
+	if err != nil || id != 4 {
+		t.Fatalf("Expected cohort 4 to be created, found %d %v", id, err)
+	}
+	if r := fetch.last(); r.Get("cohorts[0][categorytype][type]") != "system" || r.Get("cohorts[0][idnumber]") != "SIS-2024" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	cohorts, err := api.GetCohorts()
+	if err != nil {
+		t.Fatalf("GetCohorts failed: %v", err)
+	}
+	if len(cohorts) != 2 || cohorts[0].IdNumber != "SIS-2024" || !cohorts[0].Visible || cohorts[1].Visible || cohorts[1].Description != "<p>Graduates</p>" {
+		t.Errorf("Unexpected cohorts: %+v", cohorts)
+	}
+	if _, err := api.GetCohorts(4, 5); err != nil || fetch.last().Get("cohortids[1]") != "5" {
+		t.Errorf("Expected the cohort ids to be requested, found %v %v", fetch.last(), err)
+	}
+
+	members, err := api.GetCohortMembers(4)
+	if err != nil || len(members) != 2 || members[1] != 8 {
+		t.Errorf("Expected two members, found %v %v", members, err)
+	}
+
+	fetch.requests = nil
+	var ids []UserID
+	for i := 1; i <= 150; i++ {
+		ids = append(ids, UserID(i))
+	}
+	if err := api.AddCohortMembers(4, ids); err != nil {
+		t.Fatalf("AddCohortMembers failed: %v", err)
+	}
+	if len(fetch.requests) != 2 {
+		t.Errorf("Expected two calls for 150 members, found %d", len(fetch.requests))
+	}
+	if r := fetch.last(); r.Get("members[49][cohorttype][value]") != "4" || r.Get("members[49][usertype][value]") != "150" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	fetch.responses["core_cohort_add_cohort_members"] = `{"warnings":[{"item":"user","itemid":9,"warningcode":"2","message":"User not found"}]}`
+	if err := api.AddCohortMembers(4, []UserID{9}); err == nil || err.Error() != "1 of 1 people could not be added to the cohort. User not found" {
+		t.Errorf("Expected the warning to be reported, found %v", err)
+	}
+
+	if err := api.RemoveCohortMembers(4, []UserID{7, 8}); err != nil {
+		t.Fatalf("RemoveCohortMembers failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("members[1][cohortid]") != "4" || r.Get("members[1][userid]") != "8" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+}
@@ -0,0 +1,100 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Comment is a comment left on a submission by a marker or student
+type Comment struct {
+	Id       int64
+	UserId   UserID
+	FullName string
+
+	// Content is html
+	Content string
+	Created time.Time
+}
+
+type commentResult struct {
+	Id          int64  `json:"id"`
+	UserId      UserID `json:"userid"`
+	FullName    string `json:"fullname"`
+	Content     string `json:"content"`
+	TimeCreated int64  `json:"timecreated"`
+}
+
+func (m *MoodleApi) comment(c commentResult) Comment {
+	return Comment{Id: c.Id, UserId: c.UserId, FullName: c.FullName, Content: c.Content, Created: m.unix(c.TimeCreated)}
+}
+
+// GetSubmissionComments lists the comments on an assignment submission,
+// oldest first. Requires the course module id of the assignment and the id
+// of the submission, as returned by GetAssignmentSubmissions.
+func (m *MoodleApi) GetSubmissionComments(cmid CmID, submissionId int64) ([]Comment, error) {
+	var comments []Comment
+	for page := 0; ; page++ {
+		body, err := m.call("core_comment_get_comments", url.Values{
+			"contextlevel":  {"module"},
+			"instanceid":    {fmt.Sprint(cmid)},
+			"component":     {"assignsubmission_comments"},
+			"itemid":        {fmt.Sprint(submissionId)},
+			"area":          {"submission_comments"},
+			"page":          {fmt.Sprint(page)},
+			"sortdirection": {"ASC"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		type Result struct {
+			Comments []commentResult `json:"comments"`
+			Count    int             `json:"count"`
+			PerPage  int             `json:"perpage"`
+		}
+
+		var result Result
+
+		if err := json.Unmarshal([]byte(body), &result); err != nil {
+			return nil, errors.New("Server returned unexpected response. " + err.Error())
+		}
+
+		for _, c := range result.Comments {
+			comments = append(comments, m.comment(c))
+		}
+		if len(result.Comments) == 0 || len(comments) >= result.Count || result.PerPage <= 0 || len(result.Comments) < result.PerPage {
+			return comments, nil
+		}
+	}
+}
+
+// AddSubmissionComment posts a comment on an assignment submission as the
+// token's user. Content is html.
+func (m *MoodleApi) AddSubmissionComment(cmid CmID, submissionId int64, content string) (*Comment, error) {
+	body, err := m.call("core_comment_add_comments", url.Values{
+		"comments[0][contextlevel]": {"module"},
+		"comments[0][instanceid]":   {fmt.Sprint(cmid)},
+		"comments[0][component]":    {"assignsubmission_comments"},
+		"comments[0][itemid]":       {fmt.Sprint(submissionId)},
+		"comments[0][area]":         {"submission_comments"},
+		"comments[0][content]":      {content},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []commentResult
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if len(results) != 1 {
+		return nil, errors.New("Server returned unexpected response: " + body)
+	}
+
+	c := m.comment(results[0])
+	return &c, nil
+}
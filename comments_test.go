@@ -0,0 +1,40 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestSubmissionComments(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_comment_get_comments": `{"comments":[{"id":1,"content":"<p>Please check the references</p>","format":1,"timecreated":1600000000,"fullname":"Terry Teacher","userid":3},{"id":2,"content":"<p>Fixed</p>","format":1,"timecreated":1600000100,"fullname":"Sam Student","userid":7}],"count":2,"perpage":15,"canpost":true,"warnings":[]}`,
+		"core_comment_add_comments": `[{"id":3,"content":"<p>Thanks</p>","format":1,"timecreated":1600000200,"fullname":"Terry Teacher","userid":3}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	comments, err := api.GetSubmissionComments(55, 100)
+	if err != nil {
+		t.Fatalf("GetSubmissionComments failed: %v", err)
+	}
+	if len(comments) != 2 || comments[1].UserId != 7 || comments[0].Created.Unix() != 1600000000 {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+	if len(fetch.requests) != 1 {
+		t.Errorf("Expected a single page to be fetched, found %d", len(fetch.requests))
+	}
+	if q := fetch.last(); q.Get("instanceid") != "55" || q.Get("itemid") != "100" || q.Get("component") != "assignsubmission_comments" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	comment, err := api.AddSubmissionComment(55, 100, "<p>Thanks</p>")
+	if err != nil {
+		t.Fatalf("AddSubmissionComment failed: %v", err)
+	}
+	if comment.Id != 3 || comment.Content != "<p>Thanks</p>" {
+		t.Errorf("Unexpected comment: %+v", comment)
+	}
+	if q := fetch.last(); q.Get("comments[0][content]") != "<p>Thanks</p>" || q.Get("comments[0][area]") != "submission_comments" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}
@@ -0,0 +1,245 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// CompetencyFramework is a framework of competencies, such as a set of
+// professional standards.
+type CompetencyFramework struct {
+	Id          int64
+	ShortName   string
+	IdNumber    string
+	Description string
+	Visible     bool
+	ScaleId     int64
+	Created     time.Time
+	Modified    time.Time
+}
+
+// Competency is a competency within a framework. Competencies form a tree,
+// ParentId is zero for the top level.
+type Competency struct {
+	Id          int64
+	FrameworkId int64
+	ParentId    int64
+	ShortName   string
+	IdNumber    string
+	Description string
+	SortOrder   int64
+	Path        string
+}
+
+// UserCompetency is the rating of a person for a competency in a course,
+// with the evidence recorded for the rating.
+type UserCompetency struct {
+	UserId       UserID
+	CompetencyId int64
+	CourseId     CourseID
+
+	// Proficient is nil if the person has not been rated
+	Proficient *bool
+
+	// Grade is the scale item of the rating, zero if not rated
+	Grade     int64
+	GradeName string
+
+	Evidence []CompetencyEvidence
+}
+
+// CompetencyEvidence records an action that affected a rating, such as the
+// completion of an activity or a rating by a teacher.
+type CompetencyEvidence struct {
+	Id           int64
+	Action       int64
+	ActionUserId UserID
+	Description  string
+	Grade        int64
+	GradeName    string
+	Note         string
+	Url          string
+	Created      time.Time
+}
+
+type competencyFrameworkResult struct {
+	Id           int64  `json:"id"`
+	ShortName    string `json:"shortname"`
+	IdNumber     string `json:"idnumber"`
+	Description  string `json:"description"`
+	Visible      bool   `json:"visible"`
+	ScaleId      int64  `json:"scaleid"`
+	TimeCreated  int64  `json:"timecreated"`
+	TimeModified int64  `json:"timemodified"`
+}
+
+type competencyResult struct {
+	Id                    int64  `json:"id"`
+	CompetencyFrameworkId int64  `json:"competencyframeworkid"`
+	ParentId              int64  `json:"parentid"`
+	ShortName             string `json:"shortname"`
+	IdNumber              string `json:"idnumber"`
+	Description           string `json:"description"`
+	SortOrder             int64  `json:"sortorder"`
+	Path                  string `json:"path"`
+}
+
+func (c *competencyResult) competency() Competency {
+	return Competency{Id: c.Id, FrameworkId: c.CompetencyFrameworkId, ParentId: c.ParentId, ShortName: c.ShortName, IdNumber: c.IdNumber, Description: c.Description, SortOrder: c.SortOrder, Path: c.Path}
+}
+
+// GetCompetencyFrameworks lists the competency frameworks of the site
+func (m *MoodleApi) GetCompetencyFrameworks() ([]CompetencyFramework, error) {
+	body, err := m.call("core_competency_list_competency_frameworks", url.Values{
+		"sort":                  {"shortname"},
+		"context[contextlevel]": {"system"},
+		"context[instanceid]":   {"0"},
+		"includes":              {"children"},
+		"onlyvisible":           {"0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []competencyFrameworkResult
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var frameworks []CompetencyFramework
+	for _, f := range results {
+		frameworks = append(frameworks, CompetencyFramework{
+			Id:          f.Id,
+			ShortName:   f.ShortName,
+			IdNumber:    f.IdNumber,
+			Description: f.Description,
+			Visible:     f.Visible,
+			ScaleId:     f.ScaleId,
+			Created:     m.unix(f.TimeCreated),
+			Modified:    m.unix(f.TimeModified),
+		})
+	}
+	return frameworks, nil
+}
+
+// GetCompetencies lists the competencies in a framework
+func (m *MoodleApi) GetCompetencies(frameworkId int64) ([]Competency, error) {
+	body, err := m.call("core_competency_list_competencies", url.Values{
+		"filters[0][column]": {"competencyframeworkid"},
+		"filters[0][value]":  {fmt.Sprint(frameworkId)},
+		"sort":               {"sortorder"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []competencyResult
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var competencies []Competency
+	for _, c := range results {
+		competencies = append(competencies, c.competency())
+	}
+	return competencies, nil
+}
+
+// GetCourseCompetencies lists the competencies linked to a course
+func (m *MoodleApi) GetCourseCompetencies(courseId CourseID) ([]Competency, error) {
+	body, err := m.call("core_competency_list_course_competencies", url.Values{
+		"id": {fmt.Sprint(courseId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Competency competencyResult `json:"competency"`
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var competencies []Competency
+	for _, c := range results {
+		competencies = append(competencies, c.Competency.competency())
+	}
+	return competencies, nil
+}
+
+// GetUserCompetencyInCourse fetches the rating of a person for a competency
+// in a course, and the evidence for the rating. Moodle only provides this
+// through the learning plans tool, so "tool_lp_data_for_user_competency_summary_in_course"
+// must be enabled for the web service.
+func (m *MoodleApi) GetUserCompetencyInCourse(userId UserID, competencyId int64, courseId CourseID) (*UserCompetency, error) {
+	body, err := m.call("tool_lp_data_for_user_competency_summary_in_course", url.Values{
+		"userid":       {fmt.Sprint(userId)},
+		"competencyid": {fmt.Sprint(competencyId)},
+		"courseid":     {fmt.Sprint(courseId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Evidence struct {
+		Id           int64  `json:"id"`
+		Action       int64  `json:"action"`
+		ActionUserId UserID `json:"actionuserid"`
+		Description  string `json:"description"`
+		Grade        int64  `json:"grade"`
+		GradeName    string `json:"gradename"`
+		Note         string `json:"note"`
+		Url          string `json:"url"`
+		TimeCreated  int64  `json:"timecreated"`
+	}
+	type Rating struct {
+		Proficiency *bool  `json:"proficiency"`
+		Grade       int64  `json:"grade"`
+		GradeName   string `json:"gradename"`
+	}
+	type Summary struct {
+		Evidence []Evidence `json:"evidence"`
+	}
+	type Result struct {
+		UserCompetencyCourse Rating  `json:"usercompetencycourse"`
+		Summary              Summary `json:"usercompetencysummary"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	uc := &UserCompetency{
+		UserId:       userId,
+		CompetencyId: competencyId,
+		CourseId:     courseId,
+		Proficient:   result.UserCompetencyCourse.Proficiency,
+		Grade:        result.UserCompetencyCourse.Grade,
+		GradeName:    result.UserCompetencyCourse.GradeName,
+	}
+	for _, e := range result.Summary.Evidence {
+		uc.Evidence = append(uc.Evidence, CompetencyEvidence{
+			Id:           e.Id,
+			Action:       e.Action,
+			ActionUserId: e.ActionUserId,
+			Description:  e.Description,
+			Grade:        e.Grade,
+			GradeName:    e.GradeName,
+			Note:         e.Note,
+			Url:          e.Url,
+			Created:      m.unix(e.TimeCreated),
+		})
+	}
+	return uc, nil
+}
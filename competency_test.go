@@ -0,0 +1,55 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestCompetencies(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_competency_list_competency_frameworks":         `[{"id":3,"shortname":"Nursing","idnumber":"NUR","description":"","visible":true,"scaleid":2,"timecreated":1600000000,"timemodified":1600000100}]`,
+		"core_competency_list_competencies":                  `[{"id":10,"shortname":"Hygiene","idnumber":"NUR1","description":"","sortorder":0,"parentid":0,"path":"/0/","competencyframeworkid":3},{"id":11,"shortname":"Hand washing","idnumber":"NUR1.1","description":"","sortorder":0,"parentid":10,"path":"/0/10/","competencyframeworkid":3}]`,
+		"core_competency_list_course_competencies":           `[{"competency":{"id":11,"shortname":"Hand washing","idnumber":"NUR1.1","parentid":10,"competencyframeworkid":3},"coursecompetency":{"id":1,"courseid":5,"competencyid":11,"sortorder":0,"ruleoutcome":0}}]`,
+		"tool_lp_data_for_user_competency_summary_in_course": `{"usercompetencysummary":{"evidence":[{"id":40,"action":3,"actionuserid":2,"description":"The competency rating was manually set.","grade":2,"gradename":"Competent","note":"Observed in lab","url":"","timecreated":1600000200}]},"usercompetencycourse":{"id":9,"userid":7,"courseid":5,"competencyid":11,"proficiency":true,"grade":2,"gradename":"Competent"}}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	frameworks, err := api.GetCompetencyFrameworks()
+	if err != nil {
+		t.Fatalf("GetCompetencyFrameworks failed: %v", err)
+	}
+	if len(frameworks) != 1 || frameworks[0].Id != 3 || frameworks[0].IdNumber != "NUR" || frameworks[0].Modified.Unix() != 1600000100 {
+		t.Errorf("Unexpected frameworks: %+v", frameworks)
+	}
+
+	competencies, err := api.GetCompetencies(3)
+	if err != nil {
+		t.Fatalf("GetCompetencies failed: %v", err)
+	}
+	if len(competencies) != 2 || competencies[1].ParentId != 10 || competencies[1].FrameworkId != 3 {
+		t.Errorf("Unexpected competencies: %+v", competencies)
+	}
+	if q := fetch.last(); q.Get("filters[0][column]") != "competencyframeworkid" || q.Get("filters[0][value]") != "3" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	competencies, err = api.GetCourseCompetencies(5)
+	if err != nil {
+		t.Fatalf("GetCourseCompetencies failed: %v", err)
+	}
+	if len(competencies) != 1 || competencies[0].Id != 11 || competencies[0].ShortName != "Hand washing" {
+		t.Errorf("Unexpected course competencies: %+v", competencies)
+	}
+
+	uc, err := api.GetUserCompetencyInCourse(7, 11, 5)
+	if err != nil {
+		t.Fatalf("GetUserCompetencyInCourse failed: %v", err)
+	}
+	if uc.Proficient == nil || !*uc.Proficient || uc.GradeName != "Competent" {
+		t.Errorf("Unexpected rating: %+v", uc)
+	}
+	if len(uc.Evidence) != 1 || uc.Evidence[0].Note != "Observed in lab" || uc.Evidence[0].ActionUserId != 2 {
+		t.Errorf("Unexpected evidence: %+v", uc.Evidence)
+	}
+}
@@ -0,0 +1,84 @@
+package moodle
+
+import (
+	"time"
+)
+
+// CompletionTracking is how completion of an activity is tracked
+type CompletionTracking int
+
+const (
+	CompletionTrackingNone      CompletionTracking = 0
+	CompletionTrackingManual    CompletionTracking = 1
+	CompletionTrackingAutomatic CompletionTracking = 2
+)
+
+// CompletionRules describe what completing an activity means. With manual
+// tracking a person marks the activity complete themselves, with automatic
+// tracking the activity is complete once every rule is met.
+type CompletionRules struct {
+	Tracking CompletionTracking
+
+	// Expected is the date the activity is expected to be completed by,
+	// shown on the dashboard timeline. Nil if not set.
+	Expected *time.Time
+
+	RequireView      bool
+	RequireGrade     bool
+	RequirePassGrade bool
+
+	// Rules lists the name of each automatic completion rule, such as
+	// "completionview", "completionusegrade" or activity specific rules like
+	// "completionsubmit". Activity specific rules are only reported by
+	// GetCourseModules and GetCourseModulesByType on moodle 4.0 or later.
+	Rules []string
+}
+
+// moduleCompletion holds the completion settings returned by
+// core_course_get_course_module
+type moduleCompletion struct {
+	Completion                CompletionTracking `json:"completion"`
+	CompletionView            int64              `json:"completionview"`
+	CompletionExpected        int64              `json:"completionexpected"`
+	CompletionGradeItemNumber *int64             `json:"completiongradeitemnumber"`
+	CompletionPassGrade       int64              `json:"completionpassgrade"`
+}
+
+func (m *MoodleApi) completionRules(c moduleCompletion) CompletionRules {
+	rules := CompletionRules{
+		Tracking: c.Completion,
+		Expected: m.unixTime(c.CompletionExpected),
+	}
+	if c.Completion != CompletionTrackingAutomatic {
+		return rules
+	}
+	if c.CompletionView == 1 {
+		rules.addRule("completionview")
+	}
+	if c.CompletionGradeItemNumber != nil {
+		rules.addRule("completionusegrade")
+	}
+	if c.CompletionPassGrade == 1 {
+		rules.addRule("completionpassgrade")
+	}
+	return rules
+}
+
+// addRule records a rule, setting the matching Require field
+func (r *CompletionRules) addRule(name string) {
+	for _, n := range r.Rules {
+		if n == name {
+			return
+		}
+	}
+	r.Rules = append(r.Rules, name)
+	switch name {
+	case "completionview":
+		r.RequireView = true
+	case "completionusegrade":
+		r.RequireGrade = true
+	case "completionpassgrade":
+		r.RequireGrade = true
+		r.RequirePassGrade = true
+	}
+}
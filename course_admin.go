@@ -0,0 +1,122 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CourseUpdate describes changes to a course made by UpdateCourse. Blank
+// fields and nil pointers are left unchanged.
+type CourseUpdate struct {
+	Id         CourseID
+	FullName   string
+	ShortName  string
+	IdNumber   string
+	Summary    string
+	CategoryId int64
+	Start      *time.Time
+	End        *time.Time
+
+	// Visible shows or hides the course from students
+	Visible *bool
+}
+
+// UpdateCourse renames, re-dates, moves or hides a course. Requires
+// permission for "core_course_update_courses".
+func (m *MoodleApi) UpdateCourse(update CourseUpdate) error {
+	if update.Id <= 0 {
+		return errors.New("UpdateCourse() requires a valid courseId")
+	}
+
+	params := url.Values{
+		"courses[0][id]": {fmt.Sprint(update.Id)},
+	}
+	for field, value := range map[string]string{
+		"fullname":  update.FullName,
+		"shortname": update.ShortName,
+		"idnumber":  update.IdNumber,
+		"summary":   update.Summary,
+	} {
+		if value != "" {
+			params.Set("courses[0]["+field+"]", value)
+		}
+	}
+	if update.CategoryId > 0 {
+		params.Set("courses[0][categoryid]", fmt.Sprint(update.CategoryId))
+	}
+	if update.Start != nil {
+		params.Set("courses[0][startdate]", fmt.Sprint(update.Start.Unix()))
+	}
+	if update.End != nil {
+		params.Set("courses[0][enddate]", fmt.Sprint(update.End.Unix()))
+	}
+	if update.Visible != nil {
+		visible := "0"
+		if *update.Visible {
+			visible = "1"
+		}
+		params.Set("courses[0][visible]", visible)
+	}
+
+	body, err := m.call("core_course_update_courses", params)
+	if err != nil {
+		return err
+	}
+	return courseWarning(body)
+}
+
+// DeleteCourse permanently deletes a course and everything in it, including
+// enrolments, activities and grades. Requires permission for
+// "core_course_delete_courses". Consider hiding a course with UpdateCourse
+// to retire it instead.
+func (m *MoodleApi) DeleteCourse(courseId CourseID) error {
+	if courseId <= 0 {
+		return errors.New("DeleteCourse() requires a valid courseId")
+	}
+
+	body, err := m.call("core_course_delete_courses", url.Values{
+		"courseids[0]": {fmt.Sprint(courseId)},
+	})
+	if err != nil {
+		return err
+	}
+	return courseWarning(body)
+}
+
+// courseWarning returns the first warning in a response. Moodle reports the
+// courses it could not change as warnings rather than exceptions.
+func courseWarning(body string) error {
+	if body = strings.TrimSpace(body); body == "" || body == "null" {
+		return nil
+	}
+
+	type Warning struct {
+		Item        string `json:"item"`
+		ItemId      int64  `json:"itemid"`
+		WarningCode string `json:"warningcode"`
+		Message     string `json:"message"`
+	}
+	type Result struct {
+		Warnings []Warning `json:"warnings"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+	for _, w := range result.Warnings {
+		switch w.WarningCode {
+		case "unknowncourseidnumber":
+			return wrapError(w.Message, ErrNotFound)
+		case "cannotdeletecourse":
+			return wrapError(w.Message, ErrPermissionDenied)
+		}
+		return wrapError(w.Message, errorCodes[w.WarningCode])
+	}
+	return nil
+}
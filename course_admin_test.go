@@ -0,0 +1,46 @@
+package moodle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateCourse(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_update_courses": `{"warnings":[]}`,
+		"core_course_delete_courses": `{"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	start := time.Unix(1700000000, 0)
+	hidden := false
+	if err := api.UpdateCourse(CourseUpdate{Id: 5, FullName: "Biology 2024", Start: &start, Visible: &hidden}); err != nil {
+		t.Fatalf("UpdateCourse failed: %v", err)
+	}
+	r := fetch.last()
+	if r.Get("courses[0][id]") != "5" || r.Get("courses[0][fullname]") != "Biology 2024" || r.Get("courses[0][startdate]") != "1700000000" || r.Get("courses[0][visible]") != "0" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if _, ok := r["courses[0][shortname]"]; ok {
+		t.Errorf("Expected blank fields to be left unchanged, found %v", r)
+	}
+
+	fetch.responses["core_course_update_courses"] = `{"warnings":[{"item":"course","itemid":5,"warningcode":"shortnametaken","message":"Short name is already used for another course"}]}`
+	if err := api.UpdateCourse(CourseUpdate{Id: 5, ShortName: "BIO"}); err == nil || err.Error() != "Short name is already used for another course" {
+		t.Errorf("Expected the warning to be returned, found %v", err)
+	}
+
+	if err := api.DeleteCourse(5); err != nil {
+		t.Fatalf("DeleteCourse failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("courseids[0]") != "5" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	fetch.responses["core_course_delete_courses"] = `{"warnings":[{"item":"course","itemid":6,"warningcode":"unknowncourseidnumber","message":"Unknown course ID 6"}]}`
+	if err := api.DeleteCourse(6); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, found %v", err)
+	}
+}
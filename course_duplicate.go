@@ -0,0 +1,143 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// DuplicateCourseOptions describes the course created by DuplicateCourse.
+// By default activities, blocks and filters are copied, but not the people
+// enrolled in the course or their work.
+type DuplicateCourseOptions struct {
+	FullName   string
+	ShortName  string
+	CategoryId int64
+	Visible    bool
+
+	// Users copies enrolments, role assignments and the work of each person
+	Users bool
+
+	// Groups copies groups and groupings. Requires moodle 3.11 or later.
+	Groups bool
+
+	// NoActivities, NoBlocks and NoFilters leave these out of the copy
+	NoActivities bool
+	NoBlocks     bool
+	NoFilters    bool
+
+	// Timeout is how long to wait for moodle to finish copying a large
+	// course after the call itself has timed out. Defaults to 10 minutes.
+	Timeout time.Duration
+}
+
+// duplicatePollInterval is how often DuplicateCourse checks whether a copy
+// has finished
+var duplicatePollInterval = 5 * time.Second
+
+// DuplicateCourse copies a course, such as a template course at the start of
+// each term, returning the id of the new course. Moodle copies the course
+// before responding, which for a large course can take longer than the
+// request timeout. If the call times out the new course is polled for by
+// short name until it appears or the options Timeout passes.
+func (m *MoodleApi) DuplicateCourse(courseId CourseID, options DuplicateCourseOptions) (CourseID, error) {
+	if courseId <= 0 {
+		return 0, errors.New("DuplicateCourse() requires a valid courseId")
+	}
+	if options.FullName == "" || options.ShortName == "" || options.CategoryId <= 0 {
+		return 0, errors.New("DuplicateCourse() requires a full name, short name and category")
+	}
+
+	params := url.Values{
+		"courseid":   {fmt.Sprint(courseId)},
+		"fullname":   {options.FullName},
+		"shortname":  {options.ShortName},
+		"categoryid": {fmt.Sprint(options.CategoryId)},
+		"visible":    {boolParam(options.Visible)},
+	}
+	type setting struct {
+		name  string
+		value bool
+	}
+	settings := []setting{
+		{"activities", !options.NoActivities},
+		{"blocks", !options.NoBlocks},
+		{"filters", !options.NoFilters},
+		{"users", options.Users},
+		{"role_assignments", options.Users},
+		{"userscompletion", options.Users},
+	}
+	if options.Groups {
+		settings = append(settings, setting{"groups", true})
+	}
+	for i, s := range settings {
+		params.Set(fmt.Sprintf("options[%d][name]", i), s.name)
+		params.Set(fmt.Sprintf("options[%d][value]", i), boolParam(s.value))
+	}
+
+	body, err := m.call("core_course_duplicate_course", params)
+	if unreachable(err) || errors.Is(err, ErrQueued) {
+		m.info("Waiting for moodle to finish copying course %d to %s: %v", courseId, options.ShortName, err)
+		return m.waitForCourse(options.ShortName, durationOr(options.Timeout, 10*time.Minute), err)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	type Result struct {
+		Id        CourseID `json:"id"`
+		ShortName string   `json:"shortname"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return 0, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if result.Id == 0 {
+		return 0, errors.New("Server returned unexpected response. ID is missing.")
+	}
+	return result.Id, nil
+}
+
+// waitForCourse polls for a course by short name, returning cause if the
+// course does not appear before the timeout
+func (m *MoodleApi) waitForCourse(shortName string, timeout time.Duration, cause error) (CourseID, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// The result cache is bypassed, as it may hold the response from
+		// before the course was created
+		body, err := m.sendWithRetry("core_course_get_courses_by_field", url.Values{
+			"field": {"shortname"},
+			"value": {shortName},
+		}, nil)
+		if err == nil {
+			type Course struct {
+				Id CourseID `json:"id"`
+			}
+			type Result struct {
+				Courses []Course `json:"courses"`
+			}
+			var result Result
+			if err := json.Unmarshal([]byte(body), &result); err != nil {
+				return 0, errors.New("Server returned unexpected response. " + err.Error())
+			}
+			if len(result.Courses) > 0 {
+				return result.Courses[0].Id, nil
+			}
+		}
+		if time.Now().Add(duplicatePollInterval).After(deadline) {
+			return 0, cause
+		}
+		time.Sleep(duplicatePollInterval)
+	}
+}
+
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,74 @@
+package moodle
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// slowCopyLookupUrl times out copying a course, which then appears on the
+// second poll
+type slowCopyLookupUrl struct {
+	*testLookupUrl
+	polls int
+}
+
+func (s *slowCopyLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	s.testLookupUrl.Do(method, u, form, header)
+	switch form.Get("wsfunction") {
+	case "core_course_duplicate_course":
+		return "", 0, "", errors.New("Client.Timeout exceeded while awaiting headers")
+	case "core_course_get_courses_by_field":
+		s.polls++
+		if s.polls < 2 {
+			return `{"courses":[],"warnings":[]}`, 200, "application/json", nil
+		}
+		return `{"courses":[{"id":44,"shortname":"BIO-2025"}],"warnings":[]}`, 200, "application/json", nil
+	}
+	return "", 404, "text/plain", nil
+}
+
+func TestDuplicateCourse(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_duplicate_course": `{"id":43,"shortname":"BIO-2025"}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	id, err := api.DuplicateCourse(5, DuplicateCourseOptions{FullName: "Biology 2025", ShortName: "BIO-2025", CategoryId: 2, Users: true, NoBlocks: true})
+	if err != nil || id != 43 {
+		t.Fatalf("Expected course 43, found %d %v", id, err)
+	}
+	r := fetch.last()
+	if r.Get("courseid") != "5" || r.Get("shortname") != "BIO-2025" || r.Get("categoryid") != "2" || r.Get("visible") != "0" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	options := map[string]string{}
+	for i := 0; r.Get("options["+itoa(int64(i))+"][name]") != ""; i++ {
+		options[r.Get("options["+itoa(int64(i))+"][name]")] = r.Get("options[" + itoa(int64(i)) + "][value]")
+	}
+	if options["activities"] != "1" || options["blocks"] != "0" || options["users"] != "1" || options["groups"] != "" {
+		t.Errorf("Unexpected options: %v", options)
+	}
+
+	defer func(d time.Duration) { duplicatePollInterval = d }(duplicatePollInterval)
+	duplicatePollInterval = time.Millisecond
+
+	slow := &slowCopyLookupUrl{testLookupUrl: newTestLookupUrl(nil)}
+	api.SetUrlFetcher(slow)
+	id, err = api.DuplicateCourse(5, DuplicateCourseOptions{FullName: "Biology 2025", ShortName: "BIO-2025", CategoryId: 2})
+	if err != nil || id != 44 {
+		t.Fatalf("Expected to wait for course 44, found %d %v", id, err)
+	}
+	if slow.polls != 2 {
+		t.Errorf("Expected two polls, found %d", slow.polls)
+	}
+
+	slow.polls = -1000
+	if _, err := api.DuplicateCourse(5, DuplicateCourseOptions{FullName: "Biology 2025", ShortName: "BIO-2025", CategoryId: 2, Timeout: 5 * time.Millisecond}); err == nil {
+		t.Errorf("Expected the timeout to be reported")
+	}
+}
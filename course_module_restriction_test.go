@@ -1,9 +1,11 @@
 package moodle
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestRestriction(t *testing.T) {
@@ -81,3 +83,99 @@ func requireEnv(name string, t *testing.T) string {
 	}
 	return value
 }
+
+func TestRestrictionConditions(t *testing.T) {
+
+	min, max := 50.0, 80.0
+	person := &Person{Email: "student@Example.com"}
+	person.SetField("campus", "Melbourne")
+	ctx := &RestrictionContext{
+		Groups:     []CourseGroup{{Id: 10}},
+		Person:     person,
+		Grades:     map[int64]float64{7: 65},
+		Completion: map[CmID]CompletionState{101: CompletionCompletePass, 102: CompletionCompleteFail},
+		Now:        time.Unix(1541682000, 0),
+	}
+
+	tests := []struct {
+		c   RestrictionC
+		met bool
+	}{
+		{RestrictionC{Type: "group", Id: 10}, true},
+		{RestrictionC{Type: "group", Id: 20}, false},
+		{RestrictionC{Type: "date", D: ">=", T: 1541682000}, true},
+		{RestrictionC{Type: "date", D: "<", T: 1541682000}, false},
+		{RestrictionC{Type: "profile", Sf: "email", Op: "endswith", V: "@example.com"}, true},
+		{RestrictionC{Type: "profile", Cf: "campus", Op: "isequalto", V: "Sydney"}, false},
+		{RestrictionC{Type: "profile", Cf: "campus", Op: "isnotempty"}, true},
+		{RestrictionC{Type: "grade", Id: 7, Min: &min, Max: &max}, true},
+		{RestrictionC{Type: "grade", Id: 7, Max: &min}, false},
+		{RestrictionC{Type: "grade", Id: 8, Min: &min}, false},
+		{RestrictionC{Type: "completion", Cm: 101, E: 1}, true},
+		{RestrictionC{Type: "completion", Cm: 102, E: 1}, false},
+		{RestrictionC{Type: "completion", Cm: 102, E: 0}, true},
+		{RestrictionC{Type: "completion", Cm: 103, E: 0}, true},
+		{RestrictionC{Type: "completion", Cm: 102, E: 3}, true},
+	}
+	for _, test := range tests {
+		rules := &Restriction{OP: "&", C: []RestrictionC{test.c}}
+		if rules.IsRestrictedFor(ctx) == test.met {
+			t.Errorf("Expected condition %+v met=%v", test.c, test.met)
+		}
+	}
+
+	// "!&" must not match all, "!|" must not match any
+	both := []RestrictionC{{Type: "group", Id: 10}, {Type: "group", Id: 20}}
+	if (&Restriction{OP: "!&", C: both}).IsRestrictedFor(ctx) {
+		t.Errorf("Expected !& to allow a person in only one of the groups")
+	}
+	if !(&Restriction{OP: "!|", C: both}).IsRestrictedFor(ctx) {
+		t.Errorf("Expected !| to restrict a person in one of the groups")
+	}
+	if (&Restriction{OP: "|", C: both}).IsRestrictedFor(ctx) {
+		t.Errorf("Expected | to allow a person in one of the groups")
+	}
+}
+
+func TestNestedRestriction(t *testing.T) {
+
+	// In group 10, or (in group 20 and before the cut off date)
+	availability := `{"op":"|","show":true,"c":[
+		{"type":"group","id":10},
+		{"op":"&","c":[{"type":"group","id":20},{"type":"date","d":"<","t":1541682000}]}]}`
+	var rules Restriction
+	if err := json.Unmarshal([]byte(availability), &rules); err != nil {
+		t.Fatalf("Failed to parse availability: %v", err)
+	}
+	if len(rules.C) != 2 || len(rules.C[1].C) != 2 || rules.C[1].Op != "&" {
+		t.Fatalf("Expected nested condition group, found %+v", rules)
+	}
+
+	before := time.Unix(1541000000, 0)
+	after := time.Unix(1542000000, 0)
+	tests := []struct {
+		group      GroupID
+		now        time.Time
+		restricted bool
+	}{
+		{10, after, false},
+		{20, before, false},
+		{20, after, true},
+		{30, before, true},
+	}
+	for _, test := range tests {
+		ctx := &RestrictionContext{Groups: []CourseGroup{{Id: test.group}}, Now: test.now}
+		if rules.IsRestrictedFor(ctx) != test.restricted {
+			t.Errorf("Expected group %d at %v restricted=%v", test.group, test.now, test.restricted)
+		}
+	}
+
+	// A negated nested group: not (in group 20 and in group 30)
+	rules = Restriction{OP: "&", C: []RestrictionC{{Op: "!&", C: []RestrictionC{{Type: "group", Id: 20}, {Type: "group", Id: 30}}}}}
+	if rules.IsRestricted([]CourseGroup{{Id: 20}}) {
+		t.Errorf("Expected a person in only one group to be allowed")
+	}
+	if !rules.IsRestricted([]CourseGroup{{Id: 20}, {Id: 30}}) {
+		t.Errorf("Expected a person in both groups to be restricted")
+	}
+}
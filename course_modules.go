@@ -0,0 +1,221 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// GetCourseModules lists the modules in a course, in the order they appear on
+// the course page. Only modules visible to the web service user are returned.
+func (m *MoodleApi) GetCourseModules(courseId CourseID) ([]CourseModule, error) {
+	return m.getCourseContents(courseId, "")
+}
+
+// GetCourseModulesByType lists the modules of one type in a course, such as
+// "assign" or "quiz". The InstanceId of each module is the id used by the
+// module's own functions, such as the assignment id passed to
+// GetAssignmentSubmissions, while Id is the course module id (cmid).
+func (m *MoodleApi) GetCourseModulesByType(courseId CourseID, modname string) ([]CourseModule, error) {
+	return m.getCourseContents(courseId, modname)
+}
+
+// GetCourseModulesByIds fetches many course modules, keyed by course module
+// id, including the availability of each module. Modules are fetched in
+// parallel by up to concurrency workers, or one worker if concurrency is less
+// than one. If any module fails no further modules are fetched and the first
+// error is returned. To read every module in a course use GetCourseModules,
+// which needs a single call.
+func (m *MoodleApi) GetCourseModulesByIds(cmids []CmID, concurrency int) (map[CmID]*CourseModule, error) {
+	found := make([]*CourseModule, len(cmids))
+	err := forEachParallel(len(cmids), concurrency, func(i int) error {
+		cm, err := m.GetCourseModule(cmids[i])
+		if err != nil {
+			return fmt.Errorf("Failed to fetch course module %d. %w", cmids[i], err)
+		}
+		found[i] = cm
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[CmID]*CourseModule, len(cmids))
+	for i, cmid := range cmids {
+		modules[cmid] = found[i]
+	}
+	return modules, nil
+}
+
+// SetCourseModuleVisibility shows or hides an activity on the course page.
+// Hidden activities can only be seen by teachers. Requires permission for
+// "core_course_edit_module".
+func (m *MoodleApi) SetCourseModuleVisibility(cmid CmID, visible bool) error {
+	if cmid <= 0 {
+		return errors.New("SetCourseModuleVisibility() requires a valid cmid")
+	}
+	action := "hide"
+	if visible {
+		action = "show"
+	}
+	_, err := m.call("core_course_edit_module", url.Values{
+		"action": {action},
+		"id":     {fmt.Sprint(cmid)},
+	})
+	return err
+}
+
+// DeleteCourseModule permanently removes an activity from its course,
+// including any submissions and grades. Requires permission for
+// "core_course_delete_modules". Consider hiding the activity with
+// SetCourseModuleVisibility instead.
+func (m *MoodleApi) DeleteCourseModule(cmid CmID) error {
+	if cmid <= 0 {
+		return errors.New("DeleteCourseModule() requires a valid cmid")
+	}
+	_, err := m.call("core_course_delete_modules", url.Values{
+		"cmids[0]": {fmt.Sprint(cmid)},
+	})
+	return err
+}
+
+// CourseSection is a section of a course page, such as a week or topic,
+// listing its modules in the order they appear
+type CourseSection struct {
+	Id      int64
+	Number  int
+	Name    string
+	Summary string
+	Visible bool
+	Modules []CourseModule
+}
+
+// GetCourseContents lists the sections of a course with their modules,
+// including the url, description and files of each module, using a single
+// call. Only sections and modules visible to the web service user are
+// returned. Use GetCourseModules when the files are not needed.
+func (m *MoodleApi) GetCourseContents(courseId CourseID) ([]CourseSection, error) {
+	return m.getCourseSections(courseId, "", true)
+}
+
+func (m *MoodleApi) getCourseContents(courseId CourseID, modname string) ([]CourseModule, error) {
+	sections, err := m.getCourseSections(courseId, modname, false)
+	if err != nil {
+		return nil, err
+	}
+	var modules []CourseModule
+	for _, s := range sections {
+		modules = append(modules, s.Modules...)
+	}
+	return modules, nil
+}
+
+// getCourseSections calls core_course_get_contents, optionally only listing
+// modules of one type. Module files are only listed if contents is set.
+func (m *MoodleApi) getCourseSections(courseId CourseID, modname string, contents bool) ([]CourseSection, error) {
+	params := url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+		"options[0][name]":   {"excludecontents"},
+		"options[0][value]":  {boolParam(!contents)},
+	}
+	if modname != "" {
+		params.Set("options[1][name]", "modname")
+		params.Set("options[1][value]", modname)
+	}
+	body, err := m.call("core_course_get_contents", params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Rule struct {
+		RuleName string `json:"rulename"`
+	}
+	type CompletionData struct {
+		Details []Rule `json:"details"`
+	}
+	type Content struct {
+		MoodleFile
+		Type         string `json:"type"`
+		TimeModified int64  `json:"timemodified"`
+	}
+	type Module struct {
+		Id             CmID               `json:"id"`
+		Name           string             `json:"name"`
+		InstanceId     int64              `json:"instance"`
+		ModuleName     string             `json:"modname"`
+		Visible        int64              `json:"visible"`
+		Availability   *string            `json:"availability"`
+		Completion     CompletionTracking `json:"completion"`
+		CompletionData *CompletionData    `json:"completiondata"`
+		Url            string             `json:"url"`
+		Description    string             `json:"description"`
+		Contents       []Content          `json:"contents"`
+	}
+	type Section struct {
+		Id      int64    `json:"id"`
+		Number  int      `json:"section"`
+		Name    string   `json:"name"`
+		Summary string   `json:"summary"`
+		Visible *int64   `json:"visible"`
+		Modules []Module `json:"modules"`
+	}
+
+	var results []Section
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	sections := make([]CourseSection, 0, len(results))
+	for _, s := range results {
+		section := CourseSection{
+			Id:      s.Id,
+			Number:  s.Number,
+			Name:    s.Name,
+			Summary: s.Summary,
+			Visible: s.Visible == nil || *s.Visible == 1,
+		}
+		for _, mod := range s.Modules {
+			// Older versions of moodle ignore the modname option
+			if modname != "" && mod.ModuleName != modname {
+				continue
+			}
+			cm := CourseModule{
+				Id:          mod.Id,
+				CourseId:    courseId,
+				InstanceId:  mod.InstanceId,
+				SectionId:   s.Id,
+				ModuleName:  mod.ModuleName,
+				Name:        mod.Name,
+				Visible:     mod.Visible == 1,
+				Completion:  CompletionRules{Tracking: mod.Completion},
+				Url:         mod.Url,
+				Description: mod.Description,
+			}
+			if mod.CompletionData != nil && mod.Completion == CompletionTrackingAutomatic {
+				for _, r := range mod.CompletionData.Details {
+					cm.Completion.addRule(r.RuleName)
+				}
+			}
+			if mod.Availability != nil && *mod.Availability != "" {
+				if err := json.Unmarshal([]byte(*mod.Availability), &cm.Availability); err != nil {
+					return nil, errors.New("Server returned unexpected response. " + err.Error())
+				}
+			}
+			for _, c := range mod.Contents {
+				// Url modules list the external address as content
+				if c.Type != "file" {
+					continue
+				}
+				file := c.MoodleFile
+				file.Modified = m.unix(c.TimeModified)
+				cm.Files = append(cm.Files, file)
+			}
+			section.Modules = append(section.Modules, cm)
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
@@ -0,0 +1,179 @@
+package moodle
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestGetCourseModulesByType(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_get_contents": `[{"id":20,"name":"General","section":0,"modules":[{"id":100,"name":"Announcements","instance":1,"modname":"forum","visible":1,"availability":null}]},` +
+			`{"id":21,"name":"Week 1","section":1,"modules":[{"id":101,"name":"Essay","instance":7,"modname":"assign","visible":1,"availability":"{\"op\":\"&\",\"c\":[{\"type\":\"group\",\"id\":10}],\"showc\":[true]}"},{"id":102,"name":"Quiz 1","instance":3,"modname":"quiz","visible":0}]}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	modules, err := api.GetCourseModules(3)
+	if err != nil {
+		t.Fatalf("GetCourseModules failed: %v", err)
+	}
+	if len(modules) != 3 || modules[2].Visible || modules[1].SectionId != 21 || modules[1].CourseId != 3 {
+		t.Errorf("Expected three modules, found %+v", modules)
+	}
+
+	modules, err = api.GetCourseModulesByType(3, "assign")
+	if err != nil {
+		t.Fatalf("GetCourseModulesByType failed: %v", err)
+	}
+	if len(modules) != 1 || modules[0].Id != 101 || modules[0].InstanceId != 7 {
+		t.Fatalf("Expected assignment 7 with cmid 101, found %+v", modules)
+	}
+	if len(modules[0].Availability.C) != 1 || modules[0].Availability.C[0].Id != 10 {
+		t.Errorf("Expected availability to be parsed, found %+v", modules[0].Availability)
+	}
+	if r := fetch.last(); r.Get("options[1][name]") != "modname" || r.Get("options[1][value]") != "assign" {
+		t.Errorf("Expected modules to be filtered by moodle, found %v", r)
+	}
+}
+
+// moduleLookupUrl returns the course module requested
+type moduleLookupUrl struct {
+	*testLookupUrl
+}
+
+func (f *moduleLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	f.testLookupUrl.Do(method, u, form, header)
+	cmid := form.Get("cmid")
+	if cmid == "404" {
+		return `{"exception":"dml_missing_record_exception","errorcode":"invalidrecord","message":"Can't find data record in database table course_modules."}`, 200, "application/json", nil
+	}
+	return `{"cm":{"id":` + cmid + `,"course":3,"instance":1,"modname":"assign","visible":1,"availability":"{\"op\":\"&\",\"c\":[{\"type\":\"group\",\"id\":` + cmid + `}],\"showc\":[true]}"},"warnings":[]}`, 200, "application/json", nil
+}
+
+func TestGetCourseModulesByIds(t *testing.T) {
+
+	fetch := &moduleLookupUrl{newTestLookupUrl(nil)}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	modules, err := api.GetCourseModulesByIds([]CmID{101, 102, 103, 104}, 3)
+	if err != nil {
+		t.Fatalf("GetCourseModulesByIds failed: %v", err)
+	}
+	if len(modules) != 4 || len(fetch.requests) != 4 {
+		t.Fatalf("Expected four modules, found %d after %d requests", len(modules), len(fetch.requests))
+	}
+	for cmid, cm := range modules {
+		if cm.Id != cmid || cm.Availability.C[0].Id != int64(cmid) {
+			t.Errorf("Expected module %d, found %+v", cmid, cm)
+		}
+	}
+
+	if _, err := api.GetCourseModulesByIds([]CmID{101, 404, 103}, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing module to fail, found %v", err)
+	}
+}
+
+func TestCourseModuleCompletionRules(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_get_course_module": `{"cm":{"id":101,"course":3,"module":1,"name":"Essay","modname":"assign","instance":7,"section":21,"visible":1,"completion":2,"completionview":1,"completionexpected":1700000000,"completiongradeitemnumber":0,"completionpassgrade":1},"warnings":[]}`,
+		"core_course_get_contents": `[{"id":21,"modules":[` +
+			`{"id":101,"name":"Essay","instance":7,"modname":"assign","visible":1,"completion":2,"completiondata":{"state":0,"details":[{"rulename":"completionsubmit","rulevalue":{"status":0}},{"rulename":"completionusegrade","rulevalue":{"status":0}}]}},` +
+			`{"id":102,"name":"Reading","instance":4,"modname":"page","visible":1,"completion":1,"completiondata":{"state":0,"details":[]}},` +
+			`{"id":103,"name":"Notes","instance":5,"modname":"label","visible":1,"completion":0}]}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	cm, err := api.GetCourseModule(101)
+	if err != nil {
+		t.Fatalf("GetCourseModule failed: %v", err)
+	}
+	c := cm.Completion
+	if c.Tracking != CompletionTrackingAutomatic || c.Expected == nil || c.Expected.Unix() != 1700000000 || !c.RequireView || !c.RequireGrade || !c.RequirePassGrade {
+		t.Errorf("Unexpected completion rules: %+v", c)
+	}
+
+	modules, err := api.GetCourseModules(3)
+	if err != nil {
+		t.Fatalf("GetCourseModules failed: %v", err)
+	}
+	c = modules[0].Completion
+	if c.Tracking != CompletionTrackingAutomatic || len(c.Rules) != 2 || c.Rules[0] != "completionsubmit" || !c.RequireGrade || c.RequireView {
+		t.Errorf("Unexpected completion rules: %+v", c)
+	}
+	if modules[1].Completion.Tracking != CompletionTrackingManual || modules[2].Completion.Tracking != CompletionTrackingNone {
+		t.Errorf("Unexpected completion tracking: %+v %+v", modules[1].Completion, modules[2].Completion)
+	}
+}
+
+func TestGetCourseContents(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_get_contents": `[{"id":20,"name":"General","section":0,"summary":"<p>Welcome</p>","visible":1,"modules":[` +
+			`{"id":100,"name":"Reading","instance":4,"modname":"resource","visible":1,"url":"https://moodle.example.com/mod/resource/view.php?id=100","description":"<p>Chapter one</p>",` +
+			`"contents":[{"type":"file","filename":"chapter1.pdf","filepath":"/","filesize":2048,"fileurl":"https://moodle.example.com/webservice/pluginfile.php/50/mod_resource/content/1/chapter1.pdf","timemodified":1541682000,"mimetype":"application/pdf"}]},` +
+			`{"id":101,"name":"Library","instance":2,"modname":"url","visible":1,"contents":[{"type":"url","filename":"Library","fileurl":"https://library.example.com/"}]}]},` +
+			`{"id":21,"name":"Week 1","section":1,"visible":0,"modules":[]}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	sections, err := api.GetCourseContents(3)
+	if err != nil {
+		t.Fatalf("GetCourseContents failed: %v", err)
+	}
+	if len(sections) != 2 || sections[0].Name != "General" || sections[0].Summary != "<p>Welcome</p>" || !sections[0].Visible || sections[1].Visible || sections[1].Number != 1 {
+		t.Fatalf("Expected two sections, found %+v", sections)
+	}
+	if len(sections[0].Modules) != 2 || len(sections[1].Modules) != 0 {
+		t.Fatalf("Expected the modules of each section, found %+v", sections)
+	}
+	cm := sections[0].Modules[0]
+	if cm.Url != "https://moodle.example.com/mod/resource/view.php?id=100" || cm.Description != "<p>Chapter one</p>" || cm.SectionId != 20 || cm.CourseId != 3 {
+		t.Errorf("Unexpected module %+v", cm)
+	}
+	if len(cm.Files) != 1 || cm.Files[0].FileName != "chapter1.pdf" || cm.Files[0].Size != 2048 || cm.Files[0].Modified.Unix() != 1541682000 {
+		t.Errorf("Expected the files of the module, found %+v", cm.Files)
+	}
+	if files := sections[0].Modules[1].Files; len(files) != 0 {
+		t.Errorf("Expected url contents to be skipped, found %+v", files)
+	}
+	if r := fetch.last(); r.Get("options[0][value]") != "0" {
+		t.Errorf("Expected module contents to be requested, found %v", r)
+	}
+}
+
+func TestCourseModuleEditing(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_edit_module":    `"<div class=\"activity\"></div>"`,
+		"core_course_delete_modules": `null`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.SetCourseModuleVisibility(101, false); err != nil {
+		t.Fatalf("SetCourseModuleVisibility failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("action") != "hide" || r.Get("id") != "101" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if err := api.SetCourseModuleVisibility(101, true); err != nil || fetch.last().Get("action") != "show" {
+		t.Errorf("Expected module to be shown, found %v %v", fetch.last(), err)
+	}
+
+	if err := api.DeleteCourseModule(101); err != nil {
+		t.Fatalf("DeleteCourseModule failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("cmids[0]") != "101" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if err := api.DeleteCourseModule(0); err == nil {
+		t.Errorf("Expected an invalid cmid to be rejected")
+	}
+}
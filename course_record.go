@@ -0,0 +1,173 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CourseRecord is the full record of a course, as returned by
+// GetCourseByField
+type CourseRecord struct {
+	Id           CourseID
+	ShortName    string
+	FullName     string
+	DisplayName  string
+	IdNumber     string
+	Summary      string
+	CategoryId   int64
+	CategoryName string
+
+	// Format is the course format, such as "topics" or "weeks"
+	Format  string
+	Visible bool
+	Lang    string
+	Start   *time.Time
+	End     *time.Time
+
+	// EnrolmentMethods lists the enrolment plugins enabled in the course,
+	// such as "manual" and "self"
+	EnrolmentMethods []string
+
+	// CustomFields holds the values of the course custom fields, by short
+	// name. Menu fields hold the text of the selected option.
+	CustomFields []CustomField
+}
+
+// Field returns the value of a course custom field, or "" if it is not set
+func (c *CourseRecord) Field(name string) string {
+	for _, f := range c.CustomFields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Course returns the summary of the course used by GetCourses
+func (c *CourseRecord) Course() Course {
+	return Course{
+		MoodleId: c.Id,
+		Code:     c.ShortName,
+		Name:     c.FullName,
+		Summary:  c.Summary,
+		Start:    c.Start,
+		End:      c.End,
+	}
+}
+
+// GetCourseByField finds a course by "id", "shortname" or "idnumber", unlike
+// GetCourses which searches course names. Returns ErrNotFound if no course
+// matches, or ErrMultipleMatches if the id number is shared by several
+// courses.
+func (m *MoodleApi) GetCourseByField(field, value string) (*CourseRecord, error) {
+	switch field {
+	case "id", "shortname", "idnumber":
+	default:
+		return nil, errors.New("GetCourseByField() requires the field id, shortname or idnumber")
+	}
+	courses, err := m.getCoursesByField(field, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(courses) == 0 {
+		return nil, wrapError(fmt.Sprintf("No course has the %s %q", field, value), ErrNotFound)
+	}
+	if len(courses) > 1 {
+		return nil, wrapError(fmt.Sprintf("Several courses have the %s %q", field, value), ErrMultipleMatches)
+	}
+	return &courses[0], nil
+}
+
+// GetCourseByShortName finds a course by its short name
+func (m *MoodleApi) GetCourseByShortName(shortName string) (*CourseRecord, error) {
+	return m.GetCourseByField("shortname", shortName)
+}
+
+// SearchCoursesByCustomField finds the courses with a custom field value,
+// such as the courses tagged with a program code, ignoring case and
+// surrounding white space. Moodle can not search custom fields, so every
+// course is fetched with one call and filtered. Returns nil if no courses
+// match.
+func (m *MoodleApi) SearchCoursesByCustomField(shortname, value string) ([]CourseRecord, error) {
+	if shortname == "" {
+		return nil, errors.New("SearchCoursesByCustomField() requires a custom field short name")
+	}
+	courses, err := m.getCoursesByField("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	value = strings.TrimSpace(value)
+	var matches []CourseRecord
+	for _, c := range courses {
+		if strings.EqualFold(strings.TrimSpace(c.Field(shortname)), value) {
+			matches = append(matches, c)
+		}
+	}
+	return matches, nil
+}
+
+func (m *MoodleApi) getCoursesByField(field, value string) ([]CourseRecord, error) {
+	body, err := m.call("core_course_get_courses_by_field", url.Values{
+		"moodlewssettingraw": {"true"},
+		"field":              {field},
+		"value":              {value},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Id                CourseID `json:"id"`
+		ShortName         string   `json:"shortname"`
+		FullName          string   `json:"fullname"`
+		DisplayName       string   `json:"displayname"`
+		IdNumber          string   `json:"idnumber"`
+		Summary           string   `json:"summary"`
+		CategoryId        int64    `json:"categoryid"`
+		CategoryName      string   `json:"categoryname"`
+		Format            string   `json:"format"`
+		Visible           int      `json:"visible"`
+		Lang              string   `json:"lang"`
+		StartDate         int64    `json:"startdate"`
+		EndDate           int64    `json:"enddate"`
+		EnrollmentMethods []string `json:"enrollmentmethods"`
+
+		CustomFields []CustomField `json:"customfields"`
+	}
+	type Results struct {
+		Courses []Result `json:"courses"`
+	}
+
+	var results Results
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	courses := make([]CourseRecord, 0, len(results.Courses))
+	for _, c := range results.Courses {
+		courses = append(courses, CourseRecord{
+			Id:               c.Id,
+			ShortName:        c.ShortName,
+			FullName:         c.FullName,
+			DisplayName:      c.DisplayName,
+			IdNumber:         c.IdNumber,
+			Summary:          c.Summary,
+			CategoryId:       c.CategoryId,
+			CategoryName:     c.CategoryName,
+			Format:           c.Format,
+			Visible:          c.Visible == 1,
+			Lang:             c.Lang,
+			Start:            m.unixTime(c.StartDate),
+			End:              m.unixTime(c.EndDate),
+			EnrolmentMethods: c.EnrollmentMethods,
+			CustomFields:     c.CustomFields,
+		})
+	}
+	return courses, nil
+}
@@ -0,0 +1,71 @@
+package moodle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetCourseByField(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_get_courses_by_field": `{"courses":[{"id":5,"fullname":"Biology 2024","displayname":"Biology 2024","shortname":"BIO-2024","idnumber":"SIS-BIO","summary":"<p>Cells</p>","categoryid":2,"categoryname":"Science","format":"topics","visible":1,"lang":"","startdate":1700000000,"enddate":0,"enrollmentmethods":["manual","self"]}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	c, err := api.GetCourseByShortName("BIO-2024")
+	if err != nil {
+		t.Fatalf("GetCourseByShortName failed: %v", err)
+	}
+	if c.Id != 5 || c.IdNumber != "SIS-BIO" || c.CategoryName != "Science" || c.Format != "topics" || !c.Visible || c.Start == nil || c.End != nil || len(c.EnrolmentMethods) != 2 {
+		t.Errorf("Unexpected course: %+v", c)
+	}
+	if r := fetch.last(); r.Get("field") != "shortname" || r.Get("value") != "BIO-2024" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	if _, err := api.GetCourseByField("category", "2"); err == nil {
+		t.Errorf("Expected fields other than id, shortname and idnumber to be rejected")
+	}
+
+	fetch.responses["core_course_get_courses_by_field"] = `{"courses":[],"warnings":[]}`
+	if _, err := api.GetCourseByField("idnumber", "SIS-CHEM"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, found %v", err)
+	}
+	fetch.responses["core_course_get_courses_by_field"] = `{"courses":[{"id":5},{"id":6}],"warnings":[]}`
+	if _, err := api.GetCourseByField("idnumber", "SIS-BIO"); !errors.Is(err, ErrMultipleMatches) {
+		t.Errorf("Expected ErrMultipleMatches, found %v", err)
+	}
+}
+
+func TestSearchCoursesByCustomField(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_course_get_courses_by_field": `{"courses":[` +
+			`{"id":5,"fullname":"Biology 2024","shortname":"BIO-2024","customfields":[{"name":"Program","shortname":"program","type":"text","valueraw":"BSC01","value":"BSC01"}]},` +
+			`{"id":6,"fullname":"Chemistry 2024","shortname":"CHEM-2024","customfields":[{"name":"Program","shortname":"program","type":"text","valueraw":"bsc01 ","value":"bsc01 "}]},` +
+			`{"id":7,"fullname":"History 2024","shortname":"HIST-2024","customfields":[{"name":"Program","shortname":"program","type":"text","valueraw":"BA02","value":"BA02"}]},` +
+			`{"id":8,"fullname":"Orientation","shortname":"ORIENT"}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	courses, err := api.SearchCoursesByCustomField("program", "BSC01")
+	if err != nil {
+		t.Fatalf("SearchCoursesByCustomField failed: %v", err)
+	}
+	if len(courses) != 2 || courses[0].Id != 5 || courses[1].Id != 6 || courses[0].Field("program") != "BSC01" {
+		t.Errorf("Expected the courses of program BSC01, found %+v", courses)
+	}
+	if r := fetch.last(); r.Get("field") != "" || r.Get("value") != "" {
+		t.Errorf("Expected every course to be requested, found %v", r)
+	}
+
+	courses, err = api.SearchCoursesByCustomField("program", "MBA")
+	if err != nil || courses != nil {
+		t.Errorf("Expected no courses to match, found %+v %v", courses, err)
+	}
+	if _, err := api.SearchCoursesByCustomField("", "BSC01"); err == nil {
+		t.Errorf("Expected a blank field name to be rejected")
+	}
+}
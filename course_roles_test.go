@@ -0,0 +1,115 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// enrolmentLookupUrl returns one person per course, with the same id as the
+// course, and records the most calls made at once.
+type enrolmentLookupUrl struct {
+	active, peak int32
+}
+
+func (e *enrolmentLookupUrl) GetUrl(u string) (string, int, string, error) {
+	return "", 404, "", errors.New("Unexpected request")
+}
+
+func (e *enrolmentLookupUrl) PostFile(u string, r io.Reader) (string, int, string, error) {
+	return "", 404, "", errors.New("Unexpected request")
+}
+
+func (e *enrolmentLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	n := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+	for {
+		peak := atomic.LoadInt32(&e.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&e.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if form.Get("courseid") == "13" {
+		return `{"exception":"moodle_exception","errorcode":"invalidcourseid","message":"Course not found"}`, 200, "application/json", nil
+	}
+	return fmt.Sprintf(`[{"id":%s,"username":"user%s"}]`, form.Get("courseid"), form.Get("courseid")), 200, "application/json", nil
+}
+
+func TestGetRolesForCourses(t *testing.T) {
+
+	fetch := &enrolmentLookupUrl{}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	var courseIds []CourseID
+	for i := 1; i <= 12; i++ {
+		courseIds = append(courseIds, CourseID(i))
+	}
+	roles, err := api.GetRolesForCourses(courseIds, 4)
+	if err != nil {
+		t.Fatalf("GetRolesForCourses failed: %v", err)
+	}
+	if len(roles) != 12 {
+		t.Errorf("Expected 12 courses, found %d", len(roles))
+	}
+	for courseId, people := range roles {
+		if len(people) != 1 || int64(people[0].Id) != int64(courseId) {
+			t.Errorf("Unexpected people in course %d: %+v", courseId, people)
+		}
+	}
+	if fetch.peak < 2 || fetch.peak > 4 {
+		t.Errorf("Expected between 2 and 4 concurrent calls, found %d", fetch.peak)
+	}
+
+	_, err = api.GetRolesForCourses(append(courseIds, 13), 4)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the failed course to be reported, found %v", err)
+	}
+}
+
+func TestGetCourseEnrolmentCount(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_enrol_get_enrolled_users": `[{"id":4},{"id":7},{"id":9}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	count, err := api.GetCourseEnrolmentCount(5)
+	if err != nil {
+		t.Fatalf("GetCourseEnrolmentCount failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 people, found %d", count)
+	}
+	if q := fetch.last(); q.Get("options[0][name]") != "userfields" || q.Get("options[0][value]") != "id" {
+		t.Errorf("Expected only user ids to be requested, found %v", q)
+	}
+}
+
+func TestSearchCourseUsers(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_enrol_search_users": `[{"id":5,"username":"ann","firstname":"Ann","lastname":"Lee","fullname":"Ann Lee","email":"ann@example.com"}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	people, err := api.SearchCourseUsers(3, "lee")
+	if err != nil {
+		t.Fatalf("SearchCourseUsers failed: %v", err)
+	}
+	if len(people) != 1 || people[0].Id != 5 || people[0].Email != "ann@example.com" {
+		t.Errorf("Expected one matching person, found %+v", people)
+	}
+	r := fetch.last()
+	if r.Get("courseid") != "3" || r.Get("search") != "lee" || r.Get("perpage") != "50" {
+		t.Errorf("Expected moodle to filter the course, found %v", r)
+	}
+}
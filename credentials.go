@@ -0,0 +1,161 @@
+package moodle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials authenticate web service requests. TokenCredentials is used
+// by default, set alternatives using SetCredentials.
+type Credentials interface {
+	// Apply adds authentication details to the request parameters or headers
+	Apply(params url.Values, header http.Header) error
+}
+
+// RefreshableCredentials are credentials that may be discarded and
+// obtained again, for example when the server reports that they have expired.
+type RefreshableCredentials interface {
+	Credentials
+	Invalidate()
+}
+
+// TokenCredentials authenticate using a moodle web service token (wstoken).
+type TokenCredentials string
+
+// Apply adds the wstoken parameter
+func (t TokenCredentials) Apply(params url.Values, header http.Header) error {
+	params.Set("wstoken", string(t))
+	return nil
+}
+
+// BearerCredentials authenticate using an OAuth 2.0 bearer token sent in the
+// Authorization header, for moodle servers behind an OAuth2 gateway. Access
+// tokens are obtained from the Refresh function, and are refreshed shortly
+// before they expire or when the server responds with 401 Unauthorized.
+type BearerCredentials struct {
+	refresh func() (string, time.Time, error)
+	wstoken string
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// NewBearerCredentials returns credentials that obtain access tokens from
+// refresh. The refresh function returns the access token and the time it
+// expires (or the zero time if it does not expire). If the gateway forwards
+// requests to moodle that also require a wstoken, supply it as wstoken,
+// otherwise leave it blank.
+func NewBearerCredentials(refresh func() (string, time.Time, error), wstoken string) *BearerCredentials {
+	return &BearerCredentials{refresh: refresh, wstoken: wstoken}
+}
+
+// Apply adds the Authorization header, refreshing the access token if needed
+func (b *BearerCredentials) Apply(params url.Values, header http.Header) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.accessToken == "" || (!b.expiry.IsZero() && time.Now().Add(30*time.Second).After(b.expiry)) {
+		token, expiry, err := b.refresh()
+		if err != nil {
+			return errors.New("Bearer token refresh failed. " + err.Error())
+		}
+		b.accessToken = token
+		b.expiry = expiry
+	}
+
+	header.Set("Authorization", "Bearer "+b.accessToken)
+	if b.wstoken != "" {
+		params.Set("wstoken", b.wstoken)
+	}
+	return nil
+}
+
+// identity identifies the current access token without holding it, or is
+// blank before the first access token is obtained
+func (b *BearerCredentials) identity() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.accessToken == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(b.accessToken + "\n" + b.wstoken))
+	return "bearer:" + hex.EncodeToString(sum[:])
+}
+
+// Invalidate discards the current access token so that the next request
+// obtains a new one.
+func (b *BearerCredentials) Invalidate() {
+	b.mu.Lock()
+	b.accessToken = ""
+	b.mu.Unlock()
+}
+
+// ClientCredentialsRefresh returns a refresh function for NewBearerCredentials
+// that uses the OAuth 2.0 client credentials grant to obtain access tokens
+// from tokenUrl.
+func ClientCredentialsRefresh(tokenUrl, clientId, clientSecret string, scopes ...string) func() (string, time.Time, error) {
+	client := &http.Client{Timeout: 16 * time.Second}
+
+	return func() (string, time.Time, error) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if len(scopes) > 0 {
+			form.Set("scope", strings.Join(scopes, " "))
+		}
+
+		req, err := http.NewRequest("POST", tokenUrl, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.SetBasicAuth(url.QueryEscape(clientId), url.QueryEscape(clientSecret))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+
+		response, err := client.Do(req)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		type Result struct {
+			AccessToken      string `json:"access_token"`
+			ExpiresIn        int64  `json:"expires_in"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		var result Result
+		if err := json.Unmarshal(body, &result); err != nil {
+			return "", time.Time{}, errors.New(fmt.Sprintf("Token endpoint returned unexpected response (%d). %v", response.StatusCode, err))
+		}
+		if result.Error != "" {
+			return "", time.Time{}, errors.New(strings.TrimSpace(result.Error + " " + result.ErrorDescription))
+		}
+		if result.AccessToken == "" {
+			return "", time.Time{}, errors.New(fmt.Sprintf("Token endpoint returned no access token (%d)", response.StatusCode))
+		}
+
+		var expiry time.Time
+		if result.ExpiresIn > 0 {
+			expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+		}
+		return result.AccessToken, expiry, nil
+	}
+}
+
+// SetCredentials replaces the credentials used to authenticate requests.
+func (m *MoodleApi) SetCredentials(c Credentials) {
+	m.credentials = c
+}
@@ -0,0 +1,141 @@
+package moodle
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBearerCredentials(t *testing.T) {
+
+	valid := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(401)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`null`))
+	}))
+	defer server.Close()
+
+	refreshes := 0
+	credentials := NewBearerCredentials(func() (string, time.Time, error) {
+		refreshes++
+		return fmt.Sprintf("token%d", refreshes), time.Now().Add(time.Hour), nil
+	}, "")
+
+	api := NewMoodleApi(server.URL, "")
+	api.SetCredentials(credentials)
+
+	valid = "token1"
+	if err := api.AddPersonToCourseGroup(1, 2); err != nil {
+		t.Fatalf("AddPersonToCourseGroup() failed: %v", err)
+	}
+	if err := api.AddPersonToCourseGroup(1, 2); err != nil {
+		t.Fatalf("AddPersonToCourseGroup() failed: %v", err)
+	}
+	if refreshes != 1 {
+		t.Errorf("Access token should be reused until it expires, refreshed %d times", refreshes)
+	}
+
+	// Server rejects the current token, it should be refreshed and retried
+	valid = "token2"
+	if err := api.AddPersonToCourseGroup(1, 2); err != nil {
+		t.Fatalf("AddPersonToCourseGroup() should succeed after refreshing token: %v", err)
+	}
+	if refreshes != 2 {
+		t.Errorf("Access token should have been refreshed, refreshed %d times", refreshes)
+	}
+
+	valid = "never"
+	if err := api.AddPersonToCourseGroup(1, 2); err == nil {
+		t.Errorf("AddPersonToCourseGroup() should fail when the token is rejected")
+	}
+}
+
+func TestClientCredentialsRefresh(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		r.ParseForm()
+		if user != "client" || pass != "secret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"abc","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	token, expiry, err := ClientCredentialsRefresh(server.URL, "client", "secret", "moodle")()
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if token != "abc" || expiry.Before(time.Now()) {
+		t.Errorf("Unexpected token %s expiring %v", token, expiry)
+	}
+
+	if _, _, err := ClientCredentialsRefresh(server.URL, "client", "wrong")(); err == nil {
+		t.Errorf("Refresh should fail with invalid client credentials")
+	}
+}
+
+func TestTokenNotInUrl(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"/login/token.php":             `{"token":"usertoken"}`,
+		"core_group_add_group_members": `{"exception":"moodle_exception","errorcode":"x","message":"Failed"}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "secrettoken")
+	api.SetUrlFetcher(fetch)
+
+	err := api.AddPersonToCourseGroup(1, 2)
+	if err == nil {
+		t.Fatalf("AddPersonToCourseGroup() should fail")
+	}
+	if fetch.last().Get("wstoken") != "secrettoken" {
+		t.Errorf("Token should be sent in the request body")
+	}
+	if _, err := api.GetUserToken("student", "secretpassword", "moodle_mobile_app"); err != nil {
+		t.Fatalf("GetUserToken() failed: %v", err)
+	}
+
+	for _, u := range fetch.urls {
+		if strings.Contains(u, "secret") {
+			t.Errorf("Credentials should not appear in url: %s", u)
+		}
+	}
+	if strings.Contains(err.Error(), "secrettoken") {
+		t.Errorf("Credentials should not appear in error: %v", err)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`null`))
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "token")
+	api.AddPersonToCourseGroup(1, 2)
+	api.SetUserAgent("sync-agent/2.0")
+	api.AddPersonToCourseGroup(1, 2)
+
+	fetch := &DefaultLookupUrl{}
+	fetch.GetUrl(server.URL)
+	fetch.SetUserAgent("custom/1.0")
+	fetch.GetUrl(server.URL)
+
+	expected := []string{DefaultUserAgent, "sync-agent/2.0", DefaultUserAgent, "custom/1.0"}
+	if strings.Join(agents, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected user agents %v, found %v", expected, agents)
+	}
+}
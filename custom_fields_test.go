@@ -0,0 +1,38 @@
+package moodle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetCustomFieldsForUsers(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_update_users": `{"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.SetUserCustomFields(7, map[string]string{"campus": "North", "studentid": "S123"}); err != nil {
+		t.Fatalf("SetUserCustomFields failed: %v", err)
+	}
+	q := fetch.last()
+	if q.Get("users[0][id]") != "7" || q.Get("users[0][customfields][0][type]") != "campus" || q.Get("users[0][customfields][1][value]") != "S123" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	fields := make(map[UserID]map[string]string)
+	for i := 1; i <= 150; i++ {
+		fields[UserID(i)] = map[string]string{"campus": fmt.Sprint("Campus ", i)}
+	}
+	fetch.requests = nil
+	if err := api.SetCustomFieldsForUsers(fields); err != nil {
+		t.Fatalf("SetCustomFieldsForUsers failed: %v", err)
+	}
+	if len(fetch.requests) != 2 {
+		t.Fatalf("Expected two batches, found %d", len(fetch.requests))
+	}
+	if q := fetch.requests[1]; q.Get("users[0][id]") != "101" || q.Get("users[49][customfields][0][value]") != "Campus 150" || q.Get("users[50][id]") != "" {
+		t.Errorf("Unexpected second batch: %v", q)
+	}
+}
@@ -0,0 +1,136 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// These functions return the dashboard of the web service user, so are used
+// with a token belonging to the person, see WithToken and GetUserToken.
+
+// RecentItem is an activity the person recently viewed, as listed by the
+// "Recently accessed items" block.
+type RecentItem struct {
+	CmId       CmID
+	CourseId   CourseID
+	CourseName string
+	ModuleName string
+	Name       string
+	Accessed   *time.Time
+	ViewUrl    string
+}
+
+// GetRecentItems lists the activities the person most recently viewed, most
+// recent first. At most limit items are returned, or moodle's default of 9 if
+// limit is zero.
+func (m *MoodleApi) GetRecentItems(limit int) ([]RecentItem, error) {
+	body, err := m.call("block_recentlyaccesseditems_get_recent_items", url.Values{
+		"limit": {fmt.Sprint(limit)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		CmId       CmID     `json:"cmid"`
+		CourseId   CourseID `json:"courseid"`
+		CourseName string   `json:"coursename"`
+		ModuleName string   `json:"modname"`
+		Name       string   `json:"name"`
+		TimeAccess int64    `json:"timeaccess"`
+		ViewUrl    string   `json:"viewurl"`
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	items := make([]RecentItem, 0, len(results))
+	for _, r := range results {
+		items = append(items, RecentItem{
+			CmId:       r.CmId,
+			CourseId:   r.CourseId,
+			CourseName: r.CourseName,
+			ModuleName: r.ModuleName,
+			Name:       r.Name,
+			Accessed:   m.unixTime(r.TimeAccess),
+			ViewUrl:    r.ViewUrl,
+		})
+	}
+	return items, nil
+}
+
+// GetStarredCourses lists the courses the person has starred on their
+// dashboard, sorted by course code.
+func (m *MoodleApi) GetStarredCourses() ([]Course, error) {
+	body, err := m.call("core_course_get_enrolled_courses_by_timeline_classification", url.Values{
+		"classification": {"favourites"},
+		"limit":          {"0"},
+		"offset":         {"0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Id        CourseID `json:"id"`
+		Code      string   `json:"shortname"`
+		Name      string   `json:"fullname"`
+		StartDate int64    `json:"startdate"`
+		EndDate   int64    `json:"enddate"`
+	}
+	type Results struct {
+		Courses []Result `json:"courses"`
+	}
+
+	var results Results
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	courses := make([]Course, 0, len(results.Courses))
+	for _, c := range results.Courses {
+		courses = append(courses, Course{MoodleId: c.Id, Code: c.Code, Name: c.Name, Start: m.unixTime(c.StartDate), End: m.unixTime(c.EndDate)})
+	}
+	sort.Sort(ByCourseCode(courses))
+	return courses, nil
+}
+
+// SetCourseStarred stars or unstars a course on the person's dashboard
+func (m *MoodleApi) SetCourseStarred(courseId CourseID, starred bool) error {
+	favourite := "0"
+	if starred {
+		favourite = "1"
+	}
+	body, err := m.call("core_course_set_favourite_courses", url.Values{
+		"courses[0][id]":        {fmt.Sprint(courseId)},
+		"courses[0][favourite]": {favourite},
+	})
+	if err != nil {
+		return err
+	}
+
+	type Warning struct {
+		Message string `json:"message"`
+	}
+	type Result struct {
+		Warnings []Warning `json:"warnings"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if len(result.Warnings) > 0 {
+		return errors.New(result.Warnings[0].Message)
+	}
+	return nil
+}
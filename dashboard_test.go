@@ -0,0 +1,45 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"block_recentlyaccesseditems_get_recent_items":                `[{"id":1,"courseid":3,"cmid":101,"userid":5,"modname":"assign","name":"Essay","coursename":"Accounting","timeaccess":1600000000,"viewurl":"https://moodle.example.com/mod/assign/view.php?id=101"}]`,
+		"core_course_get_enrolled_courses_by_timeline_classification": `{"courses":[{"id":7,"shortname":"TAX101","fullname":"Tax","isfavourite":true},{"id":3,"shortname":"ACC101","fullname":"Accounting","startdate":1600000000,"isfavourite":true}],"nextoffset":2}`,
+		"core_course_set_favourite_courses":                           `{"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token").WithToken("student")
+	api.SetUrlFetcher(fetch)
+
+	items, err := api.GetRecentItems(5)
+	if err != nil {
+		t.Fatalf("GetRecentItems failed: %v", err)
+	}
+	if len(items) != 1 || items[0].CmId != 101 || items[0].ModuleName != "assign" || items[0].Accessed.Unix() != 1600000000 {
+		t.Errorf("Unexpected recent items: %+v", items)
+	}
+	if fetch.last().Get("limit") != "5" {
+		t.Errorf("Expected limit to be sent, found %v", fetch.last())
+	}
+
+	courses, err := api.GetStarredCourses()
+	if err != nil {
+		t.Fatalf("GetStarredCourses failed: %v", err)
+	}
+	if len(courses) != 2 || courses[0].Code != "ACC101" || courses[0].Start == nil || courses[1].Start != nil {
+		t.Errorf("Unexpected starred courses: %+v", courses)
+	}
+	if fetch.last().Get("classification") != "favourites" {
+		t.Errorf("Expected favourite courses to be requested, found %v", fetch.last())
+	}
+
+	if err := api.SetCourseStarred(3, false); err != nil {
+		t.Fatalf("SetCourseStarred failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("courses[0][id]") != "3" || r.Get("courses[0][favourite]") != "0" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+}
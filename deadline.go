@@ -0,0 +1,114 @@
+package moodle
+
+import (
+	"time"
+)
+
+// Deadline describes when an activity opens, is due and closes, in the same
+// form for assignments, quizzes and forums. Times that are not set are nil.
+type Deadline struct {
+	CourseId CourseID
+
+	// ActivityType is the module name, "assign", "quiz" or "forum"
+	ActivityType string
+	InstanceId   int64
+	CmId         CmID
+	Name         string
+
+	Open   *time.Time
+	Due    *time.Time
+	Cutoff *time.Time
+
+	// UserId is set when the deadline is an override, such as an
+	// extension, that applies to one person.
+	UserId UserID
+}
+
+// IsOverride reports whether the deadline applies to one person only
+func (d *Deadline) IsOverride() bool {
+	return d.UserId != 0
+}
+
+// Closes returns the last time submissions are accepted, the cutoff if set,
+// otherwise the due date.
+func (d *Deadline) Closes() *time.Time {
+	if d.Cutoff != nil {
+		return d.Cutoff
+	}
+	return d.Due
+}
+
+// ForUser returns a copy of the deadline with a new due date for one person.
+// A cutoff earlier than the new due date is moved to the due date, as moodle
+// accepts submissions until the end of an extension.
+func (d Deadline) ForUser(userId UserID, due time.Time) Deadline {
+	d.UserId = userId
+	d.Due = &due
+	if d.Cutoff != nil && d.Cutoff.Before(due) {
+		d.Cutoff = &due
+	}
+	return d
+}
+
+// Deadline returns the deadline of the assignment. Use ForUser to apply an
+// extension granted to one person.
+func (a *AssignmentInfo) Deadline() Deadline {
+	return Deadline{
+		CourseId:     a.CourseId,
+		ActivityType: "assign",
+		InstanceId:   a.Id,
+		CmId:         a.CmId,
+		Name:         a.Name,
+		Open:         nonZeroTime(a.AllowSubmissionsFromDate),
+		Due:          nonZeroTime(a.DueDate),
+		Cutoff:       unixTime(a.CutoffDate, a.DueDate),
+	}
+}
+
+// Deadline returns the deadline of the quiz. A quiz is due when it closes.
+func (q *QuizInfo) Deadline() Deadline {
+	return Deadline{
+		CourseId:     q.CourseId,
+		ActivityType: "quiz",
+		InstanceId:   q.Id,
+		CmId:         q.CourseModuleId,
+		Name:         q.Name,
+		Open:         nonZeroTime(q.TimeOpen),
+		Due:          nonZeroTime(q.TimeClose),
+	}
+}
+
+// Deadline returns the deadline of the forum
+func (f *ForumInfo) Deadline() Deadline {
+	return Deadline{
+		CourseId:     f.CourseId,
+		ActivityType: "forum",
+		InstanceId:   f.Id,
+		CmId:         f.CmId,
+		Name:         f.Name,
+		Due:          nonZeroTime(f.DueDate),
+		Cutoff:       nonZeroTime(f.CutoffDate),
+	}
+}
+
+// unixTime converts a moodle timestamp, where zero means not set, into the
+// same location as another time.
+func unixTime(t int64, like *time.Time) *time.Time {
+	if t == 0 {
+		return nil
+	}
+	tt := time.Unix(t, 0)
+	if like != nil {
+		tt = tt.In(like.Location())
+	}
+	return &tt
+}
+
+// nonZeroTime returns nil for times that were converted from a zero
+// moodle timestamp.
+func nonZeroTime(t *time.Time) *time.Time {
+	if t == nil || t.Unix() == 0 {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,41 @@
+package moodle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDeadlines(t *testing.T) {
+
+	due := time.Unix(1600000000, 0)
+	a := &AssignmentInfo{Id: 1, CmId: 11, CourseId: 3, Name: "Essay", DueDate: &due, CutoffDate: 1600086400}
+	d := a.Deadline()
+	if d.ActivityType != "assign" || d.CmId != 11 || d.Open != nil || !d.Due.Equal(due) || d.Cutoff.Unix() != 1600086400 {
+		t.Errorf("Unexpected assignment deadline %+v", d)
+	}
+	if d.Closes().Unix() != 1600086400 {
+		t.Errorf("Expected assignment to close at the cutoff")
+	}
+
+	extension := time.Unix(1600500000, 0)
+	o := d.ForUser(5, extension)
+	if !o.IsOverride() || d.IsOverride() || !o.Due.Equal(extension) || !o.Cutoff.Equal(extension) {
+		t.Errorf("Unexpected override %+v", o)
+	}
+
+	var q QuizInfo
+	if err := json.Unmarshal([]byte(`{"id":2,"coursemodule":12,"course":3,"name":"Quiz","timeopen":0,"timeclose":1600000000}`), &q); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	d = q.Deadline()
+	if d.ActivityType != "quiz" || d.CmId != 12 || d.Open != nil || !d.Due.Equal(due) || d.Cutoff != nil {
+		t.Errorf("Unexpected quiz deadline %+v", d)
+	}
+
+	f := &ForumInfo{Id: 3, CmId: 13, CourseId: 3, DueDate: &due}
+	d = f.Deadline()
+	if d.ActivityType != "forum" || !d.Closes().Equal(due) {
+		t.Errorf("Unexpected forum deadline %+v", d)
+	}
+}
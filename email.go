@@ -0,0 +1,282 @@
+package moodle
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// EmailMessage is a single email addressed to one recipient. If both Text
+// and Html are set the message is sent as multipart/alternative so that mail
+// clients may choose which version to display.
+type EmailMessage struct {
+	FromName  string
+	FromEmail string
+	ToName    string
+	ToEmail   string
+	Subject   string
+	Text      string
+	Html      string
+}
+
+// Bytes renders the message in a form suitable for sending to an SMTP server.
+// Headers are MIME encoded and body parts are quoted-printable encoded.
+func (e *EmailMessage) Bytes() ([]byte, error) {
+	if e.Text == "" && e.Html == "" {
+		return nil, errors.New("Email message requires a text or html body")
+	}
+
+	var w bytes.Buffer
+	from := mail.Address{Name: e.FromName, Address: e.FromEmail}
+	to := mail.Address{Name: e.ToName, Address: e.ToEmail}
+	w.WriteString("From: " + from.String() + "\r\n")
+	w.WriteString("To: " + to.String() + "\r\n")
+	w.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", e.Subject) + "\r\n")
+	w.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	w.WriteString("MIME-Version: 1.0\r\n")
+
+	if e.Text == "" || e.Html == "" {
+		contentType := "text/plain; charset=utf-8"
+		body := e.Text
+		if e.Text == "" {
+			contentType = "text/html; charset=utf-8"
+			body = e.Html
+		}
+		w.WriteString("Content-Type: " + contentType + "\r\n")
+		w.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+		w.WriteString("\r\n")
+		if err := writeQuotedPrintable(&w, body); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&w)
+	w.WriteString("Content-Type: multipart/alternative; boundary=\"" + mw.Boundary() + "\"\r\n")
+	w.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.Html},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	body = strings.Replace(body, "\n", "\r\n", -1)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// EmailTemplate holds the subject and body templates used to build an email.
+// Subject and Text are parsed with text/template, Html is parsed with
+// html/template so that values are escaped. Html may be left blank to send a
+// plain text only message.
+type EmailTemplate struct {
+	Subject string
+	Text    string
+	Html    string
+}
+
+// PasswordEmailData is passed to an EmailTemplate when rendering an email
+// containing moodle sign-in details.
+type PasswordEmailData struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Username  string
+	Password  string
+	Url       string
+}
+
+// Render executes the templates and returns a message with the subject and
+// bodies filled in. The caller is responsible for setting the addresses.
+func (t *EmailTemplate) Render(data interface{}) (*EmailMessage, error) {
+	var subject, text, html bytes.Buffer
+
+	st, err := texttemplate.New("subject").Parse(t.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+
+	if t.Text != "" {
+		tt, err := texttemplate.New("text").Parse(t.Text)
+		if err != nil {
+			return nil, err
+		}
+		if err := tt.Execute(&text, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Html != "" {
+		ht, err := htmltemplate.New("html").Parse(t.Html)
+		if err != nil {
+			return nil, err
+		}
+		if err := ht.Execute(&html, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return &EmailMessage{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		Html:    html.String(),
+	}, nil
+}
+
+var defaultPasswordEmail = EmailTemplate{
+	Subject: "Welcome to the Planetshakers College moodle",
+	Text: `Hi {{.FirstName}},
+
+Welcome to the Planetshakers College Moodle, You can sign-in using the details below:
+
+    URL: {{.Url}}
+    Username: {{.Username}}
+    Password: {{.Password}}
+
+If you have any difficulties with moodle access, please contact college@planetshakers.com
+
+God bless,
+Planetshakers College
+
+`,
+}
+
+var writingPasswordEmail = EmailTemplate{
+	Subject: "Welcome to RES101",
+	Text: `Hi {{.FirstName}},
+
+Welcome to the Planetshakers College Moodle, You now have access to RES101 in
+Moodle. You can sign-in using the details below:
+
+    URL: {{.Url}}
+    Username: {{.Username}}
+    Password: {{.Password}}
+
+God bless,
+Planetshakers College
+
+`,
+}
+
+// SetPasswordEmailTemplate replaces the welcome email sent by
+// ResetPasswordWithEmail. Supply an Html template to send a branded
+// multipart email. This template is used when no template has been set for
+// the language of the recipient.
+func (m *MoodleApi) SetPasswordEmailTemplate(t EmailTemplate) {
+	m.SetPasswordEmailTemplateForLang("", t)
+}
+
+// SetPasswordEmailTemplateForLang sets the email sent by ResetPasswordWithEmail
+// to people whose moodle language preference matches lang, for example "fr"
+// or "pt_br". A template for "pt" is also used for "pt_br" if no more
+// specific template exists.
+func (m *MoodleApi) SetPasswordEmailTemplateForLang(lang string, t EmailTemplate) {
+	if m.passwordEmail == nil {
+		m.passwordEmail = make(map[string]*EmailTemplate)
+	}
+	m.passwordEmail[normaliseLang(lang)] = &t
+}
+
+// passwordEmailTemplate chooses the best template for the language
+func (m *MoodleApi) passwordEmailTemplate(lang string) *EmailTemplate {
+	lang = normaliseLang(lang)
+	if t, ok := m.passwordEmail[lang]; ok {
+		return t
+	}
+	if i := strings.Index(lang, "_"); i > 0 {
+		if t, ok := m.passwordEmail[lang[0:i]]; ok {
+			return t
+		}
+	}
+	if t, ok := m.passwordEmail[""]; ok {
+		return t
+	}
+	return &defaultPasswordEmail
+}
+
+func normaliseLang(lang string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(lang)), "-", "_", -1)
+}
+
+// sendEmail delivers a message using the configured mailer. The from
+// address is taken from the mailer settings if not already set. Any secrets
+// in the message are masked when it is written to the debug log.
+func (m *MoodleApi) sendEmail(msg *EmailMessage, secrets ...string) error {
+	if m.mailer == nil {
+		return errors.New("Sending email requires smtp settings or a mailer to be specified.")
+	}
+	if m.fromName == "" || m.fromEmail == "" {
+		return errors.New("Sending email requires from name and email to be specified.")
+	}
+	if msg.FromEmail == "" {
+		msg.FromName = m.fromName
+		msg.FromEmail = m.fromEmail
+	}
+
+	// Secrets are masked before encoding, quoted-printable may split them
+	masked := *msg
+	masked.Text = maskSecrets(msg.Text, secrets...)
+	masked.Html = maskSecrets(msg.Html, secrets...)
+	data, err := masked.Bytes()
+	if err != nil {
+		return err
+	}
+	m.log.Debug("%s", string(data))
+
+	return m.mailer.Send(msg)
+}
+
+// sendPasswordEmail renders a password email template for a person and sends it.
+func (m *MoodleApi) sendPasswordEmail(t *EmailTemplate, p *Person, password string) error {
+	msg, err := t.Render(&PasswordEmailData{
+		FirstName: p.FirstName,
+		LastName:  p.LastName,
+		Email:     p.Email,
+		Username:  p.Email,
+		Password:  password,
+		Url:       m.base,
+	})
+	if err != nil {
+		return err
+	}
+	msg.ToName = p.FirstName + " " + p.LastName
+	msg.ToEmail = p.Email
+	return m.sendEmail(msg, password)
+}
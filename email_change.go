@@ -0,0 +1,111 @@
+package moodle
+
+import (
+	"errors"
+	"strings"
+)
+
+// EmailChangeData is passed to an EmailTemplate when rendering the notice
+// sent by ChangeUserEmail.
+type EmailChangeData struct {
+	FirstName string
+	LastName  string
+	OldEmail  string
+	NewEmail  string
+	Url       string
+}
+
+var defaultEmailChangeEmail = EmailTemplate{
+	Subject: "Your moodle email address has changed",
+	Text: `Hi {{.FirstName}},
+
+The email address of your Planetshakers College Moodle account has been
+changed from {{.OldEmail}} to {{.NewEmail}}.
+
+    URL: {{.Url}}
+
+If you did not ask for this change, please contact college@planetshakers.com
+
+God bless,
+Planetshakers College
+
+`,
+}
+
+// SetEmailChangeTemplate replaces the notice sent by ChangeUserEmail
+func (m *MoodleApi) SetEmailChangeTemplate(t EmailTemplate) {
+	m.emailChange = &t
+}
+
+// ChangeUserEmail changes the email address of an account, then re-reads the
+// account to confirm moodle has saved the new address. Moodle does not offer
+// its own email change confirmation to web service clients, so if notify is
+// set a notice is sent to both the old and new addresses using the mailer,
+// so that the person can report a change they did not ask for.
+func (m *MoodleApi) ChangeUserEmail(userId UserID, newEmail string, notify bool) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if _, err := validateUserAttribute("email", newEmail); err != nil {
+		return err
+	}
+	if notify && m.mailer == nil {
+		return errors.New("Sending email requires smtp settings or a mailer to be specified.")
+	}
+
+	p, err := m.GetPersonByMoodleId(userId)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return wrapError("Account not found in moodle", ErrNotFound)
+	}
+	oldEmail := p.Email
+
+	warnings, err := m.updateUsers([]UserUpdate{{Id: userId, Email: newEmail}}, []int{0})
+	if err != nil {
+		return err
+	}
+	if err := warnings[userId]; err != nil {
+		return err
+	}
+
+	// The account may have been cached before it was changed
+	m.InvalidateCache("core_user_get_users_by_field")
+	p, err = m.GetPersonByMoodleId(userId)
+	if err != nil {
+		return err
+	}
+	if p == nil || !strings.EqualFold(p.Email, newEmail) {
+		return errors.New("Moodle did not save the new email address")
+	}
+
+	if !notify {
+		return nil
+	}
+	t := m.emailChange
+	if t == nil {
+		t = &defaultEmailChangeEmail
+	}
+	msg, err := t.Render(&EmailChangeData{
+		FirstName: p.FirstName,
+		LastName:  p.LastName,
+		OldEmail:  oldEmail,
+		NewEmail:  p.Email,
+		Url:       m.base,
+	})
+	if err != nil {
+		return err
+	}
+	recipients := []string{p.Email}
+	if oldEmail != "" && !strings.EqualFold(oldEmail, p.Email) {
+		recipients = append(recipients, oldEmail)
+	}
+	for _, to := range recipients {
+		notice := *msg
+		notice.ToName = p.FirstName + " " + p.LastName
+		notice.ToEmail = to
+		if err := m.sendEmail(&notice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// emailChangeLookupUrl returns the account with the email address last saved
+type emailChangeLookupUrl struct {
+	*testLookupUrl
+	email string
+	save  bool
+}
+
+func (f *emailChangeLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	f.testLookupUrl.Do(method, u, form, header)
+	switch form.Get("wsfunction") {
+	case "core_user_update_users":
+		if f.save {
+			f.email = form.Get("users[0][email]")
+		}
+		return `{"warnings":[]}`, 200, "application/json", nil
+	case "core_user_get_users_by_field":
+		return `[{"id":7,"username":"ann","firstname":"Ann","lastname":"Lee","email":"` + f.email + `"}]`, 200, "application/json", nil
+	}
+	return `null`, 200, "application/json", nil
+}
+
+func TestChangeUserEmail(t *testing.T) {
+
+	fetch := &emailChangeLookupUrl{newTestLookupUrl(nil), "ann@example.com", true}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	api.SetResultCache(NewMemoryResultCache(), time.Minute)
+
+	if err := api.ChangeUserEmail(7, "ann.lee", false); err == nil {
+		t.Errorf("Expected invalid email address to be rejected")
+	}
+	if err := api.ChangeUserEmail(7, "ann.lee@example.com", true); err == nil {
+		t.Errorf("Expected notify to require a mailer")
+	}
+	if len(fetch.requests) != 0 {
+		t.Errorf("Expected no calls before validation passes, found %d", len(fetch.requests))
+	}
+
+	mailer := &flakyMailer{}
+	api.SetMailer(mailer, "College", "college@example.com")
+	if err := api.ChangeUserEmail(7, " ann.lee@example.com ", true); err != nil {
+		t.Fatalf("ChangeUserEmail failed: %v", err)
+	}
+	if r := fetch.requests[1]; r.Get("users[0][id]") != "7" || r.Get("users[0][email]") != "ann.lee@example.com" {
+		t.Errorf("Unexpected update %v", r)
+	}
+	if len(fetch.requests) != 3 {
+		t.Errorf("Expected the account to be re-read after the change, found %d calls", len(fetch.requests))
+	}
+	if strings.Join(mailer.sent, ",") != "ann.lee@example.com,ann@example.com" {
+		t.Errorf("Expected a notice to the new and old address, found %v", mailer.sent)
+	}
+
+	fetch.save = false
+	if err := api.ChangeUserEmail(7, "ann2@example.com", false); err == nil {
+		t.Errorf("Expected an unsaved change to be reported")
+	}
+}
@@ -0,0 +1,286 @@
+package moodle
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The mailers in this file send email through HTTP email APIs, for
+// deployments that are not permitted to connect to an smtp server.
+
+func emailHttpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 16 * time.Second}
+}
+
+// emailApiRequest sends a request to an email API and returns an error for
+// any non 2xx response.
+func emailApiRequest(client *http.Client, req *http.Request, provider string) error {
+	response, err := emailHttpClient(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New(fmt.Sprintf("%s returned unexpected response (%d): %s", provider, response.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return nil
+}
+
+// SendGridMailer sends email using the SendGrid v3 mail send API.
+type SendGridMailer struct {
+	ApiKey string
+
+	// Endpoint defaults to https://api.sendgrid.com/v3/mail/send
+	Endpoint string
+	Client   *http.Client
+}
+
+// Send delivers the message to the recipient
+func (s *SendGridMailer) Send(msg *EmailMessage) error {
+	if s.ApiKey == "" {
+		return errors.New("SendGridMailer requires an api key")
+	}
+
+	type Address struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	type Personalization struct {
+		To []Address `json:"to"`
+	}
+	type Content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type Request struct {
+		Personalizations []Personalization `json:"personalizations"`
+		From             Address           `json:"from"`
+		Subject          string            `json:"subject"`
+		Content          []Content         `json:"content"`
+	}
+
+	r := Request{
+		Personalizations: []Personalization{{To: []Address{{Email: msg.ToEmail, Name: msg.ToName}}}},
+		From:             Address{Email: msg.FromEmail, Name: msg.FromName},
+		Subject:          msg.Subject,
+	}
+	// SendGrid requires text/plain to be listed before text/html
+	if msg.Text != "" {
+		r.Content = append(r.Content, Content{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.Html != "" {
+		r.Content = append(r.Content, Content{Type: "text/html", Value: msg.Html})
+	}
+	if len(r.Content) == 0 {
+		return errors.New("Email message requires a text or html body")
+	}
+
+	data, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.sendgrid.com/v3/mail/send"
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return emailApiRequest(s.Client, req, "SendGrid")
+}
+
+// MailgunMailer sends email using the Mailgun messages API.
+type MailgunMailer struct {
+	Domain string
+	ApiKey string
+
+	// Endpoint defaults to https://api.mailgun.net/v3. Use
+	// https://api.eu.mailgun.net/v3 for domains in the EU region.
+	Endpoint string
+	Client   *http.Client
+}
+
+// Send delivers the message to the recipient
+func (s *MailgunMailer) Send(msg *EmailMessage) error {
+	if s.ApiKey == "" || s.Domain == "" {
+		return errors.New("MailgunMailer requires a domain and api key")
+	}
+	if msg.Text == "" && msg.Html == "" {
+		return errors.New("Email message requires a text or html body")
+	}
+
+	from := mail.Address{Name: msg.FromName, Address: msg.FromEmail}
+	to := mail.Address{Name: msg.ToName, Address: msg.ToEmail}
+
+	form := url.Values{}
+	form.Set("from", from.String())
+	form.Set("to", to.String())
+	form.Set("subject", msg.Subject)
+	if msg.Text != "" {
+		form.Set("text", msg.Text)
+	}
+	if msg.Html != "" {
+		form.Set("html", msg.Html)
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.mailgun.net/v3"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/" + url.PathEscape(s.Domain) + "/messages"
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", s.ApiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return emailApiRequest(s.Client, req, "Mailgun")
+}
+
+// SesMailer sends email using the Amazon SES v2 API. The message is sent in
+// raw MIME form so that multipart messages are delivered unchanged.
+type SesMailer struct {
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Endpoint defaults to https://email.<region>.amazonaws.com
+	Endpoint string
+	Client   *http.Client
+}
+
+// Send delivers the message to the recipient
+func (s *SesMailer) Send(msg *EmailMessage) error {
+	if s.Region == "" || s.AccessKeyId == "" || s.SecretAccessKey == "" {
+		return errors.New("SesMailer requires a region and access key")
+	}
+
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	type Raw struct {
+		Data string `json:"Data"`
+	}
+	type Content struct {
+		Raw Raw `json:"Raw"`
+	}
+	type Destination struct {
+		ToAddresses []string `json:"ToAddresses"`
+	}
+	type Request struct {
+		FromEmailAddress string      `json:"FromEmailAddress"`
+		Destination      Destination `json:"Destination"`
+		Content          Content     `json:"Content"`
+	}
+
+	data, err := json.Marshal(&Request{
+		FromEmailAddress: msg.FromEmail,
+		Destination:      Destination{ToAddresses: []string{msg.ToEmail}},
+		Content:          Content{Raw: Raw{Data: base64.StdEncoding.EncodeToString(raw)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + s.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+"/v2/email/outbound-emails", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, data, time.Now().UTC())
+
+	return emailApiRequest(s.Client, req, "SES")
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to the request
+func (s *SesMailer) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	signedHeaders := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	if s.SessionToken != "" {
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+	}
+	var canonicalHeaders bytes.Buffer
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/ses/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSha256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSha256(key, s.Region)
+	key = hmacSha256(key, "ses")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyId+"/"+scope+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
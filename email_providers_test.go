@@ -0,0 +1,100 @@
+package moodle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendGridMailer(t *testing.T) {
+
+	var body map[string]interface{}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.WriteHeader(202)
+	}))
+	defer server.Close()
+
+	m := &SendGridMailer{ApiKey: "key", Endpoint: server.URL}
+	err := m.Send(&EmailMessage{FromEmail: "a@example.com", ToEmail: "b@example.com", Subject: "Hi", Text: "text", Html: "<p>html</p>"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if auth != "Bearer key" {
+		t.Errorf("Unexpected authorization header: %s", auth)
+	}
+	if content, ok := body["content"].([]interface{}); !ok || len(content) != 2 {
+		t.Errorf("Expected text and html content: %v", body)
+	}
+}
+
+func TestMailgunMailer(t *testing.T) {
+
+	var path, user, to string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, _, _ = r.BasicAuth()
+		r.ParseForm()
+		to = r.Form.Get("to")
+	}))
+	defer server.Close()
+
+	m := &MailgunMailer{Domain: "mg.example.com", ApiKey: "key", Endpoint: server.URL + "/v3"}
+	err := m.Send(&EmailMessage{FromEmail: "a@example.com", ToName: "Bob", ToEmail: "b@example.com", Subject: "Hi", Text: "text"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if path != "/v3/mg.example.com/messages" || user != "api" || to != "\"Bob\" <b@example.com>" {
+		t.Errorf("Unexpected request: %s %s %s", path, user, to)
+	}
+}
+
+func TestSesMailer(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(403)
+			return
+		}
+		w.Write([]byte(`{"MessageId":"1"}`))
+	}))
+	defer server.Close()
+
+	m := &SesMailer{Region: "ap-southeast-2", AccessKeyId: "AKID", SecretAccessKey: "secret", Endpoint: server.URL}
+	err := m.Send(&EmailMessage{FromEmail: "a@example.com", ToEmail: "b@example.com", Subject: "Hi", Text: "text"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	m.AccessKeyId = "WRONG"
+	if err := m.Send(&EmailMessage{FromEmail: "a@example.com", ToEmail: "b@example.com", Subject: "Hi", Text: "text"}); err == nil {
+		t.Errorf("Send() should report non 2xx responses")
+	}
+}
+
+func TestSesSignature(t *testing.T) {
+
+	// Signature must be stable for identical input
+	m := &SesMailer{Region: "us-east-1", AccessKeyId: "AKID", SecretAccessKey: "secret"}
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	sign := func() string {
+		req, _ := http.NewRequest("POST", "https://email.us-east-1.amazonaws.com/v2/email/outbound-emails", nil)
+		req.Header.Set("Content-Type", "application/json")
+		m.sign(req, []byte("{}"), now)
+		return req.Header.Get("Authorization")
+	}
+	a := sign()
+	if a != sign() {
+		t.Errorf("Signature should be deterministic")
+	}
+	if !strings.Contains(a, "Credential=AKID/20200102/us-east-1/ses/aws4_request") {
+		t.Errorf("Unexpected credential scope: %s", a)
+	}
+}
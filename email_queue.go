@@ -0,0 +1,164 @@
+package moodle
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// EmailFailure records a message that could not be delivered after all
+// retries were exhausted. The message is retained so that it (and any
+// password it contains) can be resent or reported.
+type EmailFailure struct {
+	Message  *EmailMessage
+	Attempts int
+	Err      error
+}
+
+// QueuedMailer wraps another Mailer, delivering messages one at a time in
+// the background. Failed sends are retried with an increasing delay, and
+// messages are rate limited so that bulk sends do not overwhelm the smtp
+// server. Messages that still fail are passed to the failure handler and
+// returned from Close.
+//
+//	q := moodle.NewQueuedMailer(&moodle.SmtpMailer{Host: "smtp.example.com", Port: 465})
+//	q.SetRetries(3, 10*time.Second)
+//	q.SetRateLimit(time.Second)
+//	api.SetMailer(q, "College", "college@example.com")
+//	...
+//	for _, f := range q.Close() {
+//		log.Printf("Email to %s failed: %v", f.Message.ToEmail, f.Err)
+//	}
+type QueuedMailer struct {
+	mailer     Mailer
+	retries    int
+	retryDelay time.Duration
+	interval   time.Duration
+	onFailure  func(f EmailFailure)
+
+	start    sync.Once
+	queue    chan *EmailMessage
+	done     chan struct{}
+	sendMu   sync.Mutex
+	closed   bool
+	closing  chan struct{}
+	sending  sync.WaitGroup
+	mu       sync.Mutex
+	failures []EmailFailure
+}
+
+// NewQueuedMailer returns a queue that delivers messages using mailer. By
+// default a message is retried three times, starting with a five second delay.
+func NewQueuedMailer(mailer Mailer) *QueuedMailer {
+	return &QueuedMailer{
+		mailer:     mailer,
+		retries:    3,
+		retryDelay: 5 * time.Second,
+		queue:      make(chan *EmailMessage, 1000),
+		done:       make(chan struct{}),
+		closing:    make(chan struct{}),
+	}
+}
+
+// SetRetries sets how many times a failed message is retried. The delay
+// doubles after each failed attempt. Must be called before the first Send.
+func (q *QueuedMailer) SetRetries(retries int, delay time.Duration) {
+	q.retries = retries
+	q.retryDelay = delay
+}
+
+// SetRateLimit sets the minimum time between messages. Must be called before
+// the first Send.
+func (q *QueuedMailer) SetRateLimit(interval time.Duration) {
+	q.interval = interval
+}
+
+// SetFailureHandler registers a function called for each message that could
+// not be delivered. Must be called before the first Send.
+func (q *QueuedMailer) SetFailureHandler(f func(f EmailFailure)) {
+	q.onFailure = f
+}
+
+// Send adds a message to the queue. It does not wait for the message to be
+// delivered, delivery failures are reported by the failure handler and Close.
+// If the queue is full Send waits for room, and fails if Close is called
+// while it waits.
+func (q *QueuedMailer) Send(msg *EmailMessage) error {
+	q.sendMu.Lock()
+	if q.closed {
+		q.sendMu.Unlock()
+		return errors.New("Email queue is closed")
+	}
+	q.start.Do(func() { go q.run() })
+	q.sending.Add(1)
+	q.sendMu.Unlock()
+	defer q.sending.Done()
+
+	select {
+	case q.queue <- msg:
+		return nil
+	case <-q.closing:
+		return errors.New("Email queue is closed")
+	}
+}
+
+// Close stops accepting messages, waits for the queue to drain, and returns
+// every message that could not be delivered.
+func (q *QueuedMailer) Close() []EmailFailure {
+	q.sendMu.Lock()
+	if !q.closed {
+		q.closed = true
+		q.start.Do(func() { go q.run() })
+		close(q.closing)
+		q.sendMu.Unlock()
+
+		// The queue is closed once no Send is waiting to add to it
+		q.sending.Wait()
+		close(q.queue)
+	} else {
+		q.sendMu.Unlock()
+	}
+
+	<-q.done
+	return q.Failures()
+}
+
+// Failures returns the messages that have failed so far.
+func (q *QueuedMailer) Failures() []EmailFailure {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]EmailFailure(nil), q.failures...)
+}
+
+func (q *QueuedMailer) run() {
+	defer close(q.done)
+
+	var last time.Time
+	for msg := range q.queue {
+		delay := q.retryDelay
+		attempts := 0
+		var err error
+		for {
+			if wait := q.interval - time.Since(last); wait > 0 {
+				time.Sleep(wait)
+			}
+			last = time.Now()
+			attempts++
+			err = q.mailer.Send(msg)
+			if err == nil || attempts > q.retries {
+				break
+			}
+			time.Sleep(delay)
+			delay = delay * 2
+		}
+		if err != nil {
+			f := EmailFailure{Message: msg, Attempts: attempts, Err: err}
+			q.mu.Lock()
+			q.failures = append(q.failures, f)
+			q.mu.Unlock()
+			if q.onFailure != nil {
+				q.onFailure(f)
+			}
+		}
+	}
+}
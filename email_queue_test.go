@@ -0,0 +1,122 @@
+package moodle
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type flakyMailer struct {
+	mu       sync.Mutex
+	failures map[string]int
+	sent     []string
+}
+
+func (f *flakyMailer) Send(msg *EmailMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures[msg.ToEmail] > 0 {
+		f.failures[msg.ToEmail]--
+		return errors.New("temporary failure")
+	}
+	f.sent = append(f.sent, msg.ToEmail)
+	return nil
+}
+
+func TestQueuedMailer(t *testing.T) {
+
+	f := &flakyMailer{failures: map[string]int{"retry@example.com": 2, "fail@example.com": 10}}
+	q := NewQueuedMailer(f)
+	q.SetRetries(2, time.Millisecond)
+	q.SetRateLimit(time.Millisecond)
+
+	var reported []EmailFailure
+	q.SetFailureHandler(func(e EmailFailure) {
+		reported = append(reported, e)
+	})
+
+	for _, to := range []string{"ok@example.com", "retry@example.com", "fail@example.com"} {
+		if err := q.Send(&EmailMessage{ToEmail: to, Text: "Hi"}); err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+	}
+
+	failures := q.Close()
+	if len(f.sent) != 2 {
+		t.Errorf("Expected two messages to be delivered, found %v", f.sent)
+	}
+	if len(failures) != 1 || failures[0].Message.ToEmail != "fail@example.com" {
+		t.Fatalf("Expected fail@example.com to be reported as failed: %v", failures)
+	}
+	if failures[0].Attempts != 3 {
+		t.Errorf("Expected three attempts, found %d", failures[0].Attempts)
+	}
+	if len(reported) != 1 {
+		t.Errorf("Failure handler should be called once, not %d times", len(reported))
+	}
+
+	if err := q.Send(&EmailMessage{ToEmail: "late@example.com", Text: "Hi"}); err == nil {
+		t.Errorf("Send() should fail after Close()")
+	}
+}
+
+type blockingMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    int
+}
+
+func (b *blockingMailer) Send(msg *EmailMessage) error {
+	<-b.release
+	b.mu.Lock()
+	b.sent++
+	b.mu.Unlock()
+	return nil
+}
+
+func TestQueuedMailerCloseWhenFull(t *testing.T) {
+
+	b := &blockingMailer{release: make(chan struct{})}
+	q := NewQueuedMailer(b)
+
+	// One message is held by the mailer and the rest fill the queue
+	queued := cap(q.queue) + 1
+	for i := 0; i < queued; i++ {
+		if err := q.Send(&EmailMessage{ToEmail: "student@example.com"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	waiting := make(chan error)
+	go func() {
+		waiting <- q.Send(&EmailMessage{ToEmail: "late@example.com"})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	closed := make(chan []EmailFailure)
+	go func() {
+		closed <- q.Close()
+	}()
+
+	select {
+	case err := <-waiting:
+		if err == nil {
+			t.Errorf("Expected Send to a full queue to fail when the queue is closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close not to wait for a Send blocked on a full queue")
+	}
+
+	close(b.release)
+	select {
+	case failures := <-closed:
+		if len(failures) != 0 {
+			t.Errorf("Unexpected failures: %+v", failures)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close did not return")
+	}
+	if b.sent != queued {
+		t.Errorf("Expected the %d queued messages to be delivered, found %d", queued, b.sent)
+	}
+}
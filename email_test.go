@@ -0,0 +1,118 @@
+package moodle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestEmailMessageMultipart(t *testing.T) {
+
+	tmpl := EmailTemplate{
+		Subject: "Welcome {{.FirstName}}",
+		Text:    "Hi {{.FirstName}}, your password is {{.Password}}",
+		Html:    "<p>Hi {{.FirstName}}, your password is <b>{{.Password}}</b></p>",
+	}
+	msg, err := tmpl.Render(&PasswordEmailData{FirstName: "Zoë <Admin>", Password: "abcde-12345"})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	msg.FromName = "College"
+	msg.FromEmail = "college@example.com"
+	msg.ToName = "Zoë"
+	msg.ToEmail = "zoe@example.com"
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Message could not be parsed: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "Welcome Zoë <Admin>" {
+		t.Errorf("Subject should decode to \"Welcome Zoë <Admin>\", not %q", subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, found %q", mediaType)
+	}
+
+	r := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := r.NextRawPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(quotedprintable.NewReader(p))
+		parts = append(parts, p.Header.Get("Content-Type")+"|"+string(body))
+	}
+	if len(parts) != 2 {
+		t.Fatalf("Expected two parts, found %d", len(parts))
+	}
+	if !strings.HasPrefix(parts[0], "text/plain") || !strings.Contains(parts[0], "Zoë <Admin>") {
+		t.Errorf("Unexpected text part: %s", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "text/html") || !strings.Contains(parts[1], "Zoë &lt;Admin&gt;") {
+		t.Errorf("Html part should contain escaped name: %s", parts[1])
+	}
+}
+
+func TestEmailMessageTextOnly(t *testing.T) {
+
+	msg := &EmailMessage{FromEmail: "a@example.com", ToEmail: "b@example.com", Subject: "Hi", Text: "Line one\nLine two"}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Message could not be parsed: %v", err)
+	}
+	if !strings.HasPrefix(m.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected text/plain message, found %s", m.Header.Get("Content-Type"))
+	}
+	body, _ := ioutil.ReadAll(quotedprintable.NewReader(m.Body))
+	if string(body) != "Line one\r\nLine two" {
+		t.Errorf("Unexpected body %q", string(body))
+	}
+
+	msg.Text = ""
+	if _, err := msg.Bytes(); err == nil {
+		t.Errorf("Bytes() should fail when there is no body")
+	}
+}
+
+func TestPasswordEmailTemplateForLang(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "")
+	if api.passwordEmailTemplate("fr") != &defaultPasswordEmail {
+		t.Errorf("Built in template should be used when no templates are set")
+	}
+
+	api.SetPasswordEmailTemplate(EmailTemplate{Subject: "Welcome"})
+	api.SetPasswordEmailTemplateForLang("pt", EmailTemplate{Subject: "Bem-vindo"})
+	api.SetPasswordEmailTemplateForLang("pt-BR", EmailTemplate{Subject: "Bem-vindo (BR)"})
+
+	for lang, subject := range map[string]string{
+		"":      "Welcome",
+		"en":    "Welcome",
+		"pt":    "Bem-vindo",
+		"pt_pt": "Bem-vindo",
+		"pt_br": "Bem-vindo (BR)",
+		"PT_BR": "Bem-vindo (BR)",
+	} {
+		if s := api.passwordEmailTemplate(lang).Subject; s != subject {
+			t.Errorf("Language %q should use template %q, not %q", lang, subject, s)
+		}
+	}
+}
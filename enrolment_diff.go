@@ -0,0 +1,204 @@
+package moodle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// EnrolmentChangeType describes how a person's enrolment in a course changed
+type EnrolmentChangeType string
+
+const (
+	EnrolmentAdded       EnrolmentChangeType = "added"
+	EnrolmentRemoved     EnrolmentChangeType = "removed"
+	EnrolmentRoleChanged EnrolmentChangeType = "role-changed"
+)
+
+// EnrolmentChange is a difference between two lists of the people in a
+// course. Person is the current record, or the previous record if the
+// person was removed.
+type EnrolmentChange struct {
+	Type     EnrolmentChangeType
+	CourseId CourseID
+	Person   CoursePerson
+	OldRoles []CourseRole
+	NewRoles []CourseRole
+}
+
+// DiffEnrolments compares two lists of the people in a course, as returned
+// by GetCourseRoles, returning the people added, removed, and whose roles
+// changed. Changes are ordered by user id. CourseId is not set.
+func DiffEnrolments(previous, current []CoursePerson) []EnrolmentChange {
+	before := make(map[UserID]CoursePerson, len(previous))
+	for _, p := range previous {
+		before[p.Id] = p
+	}
+	after := make(map[UserID]CoursePerson, len(current))
+	for _, p := range current {
+		after[p.Id] = p
+	}
+
+	var changes []EnrolmentChange
+	for id, p := range after {
+		old, ok := before[id]
+		if !ok {
+			changes = append(changes, EnrolmentChange{Type: EnrolmentAdded, Person: p, NewRoles: p.Roles})
+		} else if !sameRoles(old.Roles, p.Roles) {
+			changes = append(changes, EnrolmentChange{Type: EnrolmentRoleChanged, Person: p, OldRoles: old.Roles, NewRoles: p.Roles})
+		}
+	}
+	for id, p := range before {
+		if _, ok := after[id]; !ok {
+			changes = append(changes, EnrolmentChange{Type: EnrolmentRemoved, Person: p, OldRoles: p.Roles})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Person.Id < changes[j].Person.Id })
+	return changes
+}
+
+// sameRoles reports whether two lists hold the same roles in any order.
+// Roles are compared by id and short name, as the id is not included in
+// every response.
+func sameRoles(a, b []CourseRole) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	type key struct {
+		id        RoleID
+		shortName string
+	}
+	roles := make(map[key]int)
+	for _, r := range a {
+		roles[key{r.Id, r.ShortName}]++
+	}
+	for _, r := range b {
+		k := key{r.Id, r.ShortName}
+		roles[k]--
+		if roles[k] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SnapshotStore saves the people in each course between runs of an
+// EnrolmentWatcher.
+type SnapshotStore interface {
+	// Load returns the saved people in a course, and false if the course
+	// has not been saved.
+	Load(courseId CourseID) ([]CoursePerson, bool, error)
+	Save(courseId CourseID, people []CoursePerson) error
+}
+
+// EnrolmentWatcher reports changes to the people in courses since the
+// previous check, for example to grant and revoke library access.
+//
+//	w := moodle.NewEnrolmentWatcher(api, moodle.NewFileSnapshotStore("/var/lib/sync/enrolments"))
+//	changes, err := w.Check(courseIds...)
+type EnrolmentWatcher struct {
+	api   *MoodleApi
+	store SnapshotStore
+}
+
+// NewEnrolmentWatcher returns a watcher that saves snapshots in store
+func NewEnrolmentWatcher(api *MoodleApi, store SnapshotStore) *EnrolmentWatcher {
+	return &EnrolmentWatcher{api: api, store: store}
+}
+
+// Check fetches the people in each course, returning the changes since the
+// previous check and saving the new snapshot. The first check of a course
+// reports everyone in the course as added. If a course fails, the changes
+// found in earlier courses are returned with the error.
+func (w *EnrolmentWatcher) Check(courseIds ...CourseID) ([]EnrolmentChange, error) {
+	var changes []EnrolmentChange
+	for _, courseId := range courseIds {
+		current, err := w.api.GetCourseRoles(courseId)
+		if err != nil {
+			return changes, err
+		}
+		previous, _, err := w.store.Load(courseId)
+		if err != nil {
+			return changes, err
+		}
+		for _, c := range DiffEnrolments(previous, current) {
+			c.CourseId = courseId
+			changes = append(changes, c)
+		}
+		if err := w.store.Save(courseId, current); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// MemorySnapshotStore is a SnapshotStore held in memory
+type MemorySnapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[CourseID][]CoursePerson
+}
+
+func (s *MemorySnapshotStore) Load(courseId CourseID) ([]CoursePerson, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	people, ok := s.snapshots[courseId]
+	return people, ok, nil
+}
+
+func (s *MemorySnapshotStore) Save(courseId CourseID, people []CoursePerson) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshots == nil {
+		s.snapshots = make(map[CourseID][]CoursePerson)
+	}
+	s.snapshots[courseId] = people
+	return nil
+}
+
+// FileSnapshotStore is a SnapshotStore that saves each course as a json file
+// in a directory. The files hold the names and email addresses of everyone
+// in the course.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a store that saves files in dir, which must
+// exist.
+func NewFileSnapshotStore(dir string) *FileSnapshotStore {
+	return &FileSnapshotStore{dir: dir}
+}
+
+func (s *FileSnapshotStore) path(courseId CourseID) string {
+	return filepath.Join(s.dir, fmt.Sprintf("course-%d.json", courseId))
+}
+
+func (s *FileSnapshotStore) Load(courseId CourseID) ([]CoursePerson, bool, error) {
+	data, err := ioutil.ReadFile(s.path(courseId))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var people []CoursePerson
+	if err := json.Unmarshal(data, &people); err != nil {
+		return nil, false, err
+	}
+	return people, true, nil
+}
+
+func (s *FileSnapshotStore) Save(courseId CourseID, people []CoursePerson) error {
+	data, err := json.Marshal(people)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(courseId) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(courseId))
+}
@@ -0,0 +1,73 @@
+package moodle
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDiffEnrolments(t *testing.T) {
+
+	student := CourseRole{Id: 5, ShortName: "student"}
+	teacher := CourseRole{Id: 3, ShortName: "editingteacher"}
+	previous := []CoursePerson{
+		{Id: 1, Roles: []CourseRole{student}},
+		{Id: 2, Roles: []CourseRole{student}},
+		{Id: 3, Roles: []CourseRole{student, teacher}},
+	}
+	current := []CoursePerson{
+		{Id: 1, Roles: []CourseRole{student}},
+		{Id: 2, Roles: []CourseRole{teacher}},
+		{Id: 3, Roles: []CourseRole{teacher, student}},
+		{Id: 4, Roles: []CourseRole{student}},
+	}
+	previous = append(previous, CoursePerson{Id: 5, Roles: []CourseRole{student}})
+
+	changes := DiffEnrolments(previous, current)
+	if len(changes) != 3 {
+		t.Fatalf("Expected three changes, found %+v", changes)
+	}
+	if changes[0].Type != EnrolmentRoleChanged || changes[0].Person.Id != 2 || changes[0].OldRoles[0].Id != 5 || changes[0].NewRoles[0].Id != 3 {
+		t.Errorf("Unexpected change: %+v", changes[0])
+	}
+	if changes[1].Type != EnrolmentAdded || changes[1].Person.Id != 4 {
+		t.Errorf("Unexpected change: %+v", changes[1])
+	}
+	if changes[2].Type != EnrolmentRemoved || changes[2].Person.Id != 5 {
+		t.Errorf("Unexpected change: %+v", changes[2])
+	}
+}
+
+func TestEnrolmentWatcher(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_enrol_get_enrolled_users": `[{"id":1,"username":"sam","roles":[{"roleid":5,"name":"","shortname":"student"}]}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	dir, err := ioutil.TempDir("", "moodle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := NewEnrolmentWatcher(api, NewFileSnapshotStore(dir))
+	changes, err := w.Check(5)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Type != EnrolmentAdded || changes[0].CourseId != 5 {
+		t.Errorf("Expected everyone to be added on the first check, found %+v", changes)
+	}
+
+	fetch.responses["core_enrol_get_enrolled_users"] = `[{"id":2,"username":"alex","roles":[]}]`
+	w = NewEnrolmentWatcher(api, NewFileSnapshotStore(dir))
+	changes, err = w.Check(5)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Type != EnrolmentRemoved || changes[0].Person.Username != "sam" || changes[1].Type != EnrolmentAdded {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
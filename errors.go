@@ -0,0 +1,150 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the api wrap one of these errors where the cause of the
+// failure is known, so that callers can check for them using errors.Is.
+//
+//	p, err := api.GetPersonByUsername(username)
+//	if errors.Is(err, moodle.ErrMultipleMatches) {
+//		...
+//	}
+var (
+	// ErrNotFound is returned when a record does not exist in moodle
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidToken is returned when moodle rejects the web service token
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrPermissionDenied is returned when the web service user lacks the
+	// capability required by a function, or the function is not enabled
+	// for the web service.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrMultipleMatches is returned when a lookup expected to find one
+	// record found several.
+	ErrMultipleMatches = errors.New("multiple matches")
+
+	// ErrResponseTooLarge is returned when a response exceeds the maximum
+	// size set with SetMaxResponseSize.
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrUsernameTaken is returned when a username is already used by
+	// another account.
+	ErrUsernameTaken = errors.New("username taken")
+)
+
+// errorCodes maps moodle exception error codes to errors
+var errorCodes = map[string]error{
+	"invalidtoken":               ErrInvalidToken,
+	"invalidlogin":               ErrInvalidToken,
+	"accessexception":            ErrPermissionDenied,
+	"nopermissions":              ErrPermissionDenied,
+	"requireloginerror":          ErrPermissionDenied,
+	"servicenotavailable":        ErrPermissionDenied,
+	"webservicefunctionnotfound": ErrPermissionDenied,
+	"invalidrecord":              ErrNotFound,
+	"invalidrecordunknown":       ErrNotFound,
+	"invaliduser":                ErrNotFound,
+	"invalidcourseid":            ErrNotFound,
+	"coursenotexist":             ErrNotFound,
+	"invalidcoursemodule":        ErrNotFound,
+	"usernameexists":             ErrUsernameTaken,
+}
+
+// retryableErrorCodes are moodle exceptions raised when moodle could not
+// obtain a lock or was otherwise interrupted before completing the call, so
+// that the same call usually succeeds when repeated.
+var retryableErrorCodes = map[string]bool{
+	"locktimeout":    true,
+	"sessionwaiterr": true,
+	"codingerror":    true,
+}
+
+// wrapError returns an error with message that wraps cause. If cause is nil
+// a plain error is returned.
+func wrapError(message string, cause error) error {
+	if cause == nil {
+		return errors.New(message)
+	}
+	return fmt.Errorf("%s (%w)", message, cause)
+}
+
+// MoodleError is returned when a web service call fails, either because the
+// request could not be made or because moodle raised an exception. Use
+// errors.As to inspect the details of a failed call.
+//
+//	var merr *moodle.MoodleError
+//	if errors.As(err, &merr) {
+//		log.Printf("%s failed with status %d: %s", merr.Function, merr.StatusCode, merr.ErrorCode)
+//	}
+type MoodleError struct {
+	// RequestId identifies the call in log output, and is sent to moodle in
+	// the RequestIdHeader header.
+	RequestId string
+
+	// Function is the web service function that was called
+	Function string
+
+	// StatusCode is the http status of the response, or zero if no response
+	// was received.
+	StatusCode int
+
+	// Url is the url that was called, with any secrets masked
+	Url string
+
+	// ErrorCode and Exception are set when moodle raised an exception, such
+	// as "invalidtoken" and "moodle_exception". Branch on ErrorCode, or use
+	// errors.Is with a sentinel error for the common codes.
+	ErrorCode string
+	Exception string
+
+	// DebugInfo holds moodle's debugging detail for an exception, such as
+	// the failing SQL. Moodle only sends it when developer debugging is
+	// enabled on the site.
+	DebugInfo string
+
+	// Message describes the failure
+	Message string
+
+	// Kind is the sentinel error matching the failure, such as
+	// ErrInvalidToken, or nil if the cause is not known.
+	Kind error
+
+	// Err is the transport error, if the request could not be made
+	Err error
+}
+
+func (e *MoodleError) Error() string {
+	message := e.Message
+	if e.Kind != nil {
+		message = message + " (" + e.Kind.Error() + ")"
+	}
+	if e.RequestId != "" {
+		message = message + " [request " + e.RequestId + "]"
+	}
+	return message
+}
+
+// Unwrap returns the transport error, if any
+func (e *MoodleError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the call may succeed if it is repeated, because
+// moodle reported a lock or session timeout, or moodle could not be reached.
+// Other exceptions, such as invalid parameters, are permanent.
+func (e *MoodleError) Retryable() bool {
+	if e.Exception != "" || e.ErrorCode != "" {
+		return retryableErrorCodes[e.ErrorCode]
+	}
+	return unreachable(e)
+}
+
+// Is reports whether the failure matches a sentinel error
+func (e *MoodleError) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
@@ -0,0 +1,110 @@
+package moodle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_user_get_users_by_field":  `[{"id":2,"username":"a"},{"id":3,"username":"a"}]`,
+		"core_webservice_get_site_info": `{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`,
+		"core_course_search_courses":    `{"exception":"required_capability_exception","errorcode":"nopermissions","message":"Sorry, but you do not currently have permissions to do that"}`,
+	}))
+
+	_, err := api.GetPersonByUsername("a")
+	if !errors.Is(err, ErrMultipleMatches) {
+		t.Errorf("Expected ErrMultipleMatches, found %v", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "Multiple moodle accounts match") {
+		t.Errorf("Expected error message to be unchanged, found %q", err.Error())
+	}
+
+	_, _, _, _, err = api.GetSiteInfo()
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, found %v", err)
+	}
+
+	_, err = api.GetCourses("")
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, found %v", err)
+	}
+}
+
+func TestMoodleError(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "secrettoken")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`,
+	}))
+
+	_, _, _, _, err := api.GetSiteInfo()
+	var merr *MoodleError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Expected a MoodleError, found %v", err)
+	}
+	if merr.Function != "core_webservice_get_site_info" || merr.ErrorCode != "invalidtoken" || merr.Exception != "moodle_exception" || merr.StatusCode != 200 {
+		t.Errorf("Unexpected error details: %+v", merr)
+	}
+	if merr.Url != "https://moodle.example.com/webservice/rest/server.php?wsfunction=core_webservice_get_site_info" {
+		t.Errorf("Unexpected error url: %s", merr.Url)
+	}
+	if strings.Contains(err.Error(), "http") || strings.Contains(err.Error(), "secrettoken") {
+		t.Errorf("Expected error message to exclude the url, found %q", err.Error())
+	}
+
+	// Transport failures keep the underlying error
+	_, err = api.GetCourses("")
+	if !errors.As(err, &merr) || merr.StatusCode != 404 || merr.Err == nil || merr.Function != "core_course_search_courses" {
+		t.Errorf("Unexpected transport error: %+v", merr)
+	}
+	if errors.Unwrap(err) != merr.Err {
+		t.Errorf("Expected the transport error to be unwrapped")
+	}
+}
+
+func TestMoodleErrorDebugInfo(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_course_search_courses": `{"exception":"dml_read_exception","errorcode":"dmlreadexception","message":"Error reading from database","debuginfo":"Unknown column 'x' in 'where clause'"}`,
+		"core_course_get_courses":    `{"exception":"invalid_parameter_exception","errorcode":"invalidparameter"}`,
+	}))
+
+	_, err := api.GetCourses("")
+	var merr *MoodleError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Expected a MoodleError, found %v", err)
+	}
+	if merr.ErrorCode != "dmlreadexception" || merr.Exception != "dml_read_exception" || merr.DebugInfo != "Unknown column 'x' in 'where clause'" || merr.Message != "Error reading from database" {
+		t.Errorf("Unexpected error details: %+v", merr)
+	}
+
+	err = api.Call(nil, "core_course_get_courses", nil, nil)
+	if !errors.As(err, &merr) || merr.ErrorCode != "invalidparameter" {
+		t.Errorf("Expected Call to return a MoodleError, found %v", err)
+	}
+}
+
+func TestRetryableErrors(t *testing.T) {
+
+	tests := []struct {
+		err       *MoodleError
+		retryable bool
+	}{
+		{&MoodleError{Exception: "moodle_exception", ErrorCode: "locktimeout"}, true},
+		{&MoodleError{Exception: "coding_exception", ErrorCode: "codingerror"}, true},
+		{&MoodleError{Exception: "invalid_parameter_exception", ErrorCode: "invalidparameter"}, false},
+		{&MoodleError{StatusCode: 503}, true},
+		{&MoodleError{StatusCode: 0, Err: errors.New("connection refused")}, true},
+		{&MoodleError{StatusCode: 404}, false},
+	}
+	for _, test := range tests {
+		if test.err.Retryable() != test.retryable {
+			t.Errorf("Expected retryable %v for %+v", test.retryable, test.err)
+		}
+	}
+}
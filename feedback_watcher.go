@@ -0,0 +1,152 @@
+package moodle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FeedbackReady is a grade given to a student since the previous check of a
+// FeedbackWatcher
+type FeedbackReady struct {
+	AssignmentId int64
+	UserId       UserID
+	Grade        float64
+	Graded       time.Time
+}
+
+// FeedbackWatcher reports assignment grades given since the previous check,
+// for example to tell students their feedback is ready. Assignments using a
+// marking workflow are reported when graded, which may be before the grades
+// are released to students.
+//
+//	w := moodle.NewFeedbackWatcher(api, lastRun)
+//	err := w.Check(assignmentIds, api.NotifyFeedbackByEmail(template))
+//	lastRun = w.Checkpoint()
+type FeedbackWatcher struct {
+	api        *MoodleApi
+	checkpoint int64
+
+	// notified holds the grades modified in the same second as the
+	// checkpoint that have already been reported
+	notified map[int64]bool
+}
+
+// NewFeedbackWatcher returns a watcher reporting grades given after since.
+// Pass the Checkpoint saved from a previous run, or the current time to only
+// report grades given from now on. A zero time reports every grade on the
+// first check.
+func NewFeedbackWatcher(api *MoodleApi, since time.Time) *FeedbackWatcher {
+	w := &FeedbackWatcher{api: api, notified: make(map[int64]bool)}
+	if !since.IsZero() {
+		w.checkpoint = since.Unix()
+	}
+	return w
+}
+
+// Checkpoint returns the time of the most recent grade reported, to be saved
+// and passed to NewFeedbackWatcher on the next run
+func (w *FeedbackWatcher) Checkpoint() time.Time {
+	return time.Unix(w.checkpoint, 0)
+}
+
+// Check fetches the grades of the assignments, calling fn for each grade
+// given since the previous check, oldest first. Grades moodle records as
+// ungraded are skipped. If fn returns an error checking stops, and the grade
+// is reported again by the next check.
+func (w *FeedbackWatcher) Check(assignmentIds []int64, fn func(FeedbackReady) error) error {
+	if len(assignmentIds) == 0 {
+		return nil
+	}
+
+	// A cached response would hide grades given since it was stored
+	w.api.InvalidateCache("mod_assign_get_grades")
+	records, err := w.api.getAssignmentGrades(assignmentIds, w.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		assignmentId int64
+		grade        GradeRecord
+	}
+	var grades []pending
+	for _, a := range records {
+		for _, g := range a.Grades {
+			if g.Grade < 0 || g.TimeModified < w.checkpoint || (g.TimeModified == w.checkpoint && w.notified[g.Id]) {
+				continue
+			}
+			grades = append(grades, pending{a.AssignmentId, g})
+		}
+	}
+	sort.Slice(grades, func(i, j int) bool {
+		if grades[i].grade.TimeModified != grades[j].grade.TimeModified {
+			return grades[i].grade.TimeModified < grades[j].grade.TimeModified
+		}
+		return grades[i].grade.Id < grades[j].grade.Id
+	})
+
+	for _, p := range grades {
+		err := fn(FeedbackReady{
+			AssignmentId: p.assignmentId,
+			UserId:       p.grade.UserId,
+			Grade:        p.grade.Grade,
+			Graded:       w.api.unix(p.grade.TimeModified),
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to report the grade of user %d in assignment %d. %w", p.grade.UserId, p.assignmentId, err)
+		}
+		if p.grade.TimeModified > w.checkpoint {
+			w.checkpoint = p.grade.TimeModified
+			w.notified = make(map[int64]bool)
+		}
+		w.notified[p.grade.Id] = true
+	}
+	return nil
+}
+
+// FeedbackEmailData is passed to an EmailTemplate when rendering the email
+// sent by NotifyFeedbackByEmail. Url links to the assignment.
+type FeedbackEmailData struct {
+	FirstName    string
+	LastName     string
+	AssignmentId int64
+	Grade        float64
+	Url          string
+}
+
+// NotifyFeedbackByEmail returns a FeedbackWatcher callback that emails each
+// student using the template and the configured mailer
+func (m *MoodleApi) NotifyFeedbackByEmail(t EmailTemplate) func(FeedbackReady) error {
+	cmids := make(map[int64]CmID)
+	return func(f FeedbackReady) error {
+		p, err := m.GetPersonByMoodleId(f.UserId)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return wrapError("Account not found in moodle", ErrNotFound)
+		}
+		cmid, ok := cmids[f.AssignmentId]
+		if !ok {
+			if cmid, err = m.ResolveCmId(f.AssignmentId); err != nil {
+				return err
+			}
+			cmids[f.AssignmentId] = cmid
+		}
+
+		msg, err := t.Render(&FeedbackEmailData{
+			FirstName:    p.FirstName,
+			LastName:     p.LastName,
+			AssignmentId: f.AssignmentId,
+			Grade:        f.Grade,
+			Url:          fmt.Sprintf("%smod/assign/view.php?id=%d", m.base, cmid),
+		})
+		if err != nil {
+			return err
+		}
+		msg.ToName = p.FirstName + " " + p.LastName
+		msg.ToEmail = p.Email
+		return m.sendEmail(msg)
+	}
+}
@@ -0,0 +1,113 @@
+package moodle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedbackWatcher(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_get_grades": `{"assignments":[` +
+			`{"assignmentid":7,"grades":[{"id":1,"userid":5,"timemodified":1700000100,"grade":"70.00"},{"id":2,"userid":6,"timemodified":1700000000,"grade":"-1.00"}]},` +
+			`{"assignmentid":8,"grades":[{"id":3,"userid":5,"timemodified":1700000050,"grade":"85.50"}]}]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	var found []FeedbackReady
+	record := func(f FeedbackReady) error {
+		found = append(found, f)
+		return nil
+	}
+
+	w := NewFeedbackWatcher(api, time.Unix(1699999000, 0))
+	if err := w.Check([]int64{7, 8}, record); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(found) != 2 || found[0].AssignmentId != 8 || found[0].Grade != 85.5 || found[1].AssignmentId != 7 || found[1].UserId != 5 {
+		t.Errorf("Expected the graded submissions oldest first, found %+v", found)
+	}
+	if r := fetch.last(); r.Get("since") != "1699999000" || r.Get("assignmentids[1]") != "8" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if w.Checkpoint().Unix() != 1700000100 {
+		t.Errorf("Expected the checkpoint to move to the latest grade, found %v", w.Checkpoint())
+	}
+
+	// Moodle includes grades modified in the second of the checkpoint
+	found = nil
+	fetch.responses["mod_assign_get_grades"] = `{"assignments":[{"assignmentid":7,"grades":[{"id":1,"userid":5,"timemodified":1700000100,"grade":"70.00"},{"id":4,"userid":9,"timemodified":1700000100,"grade":"60.00"}]}]}`
+	if err := w.Check([]int64{7, 8}, record); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(found) != 1 || found[0].UserId != 9 {
+		t.Errorf("Expected only the new grade to be reported, found %+v", found)
+	}
+	if r := fetch.last(); r.Get("since") != "1700000100" {
+		t.Errorf("Expected grades since the checkpoint to be requested, found %v", r)
+	}
+
+	fetch.responses["mod_assign_get_grades"] = `{"assignments":[{"assignmentid":7,"grades":[{"id":5,"userid":10,"timemodified":1700000200,"grade":"50.00"},{"id":6,"userid":11,"timemodified":1700000300,"grade":"55.00"}]}]}`
+	found = nil
+	fail := func(f FeedbackReady) error {
+		if f.UserId == 11 {
+			return errors.New("mail server down")
+		}
+		return record(f)
+	}
+	if err := w.Check([]int64{7}, fail); err == nil {
+		t.Errorf("Expected the callback error to be returned")
+	}
+	if w.Checkpoint().Unix() != 1700000200 {
+		t.Errorf("Expected the checkpoint to stop before the failed grade, found %v", w.Checkpoint())
+	}
+	found = nil
+	if err := w.Check([]int64{7}, record); err != nil || len(found) != 1 || found[0].UserId != 11 {
+		t.Errorf("Expected the failed grade to be reported again, found %+v %v", found, err)
+	}
+}
+
+func TestNotifyFeedbackByEmail(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_users_by_field":              `[{"id":5,"username":"ann","firstname":"Ann","lastname":"Lee","email":"ann@example.com"}]`,
+		"core_course_get_course_module_by_instance": `{"cm":{"id":101,"course":3,"instance":7,"modname":"assign","visible":1},"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	mailer := &messageMailer{}
+	api.SetMailer(mailer, "College", "college@example.com")
+
+	notify := api.NotifyFeedbackByEmail(EmailTemplate{Subject: "Your feedback is ready", Text: "Hi {{.FirstName}}, see {{.Url}}"})
+	if err := notify(FeedbackReady{AssignmentId: 7, UserId: 5, Grade: 70}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if err := notify(FeedbackReady{AssignmentId: 7, UserId: 5, Grade: 70}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(mailer.sent) != 2 || mailer.sent[0].ToEmail != "ann@example.com" || !strings.Contains(mailer.sent[0].Text, "https://moodle.example.com/mod/assign/view.php?id=101") {
+		t.Errorf("Expected a link to the assignment to be emailed, found %+v", mailer.sent)
+	}
+	count := 0
+	for _, r := range fetch.requests {
+		if r.Get("wsfunction") == "core_course_get_course_module_by_instance" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected the course module to be looked up once, found %d", count)
+	}
+}
+
+// messageMailer records the messages sent
+type messageMailer struct {
+	sent []*EmailMessage
+}
+
+func (f *messageMailer) Send(msg *EmailMessage) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
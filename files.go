@@ -0,0 +1,188 @@
+package moodle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MoodleFile describes a file stored by moodle, such as a submitted file or
+// feedback file. Url may be passed to DownloadFile.
+type MoodleFile struct {
+	FileName string    `json:"filename"`
+	FilePath string    `json:"filepath"`
+	Size     int64     `json:"filesize"`
+	Url      string    `json:"fileurl"`
+	MimeType string    `json:"mimetype"`
+	Modified time.Time `json:"-"`
+}
+
+// FileLookupUrl is implemented by a LookupUrl that can download files of
+// any content type, such as PDFs and images, which are otherwise rejected as
+// non-text responses. The body is returned unread and unmodified, and must
+// be closed by the caller.
+type FileLookupUrl interface {
+	DoFile(method, url string, form url.Values, header http.Header) (io.ReadCloser, int, error)
+}
+
+// DownloadFile fetches the contents of a file using the url reported by a
+// web service function. Moodle only serves these files to web service
+// clients through webservice/pluginfile.php, so urls to pluginfile.php are
+// rewritten. Files that are not text can only be downloaded if the url
+// fetcher implements FileLookupUrl, as DefaultLookupUrl does.
+func (m *MoodleApi) DownloadFile(fileUrl string) ([]byte, error) {
+	if _, ok := m.fetch.(FileLookupUrl); ok {
+		r, err := m.OpenFile(fileUrl)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+
+	fileUrl, params, header, id, err := m.fileRequest(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	body, status, _, err := m.fetch.Do("POST", fileUrl, params, header)
+	if err != nil {
+		return nil, m.transportError(id, "pluginfile", fileUrl, status, err)
+	}
+	if status != http.StatusOK {
+		return nil, m.transportError(id, "pluginfile", fileUrl, status, fmt.Errorf("Download failed with status %d", status))
+	}
+	return []byte(body), nil
+}
+
+// OpenFile is like DownloadFile, but returns the contents as they arrive
+// when the url fetcher implements FileLookupUrl. The caller must close the
+// file.
+func (m *MoodleApi) OpenFile(fileUrl string) (io.ReadCloser, error) {
+	f, ok := m.fetch.(FileLookupUrl)
+	if !ok {
+		data, err := m.DownloadFile(fileUrl)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	fileUrl, params, header, id, err := m.fileRequest(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	r, status, err := f.DoFile("POST", fileUrl, params, header)
+	if err != nil {
+		return nil, m.transportError(id, "pluginfile", fileUrl, status, err)
+	}
+	if status != http.StatusOK {
+		r.Close()
+		return nil, m.transportError(id, "pluginfile", fileUrl, status, fmt.Errorf("Download failed with status %d", status))
+	}
+	return r, nil
+}
+
+// fileRequest prepares a request for a file served by pluginfile.php
+func (m *MoodleApi) fileRequest(fileUrl string) (string, url.Values, http.Header, string, error) {
+	fileUrl = strings.Replace(fileUrl, "/webservice/pluginfile.php/", "/pluginfile.php/", 1)
+	fileUrl = strings.Replace(fileUrl, "/pluginfile.php/", "/webservice/pluginfile.php/", 1)
+
+	params := url.Values{}
+	header := m.header()
+	if err := m.credentials.Apply(params, header); err != nil {
+		return "", nil, nil, "", err
+	}
+	// pluginfile.php expects the token as "token" rather than "wstoken"
+	if token := params.Get("wstoken"); token != "" {
+		params.Del("wstoken")
+		params.Set("token", token)
+	}
+
+	id := newRequestId()
+	header.Set(RequestIdHeader, id)
+	m.debug("[%s] Download: %s", id, fileUrl)
+	if m.limiter != nil {
+		m.limiter.wait()
+	}
+	return fileUrl, params, header, id, nil
+}
+
+// GetAnnotatedFeedbackPdf finds the PDF of a submission annotated by the
+// marker using the assignfeedback_editpdf plugin. Returns nil if the
+// submission has not been annotated.
+func (m *MoodleApi) GetAnnotatedFeedbackPdf(assignmentId int64, userId UserID) (*MoodleFile, error) {
+	body, err := m.call("mod_assign_get_submission_status", url.Values{
+		"assignid": {fmt.Sprint(assignmentId)},
+		"userid":   {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type File struct {
+		MoodleFile
+		TimeModified int64 `json:"timemodified"`
+	}
+	type FileArea struct {
+		Area  string `json:"area"`
+		Files []File `json:"files"`
+	}
+	type Plugin struct {
+		Type      string     `json:"type"`
+		FileAreas []FileArea `json:"fileareas"`
+	}
+	type Feedback struct {
+		Plugins []Plugin `json:"plugins"`
+	}
+	type Result struct {
+		Feedback *Feedback `json:"feedback"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	if result.Feedback == nil {
+		return nil, nil
+	}
+	for _, p := range result.Feedback.Plugins {
+		if p.Type != "editpdf" {
+			continue
+		}
+		for _, a := range p.FileAreas {
+			if a.Area != "download" {
+				continue
+			}
+			for _, f := range a.Files {
+				file := f.MoodleFile
+				file.Modified = m.unix(f.TimeModified)
+				return &file, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// DownloadAnnotatedFeedbackPdf fetches the annotated PDF of a submission,
+// returning the file details and contents. Returns a nil file if the
+// submission has not been annotated.
+func (m *MoodleApi) DownloadAnnotatedFeedbackPdf(assignmentId int64, userId UserID) (*MoodleFile, []byte, error) {
+	file, err := m.GetAnnotatedFeedbackPdf(assignmentId, userId)
+	if err != nil || file == nil {
+		return nil, nil, err
+	}
+	data, err := m.DownloadFile(file.Url)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, data, nil
+}
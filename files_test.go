@@ -0,0 +1,81 @@
+package moodle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnnotatedFeedbackPdf(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_get_submission_status": `{"gradingsummary":{},"feedback":{"grade":{"grade":"75.00"},"plugins":[` +
+			`{"type":"comments","name":"Feedback comments","fileareas":[]},` +
+			`{"type":"editpdf","name":"Annotate PDF","fileareas":[{"area":"download","files":[{"filename":"feedback.pdf","filepath":"/","filesize":2048,"fileurl":"https://moodle.example.com/pluginfile.php/55/assignfeedback_editpdf/download/9/feedback.pdf","timemodified":1600000000,"mimetype":"application/pdf"}]}]}` +
+			`]},"warnings":[]}`,
+		"/webservice/pluginfile.php/55/assignfeedback_editpdf/download/9/feedback.pdf": "%PDF-1.4",
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "secret")
+	api.SetUrlFetcher(fetch)
+
+	file, data, err := api.DownloadAnnotatedFeedbackPdf(12, 7)
+	if err != nil {
+		t.Fatalf("DownloadAnnotatedFeedbackPdf failed: %v", err)
+	}
+	if file == nil || file.FileName != "feedback.pdf" || file.Size != 2048 || file.Modified.Unix() != 1600000000 {
+		t.Fatalf("Unexpected file: %+v", file)
+	}
+	if string(data) != "%PDF-1.4" {
+		t.Errorf("Unexpected contents: %q", data)
+	}
+	if q := fetch.last(); q.Get("token") != "secret" || q.Get("wstoken") != "" {
+		t.Errorf("Expected the token to be sent as a token parameter, found %v", q)
+	}
+
+	fetch.responses["mod_assign_get_submission_status"] = `{"gradingsummary":{},"warnings":[]}`
+	if file, err := api.GetAnnotatedFeedbackPdf(12, 8); err != nil || file != nil {
+		t.Errorf("Expected no file for an unmarked submission, found %+v %v", file, err)
+	}
+}
+
+func TestDownloadBinaryFile(t *testing.T) {
+
+	// Binary content with surrounding white space that must be preserved
+	pdf := []byte("\n%PDF-1.4\n\x00\xff\xfe binary \x00\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webservice/pluginfile.php/55/assignfeedback_editpdf/download/9/feedback.pdf" || r.FormValue("token") != "secret" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf)
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "secret")
+	fileUrl := server.URL + "/pluginfile.php/55/assignfeedback_editpdf/download/9/feedback.pdf"
+
+	data, err := api.DownloadFile(fileUrl)
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if !bytes.Equal(data, pdf) {
+		t.Errorf("Expected the file unchanged, found %q", data)
+	}
+
+	r, err := api.OpenFile(fileUrl)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	data, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(data, pdf) {
+		t.Errorf("Expected the file unchanged, found %q %v", data, err)
+	}
+
+	if _, err := api.DownloadFile(server.URL + "/pluginfile.php/55/missing.pdf"); err == nil {
+		t.Errorf("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,62 @@
+package moodle
+
+import (
+	"errors"
+	"sync"
+)
+
+// flightGroup coalesces identical calls made at the same time, so that one
+// request is sent to moodle and its response shared by every caller.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+	body string
+	err  error
+}
+
+func (g *flightGroup) do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.body, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	// Waiters are released even if fn panics, the panic continuing in the
+	// caller that made the call
+	completed := false
+	defer func() {
+		if !completed {
+			f.err = errors.New("Identical call in progress failed unexpectedly")
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.body, f.err = fn()
+	completed = true
+	return f.body, f.err
+}
+
+// SetCoalesceCalls controls whether identical calls that read data, made at
+// the same time by different goroutines, share one request to moodle.
+// Enabled by default.
+func (m *MoodleApi) SetCoalesceCalls(coalesce bool) {
+	if coalesce {
+		m.inflight = &flightGroup{}
+	} else {
+		m.inflight = nil
+	}
+}
@@ -0,0 +1,109 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowLookupUrl counts requests, delaying each so that calls overlap
+type slowLookupUrl struct {
+	testLookupUrl
+	count int32
+}
+
+func (s *slowLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	atomic.AddInt32(&s.count, 1)
+	time.Sleep(20 * time.Millisecond)
+	return s.testLookupUrl.Do(method, u, form, header)
+}
+
+func TestCoalesceCalls(t *testing.T) {
+
+	fetch := &slowLookupUrl{testLookupUrl: testLookupUrl{responses: map[string]string{
+		"core_group_get_course_groups": `[{"id":4,"name":"Tutorial A"}]`,
+		"core_group_add_group_members": `null`,
+	}}}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	run := func(fn func()) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				fn()
+			}()
+		}
+		wg.Wait()
+	}
+
+	run(func() {
+		groups, err := api.GetCourseGroups(3)
+		if err != nil || len(groups) != 1 {
+			t.Errorf("Unexpected groups: %v %v", groups, err)
+		}
+	})
+	if fetch.count != 1 {
+		t.Errorf("Expected identical calls to share one request, found %d", fetch.count)
+	}
+
+	// Calls that change data are never shared
+	fetch.count = 0
+	run(func() { api.AddPersonToCourseGroup(7, 4) })
+	if fetch.count != 10 {
+		t.Errorf("Expected each update to be sent, found %d", fetch.count)
+	}
+
+	fetch.count = 0
+	api.SetCoalesceCalls(false)
+	run(func() { api.GetCourseGroups(3) })
+	if fetch.count != 10 {
+		t.Errorf("Expected each call to be sent when coalescing is disabled, found %d", fetch.count)
+	}
+}
+
+func TestCoalesceCallsPanic(t *testing.T) {
+
+	g := &flightGroup{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		g.do("key", func() (string, error) {
+			close(started)
+			<-release
+			panic("broken")
+		})
+	}()
+
+	<-started
+	waiting := make(chan error)
+	go func() {
+		_, err := g.do("key", func() (string, error) { return "unexpected", nil })
+		waiting <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if r := <-panicked; r != "broken" {
+		t.Errorf("Expected the panic to continue in the caller, found %v", r)
+	}
+	select {
+	case err := <-waiting:
+		if err == nil {
+			t.Errorf("Expected the waiting call to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the waiting call to be released")
+	}
+
+	if body, err := g.do("key", func() (string, error) { return "ok", nil }); body != "ok" || err != nil {
+		t.Errorf("Expected a later call to be made, found %q %v", body, err)
+	}
+}
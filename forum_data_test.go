@@ -11,7 +11,7 @@ func TestGetForumsWithCourseId(t *testing.T) {
 	api.SetLogger(&PrintMoodleLogger{})
 
 	fmt.Println("Check for Forums")
-	forums, err := api.GetForumsWithCourseId([]int{194})
+	forums, err := api.GetForumsWithCourseId([]CourseID{194})
 	if err != nil {
 		t.Errorf("API call failed: %s", err)
 		return
@@ -1,5 +1,5 @@
 module github.com/zaddok/moodle
 
-go 1.12
+go 1.13
 
 require google.golang.org/appengine v1.6.6 // indirect
@@ -0,0 +1,89 @@
+package moodle
+
+import (
+	"sort"
+	"time"
+)
+
+// GradingBacklog lists the submissions to an assignment that are waiting to
+// be graded, longest waiting first.
+type GradingBacklog struct {
+	Assignment AssignmentInfo
+	Waiting    []UngradedSubmission
+}
+
+// UngradedSubmission is a submission made, or last changed, after it was
+// last graded
+type UngradedSubmission struct {
+	UserId    UserID
+	Submitted time.Time
+	Waiting   time.Duration
+
+	// Extension is set if the person was granted an extension
+	Extension *time.Time
+
+	// Overdue is true if the grading due date of the assignment has passed
+	Overdue bool
+}
+
+// GetGradingBacklog finds the submitted but ungraded submissions to each
+// assignment in the courses, for example to report the marking workload of
+// each course. Assignments without ungraded submissions are omitted. Each
+// assignment requires three calls to moodle.
+func (m *MoodleApi) GetGradingBacklog(courseIds []CourseID) ([]GradingBacklog, error) {
+	assignments, err := m.GetAssignmentsForCourses(courseIds)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var backlogs []GradingBacklog
+	for _, a := range assignments {
+		submissions, err := m.GetAssignmentSubmissions(a.Id)
+		if err != nil {
+			return nil, err
+		}
+		records, err := m.GetAssignmentGradeRecords(a.Id)
+		if err != nil {
+			return nil, err
+		}
+		waiting := ungradedSubmissions(submissions, records, a.GradingDueDate, now)
+		if len(waiting) > 0 {
+			backlogs = append(backlogs, GradingBacklog{Assignment: a, Waiting: waiting})
+		}
+	}
+	return backlogs, nil
+}
+
+// ungradedSubmissions compares submissions with grades. A submission is
+// ungraded if the person has no grade, or was graded before the submission
+// was last changed.
+func ungradedSubmissions(submissions []*AssignmentSubmission, records []AssignmentRecord, gradingDue *time.Time, now time.Time) []UngradedSubmission {
+	graded := make(map[UserID]int64)
+	for _, r := range records {
+		for _, g := range r.Grades {
+			if g.Grade >= 0 && g.TimeModified > graded[g.UserId] {
+				graded[g.UserId] = g.TimeModified
+			}
+		}
+	}
+
+	var waiting []UngradedSubmission
+	for _, s := range submissions {
+		if s.Status != "submitted" || s.TimeModified == nil {
+			continue
+		}
+		if t, ok := graded[s.UserId]; ok && t >= s.TimeModified.Unix() {
+			continue
+		}
+		waiting = append(waiting, UngradedSubmission{
+			UserId:    s.UserId,
+			Submitted: *s.TimeModified,
+			Waiting:   now.Sub(*s.TimeModified),
+			Extension: s.Extension,
+			Overdue:   gradingDue != nil && now.After(*gradingDue),
+		})
+	}
+	sort.SliceStable(waiting, func(i, j int) bool { return waiting[i].Waiting > waiting[j].Waiting })
+	return waiting
+}
@@ -0,0 +1,45 @@
+package moodle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGradingBacklog(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_get_assignments": `{"courses":[{"id":5,"shortname":"BIO101","fullname":"Biology","assignments":[{"id":12,"cmid":55,"name":"Essay","duedate":1600000000,"gradingduedate":1600600000}]}],"warnings":[]}`,
+		"mod_assign_get_submissions": `{"assignments":[{"assignmentid":12,"submissions":[` +
+			`{"id":100,"userid":7,"status":"submitted","gradingstatus":"notgraded","timemodified":1600000000},` +
+			`{"id":101,"userid":8,"status":"submitted","gradingstatus":"graded","timemodified":1600000000},` +
+			`{"id":102,"userid":9,"status":"submitted","gradingstatus":"graded","timemodified":1600500000},` +
+			`{"id":103,"userid":10,"status":"new","gradingstatus":"notgraded"},` +
+			`{"id":104,"userid":11,"status":"submitted","gradingstatus":"notgraded","timemodified":1600200000}` +
+			`]}],"warnings":[]}`,
+		"mod_assign_get_user_flags": `{"assignments":[],"warnings":[]}`,
+		"mod_assign_get_grades":     `{"assignments":[{"assignmentid":12,"grades":[{"id":1,"userid":8,"timemodified":1600100000,"grade":"70.00"},{"id":2,"userid":9,"timemodified":1600100000,"grade":"60.00"}]}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	backlogs, err := api.GetGradingBacklog([]CourseID{5})
+	if err != nil {
+		t.Fatalf("GetGradingBacklog failed: %v", err)
+	}
+	if len(backlogs) != 1 || backlogs[0].Assignment.Id != 12 {
+		t.Fatalf("Unexpected backlog: %+v", backlogs)
+	}
+
+	// Person 8 was graded after submitting, person 9 resubmitted after
+	// being graded, person 10 has not submitted.
+	waiting := backlogs[0].Waiting
+	if len(waiting) != 3 {
+		t.Fatalf("Expected three ungraded submissions, found %+v", waiting)
+	}
+	if waiting[0].UserId != 7 || waiting[1].UserId != 11 || waiting[2].UserId != 9 {
+		t.Errorf("Expected longest waiting first, found %+v", waiting)
+	}
+	if !waiting[0].Overdue || waiting[0].Submitted.Unix() != 1600000000 || waiting[0].Waiting < time.Since(time.Unix(1600000000, 0))-time.Minute {
+		t.Errorf("Unexpected submission: %+v", waiting[0])
+	}
+}
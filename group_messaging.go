@@ -0,0 +1,192 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// GroupEmailData is passed to an EmailTemplate when rendering an email to
+// each member of a course group. Data holds the value supplied by the caller.
+type GroupEmailData struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Group     string
+	Data      interface{}
+}
+
+// GetGroupMembers lists the people enrolled in a course who belong to a
+// group of the course.
+func (m *MoodleApi) GetGroupMembers(courseId CourseID, groupId GroupID) ([]CoursePerson, error) {
+	body, err := m.call("core_enrol_get_enrolled_users", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+		"options[0][name]":   {"groupid"},
+		"options[0][value]":  {fmt.Sprint(groupId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CoursePerson
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return results, nil
+}
+
+// GetCourseGroupByName finds a course group by name. Names are normalised
+// before they are compared, ignoring case and differences in white space, so
+// "Tutor  group a " matches "Tutor Group A". Returns ErrMultipleMatches if
+// the name matches more than one group.
+func (m *MoodleApi) GetCourseGroupByName(courseId CourseID, name string) (*CourseGroup, error) {
+	g, err := m.findCourseGroup(courseId, name, func(g *CourseGroup) string { return g.Name })
+	if g == nil && err == nil {
+		err = wrapError(fmt.Sprintf("Course %d has no group named %q", courseId, name), ErrNotFound)
+	}
+	return g, err
+}
+
+// GetCourseGroupByIdNumber finds a group in a course by its id number,
+// normalised in the same way as GetCourseGroupByName
+func (m *MoodleApi) GetCourseGroupByIdNumber(courseId CourseID, idNumber string) (*CourseGroup, error) {
+	g, err := m.findCourseGroup(courseId, idNumber, func(g *CourseGroup) string { return g.IdNumber })
+	if g == nil && err == nil {
+		err = wrapError(fmt.Sprintf("Course %d has no group with id number %q", courseId, idNumber), ErrNotFound)
+	}
+	return g, err
+}
+
+// findCourseGroup finds the group whose field matches key. Returns nil if no
+// group matches.
+func (m *MoodleApi) findCourseGroup(courseId CourseID, key string, field func(*CourseGroup) string) (*CourseGroup, error) {
+	key = normaliseGroupKey(key)
+	if key == "" {
+		return nil, nil
+	}
+	groups, err := m.GetCourseGroups(courseId)
+	if err != nil {
+		return nil, err
+	}
+	var found *CourseGroup
+	for i := range groups {
+		if normaliseGroupKey(field(&groups[i])) != key {
+			continue
+		}
+		if found != nil {
+			return nil, wrapError(fmt.Sprintf("Course %d has several groups matching %q", courseId, key), ErrMultipleMatches)
+		}
+		found = &groups[i]
+	}
+	return found, nil
+}
+
+// normaliseGroupKey lower cases a group name or id number and collapses
+// white space
+func normaliseGroupKey(key string) string {
+	return strings.ToLower(strings.Join(strings.Fields(key), " "))
+}
+
+// SendMessages sends a moodle instant message from the web service user to
+// each person. The text may contain moodle formatting. An error lists the
+// people moodle could not deliver the message to.
+func (m *MoodleApi) SendMessages(userIds []UserID, text string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	params := url.Values{}
+	for i, id := range userIds {
+		params.Set(fmt.Sprintf("messages[%d][touserid]", i), fmt.Sprint(id))
+		params.Set(fmt.Sprintf("messages[%d][text]", i), text)
+		params.Set(fmt.Sprintf("messages[%d][textformat]", i), "0")
+	}
+	body, err := m.call("core_message_send_instant_messages", params)
+	if err != nil {
+		return err
+	}
+
+	type Result struct {
+		MsgId        int64  `json:"msgid"`
+		ErrorMessage string `json:"errormessage"`
+	}
+
+	var results []Result
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var failed []string
+	for i, r := range results {
+		if r.MsgId < 0 && i < len(userIds) {
+			failed = append(failed, fmt.Sprintf("%d: %s", userIds[i], r.ErrorMessage))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New("Message could not be sent to " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// MessageCourseGroup sends a moodle instant message to every member of a
+// named course group, for example all students in a tutor group.
+func (m *MoodleApi) MessageCourseGroup(courseId CourseID, groupName, text string) error {
+	members, err := m.courseGroupMembers(courseId, groupName)
+	if err != nil {
+		return err
+	}
+	ids := make([]UserID, 0, len(members))
+	for _, p := range members {
+		ids = append(ids, p.Id)
+	}
+	return m.SendMessages(ids, text)
+}
+
+// EmailCourseGroup renders the template for every member of a named course
+// group and sends it using the configured mailer. The template is passed a
+// GroupEmailData holding data. Sending continues when an email fails, the
+// returned error lists every address that could not be sent to.
+func (m *MoodleApi) EmailCourseGroup(courseId CourseID, groupName string, t EmailTemplate, data interface{}) error {
+	members, err := m.courseGroupMembers(courseId, groupName)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, p := range members {
+		if p.Email == "" {
+			continue
+		}
+		msg, err := t.Render(&GroupEmailData{
+			FirstName: p.FirstName,
+			LastName:  p.LastName,
+			Email:     p.Email,
+			Group:     groupName,
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		msg.ToName = p.FirstName + " " + p.LastName
+		msg.ToEmail = p.Email
+		if err := m.sendEmail(msg); err != nil {
+			m.warn("Email to %s failed: %v", p.Email, err)
+			failed = append(failed, p.Email)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New("Email could not be sent to " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (m *MoodleApi) courseGroupMembers(courseId CourseID, groupName string) ([]CoursePerson, error) {
+	group, err := m.GetCourseGroupByName(courseId, groupName)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetGroupMembers(courseId, group.Id)
+}
@@ -0,0 +1,99 @@
+package moodle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCourseGroupMessaging(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_group_get_course_groups":       `[{"id":10,"courseid":3,"name":"Tutor Group A"},{"id":11,"courseid":3,"name":"Tutor Group B"}]`,
+		"core_enrol_get_enrolled_users":      `[{"id":5,"firstname":"Ann","lastname":"Lee","email":"ann@example.com"},{"id":6,"firstname":"Bo","lastname":"Ng","email":"fail@example.com"}]`,
+		"core_message_send_instant_messages": `[{"msgid":91},{"msgid":-1,"errormessage":"Messaging is disabled"}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	err := api.MessageCourseGroup(3, "tutor group b", "Tutorial moved to Friday")
+	if err == nil {
+		t.Errorf("Expected failed message to be reported")
+	}
+	r := fetch.last()
+	if r.Get("messages[1][touserid]") != "6" || r.Get("messages[0][text]") != "Tutorial moved to Friday" {
+		t.Errorf("Expected a message to each member, found %v", r)
+	}
+	if members := fetch.requests[len(fetch.requests)-2]; members.Get("options[0][value]") != "11" {
+		t.Errorf("Expected members of group 11 to be requested, found %v", members)
+	}
+
+	mailer := &flakyMailer{failures: map[string]int{"fail@example.com": 1}}
+	api.SetMailer(mailer, "College", "college@example.com")
+	err = api.EmailCourseGroup(3, "Tutor Group A", EmailTemplate{Subject: "{{.Group}}", Text: "Hi {{.FirstName}}, {{.Data}}"}, "see you Friday")
+	if err == nil || err.Error() != "Email could not be sent to fail@example.com" {
+		t.Errorf("Expected failed email to be reported, found %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0] != "ann@example.com" {
+		t.Errorf("Expected email to be sent to remaining members, found %v", mailer.sent)
+	}
+
+	if err := api.MessageCourseGroup(3, "Tutor Group C", "Hi"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected unknown group to be reported, found %v", err)
+	}
+}
+
+func TestCourseGroupIdNumber(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_group_create_groups":     `[{"id":12,"courseid":3,"name":"Tutor Group C","description":"","idnumber":"SIS-C"}]`,
+		"core_group_update_groups":     `null`,
+		"core_group_get_course_groups": `[{"id":10,"courseid":3,"name":"Tutor Group A","idnumber":""},{"id":12,"courseid":3,"name":"Tutor Group C","idnumber":"SIS-C"}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	id, err := api.AddGroupToCourseWithIdNumber(3, "Tutor Group C", "", "SIS-C")
+	if err != nil || id != 12 {
+		t.Fatalf("Expected group 12 to be created, found %d %v", id, err)
+	}
+	if r := fetch.last(); r.Get("groups[0][idnumber]") != "SIS-C" {
+		t.Errorf("Expected the id number to be sent, found %v", r)
+	}
+
+	g, err := api.GetCourseGroupByIdNumber(3, "SIS-C")
+	if err != nil || g.Id != 12 {
+		t.Fatalf("Expected group 12 to be found by id number, found %+v %v", g, err)
+	}
+	if _, err := api.GetCourseGroupByIdNumber(3, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected groups without an id number not to match, found %v", err)
+	}
+
+	g.Name = "Tutor Group D"
+	if err := api.UpdateCourseGroup(*g); err != nil {
+		t.Fatalf("UpdateCourseGroup failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("groups[0][id]") != "12" || r.Get("groups[0][name]") != "Tutor Group D" || r.Get("groups[0][idnumber]") != "SIS-C" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+}
+
+func TestFindCourseGroup(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_group_get_course_groups": `[{"id":10,"name":"Tutor Group A","idnumber":"sis-a"},{"id":11,"name":"Lab  1","idnumber":""},{"id":12,"name":"lab 1","idnumber":"SIS-L1"}]`,
+	}))
+
+	if g, err := api.GetCourseGroupByName(3, " tutor   group a"); err != nil || g.Id != 10 {
+		t.Errorf("Expected names to match after normalising, found %+v %v", g, err)
+	}
+	if g, err := api.GetCourseGroupByIdNumber(3, "SIS-A "); err != nil || g.Id != 10 {
+		t.Errorf("Expected id numbers to match after normalising, found %+v %v", g, err)
+	}
+	if _, err := api.GetCourseGroupByName(3, "Lab 1"); !errors.Is(err, ErrMultipleMatches) {
+		t.Errorf("Expected an ambiguous name to be reported, found %v", err)
+	}
+	if _, err := api.GetCourseGroupByName(3, " "); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a blank name not to match, found %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// CourseGrouping is a named set of groups in a course
+type CourseGrouping struct {
+	Id       int64         `json:"id"`
+	Name     string        `json:"name"`
+	IdNumber string        `json:"idnumber"`
+	Groups   []CourseGroup `json:"groups"`
+}
+
+// GetCourseGroupings lists the groupings in a course with the groups in
+// each grouping.
+func (m *MoodleApi) GetCourseGroupings(courseId CourseID) ([]CourseGrouping, error) {
+	body, err := m.call("core_group_get_course_groupings", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groupings []CourseGrouping
+
+	if err := json.Unmarshal([]byte(body), &groupings); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if len(groupings) == 0 {
+		return nil, nil
+	}
+
+	// The groups of each grouping are only returned by core_group_get_groupings
+	params := url.Values{
+		"moodlewssettingraw": {"true"},
+		"returngroups":       {"1"},
+	}
+	for i, g := range groupings {
+		params.Set(fmt.Sprintf("groupingids[%d]", i), fmt.Sprint(g.Id))
+	}
+	body, err = m.call("core_group_get_groupings", params)
+	if err != nil {
+		return nil, err
+	}
+
+	groupings = nil
+	if err := json.Unmarshal([]byte(body), &groupings); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	return groupings, nil
+}
+
+// GroupingsOf returns the ids of the groupings that contain any of the groups
+func GroupingsOf(groups []CourseGroup, groupings []CourseGrouping) []int64 {
+	member := make(map[GroupID]bool)
+	for _, g := range groups {
+		member[g.Id] = true
+	}
+	var ids []int64
+	for _, grouping := range groupings {
+		for _, g := range grouping.Groups {
+			if member[g.Id] {
+				ids = append(ids, grouping.Id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// GetPersonCourseGroupings lists the ids of the groupings a person belongs
+// to in a course, through membership of one of the grouping's groups.
+func (m *MoodleApi) GetPersonCourseGroupings(courseId CourseID, userId UserID) ([]int64, error) {
+	groups, err := m.GetPersonCourseGroups(courseId, userId)
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	groupings, err := m.GetCourseGroupings(courseId)
+	if err != nil {
+		return nil, err
+	}
+	return GroupingsOf(groups, groupings), nil
+}
@@ -0,0 +1,105 @@
+package moodle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HealthStatus describes the result of Ping
+type HealthStatus struct {
+	// Reachable is true if moodle responded
+	Reachable bool
+
+	// TokenValid is true if moodle accepted the token
+	TokenValid bool
+
+	Latency  time.Duration
+	SiteName string
+	Release  string
+	UserId   UserID
+
+	// MissingFunctions lists required functions that are not enabled for
+	// the web service
+	MissingFunctions []string
+
+	// Err is the reason the site is not healthy
+	Err error
+}
+
+// Healthy reports whether moodle is reachable, the token is valid and every
+// required function is available.
+func (h *HealthStatus) Healthy() bool {
+	return h.Reachable && h.TokenValid && len(h.MissingFunctions) == 0
+}
+
+// Ping checks that moodle is reachable and the token is valid by calling
+// core_webservice_get_site_info, and that each of the required functions is
+// enabled for the web service. It is intended for readiness probes. The
+// returned error is nil only if the site is healthy, the status is always
+// returned. If ctx is done before moodle responds Ping returns without
+// waiting for the call to finish. Ping always calls moodle, even when a
+// result cache is set.
+func (m *MoodleApi) Ping(ctx context.Context, required ...string) (*HealthStatus, error) {
+	start := time.Now()
+	// A readiness probe must not be answered from the result cache
+	body, err := withContext(ctx, func() (string, error) {
+		return m.callUncached("core_webservice_get_site_info", url.Values{})
+	})
+
+	status := &HealthStatus{}
+	if ctx.Err() != nil && err == ctx.Err() {
+		status.Err = err
+		return status, status.Err
+	}
+	status.Latency = time.Since(start)
+
+	if err != nil {
+		var merr *MoodleError
+		status.Reachable = errors.As(err, &merr) && !unreachable(err)
+		status.Err = err
+		return status, status.Err
+	}
+	status.Reachable = true
+
+	type Function struct {
+		Name string `json:"name"`
+	}
+	type Result struct {
+		SiteName  string     `json:"sitename"`
+		Release   string     `json:"release"`
+		UserId    UserID     `json:"userid"`
+		Functions []Function `json:"functions"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		status.Err = errors.New("Server returned unexpected response. " + err.Error())
+		return status, status.Err
+	}
+	status.TokenValid = true
+	status.SiteName = result.SiteName
+	status.Release = result.Release
+	status.UserId = result.UserId
+
+	available := make(map[string]bool)
+	for _, f := range result.Functions {
+		available[f.Name] = true
+	}
+	for _, f := range required {
+		if !available[f] {
+			status.MissingFunctions = append(status.MissingFunctions, f)
+		}
+	}
+	if len(status.MissingFunctions) > 0 {
+		sort.Strings(status.MissingFunctions)
+		status.Err = wrapError("Functions not enabled for the web service: "+strings.Join(status.MissingFunctions, ", "), ErrPermissionDenied)
+		return status, status.Err
+	}
+	return status, nil
+}
@@ -0,0 +1,80 @@
+package moodle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"sitename":"Example","username":"ws","userid":2,"release":"4.1.2 (Build: 20230313)","functions":[{"name":"core_user_get_users","version":"4.1"},{"name":"core_webservice_get_site_info","version":"4.1"}]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	status, err := api.Ping(context.Background(), "core_user_get_users")
+	if err != nil || !status.Healthy() {
+		t.Fatalf("Expected site to be healthy, found %+v %v", status, err)
+	}
+	if status.SiteName != "Example" || status.UserId != 2 || status.Release == "" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	status, err = api.Ping(context.Background(), "core_user_get_users", "mod_quiz_get_user_attempts")
+	if !errors.Is(err, ErrPermissionDenied) || status.Healthy() || len(status.MissingFunctions) != 1 {
+		t.Errorf("Expected a missing function, found %+v %v", status, err)
+	}
+
+	fetch.responses["core_webservice_get_site_info"] = `{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`
+	status, err = api.Ping(context.Background())
+	if !errors.Is(err, ErrInvalidToken) || !status.Reachable || status.TokenValid {
+		t.Errorf("Expected an invalid token, found %+v %v", status, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	api.SetUrlFetcher(&unreachableLookupUrl{testLookupUrl: fetch, down: true})
+	status, err = api.Ping(ctx)
+	if err == nil || status.Reachable {
+		t.Errorf("Expected ping to fail, found %+v %v", status, err)
+	}
+}
+
+func TestPingBypassesResultCache(t *testing.T) {
+
+	down := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sitename":"Example","username":"ws","userid":2,"functions":[]}`))
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "token")
+	api.SetResultCache(NewMemoryResultCache(), time.Hour)
+
+	status, err := api.Ping(context.Background())
+	if err != nil || !status.Healthy() {
+		t.Fatalf("Expected moodle to be healthy, found %+v %v", status, err)
+	}
+	if _, err := api.GetSiteInfoStruct(); err != nil {
+		t.Fatalf("GetSiteInfoStruct failed: %v", err)
+	}
+
+	down = true
+	status, err = api.Ping(context.Background())
+	if err == nil || status.Healthy() {
+		t.Errorf("Expected Ping to report the outage rather than a cached response, found %+v", status)
+	}
+	if _, err := api.ValidateToken(); err == nil {
+		t.Errorf("Expected ValidateToken to call moodle rather than use a cached response")
+	}
+}
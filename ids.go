@@ -0,0 +1,25 @@
+package moodle
+
+// Moodle identifies records by integer ids. Each kind of id has its own type
+// so that passing a course id where a user id is expected, or transposing
+// arguments such as SetRole(personId, roleId, courseId), is a compile error.
+//
+// Untyped constants convert automatically, int64 values require an explicit
+// conversion:
+//
+//	api.SetRole(moodle.UserID(p.MoodleId), moodle.RoleID(5), moodle.CourseID(c))
+
+// UserID identifies a moodle user account
+type UserID int64
+
+// CourseID identifies a moodle course
+type CourseID int64
+
+// RoleID identifies a moodle role, such as student or teacher
+type RoleID int64
+
+// GroupID identifies a group within a course
+type GroupID int64
+
+// CmID identifies a course module, an activity or resource within a course
+type CmID int64
@@ -0,0 +1,84 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Insight is a notification sent by a moodle analytics model, such as
+// "Students at risk of dropping out", telling a teacher that new
+// predictions are available for a course.
+type Insight struct {
+	Id        int64
+	ModelId   int64
+	ContextId int64
+	Subject   string
+	Message   string
+	Url       string
+	Read      bool
+	Created   time.Time
+}
+
+// GetInsights lists the analytics insights sent to a person, newest first.
+// Moodle does not provide a web service to read predictions directly, they
+// are delivered to teachers and managers as notifications linking to the
+// insights report, so the notifications are read with
+// "message_popup_get_popup_notifications". Only notifications the token's
+// user may read are returned, usually requiring the token to belong to the
+// person.
+func (m *MoodleApi) GetInsights(userId UserID) ([]Insight, error) {
+	body, err := m.call("message_popup_get_popup_notifications", url.Values{
+		"useridto":    {fmt.Sprint(userId)},
+		"newestfirst": {"1"},
+		"limit":       {"0"},
+		"offset":      {"0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Notification struct {
+		Id          int64  `json:"id"`
+		Subject     string `json:"subject"`
+		Message     string `json:"smallmessage"`
+		ContextUrl  string `json:"contexturl"`
+		TimeCreated int64  `json:"timecreated"`
+		Read        bool   `json:"read"`
+		Component   string `json:"component"`
+		EventType   string `json:"eventtype"`
+	}
+	type Result struct {
+		Notifications []Notification `json:"notifications"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var insights []Insight
+	for _, n := range result.Notifications {
+		if n.Component != "moodle" || n.EventType != "insights" {
+			continue
+		}
+		insight := Insight{
+			Id:      n.Id,
+			Subject: n.Subject,
+			Message: n.Message,
+			Url:     n.ContextUrl,
+			Read:    n.Read,
+			Created: m.unix(n.TimeCreated),
+		}
+		if u, err := url.Parse(n.ContextUrl); err == nil {
+			insight.ModelId, _ = strconv.ParseInt(u.Query().Get("modelid"), 10, 64)
+			insight.ContextId, _ = strconv.ParseInt(u.Query().Get("contextid"), 10, 64)
+		}
+		insights = append(insights, insight)
+	}
+	return insights, nil
+}
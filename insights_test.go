@@ -0,0 +1,31 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestGetInsights(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"message_popup_get_popup_notifications": `{"notifications":[` +
+			`{"id":31,"useridto":4,"subject":"Students at risk of dropping out insights for course Biology 101","smallmessage":"3 students at risk","contexturl":"https://moodle.example.com/report/insights/insights.php?modelid=2&contextid=88","timecreated":1600000000,"read":false,"component":"moodle","eventtype":"insights"},` +
+			`{"id":32,"useridto":4,"subject":"Assignment due","contexturl":"https://moodle.example.com/mod/assign/view.php?id=5","timecreated":1600000100,"read":true,"component":"mod_assign","eventtype":"assign_notification"}` +
+			`],"unreadcount":1}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	insights, err := api.GetInsights(4)
+	if err != nil {
+		t.Fatalf("GetInsights failed: %v", err)
+	}
+	if len(insights) != 1 {
+		t.Fatalf("Expected one insight, found %+v", insights)
+	}
+	if insights[0].ModelId != 2 || insights[0].ContextId != 88 || insights[0].Read || insights[0].Created.Unix() != 1600000000 {
+		t.Errorf("Unexpected insight: %+v", insights[0])
+	}
+	if q := fetch.last(); q.Get("useridto") != "4" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build integration
+// +build integration
+
+package moodle
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/zaddok/moodle/moodletest"
+)
+
+// TestMain starts a disposable moodle site for the tests that need
+// MOODLE_URL and MOODLE_KEY, unless they are already set. Run with:
+//
+//	go test -tags integration
+func TestMain(m *testing.M) {
+	if os.Getenv("MOODLE_URL") != "" {
+		os.Exit(m.Run())
+	}
+
+	site, err := moodletest.Start(nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start moodle: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("MOODLE_URL", site.URL)
+	os.Setenv("MOODLE_KEY", site.Token)
+
+	code := m.Run()
+	site.Close()
+	os.Exit(code)
+}
+
+func TestIntegrationFixtures(t *testing.T) {
+
+	api := NewMoodleApi(requireEnv("MOODLE_URL", t), requireEnv("MOODLE_KEY", t))
+
+	p, err := api.GetPersonByUsername("student1")
+	if err != nil {
+		t.Fatalf("GetPersonByUsername failed: %v", err)
+	}
+	if p == nil || p.Email != "student1@example.com" {
+		t.Fatalf("Expected the student1 fixture, found %+v", p)
+	}
+
+	courses, err := api.GetPersonCourseList(p.MoodleId)
+	if err != nil {
+		t.Fatalf("GetPersonCourseList failed: %v", err)
+	}
+	if len(courses) != 2 {
+		t.Errorf("Expected student1 to be enrolled in two courses, found %d", len(courses))
+	}
+}
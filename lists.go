@@ -0,0 +1,70 @@
+package moodle
+
+// The methods in this file return plain slices of values, in place of the
+// older methods that return slices of pointers. Each returns nil when
+// nothing is found.
+
+// GetAssignmentsForCourses fetches the assignments in the courses
+func (m *MoodleApi) GetAssignmentsForCourses(courseIds []CourseID) ([]AssignmentInfo, error) {
+	results, err := m.GetAssignmentsWithCourseId(courseIds)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	assignments := make([]AssignmentInfo, 0, len(results))
+	for _, i := range results {
+		assignments = append(assignments, *i)
+	}
+	return assignments, nil
+}
+
+// GetQuizzesForCourses fetches the quizzes in the courses
+func (m *MoodleApi) GetQuizzesForCourses(courseIds []CourseID) ([]QuizInfo, error) {
+	results, err := m.GetQuizzesWithCourseId(courseIds)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	quizzes := make([]QuizInfo, 0, len(results))
+	for _, i := range results {
+		quizzes = append(quizzes, *i)
+	}
+	return quizzes, nil
+}
+
+// GetForumsForCourses fetches the forums in the courses
+func (m *MoodleApi) GetForumsForCourses(courseIds []CourseID) ([]ForumInfo, error) {
+	results, err := m.GetForumsWithCourseId(courseIds)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	forums := make([]ForumInfo, 0, len(results))
+	for _, i := range results {
+		forums = append(forums, *i)
+	}
+	return forums, nil
+}
+
+// GetForumDiscussions fetches the discussions in a forum
+func (m *MoodleApi) GetForumDiscussions(forumId int) ([]ForumDiscussion, error) {
+	results, err := m.GetForumsDiscussions(forumId)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	discussions := make([]ForumDiscussion, 0, len(results))
+	for _, i := range results {
+		discussions = append(discussions, *i)
+	}
+	return discussions, nil
+}
+
+// GetSubmissionsForAssignment fetches the submissions made to an assignment
+func (m *MoodleApi) GetSubmissionsForAssignment(assignmentId int64) ([]AssignmentSubmission, error) {
+	results, err := m.GetAssignmentSubmissions(assignmentId)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	submissions := make([]AssignmentSubmission, 0, len(results))
+	for _, i := range results {
+		submissions = append(submissions, *i)
+	}
+	return submissions, nil
+}
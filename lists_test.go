@@ -0,0 +1,46 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestPlainSliceMethods(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_users":             `{"users":[],"warnings":[]}`,
+		"mod_assign_get_grades":           `{"assignments":[],"warnings":[]}`,
+		"mod_forum_get_forum_discussions": `{"discussions":[{"id":4,"name":"Welcome"},{"id":5,"name":"Questions"}]}`,
+	})
+	api.SetUrlFetcher(fetch)
+
+	people, err := api.FindPeopleByAttribute("email", "nobody@example.com")
+	if err != nil || people != nil {
+		t.Errorf("Expected nil when no people match, found %v, %v", people, err)
+	}
+	old, err := api.GetPeopleByAttribute("email", "nobody@example.com")
+	if err != nil || old == nil || len(*old) != 0 {
+		t.Errorf("Expected deprecated method to return a pointer to an empty slice, found %v, %v", old, err)
+	}
+
+	records, err := api.GetAssignmentGradeRecords(1, 2)
+	if err != nil || records != nil {
+		t.Errorf("Expected nil when there are no grades, found %v, %v", records, err)
+	}
+
+	discussions, err := api.GetForumDiscussions(3)
+	if err != nil {
+		t.Fatalf("GetForumDiscussions failed: %v", err)
+	}
+	if len(discussions) != 2 || discussions[1].Name != "Questions" {
+		t.Errorf("Expected two discussions, found %v", discussions)
+	}
+
+	discussions, err = api.GetForumDiscussionsForGroup(3, 12)
+	if err != nil || len(discussions) != 2 {
+		t.Fatalf("GetForumDiscussionsForGroup failed: %v %v", discussions, err)
+	}
+	if r := fetch.last(); r.Get("forumid") != "3" || r.Get("groupid") != "12" {
+		t.Errorf("Expected discussions to be filtered by group, found %v", r)
+	}
+}
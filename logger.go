@@ -0,0 +1,96 @@
+package moodle
+
+import (
+	"fmt"
+	"time"
+)
+
+// MoodleLogger receives debug output, such as the url of each call made to
+// moodle. Tokens and passwords are masked before they reach the logger.
+type MoodleLogger interface {
+	Debug(message string, items ...interface{}) error
+}
+
+// LeveledMoodleLogger receives output at different levels of severity.
+// Info reports events such as a call being retried, Warn reports warnings
+// returned by moodle and slow calls, Error reports failed calls.
+type LeveledMoodleLogger interface {
+	MoodleLogger
+	Info(message string, items ...interface{}) error
+	Warn(message string, items ...interface{}) error
+	Error(message string, items ...interface{}) error
+}
+
+type NilMoodleLogger struct {
+}
+
+func (ml *NilMoodleLogger) Debug(message string, items ...interface{}) error {
+	return nil
+}
+
+func (ml *NilMoodleLogger) Info(message string, items ...interface{}) error {
+	return nil
+}
+
+func (ml *NilMoodleLogger) Warn(message string, items ...interface{}) error {
+	return nil
+}
+
+func (ml *NilMoodleLogger) Error(message string, items ...interface{}) error {
+	return nil
+}
+
+// debugLogger adapts a MoodleLogger that only supports Debug, passing
+// every level to Debug with the level name as a prefix.
+type debugLogger struct {
+	MoodleLogger
+}
+
+func (ml debugLogger) Info(message string, items ...interface{}) error {
+	return ml.Debug("INFO: "+message, items...)
+}
+
+func (ml debugLogger) Warn(message string, items ...interface{}) error {
+	return ml.Debug("WARN: "+message, items...)
+}
+
+func (ml debugLogger) Error(message string, items ...interface{}) error {
+	return ml.Debug("ERROR: "+message, items...)
+}
+
+// SetLogger sets the logger that receives output from the api. If the
+// logger implements LeveledMoodleLogger each message is sent at the
+// appropriate level, otherwise all messages are sent to Debug.
+func (m *MoodleApi) SetLogger(l MoodleLogger) {
+	if l == nil {
+		l = &NilMoodleLogger{}
+	}
+	if leveled, ok := l.(LeveledMoodleLogger); ok {
+		m.log = leveled
+	} else {
+		m.log = debugLogger{l}
+	}
+}
+
+// SetSlowCallThreshold sets how long a call may take before a warning is
+// logged. Defaults to 10 seconds, zero disables the warning.
+func (m *MoodleApi) SetSlowCallThreshold(threshold time.Duration) {
+	m.slowThreshold = threshold
+}
+
+// debug formats a message and sends it to the logger with secrets masked
+func (m *MoodleApi) debug(message string, items ...interface{}) {
+	m.log.Debug("%s", maskSecrets(fmt.Sprintf(message, items...)))
+}
+
+func (m *MoodleApi) info(message string, items ...interface{}) {
+	m.log.Info("%s", maskSecrets(fmt.Sprintf(message, items...)))
+}
+
+func (m *MoodleApi) warn(message string, items ...interface{}) {
+	m.log.Warn("%s", maskSecrets(fmt.Sprintf(message, items...)))
+}
+
+func (m *MoodleApi) logError(message string, items ...interface{}) {
+	m.log.Error("%s", maskSecrets(fmt.Sprintf(message, items...)))
+}
@@ -0,0 +1,112 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type levelMoodleLogger struct {
+	lines []string
+}
+
+func (ml *levelMoodleLogger) log(level, message string, items ...interface{}) error {
+	ml.lines = append(ml.lines, level+" "+fmt.Sprintf(message, items...))
+	return nil
+}
+
+func (ml *levelMoodleLogger) Debug(message string, items ...interface{}) error {
+	return ml.log("DEBUG", message, items...)
+}
+
+func (ml *levelMoodleLogger) Info(message string, items ...interface{}) error {
+	return ml.log("INFO", message, items...)
+}
+
+func (ml *levelMoodleLogger) Warn(message string, items ...interface{}) error {
+	return ml.log("WARN", message, items...)
+}
+
+func (ml *levelMoodleLogger) Error(message string, items ...interface{}) error {
+	return ml.log("ERROR", message, items...)
+}
+
+func (ml *levelMoodleLogger) contains(level, text string) bool {
+	for _, l := range ml.lines {
+		if strings.HasPrefix(l, level+" ") && strings.Contains(l, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLeveledLogger(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"sitename":"Test","firstname":"Admin","lastname":"User","userid":2,"warnings":[{"item":"site","warningcode":"nopermission","message":"No permission"}]}`,
+	})
+	log := &levelMoodleLogger{}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	api.SetLogger(log)
+
+	if _, _, _, _, err := api.GetSiteInfo(); err != nil {
+		t.Fatalf("GetSiteInfo failed: %v", err)
+	}
+	if !log.contains("WARN", "No permission (nopermission)") {
+		t.Errorf("Expected moodle warning to be logged as a warning: %v", log.lines)
+	}
+	if !log.contains("DEBUG", "core_webservice_get_site_info") {
+		t.Errorf("Expected fetch to be logged as debug: %v", log.lines)
+	}
+
+	if _, err := api.GetPersonByUsername("missing"); err == nil {
+		t.Fatalf("Expected GetPersonByUsername to fail")
+	}
+	if !log.contains("ERROR", "core_user_get_users_by_field") {
+		t.Errorf("Expected failed call to be logged as an error: %v", log.lines)
+	}
+}
+
+func TestDebugLoggerAdapter(t *testing.T) {
+
+	log := &recordMoodleLogger{}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{}))
+	api.SetLogger(log)
+
+	api.GetPersonByUsername("missing")
+	if !strings.Contains(strings.Join(log.lines, "\n"), "ERROR: %s") {
+		t.Errorf("Expected errors to be sent to Debug with a level prefix: %v", log.lines)
+	}
+}
+
+func TestRequestId(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`,
+	})
+	log := &levelMoodleLogger{}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	api.SetLogger(log)
+
+	_, _, _, _, err := api.GetSiteInfo()
+	id := fetch.headers[0].Get(RequestIdHeader)
+	if id == "" {
+		t.Fatalf("Expected a request id header to be sent")
+	}
+	if !log.contains("DEBUG", "["+id+"] Fetch:") || !log.contains("ERROR", "["+id+"] Call to core_webservice_get_site_info failed") {
+		t.Errorf("Expected request id to be logged: %v", log.lines)
+	}
+	var merr *MoodleError
+	if !errors.As(err, &merr) || merr.RequestId != id || !strings.Contains(err.Error(), id) {
+		t.Errorf("Expected request id in error, found %v", err)
+	}
+
+	api.GetSiteInfo()
+	if fetch.headers[1].Get(RequestIdHeader) == id {
+		t.Errorf("Expected each call to have a different request id")
+	}
+}
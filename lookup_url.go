@@ -1,99 +1,214 @@
 package moodle
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
-	"math/rand"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
-var cookieJar *cookiejar.Jar
-
-var ua int = -1
-var uaHeaders [][][]string = [][][]string{
-	{
-		{"DNT", "1"},
-		{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
-		{"User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/603.3.4 (KHTML, like Gecko) Version/10.1.2 Safari/603.3.4"},
-		{"Upgrade-Insecure-Requests", "1"},
-		{"Accept-Language", "en-au"},
-	},
-	{
-		{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8"},
-		{"Accept-Language", "en-AU,en;q=0.8,en-US;q=0.6"},
-		{"User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.79 Safari/537.36"},
-		{"Upgrade-Insecure-Requests", "1"},
-	},
+var (
+	cookieJar     *cookiejar.Jar
+	cookieJarOnce sync.Once
+)
+
+// sharedCookieJar returns the cookie jar shared by all requests
+func sharedCookieJar() *cookiejar.Jar {
+	cookieJarOnce.Do(func() {
+		if cookieJar == nil {
+			cookieJar, _ = cookiejar.New(nil)
+		}
+	})
+	return cookieJar
 }
 
+// Version of this library, reported in the default User-Agent header.
+const Version = "1.1.0"
+
+// DefaultUserAgent identifies this library to the moodle server.
+const DefaultUserAgent = "zaddok-moodle-go/" + Version
+
 type LookupUrl interface {
 	GetUrl(url string) (string, int, string, error)
 	PostFile(url string, r io.Reader) (string, int, string, error)
+	// Do performs a request using the specified method. If form is not nil
+	// it is sent as an application/x-www-form-urlencoded body. Any headers
+	// supplied are added to the request.
+	Do(method, url string, form url.Values, header http.Header) (string, int, string, error)
 }
 
+// DefaultLookupUrl fetches urls using net/http. It is safe for concurrent
+// use.
 type DefaultLookupUrl struct {
-	client *http.Client
+	client     *http.Client
+	clientOnce sync.Once
+	userAgent  string
+	options    TransportOptions
+	maxSize    int64
 }
 
-// Fetch the content of a URL. Returns the contents, httpStatus, contentType, errorCode.
-func (d *DefaultLookupUrl) GetUrl(url string) (string, int, string, error) {
-	if d.client == nil {
-		netTransport := &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: 8 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 8 * time.Second,
-		}
+// TransportOptions tune the connections made by DefaultLookupUrl. High
+// volume callers can keep more connections open to the moodle server, so
+// that requests do not wait for a new TLS handshake. Zero values use the
+// defaults noted.
+type TransportOptions struct {
+	// MaxIdleConns limits idle connections to all hosts. Defaults to 100.
+	MaxIdleConns int
 
-		if cookieJar == nil {
-			cookieJar, _ = cookiejar.New(nil)
+	// MaxIdleConnsPerHost limits idle connections to the moodle server.
+	// Defaults to 2, set it to the number of concurrent callers.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is how long an idle connection is kept open.
+	// Defaults to 90 seconds.
+	IdleConnTimeout time.Duration
+
+	// ForceAttemptHTTP2 enables HTTP/2 if the server supports it
+	ForceAttemptHTTP2 bool
+
+	// DialTimeout and TLSHandshakeTimeout default to 8 seconds, Timeout
+	// limits each request and defaults to 16 seconds.
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	Timeout             time.Duration
+
+	// Proxy selects the proxy for each request. Defaults to the proxy set
+	// by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLSClientConfig configures TLS connections, for example to trust a
+	// private certificate authority or present a client certificate.
+	TLSClientConfig *tls.Config
+
+	// MaxResponseSize limits the size of a response, see SetMaxResponseSize
+	MaxResponseSize int64
+}
+
+// NewDefaultLookupUrl returns a DefaultLookupUrl using the transport
+// options. Use it with SetUrlFetcher:
+//
+//	api.SetUrlFetcher(moodle.NewDefaultLookupUrl(&moodle.TransportOptions{
+//		MaxIdleConnsPerHost: 20,
+//		ForceAttemptHTTP2:   true,
+//	}))
+func NewDefaultLookupUrl(options *TransportOptions) *DefaultLookupUrl {
+	d := &DefaultLookupUrl{}
+	if options != nil {
+		d.options = *options
+		d.maxSize = options.MaxResponseSize
+	}
+	return d
+}
+
+// NewDefaultLookupUrlWithClient returns a DefaultLookupUrl that makes
+// requests using client, for deployments that need full control of the
+// transport. The client is used unchanged, so set its Jar if moodle session
+// cookies should be kept between requests.
+func NewDefaultLookupUrlWithClient(client *http.Client) *DefaultLookupUrl {
+	d := &DefaultLookupUrl{}
+	d.clientOnce.Do(func() {
+		d.client = client
+	})
+	return d
+}
+
+// SetUserAgent sets the User-Agent header sent with each request. Defaults
+// to DefaultUserAgent.
+func (d *DefaultLookupUrl) SetUserAgent(userAgent string) {
+	d.userAgent = userAgent
+}
+
+// SetMaxResponseSize limits the number of bytes read from a response, so
+// that a call returning far more data than expected, such as the gradebook
+// of a large course, fails with ErrResponseTooLarge rather than exhausting
+// memory. Zero, the default, sets no limit. Responses read with DoStream,
+// such as those of StreamCourseRoles and OpenFile, are not limited, so use
+// the streaming and paged functions to read large amounts of data.
+func (d *DefaultLookupUrl) SetMaxResponseSize(bytes int64) {
+	d.maxSize = bytes
+}
+
+func (d *DefaultLookupUrl) setUserAgent(req *http.Request) {
+	if req.Header.Get("User-Agent") != "" {
+		return
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	} else {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+}
+
+func (d *DefaultLookupUrl) httpClient() *http.Client {
+	d.clientOnce.Do(func() {
+		o := d.options
+		proxy := o.Proxy
+		if proxy == nil {
+			proxy = http.ProxyFromEnvironment
+		}
+		netTransport := &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   durationOr(o.DialTimeout, 8*time.Second),
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout: durationOr(o.TLSHandshakeTimeout, 8*time.Second),
+			MaxIdleConns:        intOr(o.MaxIdleConns, 100),
+			MaxIdleConnsPerHost: o.MaxIdleConnsPerHost,
+			IdleConnTimeout:     durationOr(o.IdleConnTimeout, 90*time.Second),
+			ForceAttemptHTTP2:   o.ForceAttemptHTTP2,
+			TLSClientConfig:     o.TLSClientConfig,
 		}
 
 		d.client = &http.Client{
-			Timeout:   time.Second * 16,
+			Timeout:   durationOr(o.Timeout, 16*time.Second),
 			Transport: netTransport,
-			Jar:       cookieJar,
+			Jar:       sharedCookieJar(),
 		}
-	}
+	})
+	return d.client
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// Fetch the content of a URL. Returns the contents, httpStatus, contentType, errorCode.
+func (d *DefaultLookupUrl) GetUrl(url string) (string, int, string, error) {
+	req, err := d.newRequest("GET", url, nil, nil)
 	if err != nil {
 		return "", 0, "", err
 	}
+	return d.read(req)
+}
 
-	if ua < 0 {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		ua = r.Intn(len(uaHeaders))
-	}
-	for _, v := range uaHeaders[ua] {
-		req.Header.Set(v[0], v[1])
+// PostFile uploads binary content to the specified url
+func (d *DefaultLookupUrl) PostFile(url string, r io.Reader) (string, int, string, error) {
+	req, err := d.newRequest("POST", url, r, nil)
+	if err != nil {
+		return "", 0, "", err
 	}
-	//req.Header.Set("Accept-Encoding","gzip, deflate")
+	return d.read(req)
+}
 
-	response, err1 := d.client.Get(url)
-	if err1 != nil {
-		return "", 0, "", err1
+// read makes a request, returning the contents, httpStatus and contentType
+func (d *DefaultLookupUrl) read(req *http.Request) (string, int, string, error) {
+	response, err := d.httpClient().Do(req)
+	if err != nil {
+		return "", 0, "", err
 	}
+	defer response.Body.Close()
 
 	contentType := response.Header.Get("Content-Type")
-	if response.StatusCode == 200 &&
-		!strings.HasPrefix(contentType, "application/xml") &&
-		!strings.HasPrefix(contentType, "application/json") &&
-		!strings.HasPrefix(contentType, "application/rss+xml") &&
-		!strings.HasPrefix(contentType, "application/atom+xml") &&
-		!strings.HasPrefix(contentType, "text/html") &&
-		!strings.HasPrefix(contentType, "text/json") &&
-		!strings.HasPrefix(contentType, "text/plain") &&
-		!strings.HasPrefix(contentType, "text/xml") {
+	if response.StatusCode == 200 && !isTextContentType(contentType) {
 		return "", 0, contentType, errors.New("Ignored non-text response: " + contentType)
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
+	body, err := readResponse(response.Body, d.maxSize)
 	if err != nil {
 		return "", 0, "", err
 	}
@@ -101,62 +216,145 @@ func (d *DefaultLookupUrl) GetUrl(url string) (string, int, string, error) {
 	return strings.TrimSpace(string(body)), response.StatusCode, contentType, nil
 }
 
-// PostFile uploads binary content to the specified url
-func (d *DefaultLookupUrl) PostFile(url string, r io.Reader) (string, int, string, error) {
-	var netTransport = &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: 8 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: 8 * time.Second,
+// Do performs a request with an optional form body and additional headers.
+// Returns the contents, httpStatus, contentType, errorCode.
+func (d *DefaultLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	body, status, responseHeader, err := d.DoWithHeader(method, u, form, header)
+	return body, status, responseHeader.Get("Content-Type"), err
+}
+
+// DoWithHeader performs a request in the same way as Do, returning the
+// response headers rather than only the content type.
+func (d *DefaultLookupUrl) DoWithHeader(method, u string, form url.Values, header http.Header) (string, int, http.Header, error) {
+	response, err := d.do(method, u, form, header)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	defer response.Body.Close()
+
+	contentType := response.Header.Get("Content-Type")
+	if response.StatusCode == 200 && !isTextContentType(contentType) {
+		return "", 0, response.Header, errors.New("Ignored non-text response: " + contentType)
 	}
 
-	if cookieJar == nil {
-		cookieJar, _ = cookiejar.New(nil)
+	data, err := readResponse(response.Body, d.maxSize)
+	if err != nil {
+		return "", 0, nil, err
 	}
 
-	var client = &http.Client{
-		Timeout:   time.Second * 16,
-		Transport: netTransport,
-		Jar:       cookieJar,
+	if response.StatusCode == http.StatusUnauthorized {
+		return strings.TrimSpace(string(data)), response.StatusCode, response.Header, errors.New("Server returned " + response.Status)
 	}
 
-	req, err := http.NewRequest("POST", url, r)
+	return strings.TrimSpace(string(data)), response.StatusCode, response.Header, nil
+}
+
+// DoStream performs a request in the same way as Do, returning the response
+// body unread. The caller must close the body.
+func (d *DefaultLookupUrl) DoStream(method, u string, form url.Values, header http.Header) (io.ReadCloser, int, error) {
+	response, err := d.do(method, u, form, header)
 	if err != nil {
-		return "", 0, "", err
+		return nil, 0, err
 	}
 
-	if ua < 0 {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		ua = r.Intn(len(uaHeaders))
+	contentType := response.Header.Get("Content-Type")
+	if response.StatusCode == 200 && !isTextContentType(contentType) {
+		response.Body.Close()
+		return nil, 0, errors.New("Ignored non-text response: " + contentType)
 	}
-	for _, v := range uaHeaders[ua] {
-		req.Header.Set(v[0], v[1])
+	if response.StatusCode == http.StatusUnauthorized {
+		response.Body.Close()
+		return nil, response.StatusCode, errors.New("Server returned " + response.Status)
 	}
-	//req.Header.Set("Accept-Encoding","gzip, deflate")
 
-	response, err1 := client.Do(req)
-	if err1 != nil {
-		return "", 0, "", err1
+	return response.Body, response.StatusCode, nil
+}
+
+// DoFile performs a request in the same way as DoStream, accepting a
+// response of any content type, so that binary files can be downloaded
+func (d *DefaultLookupUrl) DoFile(method, u string, form url.Values, header http.Header) (io.ReadCloser, int, error) {
+	response, err := d.do(method, u, form, header)
+	if err != nil {
+		return nil, 0, err
 	}
-	defer response.Body.Close()
+	return response.Body, response.StatusCode, nil
+}
 
-	contentType := response.Header.Get("Content-Type")
-	if response.StatusCode == 200 &&
-		!strings.HasPrefix(contentType, "application/xml") &&
-		!strings.HasPrefix(contentType, "application/json") &&
-		!strings.HasPrefix(contentType, "application/rss+xml") &&
-		!strings.HasPrefix(contentType, "application/atom+xml") &&
-		!strings.HasPrefix(contentType, "text/html") &&
-		!strings.HasPrefix(contentType, "text/json") &&
-		!strings.HasPrefix(contentType, "text/plain") &&
-		!strings.HasPrefix(contentType, "text/xml") {
-		return "", 0, contentType, errors.New("Ignored non-text response: " + contentType)
+func (d *DefaultLookupUrl) do(method, u string, form url.Values, header http.Header) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
+	req, err := d.newRequest(method, u, body, header)
 	if err != nil {
-		return "", 0, "", err
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	return strings.TrimSpace(string(body)), response.StatusCode, contentType, nil
+	return d.httpClient().Do(req)
+}
+
+// newRequest prepares a request, adding the User-Agent and any headers
+// supplied. Every request made by DefaultLookupUrl is prepared here.
+func (d *DefaultLookupUrl) newRequest(method, u string, body io.Reader, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	d.setUserAgent(req)
+	return req, nil
+}
+
+// readResponse reads a response body, failing with ErrResponseTooLarge if
+// it is longer than limit bytes. A limit of zero reads the whole body.
+func readResponse(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, wrapError(fmt.Sprintf("Response exceeds the maximum size of %d bytes. Use a paged or streaming function to read large amounts of data", limit), ErrResponseTooLarge)
+	}
+	return data, nil
+}
+
+func isTextContentType(contentType string) bool {
+	for _, prefix := range []string{
+		"application/xml",
+		"application/json",
+		"application/rss+xml",
+		"application/atom+xml",
+		"text/html",
+		"text/json",
+		"text/plain",
+		"text/xml",
+	} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+func intOr(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
 }
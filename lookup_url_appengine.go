@@ -6,12 +6,18 @@ import (
 	"context"
 	"errors"
 	"google.golang.org/appengine/urlfetch"
-	"io/ioutil"
+	"io"
+	"net/http"
+	"net/url"
 	"strings"
 )
 
 type GoogleLookupUrl struct {
 	Context context.Context
+
+	// MaxResponseSize limits the number of bytes read from a response, see
+	// DefaultLookupUrl.SetMaxResponseSize. Zero sets no limit.
+	MaxResponseSize int64
 }
 
 func (d *GoogleLookupUrl) GetUrl(url string) (string, int, string, error) {
@@ -36,7 +42,79 @@ func (d *GoogleLookupUrl) GetUrl(url string) (string, int, string, error) {
 		return "", 0, contentType, errors.New("Ignored non-text response: " + contentType)
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
+	body, err := readResponse(response.Body, d.MaxResponseSize)
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	return strings.TrimSpace(string(body)), response.StatusCode, contentType, nil
+}
+
+func (d *GoogleLookupUrl) PostFile(url string, r io.Reader) (string, int, string, error) {
+	req, err := http.NewRequest("POST", url, r)
+	if err != nil {
+		return "", 0, "", err
+	}
+	return d.do(req)
+}
+
+func (d *GoogleLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return "", 0, "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return d.do(req)
+}
+
+// DoFile performs a request accepting a response of any content type, so
+// that binary files can be downloaded. The caller must close the body.
+func (d *GoogleLookupUrl) DoFile(method, u string, form url.Values, header http.Header) (io.ReadCloser, int, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	response, err := urlfetch.Client(d.Context).Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	return response.Body, response.StatusCode, nil
+}
+
+func (d *GoogleLookupUrl) do(req *http.Request) (string, int, string, error) {
+	client := urlfetch.Client(d.Context)
+
+	response, err := client.Do(req)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer response.Body.Close()
+
+	contentType := response.Header.Get("Content-Type")
+	if response.StatusCode == 200 && !isTextContentType(contentType) {
+		return "", 0, contentType, errors.New("Ignored non-text response: " + contentType)
+	}
+
+	body, err := readResponse(response.Body, d.MaxResponseSize)
 	if err != nil {
 		return "", 0, "", err
 	}
@@ -0,0 +1,203 @@
+package moodle
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLookupUrl is a LookupUrl that returns canned responses keyed by
+// wsfunction (or by path for non web service requests), recording each
+// request made.
+type testLookupUrl struct {
+	mu        sync.Mutex
+	responses map[string]string
+	requests  []url.Values
+	headers   []http.Header
+	urls      []string
+}
+
+func newTestLookupUrl(responses map[string]string) *testLookupUrl {
+	return &testLookupUrl{responses: responses}
+}
+
+func (t *testLookupUrl) respond(u string, form url.Values, header http.Header) (string, int, string, error) {
+	p, err := url.Parse(u)
+	if err != nil {
+		return "", 0, "", err
+	}
+	q := p.Query()
+	for k, v := range form {
+		q[k] = v
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = append(t.requests, q)
+	t.urls = append(t.urls, u)
+	t.headers = append(t.headers, header)
+	key := q.Get("wsfunction")
+	if key == "" {
+		key = p.Path
+	}
+	body, ok := t.responses[key]
+	if !ok {
+		return "", 404, "text/html", errors.New("No test response for " + key)
+	}
+	return body, 200, "application/json", nil
+}
+
+func (t *testLookupUrl) GetUrl(u string) (string, int, string, error) {
+	return t.respond(u, nil, nil)
+}
+
+func (t *testLookupUrl) PostFile(u string, r io.Reader) (string, int, string, error) {
+	return t.respond(u, nil, nil)
+}
+
+func (t *testLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	return t.respond(u, form, header)
+}
+
+// last returns the parameters of the most recent request
+func (t *testLookupUrl) last() url.Values {
+	if len(t.requests) == 0 {
+		return url.Values{}
+	}
+	return t.requests[len(t.requests)-1]
+}
+
+func TestTransportOptions(t *testing.T) {
+
+	d := NewDefaultLookupUrl(&TransportOptions{MaxIdleConnsPerHost: 20, IdleConnTimeout: time.Minute, ForceAttemptHTTP2: true, Timeout: time.Minute})
+	client := d.httpClient()
+	transport := client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 20 || transport.IdleConnTimeout != time.Minute || !transport.ForceAttemptHTTP2 {
+		t.Errorf("Expected transport options to be applied, found %+v", transport)
+	}
+	if transport.MaxIdleConns != 100 || transport.TLSHandshakeTimeout != 8*time.Second || client.Timeout != time.Minute {
+		t.Errorf("Expected unset options to use the defaults")
+	}
+	if d.httpClient() != client {
+		t.Errorf("Expected the client to be reused")
+	}
+}
+
+func TestCustomHttpClient(t *testing.T) {
+
+	proxy, _ := url.Parse("http://proxy.example.com:3128")
+	config := &tls.Config{ServerName: "moodle.example.com"}
+	d := NewDefaultLookupUrl(&TransportOptions{Proxy: http.ProxyURL(proxy), TLSClientConfig: config})
+	transport := d.httpClient().Transport.(*http.Transport)
+	if transport.TLSClientConfig != config {
+		t.Errorf("Expected TLS config to be applied")
+	}
+	req, _ := http.NewRequest("GET", "https://moodle.example.com/", nil)
+	if p, err := transport.Proxy(req); err != nil || p == nil || p.Host != "proxy.example.com:3128" {
+		t.Errorf("Expected proxy to be applied, found %v %v", p, err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"via":"` + r.Header.Get("X-Via") + `"}`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: headerTransport{}}
+	d = NewDefaultLookupUrlWithClient(client)
+	if d.httpClient() != client {
+		t.Errorf("Expected the supplied client to be used")
+	}
+	body, status, _, err := d.Do("POST", server.URL, url.Values{}, nil)
+	if err != nil || status != 200 || body != `{"via":"custom"}` {
+		t.Errorf("Expected request to use the custom transport, found %s %d %v", body, status, err)
+	}
+}
+
+// headerTransport marks each request it sends
+type headerTransport struct{}
+
+func (headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Via", "custom")
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestMaxResponseSize(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"grades":[1,2,3,4,5,6,7,8,9]}`))
+	}))
+	defer server.Close()
+
+	d := NewDefaultLookupUrl(&TransportOptions{MaxResponseSize: 16})
+	if _, _, _, err := d.Do("POST", server.URL, url.Values{}, nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, found %v", err)
+	}
+
+	api := NewMoodleApi(server.URL+"/", "token")
+	api.SetUrlFetcher(d)
+	if _, err := api.GetSiteInfoStruct(); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected calls to fail with ErrResponseTooLarge, found %v", err)
+	}
+
+	d.SetMaxResponseSize(30)
+	if body, _, _, err := d.Do("POST", server.URL, url.Values{}, nil); err != nil || len(body) != 30 {
+		t.Errorf("Expected a response of the maximum size to be read, found %q %v", body, err)
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+
+	var mu sync.Mutex
+	var seen []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sitename":"Example","userid":2}`))
+	}))
+	defer server.Close()
+
+	d := NewDefaultLookupUrl(nil)
+	d.SetUserAgent("agent/1")
+	if _, _, _, err := d.GetUrl(server.URL); err != nil {
+		t.Fatalf("GetUrl failed: %v", err)
+	}
+	if _, _, _, err := d.PostFile(server.URL, nil); err != nil {
+		t.Fatalf("PostFile failed: %v", err)
+	}
+	for _, h := range seen {
+		if h.Get("User-Agent") != "agent/1" {
+			t.Errorf("Expected the User-Agent to be sent, found %v", h)
+		}
+	}
+
+	seen = nil
+	api := NewMoodleApi(server.URL+"/", "token")
+	api.SetUrlFetcher(d)
+	api.SetHeader("X-Proxy-Auth", "secret")
+	traced := api.WithHeader("X-Trace-Id", "abc")
+	if _, err := traced.GetSiteInfoStruct(); err != nil {
+		t.Fatalf("GetSiteInfoStruct failed: %v", err)
+	}
+	if _, err := api.GetSiteInfoStruct(); err != nil {
+		t.Fatalf("GetSiteInfoStruct failed: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("Expected two requests, found %d", len(seen))
+	}
+	if seen[0].Get("X-Proxy-Auth") != "secret" || seen[0].Get("X-Trace-Id") != "abc" || seen[0].Get(RequestIdHeader) == "" {
+		t.Errorf("Expected headers to be sent with the call, found %v", seen[0])
+	}
+	if seen[1].Get("X-Proxy-Auth") != "secret" || seen[1].Get("X-Trace-Id") != "" {
+		t.Errorf("Expected WithHeader to leave the original api unchanged, found %v", seen[1])
+	}
+}
@@ -0,0 +1,36 @@
+package moodle
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Debug output passes through maskSecrets so that tokens and passwords are
+// not written to logs. Values are matched by the name of the parameter or
+// json field that holds them.
+var secretPatterns = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	// url encoded parameters, such as wstoken=abc or users[0][password]=abc
+	{regexp.MustCompile(`(?i)([\w\[\]%]*(?:token|password|secret)[\w\[\]%]*=)[^&\s"]+`), "${1}****"},
+	// json fields, such as "token":"abc"
+	{regexp.MustCompile(`(?i)("\w*(?:token|password|secret)\w*"\s*:\s*")(?:[^"\\]|\\.)*"`), "${1}****\""},
+	// authorization headers
+	{regexp.MustCompile(`(?i)(Bearer\s+)[^\s"]+`), "${1}****"},
+}
+
+// maskSecrets replaces tokens and passwords in s with asterisks. Any extra
+// secrets supplied, such as a generated password, are also replaced wherever
+// they appear.
+func maskSecrets(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.Replace(s, secret, "****", -1)
+		}
+	}
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replace)
+	}
+	return s
+}
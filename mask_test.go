@@ -0,0 +1,70 @@
+package moodle
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type recordMoodleLogger struct {
+	lines []string
+}
+
+func (ml *recordMoodleLogger) Debug(message string, items ...interface{}) error {
+	ml.lines = append(ml.lines, message)
+	for _, i := range items {
+		ml.lines = append(ml.lines, i.(string))
+	}
+	return nil
+}
+
+func TestMaskSecrets(t *testing.T) {
+
+	tests := []struct {
+		in, out string
+	}{
+		{"wsfunction=x&wstoken=abc123&moodlewsrestformat=json", "wsfunction=x&wstoken=****&moodlewsrestformat=json"},
+		{"users%5B0%5D%5Bpassword%5D=Secret%21&users%5B0%5D%5Bemail%5D=a%40b.com", "users%5B0%5D%5Bpassword%5D=****&users%5B0%5D%5Bemail%5D=a%40b.com"},
+		{`{"token":"abc123","privatetoken":"x\"y"}`, `{"token":"****","privatetoken":"****"}`},
+		{"Authorization: Bearer eyJhbGci", "Authorization: Bearer ****"},
+		{"no secrets here", "no secrets here"},
+	}
+	for _, test := range tests {
+		if out := maskSecrets(test.in); out != test.out {
+			t.Errorf("maskSecrets(%q) returned %q, expected %q", test.in, out, test.out)
+		}
+	}
+
+	if out := maskSecrets("Password: hunter2", "hunter2"); out != "Password: ****" {
+		t.Errorf("Expected extra secret to be masked, found %q", out)
+	}
+}
+
+func TestDebugOutputMasked(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_create_users": `[{"id":7,"username":"jsmith"}]`,
+		"/login/token.php":       `{"token":"usertoken","privatetoken":"private"}`,
+	})
+	log := &recordMoodleLogger{}
+	api := NewMoodleApi("https://moodle.example.com/", "secrettoken")
+	api.SetUrlFetcher(fetch)
+	api.SetLogger(log)
+
+	if _, err := api.AddUser("John", "Smith", "john@example.com", "jsmith", "Pa55word!"); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if _, err := api.GetUserToken("jsmith", "Pa55word!", "moodle_mobile_app"); err != nil {
+		t.Fatalf("GetUserToken failed: %v", err)
+	}
+
+	output := strings.Join(log.lines, "\n")
+	for _, secret := range []string{"secrettoken", url.QueryEscape("Pa55word!"), "Pa55word!", "usertoken", "private\""} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Debug output contains %q: %s", secret, output)
+		}
+	}
+	if !strings.Contains(output, "core_user_create_users") {
+		t.Errorf("Expected debug output to include the function called: %s", output)
+	}
+}
@@ -0,0 +1,61 @@
+package moodle
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives the duration and outcome of each call made to moodle,
+// for example to record them with prometheus. Site is the name given to the
+// api by a Registry, or the moodle url if the api is not in a registry.
+// ObserveCall may be called from several goroutines at once.
+type Metrics interface {
+	ObserveCall(site, function string, elapsed time.Duration, err error)
+}
+
+// SetMetrics sets where the outcome of each call is recorded. Nil disables
+// metrics.
+func (m *MoodleApi) SetMetrics(metrics Metrics) {
+	m.metrics = metrics
+}
+
+// siteName identifies the api when reporting metrics
+func (m *MoodleApi) siteName() string {
+	if m.name != "" {
+		return m.name
+	}
+	return m.base
+}
+
+// SetRateLimit sets the minimum time between requests sent to moodle, so
+// that bulk operations do not overwhelm the server. Zero disables the limit.
+func (m *MoodleApi) SetRateLimit(interval time.Duration) {
+	if interval <= 0 {
+		m.limiter = nil
+		return
+	}
+	m.limiter = &rateLimiter{interval: interval}
+}
+
+// rateLimiter spaces requests at least interval apart
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request may be sent
+func (r *rateLimiter) wait() {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
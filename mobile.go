@@ -0,0 +1,168 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Login types reported by the mobile app configuration
+const (
+	LoginInApp     = 1
+	LoginInBrowser = 2
+	LoginEmbedded  = 3
+)
+
+// PublicConfig holds the site settings moodle publishes to the mobile app
+// before a person has logged in.
+type PublicConfig struct {
+	WwwRoot            string `json:"wwwroot"`
+	HttpsWwwRoot       string `json:"httpswwwroot"`
+	SiteName           string `json:"sitename"`
+	GuestLogin         int    `json:"guestlogin"`
+	RememberUsername   int    `json:"rememberusername"`
+	AuthLoginViaEmail  int    `json:"authloginviaemail"`
+	RegisterAuth       string `json:"registerauth"`
+	ForgottenPassword  string `json:"forgottenpasswordurl"`
+	AuthInstructions   string `json:"authinstructions"`
+	MaintenanceEnabled int    `json:"maintenanceenabled"`
+	MaintenanceMessage string `json:"maintenancemessage"`
+	LogoUrl            string `json:"logourl"`
+	CompactLogoUrl     string `json:"compactlogourl"`
+
+	// EnableMobileWebService is 1 if the mobile app may connect to the site
+	EnableMobileWebService int `json:"enablemobilewebservice"`
+
+	// TypeOfLogin is one of LoginInApp, LoginInBrowser or LoginEmbedded
+	TypeOfLogin int `json:"typeoflogin"`
+
+	// LaunchUrl is the url that starts a browser or embedded login
+	LaunchUrl         string             `json:"launchurl"`
+	MobileCssUrl      string             `json:"mobilecssurl"`
+	DisabledFeatures  string             `json:"tool_mobile_disabledfeatures"`
+	IdentityProviders []IdentityProvider `json:"identityproviders"`
+	Country           string             `json:"country"`
+	Lang              string             `json:"lang"`
+	SupportName       string             `json:"supportname"`
+	SupportEmail      string             `json:"supportemail"`
+}
+
+// IdentityProvider is an external login option, such as OAuth 2
+type IdentityProvider struct {
+	Name    string `json:"name"`
+	IconUrl string `json:"iconurl"`
+	Url     string `json:"url"`
+}
+
+// GetPublicConfig fetches the site settings needed to start a login
+func (m *MoodleApi) GetPublicConfig() (*PublicConfig, error) {
+	body, err := m.call("tool_mobile_get_public_config", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var config PublicConfig
+
+	if err := json.Unmarshal([]byte(body), &config); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return &config, nil
+}
+
+// GetMobileConfig fetches the mobile app settings of the site, keyed by
+// setting name. If section is not empty only the settings in that section
+// of the mobile app administration page are returned.
+func (m *MoodleApi) GetMobileConfig(section string) (map[string]string, error) {
+	params := url.Values{}
+	if section != "" {
+		params.Set("section", section)
+	}
+	body, err := m.call("tool_mobile_get_config", params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Setting struct {
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	}
+	type Result struct {
+		Settings []Setting `json:"settings"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	settings := make(map[string]string)
+	for _, s := range result.Settings {
+		var value string
+		if err := json.Unmarshal(s.Value, &value); err != nil {
+			// Some settings are returned as numbers
+			value = string(s.Value)
+		}
+		settings[s.Name] = value
+	}
+	return settings, nil
+}
+
+// UserDevice is a phone or tablet on which a person has signed in to the
+// mobile app
+type UserDevice struct {
+	Id       int64  `json:"id"`
+	AppId    string `json:"appid"`
+	Name     string `json:"name"`
+	Model    string `json:"model"`
+	Platform string `json:"platform"`
+	Version  string `json:"version"`
+
+	// PushId is the id used to send push notifications to the device. It
+	// is blank if the device is not registered for notifications.
+	PushId string `json:"pushid"`
+	Uuid   string `json:"uuid"`
+
+	Created  time.Time `json:"-"`
+	Modified time.Time `json:"-"`
+}
+
+// GetUserDevices lists the devices on which a person has registered the
+// mobile app, for example to check whether a person who is not receiving
+// push notifications has the app installed. Returns ErrPermissionDenied if
+// the site does not provide core_user_get_user_devices to the web service.
+func (m *MoodleApi) GetUserDevices(userId UserID) ([]UserDevice, error) {
+	body, err := m.call("core_user_get_user_devices", url.Values{
+		"userid": {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Device struct {
+		UserDevice
+		TimeCreated  int64 `json:"timecreated"`
+		TimeModified int64 `json:"timemodified"`
+	}
+	type Result struct {
+		Devices []Device `json:"devices"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	devices := make([]UserDevice, 0, len(result.Devices))
+	for _, d := range result.Devices {
+		device := d.UserDevice
+		device.Created = m.unix(d.TimeCreated)
+		device.Modified = m.unix(d.TimeModified)
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
@@ -0,0 +1,54 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestMobileConfig(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"tool_mobile_get_public_config": `{"wwwroot":"https://moodle.example.com","httpswwwroot":"https://moodle.example.com","sitename":"Example","guestlogin":0,"rememberusername":1,"logourl":"https://moodle.example.com/logo.png","typeoflogin":2,"launchurl":"https://moodle.example.com/admin/tool/mobile/launch.php","identityproviders":[{"name":"Google","iconurl":"https://moodle.example.com/google.png","url":"https://moodle.example.com/auth/oauth2/login.php?id=1"}],"warnings":[]}`,
+		"tool_mobile_get_config":        `{"settings":[{"name":"sitename","value":"Example"},{"name":"tool_mobile_forcelogout","value":0}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	config, err := api.GetPublicConfig()
+	if err != nil {
+		t.Fatalf("GetPublicConfig failed: %v", err)
+	}
+	if config.SiteName != "Example" || config.TypeOfLogin != LoginInBrowser || config.LogoUrl == "" || len(config.IdentityProviders) != 1 {
+		t.Errorf("Unexpected public config: %+v", config)
+	}
+
+	settings, err := api.GetMobileConfig("mobileapp")
+	if err != nil {
+		t.Fatalf("GetMobileConfig failed: %v", err)
+	}
+	if settings["sitename"] != "Example" || settings["tool_mobile_forcelogout"] != "0" {
+		t.Errorf("Unexpected settings: %v", settings)
+	}
+	if q := fetch.last(); q.Get("section") != "mobileapp" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}
+
+func TestGetUserDevices(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_user_devices": `{"devices":[{"id":3,"userid":7,"appid":"com.moodle.moodlemobile","name":"Pixel 7","model":"Pixel 7","platform":"Android","version":"14","pushid":"abc123","uuid":"u-1","timecreated":1600000000,"timemodified":1600100000}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	devices, err := api.GetUserDevices(7)
+	if err != nil {
+		t.Fatalf("GetUserDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Platform != "Android" || devices[0].PushId != "abc123" || devices[0].Modified.Unix() != 1600100000 {
+		t.Errorf("Unexpected devices: %+v", devices)
+	}
+	if r := fetch.last(); r.Get("userid") != "7" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+}
@@ -24,9 +24,7 @@
 package moodle
 
 import (
-	"bytes"
 	crand "crypto/rand"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
@@ -35,7 +33,7 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
-	"net/smtp"
+	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
@@ -47,18 +45,43 @@ import (
 // https://docs.moodle.org/dev/Web_service_API_functions
 
 type MoodleApi struct {
-	base  string
-	token string
+	base        string
+	credentials Credentials
 
-	smtpUser      string
-	smtpPassword  string
-	smtpHost      string
-	smtpPort      int
-	smtpFromName  string
-	smtpFromEmail string
+	mailer        Mailer
+	fromName      string
+	fromEmail     string
+	passwordEmail map[string]*EmailTemplate
+	emailChange   *EmailTemplate
 
-	log   MoodleLogger
-	fetch LookupUrl
+	passwordPolicy *PasswordPolicy
+
+	availabilityFunction    string
+	plagiarismFunction      string
+	readOnlyFunctions       map[string]bool
+	assignmentDatesFunction string
+	quizDatesFunction       string
+
+	location *time.Location
+	keepRaw  bool
+
+	cache    ResponseCache
+	results  *resultCache
+	inflight *flightGroup
+	limiter  *rateLimiter
+	retry    *RetryPolicy
+
+	mutations *mutationQueue
+
+	name    string
+	metrics Metrics
+
+	userAgent string
+	headers   http.Header
+
+	log           LeveledMoodleLogger
+	slowThreshold time.Duration
+	fetch         LookupUrl
 }
 
 func NewMoodleApi(base string, token string) *MoodleApi {
@@ -68,20 +91,37 @@ func NewMoodleApi(base string, token string) *MoodleApi {
 		}
 	}
 	return &MoodleApi{
-		base:  base,
-		token: token,
-		log:   &NilMoodleLogger{},
-		fetch: &DefaultLookupUrl{},
+		base:          base,
+		credentials:   TokenCredentials(token),
+		log:           &NilMoodleLogger{},
+		slowThreshold: 10 * time.Second,
+		location:      time.Local,
+		inflight:      &flightGroup{},
+		fetch:         &DefaultLookupUrl{},
 	}
 }
 
+// SetSmtpSettings configures the smtp server used to send email. The
+// connection uses implicit TLS on any port, and the server certificate is
+// verified. For STARTTLS, or to skip verification, use SetMailer with a
+// custom SmtpMailer.
 func (m *MoodleApi) SetSmtpSettings(host string, port int, user, password string, fromName, fromEmail string) {
-	m.smtpUser = user
-	m.smtpPassword = password
-	m.smtpHost = host
-	m.smtpPort = port
-	m.smtpFromName = fromName
-	m.smtpFromEmail = fromEmail
+	m.mailer = &SmtpMailer{
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+	}
+	m.fromName = fromName
+	m.fromEmail = fromEmail
+}
+
+// SetMailer replaces the mailer used to send email, and sets the address
+// email is sent from.
+func (m *MoodleApi) SetMailer(mailer Mailer, fromName, fromEmail string) {
+	m.mailer = mailer
+	m.fromName = fromName
+	m.fromEmail = fromEmail
 }
 
 func (m *MoodleApi) MoodleUrl() string {
@@ -89,7 +129,7 @@ func (m *MoodleApi) MoodleUrl() string {
 }
 
 type Course struct {
-	MoodleId    int64         `json:"id,omitempty"`
+	MoodleId    CourseID      `json:"id,omitempty"`
 	Code        string        `json:"shortname,omitempty"`
 	Name        string        `json:"fullname,omitempty"`
 	Summary     string        `json:",omitempty"`
@@ -101,17 +141,32 @@ type Course struct {
 }
 
 type Person struct {
-	MoodleId             int64  `json:",omitempty"`
+	MoodleId             UserID `json:",omitempty"`
 	Username             string `json:",omitempty"`
 	Email                string `json:",omitempty"`
 	FirstName            string `json:",omitempty"`
 	LastName             string `json:",omitempty"`
 	ProfileImageUrl      string `json:"profileimageurl,omitempty"`
 	ProfileImageUrlSmall string `json:"profileimageurlsmall,omitempty"`
+	Lang                 string `json:",omitempty"`
 	Suspended            bool
 	Created              *time.Time    `json:",omitempty"`
 	Roles                []*Role       `json:"role,omitempty"`
 	CustomField          []CustomField `json:"customfields,omitempty"`
+
+	// Auth is the authentication method, such as "manual" or "ldap".
+	// FirstAccess and LastAccess are nil if the person has never signed in.
+	// These are only set by GetPersonByMoodleId and GetAccessReport.
+	Auth        string     `json:",omitempty"`
+	FirstAccess *time.Time `json:",omitempty"`
+	LastAccess  *time.Time `json:",omitempty"`
+
+	// Unconfirmed is set for self registered accounts whose email address
+	// has not been confirmed. Moodle never returns deleted accounts.
+	Unconfirmed bool `json:",omitempty"`
+
+	// Raw is the json moodle returned for the person, when SetKeepRawJSON is enabled
+	Raw json.RawMessage `json:"-"`
 }
 
 func (p *Person) Field(name string) string {
@@ -154,6 +209,7 @@ type Submission struct {
 
 type Assignment struct {
 	MoodleId    int64        `json:",omitempty"`
+	CmId        CmID         `json:",omitempty"`
 	Name        string       `json:",omitempty"`
 	Due         *time.Time   `json:",omitempty"`
 	Weight      float64      `json:",omitempty"`
@@ -165,7 +221,7 @@ type Assignment struct {
 
 type RoleInfo struct {
 	Name     string `json:",omitempty"`
-	MoodleId int64  `json:"-"`
+	MoodleId RoleID `json:"-"`
 }
 
 type GradeInfo struct {
@@ -186,50 +242,288 @@ func (a ByCourseCode) Less(i, j int) bool {
 }
 
 func readError(body string) string {
-	if !strings.HasPrefix(body, "{\"exception\":\"") || strings.Index(body, "\"message\":\"") < 0 {
-		return ""
+	return readException(body).Message
+}
+
+// moodleException is the response moodle returns when a function raises an
+// exception
+type moodleException struct {
+	Message   string `json:"message"`
+	Exception string `json:"exception"`
+	ErrorCode string `json:"errorcode"`
+	DebugInfo string `json:"debuginfo"`
+}
+
+// readException reads a moodle exception. The message is the exception name
+// if moodle did not supply a message.
+func readException(body string) moodleException {
+	var response moodleException
+	if !strings.HasPrefix(body, "{\"exception\":\"") {
+		return response
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return moodleException{}
+	}
+	if response.Message == "" {
+		response.Message = response.Exception
+	}
+	return response
+}
+
+// readWarnings returns the messages of any warnings included in a moodle
+// response object.
+func readWarnings(body string) []string {
+	if !strings.HasPrefix(body, "{") || strings.Index(body, "\"warnings\":[{") < 0 {
+		return nil
 	}
 
 	type Response struct {
-		Message   string `json:"message"`
-		Exception string `json:"exception"`
-		ErrorCode string `json:"errorcode"`
-		DebugInfo string `json:"debuginfo"`
+		Warnings []struct {
+			Item        string `json:"item"`
+			WarningCode string `json:"warningcode"`
+			Message     string `json:"message"`
+		} `json:"warnings"`
 	}
 	var response Response
 	if err := json.Unmarshal([]byte(body), &response); err != nil {
-		return ""
+		return nil
+	}
+
+	var warnings []string
+	for _, w := range response.Warnings {
+		warnings = append(warnings, w.Message+" ("+w.WarningCode+")")
+	}
+	return warnings
+}
+
+// header returns the headers sent with each request
+func (m *MoodleApi) header() http.Header {
+	header := http.Header{}
+	if m.userAgent != "" {
+		header.Set("User-Agent", m.userAgent)
+	}
+	for k, v := range m.headers {
+		header[k] = v
+	}
+	return header
+}
+
+// RequestIdHeader is the header used to send the id of each call to moodle.
+// Configure the web server to record it in the access log, for example with
+// the apache log format "%{X-Request-Id}i", to match log entries to failed
+// calls.
+const RequestIdHeader = "X-Request-Id"
+
+// call invokes a moodle web service function and returns the response body.
+// Transport failures and moodle exceptions are returned as errors.
+func (m *MoodleApi) call(function string, params url.Values) (string, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	var key string
+	var ttl time.Duration
+	if m.results != nil && m.readOnly(function) {
+		if ttl = m.results.cacheTTL(function); ttl > 0 {
+			key, _ = m.scopedCacheKey(function, params)
+		}
+		if key != "" {
+			if body, ok := m.results.cache.Get(key); ok {
+				m.debug("Using cached result of %s", function)
+				return body, nil
+			}
+		}
+	}
+
+	start := time.Now()
+	var body string
+	var err error
+	switch {
+	case m.mutations != nil && !m.readOnly(function):
+		body, err = m.sendOrQueue(function, params)
+	case m.inflight == nil || !m.readOnly(function):
+		body, err = m.sendWithRetry(function, params, nil)
+	default:
+		body, err = m.inflight.do(cacheKey(function, params), func() (string, error) {
+			return m.sendWithRetry(function, params, nil)
+		})
+	}
+	if m.metrics != nil {
+		m.metrics.ObserveCall(m.siteName(), function, time.Since(start), err)
+	}
+	if err == nil && key != "" {
+		m.results.cache.Set(key, body, ttl)
+	}
+	return body, err
+}
+
+// callUncached makes a call without using the result cache, or sharing the
+// response to an identical call in progress, for checks such as Ping that
+// must see the current state of moodle
+func (m *MoodleApi) callUncached(function string, params url.Values) (string, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	start := time.Now()
+	body, err := m.send(function, params, nil)
+	if m.metrics != nil {
+		m.metrics.ObserveCall(m.siteName(), function, time.Since(start), err)
+	}
+	return body, err
+}
+
+// send makes the request for a call, adding any extra headers supplied
+func (m *MoodleApi) send(function string, params url.Values, extra http.Header) (string, error) {
+	params.Set("wsfunction", function)
+	params.Set("moodlewsrestformat", "json")
+
+	// Each call is given an id that is logged, sent to moodle, and included
+	// in any error returned.
+	id := newRequestId()
+
+	var key string
+	var cached *CachedResponse
+	if m.cache != nil && m.readOnly(function) {
+		key, _ = m.scopedCacheKey(function, params)
+	}
+	if key != "" {
+		if c, ok := m.cache.Get(key); ok {
+			cached = &c
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		header := m.header()
+		for k, v := range extra {
+			header[k] = v
+		}
+		header.Set(RequestIdHeader, id)
+		if cached != nil {
+			conditionalHeaders(header, cached)
+		}
+		if err := m.credentials.Apply(params, header); err != nil {
+			return "", err
+		}
+
+		// Parameters (including the token) are sent in the request body so
+		// they do not appear in server or proxy access logs. Only the
+		// function name is included in the url.
+		l := m.base + "webservice/rest/server.php?wsfunction=" + url.QueryEscape(function)
+		m.debug("[%s] Fetch: %s %s", id, l, params.Encode())
+		if m.limiter != nil {
+			m.limiter.wait()
+		}
+		start := time.Now()
+		var body string
+		var status int
+		var responseHeader http.Header
+		var err error
+		if f, ok := m.fetch.(HeaderLookupUrl); ok {
+			body, status, responseHeader, err = f.DoWithHeader("POST", l, params, header)
+		} else {
+			body, status, _, err = m.fetch.Do("POST", l, params, header)
+		}
+		elapsed := time.Since(start)
+		m.debug("[%s] Response: %s", id, body)
+		if m.slowThreshold > 0 && elapsed > m.slowThreshold {
+			m.warn("[%s] Slow call to %s took %s", id, function, elapsed)
+		}
+
+		if status == http.StatusNotModified && cached != nil && err == nil {
+			m.debug("[%s] Using cached response to %s", id, function)
+			return cached.Body, nil
+		}
+
+		// Expired bearer tokens are refreshed and the call retried once
+		if status == http.StatusUnauthorized && attempt == 0 {
+			if r, ok := m.credentials.(RefreshableCredentials); ok {
+				m.info("[%s] Call to %s was unauthorised, refreshing token and retrying", id, function)
+				r.Invalidate()
+				continue
+			}
+		}
+		if err != nil {
+			return "", m.transportError(id, function, l, status, err)
+		}
+
+		if strings.HasPrefix(body, "{\"exception\":\"") {
+			if attempt == 0 && m.refreshInvalidToken(id, function, body) {
+				continue
+			}
+			return body, m.exceptionError(id, function, l, status, body)
+		}
+
+		// Proxies and overloaded servers respond without a moodle exception
+		if status >= http.StatusInternalServerError {
+			return "", m.transportError(id, function, l, status, fmt.Errorf("Server returned status %d", status))
+		}
+
+		for _, w := range readWarnings(body) {
+			m.warn("[%s] Call to %s returned warning: %s", id, function, w)
+		}
+
+		if key != "" && responseHeader != nil {
+			etag, modified := responseHeader.Get("ETag"), responseHeader.Get("Last-Modified")
+			if etag != "" || modified != "" {
+				m.cache.Set(key, CachedResponse{Body: body, ETag: etag, LastModified: modified})
+			}
+		}
+
+		return body, nil
 	}
+}
 
-	if response.Message != "" {
-		return response.Message
+// transportError logs and returns a failure to make a request
+func (m *MoodleApi) transportError(id, function, l string, status int, err error) error {
+	m.logError("[%s] Call to %s failed: %v", id, function, err)
+	e := &MoodleError{RequestId: id, Function: function, StatusCode: status, Url: maskSecrets(l), Message: err.Error(), Err: err}
+	switch status {
+	case http.StatusUnauthorized:
+		e.Kind = ErrInvalidToken
+	case http.StatusForbidden:
+		e.Kind = ErrPermissionDenied
 	}
-	return response.Exception
+	return e
+}
 
+// exceptionError logs and returns an exception raised by moodle
+func (m *MoodleApi) exceptionError(id, function, l string, status int, body string) error {
+	e := readException(body)
+	m.logError("[%s] Call to %s failed: %s", id, function, e.Message)
+	if e.DebugInfo != "" {
+		m.debug("[%s] Debug info: %s", id, e.DebugInfo)
+	}
+	return &MoodleError{
+		RequestId:  id,
+		Function:   function,
+		StatusCode: status,
+		Url:        maskSecrets(l),
+		ErrorCode:  e.ErrorCode,
+		Exception:  e.Exception,
+		DebugInfo:  maskSecrets(e.DebugInfo),
+		Message:    e.Message,
+		Kind:       errorCodes[e.ErrorCode],
+	}
 }
 
 // Get Moodle Account details matching by username. Returns nil if not found. Returns error if multiple matches are found.
 func (m *MoodleApi) GetPersonByUsername(username string) (*Person, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&field=username&values[0]=%s", m.base, m.token, "core_user_get_users_by_field",
-		url.QueryEscape(username))
-	body, _, _, err := m.fetch.GetUrl(url)
-	m.log.Debug("Fetch: %s", url)
-
+	body, err := m.call("core_user_get_users_by_field", url.Values{
+		"field":     {"username"},
+		"values[0]": {username},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message)
-	}
-
 	type Result struct {
-		Id           int64         `json:"id"`
+		Id           UserID        `json:"id"`
 		FirstName    string        `json:"firstname"`
 		LastName     string        `json:"lastname"`
 		Email        string        `json:"email"`
 		Username     string        `json:"username"`
+		Lang         string        `json:"lang"`
+		Suspended    bool          `json:"suspended"`
+		Confirmed    *bool         `json:"confirmed"`
 		CustomFields []CustomField `json:"customfields"`
 	}
 
@@ -239,12 +533,13 @@ func (m *MoodleApi) GetPersonByUsername(username string) (*Person, error) {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
 	if len(results) > 1 {
-		return nil, errors.New("Multiple moodle accounts match this username")
+		return nil, wrapError("Multiple moodle accounts match this username", ErrMultipleMatches)
 	}
 
+	raw := m.rawItems(body, "")
 	var person *Person
-	for _, i := range results {
-		person = &Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username}
+	for n, i := range results {
+		person = &Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, Lang: i.Lang, Suspended: i.Suspended, Unconfirmed: unconfirmed(i.Confirmed), Raw: rawItem(raw, n)}
 		for _, c := range i.CustomFields {
 			person.CustomField = append(person.CustomField, CustomField{Name: c.Name, Value: c.Value})
 		}
@@ -254,44 +549,31 @@ func (m *MoodleApi) GetPersonByUsername(username string) (*Person, error) {
 	return person, nil
 }
 
-type MoodleLogger interface {
-	Debug(message string, items ...interface{}) error
-}
-
-type NilMoodleLogger struct {
-}
-
-func (ml *NilMoodleLogger) Debug(message string, items ...interface{}) error {
-	return nil
-}
-
-func (m *MoodleApi) SetLogger(l MoodleLogger) {
-	m.log = l
-}
-
 // Get Moodle Account details matching by moodle id. Returns nil if not found.
-func (m *MoodleApi) GetPersonByMoodleId(id int64) (*Person, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&field=id&values[0]=%d", m.base, m.token, "core_user_get_users_by_field",
-		id)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) GetPersonByMoodleId(id UserID) (*Person, error) {
+	body, err := m.call("core_user_get_users_by_field", url.Values{
+		"field":     {"id"},
+		"values[0]": {fmt.Sprint(id)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message)
-	}
-
 	type Result struct {
-		Id           int64         `json:"id"`
-		FirstName    string        `json:"firstname"`
-		LastName     string        `json:"lastname"`
-		Email        string        `json:"email"`
-		Username     string        `json:"username"`
-		CustomFields []CustomField `json:"customfields"`
+		Id                   UserID        `json:"id"`
+		FirstName            string        `json:"firstname"`
+		LastName             string        `json:"lastname"`
+		Email                string        `json:"email"`
+		Username             string        `json:"username"`
+		ProfileImageUrl      string        `json:"profileimageurl,omitempty"`
+		ProfileImageUrlSmall string        `json:"profileimageurlsmall,omitempty"`
+		Lang                 string        `json:"lang"`
+		Auth                 string        `json:"auth"`
+		FirstAccess          int64         `json:"firstaccess"`
+		LastAccess           int64         `json:"lastaccess"`
+		Suspended            bool          `json:"suspended"`
+		Confirmed            *bool         `json:"confirmed"`
+		CustomFields         []CustomField `json:"customfields"`
 	}
 
 	var results []Result
@@ -300,12 +582,18 @@ func (m *MoodleApi) GetPersonByMoodleId(id int64) (*Person, error) {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
 	if len(results) > 1 {
-		return nil, errors.New("Multiple moodle accounts match this username")
+		return nil, wrapError("Multiple moodle accounts match this username", ErrMultipleMatches)
 	}
 
+	raw := m.rawItems(body, "")
 	var person *Person
-	for _, i := range results {
-		person = &Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username}
+	for n, i := range results {
+		if strings.Index(i.ProfileImageUrl, "gravatar") > 0 {
+			i.ProfileImageUrl = ""
+			i.ProfileImageUrlSmall = ""
+		}
+		person = &Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, ProfileImageUrl: i.ProfileImageUrl, ProfileImageUrlSmall: i.ProfileImageUrlSmall, Lang: i.Lang, Suspended: i.Suspended, Unconfirmed: unconfirmed(i.Confirmed), Raw: rawItem(raw, n),
+			Auth: i.Auth, FirstAccess: m.unixTime(i.FirstAccess), LastAccess: m.unixTime(i.LastAccess)}
 		for _, c := range i.CustomFields {
 			person.CustomField = append(person.CustomField, CustomField{Name: c.Name, Value: c.Value})
 		}
@@ -319,8 +607,8 @@ type UploadResponse struct {
 	ItemId int64 `json:"itemid"`
 }
 
-// SetProfilePicture uploads a draft file, set is as a profile picture, then removes the draft file
-func (m *MoodleApi) SetProfilePicture(userMoodleId int64, r io.Reader) error {
+// SetProfilePicture uploads a draft file, then sets it as the profile picture
+func (m *MoodleApi) SetProfilePicture(userMoodleId UserID, r io.Reader) error {
 	now := time.Now()
 
 	data, err := ioutil.ReadAll(r)
@@ -328,21 +616,22 @@ func (m *MoodleApi) SetProfilePicture(userMoodleId int64, r io.Reader) error {
 		return err
 	}
 	img := base64.StdEncoding.EncodeToString(data)
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&filearea=draft&instanceid=%d&component=user&filepath=/&contextlevel=user&filename=profilepic%s.jpg&filecontent=%s&itemid=%d", m.base, m.token, "core_files_upload", userMoodleId, now.Format("20060102150405"), url.QueryEscape(img), userMoodleId)
 
 	// 1. Upload a draft file
-	//url := fmt.Sprintf("%swebservice/upload.php?token=%s&wsfunction=%s&moodlewsrestformat=json&filearea=draft&instanceid=%d&component=user&filepath=/&contextlevel=user&filename=profilepic%s.jpg&itemid=%d", m.base, m.token, "core_files_upload", userMoodleId, now.Format("20060102150405"), userMoodleId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
+	body, err := m.call("core_files_upload", url.Values{
+		"component":    {"user"},
+		"filearea":     {"draft"},
+		"itemid":       {fmt.Sprint(userMoodleId)},
+		"filepath":     {"/"},
+		"filename":     {"profilepic" + now.Format("20060102150405") + ".jpg"},
+		"filecontent":  {img},
+		"contextlevel": {"user"},
+		"instanceid":   {fmt.Sprint(userMoodleId)},
+	})
 	if err != nil {
 		return err
 	}
-	fmt.Println(body)
 	var draftFileId int64 = 0
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
 	if strings.Index(body, "\"itemid\":") > 0 {
 		var u UploadResponse
 		if err := json.Unmarshal([]byte(body), &u); err != nil {
@@ -352,60 +641,71 @@ func (m *MoodleApi) SetProfilePicture(userMoodleId int64, r io.Reader) error {
 	} else {
 		return errors.New("Server returned unexpected response: " + body)
 	}
-	fmt.Println(draftFileId)
+	m.debug("Uploaded profile picture draft %d", draftFileId)
 
 	// 2. Update the profile picture
-	url = fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&draftitemid=%d&userid=%d", m.base, m.token, "core_user_update_picture", draftFileId, userMoodleId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err = m.fetch.GetUrl(url)
+	body, err = m.call("core_user_update_picture", url.Values{
+		"draftitemid": {fmt.Sprint(draftFileId)},
+		"userid":      {fmt.Sprint(userMoodleId)},
+	})
 	if err != nil {
 		return err
 	}
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
 	if strings.TrimSpace(body) != "null" {
 		return errors.New("Server returned unexpected response: " + body)
 	}
 
-	fmt.Println("profile set")
+	m.debug("Profile picture set for %d", userMoodleId)
+	return nil
+}
 
-	// 3. Remove the draft file
-	/*
-		url = fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&draftitemid=0&delete=1", m.base, m.token, "core_user_update_picture")
-		m.log.Debug("Fetch: %s", url)
-		body, _, _, err = m.fetch.GetUrl(url)
-		if err != nil {
-			return err
-		}
-		if strings.HasPrefix(body, "{\"exception\":\"") {
-			message := readError(body)
-			return errors.New(message + ". " + url)
-		}
-		if strings.TrimSpace(body) != "null" {
-			return errors.New("Server returned unexpected response: " + body)
-		}*/
+// RemoveProfilePicture deletes the profile picture of a person, so that the
+// default picture is shown.
+func (m *MoodleApi) RemoveProfilePicture(userId UserID) error {
+	body, err := m.call("core_user_update_picture", url.Values{
+		"draftitemid": {"0"},
+		"delete":      {"1"},
+		"userid":      {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return err
+	}
+
+	type Result struct {
+		Success bool `json:"success"`
+	}
 
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if !result.Success {
+		return errors.New("Server returned unexpected response: " + body)
+	}
+
+	m.debug("Profile picture removed for %d", userId)
 	return nil
 }
 
-// Set the password for a moodle account. Password must match moodle password policy.
-func (m *MoodleApi) ResetPassword(moodleId int64, password string) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][id]=%d&users[0][password]=%s", m.base, m.token, "core_user_update_users", moodleId,
-		url.QueryEscape(password))
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
+// Set the password for a moodle account. Password must match moodle password
+// policy. If a policy has been set with SetPasswordPolicy the password is
+// checked before it is sent to moodle.
+func (m *MoodleApi) ResetPassword(moodleId UserID, password string) error {
+	if m.passwordPolicy != nil {
+		if err := m.passwordPolicy.Validate(password); err != nil {
+			return err
+		}
+	}
 
+	body, err := m.call("core_user_update_users", url.Values{
+		"users[0][id]":       {fmt.Sprint(moodleId)},
+		"users[0][password]": {password},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	if strings.TrimSpace(body) != "null" {
 		return errors.New("Server returned unexpected response: " + body)
 	}
@@ -415,28 +715,25 @@ func (m *MoodleApi) ResetPassword(moodleId int64, password string) error {
 
 // Get moodle account matching by email address.
 func (m *MoodleApi) GetPersonByEmail(email string) (*Person, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&field=email&values[0]=%s", m.base, m.token, "core_user_get_users_by_field",
-		url.QueryEscape(email))
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	body, err := m.call("core_user_get_users_by_field", url.Values{
+		"field":     {"email"},
+		"values[0]": {email},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message + ". " + url)
-	}
-
 	type Result struct {
-		Id                   int64         `json:"id"`
+		Id                   UserID        `json:"id"`
 		FirstName            string        `json:"firstname"`
 		LastName             string        `json:"lastname"`
 		Email                string        `json:"email"`
 		Username             string        `json:"username"`
 		ProfileImageUrl      string        `json:"profileimageurl,omitempty"`
 		ProfileImageUrlSmall string        `json:"profileimageurlsmall,omitempty"`
+		Lang                 string        `json:"lang"`
+		Suspended            bool          `json:"suspended"`
+		Confirmed            *bool         `json:"confirmed"`
 		CustomFields         []CustomField `json:"customfields"`
 	}
 
@@ -446,13 +743,14 @@ func (m *MoodleApi) GetPersonByEmail(email string) (*Person, error) {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
 
+	raw := m.rawItems(body, "")
 	people := make([]Person, 0, len(results))
-	for _, i := range results {
+	for n, i := range results {
 		if strings.Index(i.ProfileImageUrl, "gravatar") > 0 {
 			i.ProfileImageUrl = ""
 			i.ProfileImageUrlSmall = ""
 		}
-		p := Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, ProfileImageUrl: i.ProfileImageUrl, ProfileImageUrlSmall: i.ProfileImageUrlSmall}
+		p := Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, ProfileImageUrl: i.ProfileImageUrl, ProfileImageUrlSmall: i.ProfileImageUrlSmall, Lang: i.Lang, Suspended: i.Suspended, Unconfirmed: unconfirmed(i.Confirmed), Raw: rawItem(raw, n)}
 		for _, c := range i.CustomFields {
 			p.CustomField = append(p.CustomField, CustomField{Name: c.Name, Value: c.Value})
 		}
@@ -466,11 +764,9 @@ func (m *MoodleApi) GetPersonByEmail(email string) (*Person, error) {
 		return &people[0], nil
 	}
 
-	return nil, errors.New("Multiple moodle accounts match this email address")
+	return nil, wrapError("Multiple moodle accounts match this email address", ErrMultipleMatches)
 }
 
-const rst = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
-
 func NewCryptoSeededSource() rand.Source {
 	var seed int64
 	binary.Read(crand.Reader, binary.BigEndian, &seed)
@@ -479,60 +775,12 @@ func NewCryptoSeededSource() rand.Source {
 
 // RandomPassword differs from RandomString in that it ensures we dont have
 // a series of repeated or incrementing characters, and ensures we have at
-// least one uppercase lowercase, and number character.
+// least one uppercase lowercase, and number character. Use
+// RandomPasswordForPolicy to generate passwords for a different policy.
 func RandomPassword() string {
-	size := 10
-	random := rand.New(NewCryptoSeededSource())
-
-	bytes := make([]byte, size)
-	hasNumber := false
-	hasLowercase := false
-	hasUppercase := false
-	var last byte = 0
-	for i := 0; i < size; i++ {
-		if size > 2 && i == size-1 && hasUppercase == false {
-			c := 'B' + uint8(random.Int31n(25))
-			if c == 'O' {
-				c = 'A'
-			}
-			bytes[i] = c
-			continue
-		}
-		if size > 3 && i == size-2 && hasLowercase == false {
-			c := 'b' + uint8(random.Int31n(25))
-			if c == 'l' {
-				c = 'a'
-			}
-			bytes[i] = c
-			continue
-		}
-		if size > 4 && i == size-3 && hasNumber == false {
-			c := '2' + uint8(random.Int31n(8))
-			bytes[i] = c
-			continue
-		}
-
-		x := random.Intn(len(rst))
-		c := rst[x]
-
-		if c == last || c == last+1 {
-			i = i - 1
-			continue
-		}
-
-		if c <= '9' {
-			hasNumber = true
-		} else if c <= 'Z' {
-			hasUppercase = true
-		} else {
-			hasLowercase = true
-		}
-
-		bytes[i] = c
-		last = c
-	}
-	s := string(bytes)
-	return s[0:5] + "-" + s[5:]
+	// The default policy can always be satisfied
+	p, _ := RandomPasswordForPolicy(nil)
+	return p
 }
 
 // Reset the password for a moodle account, and email the password to the user
@@ -542,102 +790,19 @@ func (m *MoodleApi) ResetPasswordWithEmail(email string) error {
 		return err
 	}
 	if p == nil {
-		return errors.New("Email address not found in moodle")
+		return wrapError("Email address not found in moodle", ErrNotFound)
 	}
 
-	pwd := RandomPassword()
-	err = m.ResetPassword(p.MoodleId, pwd)
-	if err != nil {
-		return errors.New("Password Reset failed. " + err.Error())
-	}
-
-	if m.smtpHost == "" || m.smtpPort == 0 {
-		return errors.New("ResetPasswordWithEmail() requires smtp host and port to be specified.")
-	}
-	if m.smtpUser == "" || m.smtpPassword == "" {
-		return errors.New("ResetPasswordWithEmail() requires smtp user and password to be specified.")
-	}
-	if m.smtpFromName == "" || m.smtpFromEmail == "" {
-		return errors.New("ResetPasswordWithEmail() requires smtp from name and email to be specified.")
-	}
-
-	var w bytes.Buffer
-	w.Write([]byte(fmt.Sprintf("From: %s <%s>\r\n", m.smtpFromName, m.smtpFromEmail)))
-	w.Write([]byte(fmt.Sprintf("To: %s\r\n", p.FirstName+" "+p.LastName+" <"+p.Email+">")))
-	w.Write([]byte(fmt.Sprintf("Subject: Welcome to the Planetshakers College moodle\r\n")))
-	w.Write([]byte("Content-Type: text/plain; charset=utf-8; format=flowed\r\n"))
-	w.Write([]byte("Content-Transfer-Encoding: 8bit\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("Hi " + p.FirstName + ",\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("Welcome to the Planetshakers College Moodle, You can sign-in using the details below:\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("    URL: " + m.base + "\r\n"))
-	w.Write([]byte("    Username: " + p.Email + "\r\n"))
-	w.Write([]byte("    Password: " + pwd + "\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("If you have any difficulties with moodle access, please contact college@planetshakers.com\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("God bless,\r\n"))
-	w.Write([]byte("Planetshakers College\r\n"))
-	w.Write([]byte("\r\n"))
-	msg := w.Bytes()
-	fmt.Println(string(msg))
-
-	var auth smtp.Auth
-	if m.smtpUser != "" && m.smtpPassword != "" {
-		auth = smtp.PlainAuth("", m.smtpUser, m.smtpPassword, m.smtpHost)
-	}
-
-	// TLS config
-	tlsconfig := &tls.Config{
-		InsecureSkipVerify: true,
-		ServerName:         m.smtpHost,
-	}
-
-	// Here is the key, you need to call tls.Dial instead of smtp.Dial
-	// for smtp servers running on 465 that require an ssl connection
-	// from the very beginning (no starttls)
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%d", m.smtpHost, m.smtpPort), tlsconfig)
-	if err != nil {
-		return errors.New(fmt.Sprintf("tls.Dial(\"%s:%d\") failed: %v", m.smtpHost, m.smtpPort, err))
-	}
-
-	c, err := smtp.NewClient(conn, m.smtpHost)
-	if err != nil {
-		return errors.New(fmt.Sprintf("SMTP.NewClient() failed: %v", err))
-	}
-
-	if err = c.Auth(auth); err != nil {
-		return errors.New(fmt.Sprintf("SMTP.Auth() failed: %v", err))
-	}
-
-	if err = c.Mail(m.smtpFromEmail); err != nil {
-		return errors.New(fmt.Sprintf("SMTP.Mail() failed: %v", err))
-	}
-
-	if err = c.Rcpt(p.Email); err != nil {
-		return errors.New(fmt.Sprintf("SMTP.Rcpt() failed: %v", err))
-	}
-
-	w1, err := c.Data()
-	if err != nil {
-		return errors.New(fmt.Sprintf("SMTP.Data() failed: %v", err))
-	}
-
-	_, err = w1.Write([]byte(msg))
+	pwd, err := RandomPasswordForPolicy(m.passwordPolicy)
 	if err != nil {
 		return err
 	}
-
-	err = w1.Close()
+	err = m.ResetPassword(p.MoodleId, pwd)
 	if err != nil {
-		return errors.New(fmt.Sprintf("SMTP.Close() failed: %v", err))
+		return fmt.Errorf("Password Reset failed. %w", err)
 	}
 
-	c.Quit()
-
-	return nil
+	return m.sendPasswordEmail(m.passwordEmailTemplate(p.Lang), p, pwd)
 }
 
 // Reset the password for a moodle account, and email the password to the user
@@ -647,126 +812,56 @@ func (m *MoodleApi) WritingResetPasswordWithEmail(email string) error {
 		return err
 	}
 	if p == nil {
-		return errors.New("Email address not found in moodle")
-	}
-
-	pwd := RandomPassword()
-	err = m.ResetPassword(p.MoodleId, pwd)
-	if err != nil {
-		return err
-	}
-
-	if m.smtpHost == "" || m.smtpPort == 0 {
-		return errors.New("ResetPasswordWithEmail() requires smtp host and port to be specified.")
-	}
-	if m.smtpUser == "" || m.smtpPassword == "" {
-		return errors.New("ResetPasswordWithEmail() requires smtp user and password to be specified.")
-	}
-	if m.smtpFromName == "" || m.smtpFromEmail == "" {
-		return errors.New("ResetPasswordWithEmail() requires smtp from name and email to be specified.")
-	}
-
-	var w bytes.Buffer
-	w.Write([]byte(fmt.Sprintf("From: %s <%s>\r\n", m.smtpFromName, m.smtpFromEmail)))
-	w.Write([]byte(fmt.Sprintf("To: %s\r\n", p.FirstName+" "+p.LastName+" <"+p.Email+">")))
-	w.Write([]byte(fmt.Sprintf("Subject: Welcome to RES101\r\n")))
-	w.Write([]byte("Content-Type: text/plain; charset=utf-8; format=flowed\r\n"))
-	w.Write([]byte("Content-Transfer-Encoding: 8bit\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("Hi " + p.FirstName + ",\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("Welcome to the Planetshakers College Moodle, You now have access to RES101 in\r\n"))
-	w.Write([]byte("Moodle. You can sign-in using the details below:\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("    URL: " + m.base + "\r\n"))
-	w.Write([]byte("    Username: " + p.Email + "\r\n"))
-	w.Write([]byte("    Password: " + pwd + "\r\n"))
-	w.Write([]byte("\r\n"))
-	w.Write([]byte("God bless,\r\n"))
-	w.Write([]byte("Planetshakers College\r\n"))
-	w.Write([]byte("\r\n"))
-	msg := w.Bytes()
-	fmt.Println(string(msg))
-
-	var auth smtp.Auth
-	if m.smtpUser != "" && m.smtpPassword != "" {
-		auth = smtp.PlainAuth("", m.smtpUser, m.smtpPassword, m.smtpHost)
-	}
-
-	// TLS config
-	tlsconfig := &tls.Config{
-		InsecureSkipVerify: true,
-		ServerName:         m.smtpHost,
-	}
-
-	// Here is the key, you need to call tls.Dial instead of smtp.Dial
-	// for smtp servers running on 465 that require an ssl connection
-	// from the very beginning (no starttls)
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%d", m.smtpHost, m.smtpPort), tlsconfig)
-	if err != nil {
-		return err
-	}
-
-	c, err := smtp.NewClient(conn, m.smtpHost)
-	if err != nil {
-		return err
-	}
-
-	if err = c.Auth(auth); err != nil {
-		return err
-	}
-
-	if err = c.Mail(m.smtpFromEmail); err != nil {
-		return err
-	}
-
-	if err = c.Rcpt(p.Email); err != nil {
-		return err
-	}
-
-	w1, err := c.Data()
-	if err != nil {
-		return err
+		return wrapError("Email address not found in moodle", ErrNotFound)
 	}
 
-	_, err = w1.Write([]byte(msg))
+	pwd, err := RandomPasswordForPolicy(m.passwordPolicy)
 	if err != nil {
 		return err
 	}
-
-	err = w1.Close()
+	err = m.ResetPassword(p.MoodleId, pwd)
 	if err != nil {
 		return err
 	}
 
-	c.Quit()
-
-	return nil
+	return m.sendPasswordEmail(&writingPasswordEmail, p, pwd)
 }
 
 // Fetch moodle accounts that match match by first and last name.
+//
+// Deprecated: use FindPeopleByName, which returns a plain slice.
 func (m *MoodleApi) GetPeopleByFirstNameLastName(firstname, lastname string) (*[]Person, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&criteria[0][key]=firstname&criteria[0][value]=%s&criteria[0][key]=lastname&criteria[0][value]=%s", m.base, m.token, "core_user_get_users",
-		url.QueryEscape(firstname),
-		url.QueryEscape(lastname))
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	people, err := m.FindPeopleByName(firstname, lastname)
 	if err != nil {
 		return nil, err
 	}
+	if people == nil {
+		people = []Person{}
+	}
+	return &people, nil
+}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message + ". " + url)
+// FindPeopleByName fetches moodle accounts with a matching first and last
+// name. Returns nil if no accounts match.
+func (m *MoodleApi) FindPeopleByName(firstname, lastname string) ([]Person, error) {
+	body, err := m.call("core_user_get_users", url.Values{
+		"criteria[0][key]":   {"firstname"},
+		"criteria[0][value]": {firstname},
+		"criteria[1][key]":   {"lastname"},
+		"criteria[1][value]": {lastname},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	type Result struct {
-		Id           int64         `json:"id"`
+		Id           UserID        `json:"id"`
 		FirstName    string        `json:"firstname"`
 		LastName     string        `json:"lastname"`
 		Email        string        `json:"email"`
 		Username     string        `json:"username"`
+		Suspended    bool          `json:"suspended"`
+		Confirmed    *bool         `json:"confirmed"`
 		CustomFields []CustomField `json:"customfields"`
 	}
 	type Results struct {
@@ -780,42 +875,54 @@ func (m *MoodleApi) GetPeopleByFirstNameLastName(firstname, lastname string) (*[
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
 
-	people := make([]Person, 0, len(results.People))
-	for _, i := range results.People {
+	raw := m.rawItems(body, "users")
+	var people []Person
+	for n, i := range results.People {
 		if strings.ToLower(i.FirstName) == strings.ToLower(firstname) &&
 			strings.ToLower(i.LastName) == strings.ToLower(lastname) {
-			people = append(people, Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username})
+			people = append(people, Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, Suspended: i.Suspended, Unconfirmed: unconfirmed(i.Confirmed), Raw: rawItem(raw, n)})
 		}
 	}
 
-	return &people, nil
+	return people, nil
 }
 
 // Fetch moodle accounts that have a specific field. For example: api.GetPersonByAttribute("firstname", "James")
+//
+// Deprecated: use FindPeopleByAttribute, which returns a plain slice.
 func (m *MoodleApi) GetPeopleByAttribute(attribute, value string) (*[]Person, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&criteria[0][key]=%s&criteria[0][value]=%s", m.base, m.token, "core_user_get_users",
-		url.QueryEscape(attribute),
-		url.QueryEscape(value))
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	people, err := m.FindPeopleByAttribute(attribute, value)
 	if err != nil {
 		return nil, err
 	}
+	if people == nil {
+		people = []Person{}
+	}
+	return &people, nil
+}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message + ". " + url)
+// FindPeopleByAttribute fetches moodle accounts that have a specific field
+// value. Returns nil if no accounts match.
+func (m *MoodleApi) FindPeopleByAttribute(attribute, value string) ([]Person, error) {
+	body, err := m.call("core_user_get_users", url.Values{
+		"criteria[0][key]":   {attribute},
+		"criteria[0][value]": {value},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	type Result struct {
-		Id                   int64         `json:"id"`
+		Id                   UserID        `json:"id"`
 		FirstName            string        `json:"firstname"`
 		LastName             string        `json:"lastname"`
 		Email                string        `json:"email"`
 		Username             string        `json:"username"`
 		ProfileImageUrl      string        `json:"profileimageurl,omitempty"`
 		ProfileImageUrlSmall string        `json:"profileimageurlsmall,omitempty"`
+		Lang                 string        `json:"lang"`
+		Suspended            bool          `json:"suspended"`
+		Confirmed            *bool         `json:"confirmed"`
 		CustomFields         []CustomField `json:"customfields"`
 	}
 	type Results struct {
@@ -829,72 +936,65 @@ func (m *MoodleApi) GetPeopleByAttribute(attribute, value string) (*[]Person, er
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
 
-	people := make([]Person, 0, len(results.People))
-	for _, i := range results.People {
+	raw := m.rawItems(body, "users")
+	var people []Person
+	for n, i := range results.People {
 		if strings.Index(i.ProfileImageUrl, "gravatar") > 0 {
 			i.ProfileImageUrl = ""
 			i.ProfileImageUrlSmall = ""
 		}
-		p := Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, ProfileImageUrl: i.ProfileImageUrl, ProfileImageUrlSmall: i.ProfileImageUrlSmall}
+		p := Person{MoodleId: i.Id, FirstName: i.FirstName, LastName: i.LastName, Email: i.Email, Username: i.Username, ProfileImageUrl: i.ProfileImageUrl, ProfileImageUrlSmall: i.ProfileImageUrlSmall, Lang: i.Lang, Suspended: i.Suspended, Unconfirmed: unconfirmed(i.Confirmed), Raw: rawItem(raw, n)}
 		for _, c := range i.CustomFields {
 			p.CustomField = append(p.CustomField, CustomField{Name: c.Name, Value: c.Value})
 		}
 		people = append(people, p)
 	}
 
-	return &people, nil
+	return people, nil
 }
 
 // Moodle's bug causes role_id to be ignored: https://tracker.moodle.org/browse/MDL-51152
-func (m *MoodleApi) UnsetRole(personId int64, roleId int64, courseId int64) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&enrolments[0][roleid]=%d&enrolments[0][userid]=%d&enrolments[0][courseid]=%d", m.base, m.token, "enrol_manual_unenrol_users", roleId, personId, courseId)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+func (m *MoodleApi) UnsetRole(personId UserID, roleId RoleID, courseId CourseID) error {
+	_, err := m.call("enrol_manual_unenrol_users", url.Values{
+		"enrolments[0][roleid]":   {fmt.Sprint(roleId)},
+		"enrolments[0][userid]":   {fmt.Sprint(personId)},
+		"enrolments[0][courseid]": {fmt.Sprint(courseId)},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	return nil
 }
 
-func (m *MoodleApi) SetRole(personId int64, roleId int64, courseId int64) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&enrolments[0][roleid]=%d&enrolments[0][userid]=%d&enrolments[0][courseid]=%d", m.base, m.token, "enrol_manual_enrol_users", roleId, personId, courseId)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+func (m *MoodleApi) SetRole(personId UserID, roleId RoleID, courseId CourseID) error {
+	_, err := m.call("enrol_manual_enrol_users", url.Values{
+		"enrolments[0][roleid]":   {fmt.Sprint(roleId)},
+		"enrolments[0][userid]":   {fmt.Sprint(personId)},
+		"enrolments[0][courseid]": {fmt.Sprint(courseId)},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	return nil
 }
 
-func (m *MoodleApi) SetUserAttribute(personId int64, attribute, value string) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][id]=%d&users[0][%s]=%s", m.base, m.token, "core_user_update_users", personId,
-		url.QueryEscape(attribute),
-		url.QueryEscape(value),
-	)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+// SetUserAttribute sets one field of a person, such as "city" or
+// "suspended". The attribute and value are checked before they are sent to
+// moodle. Custom profile fields are set with SetUserCustomField.
+func (m *MoodleApi) SetUserAttribute(personId UserID, attribute, value string) error {
+	value, err := validateUserAttribute(attribute, value)
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
+	body, err := m.call("core_user_update_users", url.Values{
+		"users[0][id]":                {fmt.Sprint(personId)},
+		"users[0][" + attribute + "]": {value},
+	})
+	if err != nil {
+		return err
 	}
 
 	if strings.TrimSpace(body) != "" {
@@ -908,27 +1008,18 @@ func (m *MoodleApi) SetUserAttribute(personId int64, attribute, value string) er
 // a specific user. The userId parameter is the same ID that appears in the
 // moodle URL when viewing a user. The assessmentId is not the same ID as the
 // ID shown in a URL when viewing an assessment, it is the ID from the
-// mdl_assign table. This API updates the mdl_assign_user_flags database
-// table.
-func (m *MoodleApi) SetAssessmentExtensionDate(userId, assessmentId int64, newDueDate time.Time) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&assignmentid=%d&userflags[0][userid]=%d&userflags[0][extensionduedate]=%d", m.base, m.token,
-		"mod_assign_set_user_flags",
-		assessmentId,
-		userId,
-		newDueDate.Unix())
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
-
+// mdl_assign table, see ResolveAssignmentId. This API updates the
+// mdl_assign_user_flags database table.
+func (m *MoodleApi) SetAssessmentExtensionDate(userId UserID, assessmentId int64, newDueDate time.Time) error {
+	body, err := m.call("mod_assign_set_user_flags", url.Values{
+		"assignmentid":                   {fmt.Sprint(assessmentId)},
+		"userflags[0][userid]":           {fmt.Sprint(userId)},
+		"userflags[0][extensionduedate]": {fmt.Sprint(newDueDate.Unix())},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	if strings.HasPrefix(strings.TrimSpace(body), "[{") && strings.Index(body, "\"id\":") > 0 {
 		return nil
 	}
@@ -936,24 +1027,16 @@ func (m *MoodleApi) SetAssessmentExtensionDate(userId, assessmentId int64, newDu
 	return errors.New("Server returned unexpected response: " + body)
 }
 
-func (m *MoodleApi) SetUserCustomField(personId int64, attribute, value string) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][id]=%d&users[0][customfields][0][type]=%s&users[0][customfields][0][value]=%s", m.base, m.token, "core_user_update_users", personId,
-		url.QueryEscape(attribute),
-		url.QueryEscape(value),
-	)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) SetUserCustomField(personId UserID, attribute, value string) error {
+	body, err := m.call("core_user_update_users", url.Values{
+		"users[0][id]":                     {fmt.Sprint(personId)},
+		"users[0][customfields][0][type]":  {attribute},
+		"users[0][customfields][0][value]": {value},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	if strings.TrimSpace(body) != "" {
 		return errors.New("Server returned unexpected response: " + body)
 	}
@@ -961,20 +1044,67 @@ func (m *MoodleApi) SetUserCustomField(personId int64, attribute, value string)
 	return nil
 }
 
-func (m *MoodleApi) RemovePersonFromCourseGroup(personId int64, groupId int64) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&members[0][userid]=%d&members[0][groupid]=%d", m.base, m.token, "core_group_delete_group_members", personId, groupId)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+// SetUserCustomFields sets several custom profile fields of a person in
+// one call. Fields are keyed by the short name of the custom field.
+func (m *MoodleApi) SetUserCustomFields(personId UserID, fields map[string]string) error {
+	return m.SetCustomFieldsForUsers(map[UserID]map[string]string{personId: fields})
+}
+
+// customFieldBatchSize is the number of people updated by each call made by
+// SetCustomFieldsForUsers
+const customFieldBatchSize = 100
+
+// SetCustomFieldsForUsers sets custom profile fields of many people. People
+// are updated in batches, making one call for every 100 people. If a batch
+// fails no further batches are sent.
+func (m *MoodleApi) SetCustomFieldsForUsers(fields map[UserID]map[string]string) error {
+	ids := make([]UserID, 0, len(fields))
+	for id := range fields {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for start := 0; start < len(ids); start += customFieldBatchSize {
+		end := start + customFieldBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		params := url.Values{}
+		for n, id := range ids[start:end] {
+			params.Set(fmt.Sprintf("users[%d][id]", n), fmt.Sprint(id))
+			names := make([]string, 0, len(fields[id]))
+			for name := range fields[id] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for f, name := range names {
+				params.Set(fmt.Sprintf("users[%d][customfields][%d][type]", n, f), name)
+				params.Set(fmt.Sprintf("users[%d][customfields][%d][value]", n, f), fields[id][name])
+			}
+		}
+
+		body, err := m.call("core_user_update_users", params)
+		if err != nil {
+			return err
+		}
+		// Older versions of moodle return nothing, newer versions return a
+		// list of warnings, which have already been logged
+		if body = strings.TrimSpace(body); body != "" && body != "null" && !strings.HasPrefix(body, "{\"warnings\"") {
+			return errors.New("Server returned unexpected response: " + body)
+		}
+	}
+	return nil
+}
+
+func (m *MoodleApi) RemovePersonFromCourseGroup(personId UserID, groupId GroupID) error {
+	body, err := m.call("core_group_delete_group_members", url.Values{
+		"members[0][userid]":  {fmt.Sprint(personId)},
+		"members[0][groupid]": {fmt.Sprint(groupId)},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	type SiteInfo struct {
 		Sitename  string
 		Firstname string
@@ -983,26 +1113,21 @@ func (m *MoodleApi) RemovePersonFromCourseGroup(personId int64, groupId int64) e
 	}
 
 	if strings.TrimSpace(body) != "null" {
-		return errors.New("Server returned unexpected response: " + body + "--" + url)
+		return errors.New("Server returned unexpected response: " + body)
 	}
 
 	return nil
 }
 
-func (m *MoodleApi) AddPersonToCourseGroup(personId int64, groupId int64) error {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&members[0][userid]=%d&members[0][groupid]=%d", m.base, m.token, "core_group_add_group_members", personId, groupId)
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+func (m *MoodleApi) AddPersonToCourseGroup(personId UserID, groupId GroupID) error {
+	body, err := m.call("core_group_add_group_members", url.Values{
+		"members[0][userid]":  {fmt.Sprint(personId)},
+		"members[0][groupid]": {fmt.Sprint(groupId)},
+	})
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + url)
-	}
-
 	type SiteInfo struct {
 		Sitename  string
 		Firstname string
@@ -1011,13 +1136,21 @@ func (m *MoodleApi) AddPersonToCourseGroup(personId int64, groupId int64) error
 	}
 
 	if strings.TrimSpace(body) != "null" {
-		return errors.New("Server returned unexpected response: " + body + "--" + url)
+		return errors.New("Server returned unexpected response: " + body)
 	}
 
 	return nil
 }
 
-func (m *MoodleApi) AddGroupToCourse(courseId int64, groupName, groupDescription string) (int64, error) {
+func (m *MoodleApi) AddGroupToCourse(courseId CourseID, groupName, groupDescription string) (GroupID, error) {
+	return m.AddGroupToCourseWithIdNumber(courseId, groupName, groupDescription, "")
+}
+
+// AddGroupToCourseWithIdNumber creates a group with an id number, such as
+// the key of the class in a student information system, so that the group
+// can later be found with GetCourseGroupByIdNumber. Moodle requires id
+// numbers to be unique within a course.
+func (m *MoodleApi) AddGroupToCourseWithIdNumber(courseId CourseID, groupName, groupDescription, idNumber string) (GroupID, error) {
 	if courseId <= 0 {
 		return 0, errors.New("AddGroupToCourse() requires a valid courseId")
 	}
@@ -1025,10 +1158,15 @@ func (m *MoodleApi) AddGroupToCourse(courseId int64, groupName, groupDescription
 		return 0, errors.New("AddGroupToCourse() requires a valid groupName")
 	}
 
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&groups[0][courseid]=%d&groups[0][name]=%s&groups[0][description]=%s", m.base, m.token, "core_group_create_groups", courseId, url.QueryEscape(groupName), url.QueryEscape(groupDescription))
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
+	params := url.Values{
+		"groups[0][courseid]":    {fmt.Sprint(courseId)},
+		"groups[0][name]":        {groupName},
+		"groups[0][description]": {groupDescription},
+	}
+	if idNumber != "" {
+		params.Set("groups[0][idnumber]", idNumber)
+	}
+	body, err := m.call("core_group_create_groups", params)
 	if err != nil {
 		return 0, err
 	}
@@ -1036,13 +1174,8 @@ func (m *MoodleApi) AddGroupToCourse(courseId int64, groupName, groupDescription
 		return 0, errors.New("Moodle returned no response")
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return 0, errors.New(message + ". " + url)
-	}
-
 	type GroupInfo struct {
-		Id          int64
+		Id          GroupID
 		Courseid    int64
 		Name        string
 		Description string
@@ -1062,39 +1195,51 @@ func (m *MoodleApi) AddGroupToCourse(courseId int64, groupName, groupDescription
 
 }
 
-func (m *MoodleApi) AddUser(firstName, lastName, email, username, password string) (int64, error) {
+// UpdateCourseGroup changes the name, description and id number of a group.
+// Requires moodle 3.6 or later.
+func (m *MoodleApi) UpdateCourseGroup(group CourseGroup) error {
+	if len(strings.TrimSpace(group.Name)) == 0 {
+		return errors.New("UpdateCourseGroup() requires a valid group name")
+	}
+
+	body, err := m.call("core_group_update_groups", url.Values{
+		"groups[0][id]":          {fmt.Sprint(group.Id)},
+		"groups[0][name]":        {group.Name},
+		"groups[0][description]": {group.Description},
+		"groups[0][idnumber]":    {group.IdNumber},
+	})
+	if err != nil {
+		return err
+	}
+
+	if body = strings.TrimSpace(body); body != "" && body != "null" {
+		return errors.New("Server returned unexpected response: " + body)
+	}
+
+	return nil
+}
+
+func (m *MoodleApi) AddUser(firstName, lastName, email, username, password string) (UserID, error) {
 
 	if strings.Index(email, "@") < 0 {
 		return 0, errors.New("Invalid email address")
 	}
 
-	var l string
+	params := url.Values{
+		"users[0][firstname]": {firstName},
+		"users[0][lastname]":  {lastName},
+		"users[0][email]":     {email},
+		"users[0][username]":  {username},
+	}
 	if password == "" {
-		l = fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][firstname]=%s&users[0][lastname]=%s&users[0][email]=%s&users[0][username]=%s&users[0][createpassword]=1", m.base, m.token, "core_user_create_users",
-			url.QueryEscape(firstName),
-			url.QueryEscape(lastName),
-			url.QueryEscape(email),
-			url.QueryEscape(username))
+		params.Set("users[0][createpassword]", "1")
 	} else {
-		l = fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][firstname]=%s&users[0][lastname]=%s&users[0][email]=%s&users[0][username]=%s&users[0][password]=%s", m.base, m.token, "core_user_create_users",
-			url.QueryEscape(firstName),
-			url.QueryEscape(lastName),
-			url.QueryEscape(email),
-			url.QueryEscape(username),
-			url.QueryEscape(password))
-	}
-	//fmt.Println(l)
-	m.log.Debug("Fetch: %s", l)
-
-	body, _, _, err := m.fetch.GetUrl(l)
-	fmt.Println(body)
-	if err != nil {
-		return 0, err
+		params.Set("users[0][password]", password)
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return 0, errors.New(message + ". " + l)
+	body, err := m.call("core_user_create_users", params)
+	if err != nil {
+		return 0, err
 	}
 
 	type SiteInfo struct {
@@ -1116,68 +1261,56 @@ func (m *MoodleApi) AddUser(firstName, lastName, email, username, password strin
 		return 0, errors.New("Server returned unexpected response. ID is missing. " + err.Error())
 	}
 
-	return int64(data[0]["id"].(float64)), nil
+	return UserID(data[0]["id"].(float64)), nil
 }
 
 // UpdateUser updates the basic details of a moodle account. Requires permission for "core_user_update_users". Password is only updated if password is not blank.
-func (m *MoodleApi) UpdateUser(id int64, firstName, lastName, email, username, password string) error {
+func (m *MoodleApi) UpdateUser(id UserID, firstName, lastName, email, username, password string) error {
 
 	if strings.Index(email, "@") < 0 {
 		return errors.New("Invalid email address")
 	}
 
-	var l string
-	l = fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&users[0][id]=%d&users[0][firstname]=%s&users[0][lastname]=%s&users[0][email]=%s&users[0][username]=%s", m.base, m.token, "core_user_update_users", id,
-		url.QueryEscape(firstName),
-		url.QueryEscape(lastName),
-		url.QueryEscape(email),
-		url.QueryEscape(username))
+	params := url.Values{
+		"users[0][id]":        {fmt.Sprint(id)},
+		"users[0][firstname]": {firstName},
+		"users[0][lastname]":  {lastName},
+		"users[0][email]":     {email},
+		"users[0][username]":  {username},
+	}
 	if password != "" {
-		l = l + "&users[0][password]=" + url.QueryEscape(password)
+		params.Set("users[0][password]", password)
 	}
-	//fmt.Println(l)
-	m.log.Debug("Fetch: %s", l)
 
-	body, _, _, err := m.fetch.GetUrl(l)
-	fmt.Println(body)
+	_, err := m.call("core_user_update_users", params)
 	if err != nil {
 		return err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return errors.New(message + ". " + l)
-	}
-
 	return nil
 }
 
 type CourseGroup struct {
-	Id          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Id          GroupID `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	IdNumber    string  `json:"idnumber,omitempty"`
 }
 
 type CourseRole struct {
-	Id        int64  `json:"id"`
+	Id        RoleID `json:"id"`
 	Name      string `json:"name"`
 	ShortName string `json:"shortname"`
 }
 
-func (m *MoodleApi) GetPersonCourseList(userId int64) ([]Course, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&userid=%d", m.base, m.token, "core_enrol_get_users_courses", userId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) GetPersonCourseList(userId UserID) ([]Course, error) {
+	body, err := m.call("core_enrol_get_users_courses", url.Values{
+		"userid": {fmt.Sprint(userId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message + ". " + url)
-	}
-
 	var results []Course
 
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
@@ -1188,20 +1321,15 @@ func (m *MoodleApi) GetPersonCourseList(userId int64) ([]Course, error) {
 }
 
 // List the details of each group in a course. Fetches: id, name, and shortname
-func (m *MoodleApi) GetCourseGroups(courseId int64) ([]CourseGroup, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&courseid=%d", m.base, m.token, "core_group_get_course_groups", courseId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) GetCourseGroups(courseId CourseID) ([]CourseGroup, error) {
+	body, err := m.call("core_group_get_course_groups", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		message := readError(body)
-		return nil, errors.New(message + ". " + url)
-	}
-
 	var results []CourseGroup
 
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
@@ -1218,7 +1346,7 @@ type CustomField struct {
 }
 
 type CoursePerson struct {
-	Id           int64         `json:"id"`
+	Id           UserID        `json:"id"`
 	Username     string        `json:"username"`
 	FirstName    string        `json:"firstname"`
 	LastName     string        `json:"lastname"`
@@ -1228,6 +1356,9 @@ type CoursePerson struct {
 	Groups       []CourseGroup `json:"groups"`
 	Roles        []CourseRole  `json:"roles"`
 	CustomFields []CustomField `json:"customfields"`
+
+	// Raw is the json moodle returned for the person, when SetKeepRawJSON is enabled
+	Raw json.RawMessage `json:"-"`
 }
 
 func (cp *CoursePerson) FirstAccessTime() *time.Time {
@@ -1276,7 +1407,7 @@ func (cp *CoursePerson) HasRoleNamed(name string) bool {
 }
 
 type GradebookEntry struct {
-	UserId   int64           `json:"userid"`
+	UserId   UserID          `json:"userid"`
 	Name     string          `json:"userfullname"`
 	MaxDepth int64           `json:"maxdepth"`
 	Item     []GradebookItem `json:"gradeitems"`
@@ -1291,8 +1422,8 @@ type GradebookItem struct {
 	ItemNumber          int64   `json:"itemnumber"`
 	CategoryId          int64   `json:"categoryid"`
 	OutcomeId           int64   `json:"outcomeid"`
-	CmId                int64   `json:"cmid"`
-	GradedDate          int64   `json:"gradedategraded"`
+	CmId                CmID    `json:"cmid"`
+	GradedDate          int64   `json:"-"` // Deprecated: use GradeDateGraded
 	GradeRaw            float64 `json:"graderaw"`
 	GradeMax            float64 `json:"grademax"`
 	GradeFormatted      string  `json:"gradeformatted"`
@@ -1301,6 +1432,9 @@ type GradebookItem struct {
 	PercentageFormatted string  `json:"percentageformatted"`
 	WeightRaw           float64 `json:"weightraw"`
 	GradeIsHidden       bool    `json:"gradeishidden"`
+
+	// Raw is the json moodle returned for the item, when SetKeepRawJSON is enabled
+	Raw json.RawMessage `json:"-"`
 }
 
 func (i *GradebookItem) InferGrade() float64 {
@@ -1333,19 +1467,15 @@ func (e *GradebookItem) Graded() *time.Time {
 }
 
 // List all gradebook data associated with a course.
-func (m *MoodleApi) GetCourseGradebook(courseId int64) ([]GradebookEntry, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&courseid=%d", m.base, m.token, "gradereport_user_get_grade_items", courseId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) GetCourseGradebook(courseId CourseID) ([]GradebookEntry, error) {
+	body, err := m.call("gradereport_user_get_grade_items", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type Results struct {
 		Usergrades []GradebookEntry `json:"usergrades"`
 	}
@@ -1354,51 +1484,129 @@ func (m *MoodleApi) GetCourseGradebook(courseId int64) ([]GradebookEntry, error)
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
+	m.rawGradebookItems(body, results.Usergrades)
 
 	return results.Usergrades[:], nil
 }
 
 // List all people in a course. Results include the persons roles and groups
-func (m *MoodleApi) GetCourseRoles(courseId int64) ([]CoursePerson, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&courseid=%d", m.base, m.token, "core_enrol_get_enrolled_users", courseId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+func (m *MoodleApi) GetCourseRoles(courseId CourseID) ([]CoursePerson, error) {
+	body, err := m.call("core_enrol_get_enrolled_users", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
+	var results []CoursePerson
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	raw := m.rawItems(body, "")
+	for n := range results {
+		results[n].Raw = rawItem(raw, n)
+	}
+
+	return results[:], nil
+}
+
+// GetCourseEnrolmentCount counts the people enrolled in a course. Only the
+// id of each person is requested, so this is much cheaper than
+// GetCourseRoles for courses with many people.
+func (m *MoodleApi) GetCourseEnrolmentCount(courseId CourseID) (int, error) {
+	body, err := m.call("core_enrol_get_enrolled_users", url.Values{
+		"courseid":          {fmt.Sprint(courseId)},
+		"options[0][name]":  {"userfields"},
+		"options[0][value]": {"id"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var results []struct{}
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return 0, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return len(results), nil
+}
+
+// SearchCourseUsers finds people enrolled in a course whose name, email or
+// other identity fields contain the query. Moodle filters the people, so this
+// is suitable for typeahead pickers in large courses. At most
+// SearchCourseUsersLimit people are returned, and roles and groups are not
+// included.
+func (m *MoodleApi) SearchCourseUsers(courseId CourseID, query string) ([]CoursePerson, error) {
+	body, err := m.call("core_enrol_search_users", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+		"search":             {query},
+		"searchanywhere":     {"1"},
+		"page":               {"0"},
+		"perpage":            {fmt.Sprint(SearchCourseUsersLimit)},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	var results []CoursePerson
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
+	raw := m.rawItems(body, "")
+	for n := range results {
+		results[n].Raw = rawItem(raw, n)
+	}
 
-	return results[:], nil
+	return results, nil
 }
 
-func (m *MoodleApi) GetCourses(value string) ([]Course, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&criterianame=search&criteriavalue=%s", m.base, m.token, "core_course_search_courses", url.QueryEscape(value))
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
+// SearchCourseUsersLimit is the maximum number of people returned by
+// SearchCourseUsers.
+var SearchCourseUsersLimit = 50
 
+// GetRolesForCourses lists the people in each course, keyed by course id.
+// Courses are fetched in parallel by up to concurrency workers, or one
+// worker if concurrency is less than one. If any course fails no further
+// courses are fetched and the first error is returned.
+func (m *MoodleApi) GetRolesForCourses(courseIds []CourseID, concurrency int) (map[CourseID][]CoursePerson, error) {
+	people := make([][]CoursePerson, len(courseIds))
+	err := forEachParallel(len(courseIds), concurrency, func(i int) error {
+		p, err := m.GetCourseRoles(courseIds[i])
+		if err != nil {
+			return fmt.Errorf("Failed to fetch people in course %d. %w", courseIds[i], err)
+		}
+		people[i] = p
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
+	roles := make(map[CourseID][]CoursePerson, len(courseIds))
+	for i, courseId := range courseIds {
+		roles[courseId] = people[i]
+	}
+	return roles, nil
+}
+
+func (m *MoodleApi) GetCourses(value string) ([]Course, error) {
+	body, err := m.call("core_course_search_courses", url.Values{
+		"moodlewssettingraw": {"true"},
+		"criterianame":       {"search"},
+		"criteriavalue":      {value},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	type Result struct {
-		Id          int64  `json:"id"`
-		Code        string `json:"shortname"`
-		Name        string `json:"fullname"`
-		DisplayName string `json:"displayname"`
-		CategoryId  int64  `json:"categoryid"`
+		Id          CourseID `json:"id"`
+		Code        string   `json:"shortname"`
+		Name        string   `json:"fullname"`
+		DisplayName string   `json:"displayname"`
+		CategoryId  int64    `json:"categoryid"`
 	}
 	type Results struct {
 		Courses []Result `json:"courses"`
@@ -1420,108 +1628,19 @@ func (m *MoodleApi) GetCourses(value string) ([]Course, error) {
 	return subjects[:], nil
 }
 
+// GetSiteInfo returns the site name, and the name and id of the token's
+// user. Use GetSiteInfoStruct for other details of the site.
 func (m *MoodleApi) GetSiteInfo() (string, string, string, int64, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true", m.base, m.token, "core_webservice_get_site_info")
-	m.log.Debug("Fetch: %s", url)
-
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	info, err := m.getSiteInfo()
 	if err != nil {
 		return "", "", "", 0, err
 	}
-
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return "", "", "", 0, errors.New(body)
-	}
-
-	type SiteInfo struct {
-		Sitename  string
-		Firstname string
-		Lastname  string
-		Userid    int64
-	}
-
-	var data map[string]interface{}
-
-	if err := json.Unmarshal([]byte(body), &data); err != nil {
-		return "", "", "", 0, errors.New("Server returned unexpected response. " + err.Error())
-	}
-
-	return data["sitename"].(string), data["firstname"].(string), data["lastname"].(string), int64(data["userid"].(float64)), nil
-}
-
-func (r *Restriction) IsRestricted(groups []CourseGroup) bool {
-	switch r.OP {
-	case "&":
-		// Check user is in every group
-		for _, r := range r.C {
-			found := false
-			for _, g := range groups {
-				if r.Id == g.Id {
-					found = true
-				}
-			}
-			if !found {
-				return true
-			}
-		}
-		return false
-	case "!&":
-		// Check user is not in every group
-		for _, r := range r.C {
-			found := false
-			for _, g := range groups {
-				if r.Id == g.Id {
-					found = true
-				}
-			}
-			if found {
-				return true
-			}
-		}
-		return false
-	case "|":
-		// Check user is in one of the groups
-		for _, r := range r.C {
-			for _, g := range groups {
-				if r.Id == g.Id {
-					return false
-				}
-			}
-		}
-		return true
-	case "!|":
-		// Check user is not in one of the groups
-		for _, r := range r.C {
-			for _, g := range groups {
-				if r.Id == g.Id {
-					return true
-				}
-			}
-		}
-		return false
-	default:
-		return false
-	}
-}
-
-type Restriction struct {
-	OP    string         `json:"op"`
-	C     []RestrictionC `json:"c"`
-	Show  bool           `json:"show"`
-	ShowC []bool         `json:"showc"`
-}
-
-type RestrictionC struct {
-	Type string `json:"type"`
-	Id   int64  `json:"id"`
-	D    string `json:"d"`
-	T    int64  `json:"t"`
+	return info.SiteName, info.FirstName, info.LastName, int64(info.UserId), nil
 }
 
 type CourseModule struct {
-	Id           int64       `json:"id"`
-	CourseId     int64       `json:"course"`
+	Id           CmID        `json:"id"`
+	CourseId     CourseID    `json:"course"`
 	ModuleId     int64       `json:"module"`
 	InstanceId   int64       `json:"instance"`
 	SectionId    int64       `json:"section"`
@@ -1531,34 +1650,40 @@ type CourseModule struct {
 	Grade        int64       `json:"grade"`
 	Visible      bool        `json:"visible"`
 	Added        *time.Time  `json:"added"`
-}
 
-func (m *MoodleApi) GetCourseModule(cmid int64) (*CourseModule, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&cmid=%d", m.base, m.token, "core_course_get_course_module", cmid)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
+	// Completion describes when the activity is considered complete
+	Completion CompletionRules `json:"completion"`
+
+	// Url, Description and Files are only set by GetCourseContents.
+	// Description is only included if shown on the course page.
+	Url         string       `json:"url,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Files       []MoodleFile `json:"files,omitempty"`
+}
 
+func (m *MoodleApi) GetCourseModule(cmid CmID) (*CourseModule, error) {
+	body, err := m.call("core_course_get_course_module", url.Values{
+		"moodlewssettingraw": {"true"},
+		"cmid":               {fmt.Sprint(cmid)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type CourseModuleInt struct {
-		Id           int64  `json:"id"`
-		CourseId     int64  `json:"course"`
-		ModuleId     int64  `json:"module"`
-		InstanceId   int64  `json:"instance"`
-		SectionId    int64  `json:"section"`
-		ModuleName   string `json:"modname"`
-		Name         string `json:"name"`
-		Grade        int64  `json:"grade"`
-		GradePass    string `json:"gradepass"`
-		Availability string `json:"availability"`
-		Added        int64  `json:"added"`
-		Visible      int64  `json:"visible"`
+		Id           CmID     `json:"id"`
+		CourseId     CourseID `json:"course"`
+		ModuleId     int64    `json:"module"`
+		InstanceId   int64    `json:"instance"`
+		SectionId    int64    `json:"section"`
+		ModuleName   string   `json:"modname"`
+		Name         string   `json:"name"`
+		Grade        int64    `json:"grade"`
+		GradePass    string   `json:"gradepass"`
+		Availability string   `json:"availability"`
+		Added        int64    `json:"added"`
+		Visible      int64    `json:"visible"`
+		moduleCompletion
 	}
 
 	type Result struct {
@@ -1573,7 +1698,7 @@ func (m *MoodleApi) GetCourseModule(cmid int64) (*CourseModule, error) {
 
 	var t *time.Time
 	if result.CM.Added != 0 {
-		tt := time.Unix(result.CM.Added, 0)
+		tt := m.unix(result.CM.Added)
 		t = &tt
 	}
 	cm := &CourseModule{
@@ -1586,7 +1711,8 @@ func (m *MoodleApi) GetCourseModule(cmid int64) (*CourseModule, error) {
 		Name:       result.CM.Name,
 		Grade:      result.CM.Grade,
 		Visible:    result.CM.Visible == 1,
-		Added:      t}
+		Added:      t,
+		Completion: m.completionRules(result.CM.moduleCompletion)}
 
 	if result.CM.Availability != "" {
 		if err := json.Unmarshal([]byte(result.CM.Availability), &cm.Availability); err != nil {
@@ -1599,8 +1725,8 @@ func (m *MoodleApi) GetCourseModule(cmid int64) (*CourseModule, error) {
 
 type AssignmentInfo struct {
 	Id                       int64      `json:"id"`
-	CmId                     int64      `json:"cmid"`
-	CourseId                 int64      `json:"courseid"`
+	CmId                     CmID       `json:"cmid"`
+	CourseId                 CourseID   `json:"courseid"`
 	CourseCode               string     `json:"coursecode"`
 	CourseName               string     `json:"coursename"`
 	Name                     string     `json:"name"`
@@ -1618,31 +1744,31 @@ type AssignmentInfo struct {
 	ExtensionDate            *time.Time `json:"extensiondate"`
 }
 
-func (m *MoodleApi) GetAssignmentsWithCourseId(courseIds []int) ([]*AssignmentInfo, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&includenotenrolledcourses=1", m.base, m.token, "mod_assign_get_assignments")
+func (m *MoodleApi) GetAssignmentsWithCourseId(courseIds []CourseID) ([]*AssignmentInfo, error) {
+	params := url.Values{
+		"moodlewssettingraw":        {"true"},
+		"includenotenrolledcourses": {"1"},
+	}
 	for i, c := range courseIds {
-		url = fmt.Sprintf("%s&courseids%%5B%d%%5D=%d", url, i, c)
+		params.Set(fmt.Sprintf("courseids[%d]", i), fmt.Sprint(c))
 	}
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	body, err := m.call("mod_assign_get_assignments", params)
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type AssignInfo struct {
-		Id      int64  `json:"id"`
-		CmId    int64  `json:"cmid"`
-		Name    string `json:"name"`
-		DueDate int64  `json:"duedate"`
+		Id                       int64  `json:"id"`
+		CmId                     CmID   `json:"cmid"`
+		Name                     string `json:"name"`
+		DueDate                  int64  `json:"duedate"`
+		AllowSubmissionsFromDate int64  `json:"allowsubmissionsfromdate"`
+		CutoffDate               int64  `json:"cutoffdate"`
+		GradingDueDate           int64  `json:"gradingduedate"`
 	}
 
 	type CourseAssign struct {
-		Id          int64        `json:"id"`
+		Id          CourseID     `json:"id"`
 		Code        string       `json:"shortname"`
 		Name        string       `json:"fullname"`
 		Assignments []AssignInfo `json:"assignments"`
@@ -1663,10 +1789,11 @@ func (m *MoodleApi) GetAssignmentsWithCourseId(courseIds []int) ([]*AssignmentIn
 		for _, a := range c.Assignments {
 			var t *time.Time
 			if a.DueDate != 0 {
-				tt := time.Unix(a.DueDate, 0)
+				tt := m.unix(a.DueDate)
 				t = &tt
 			}
-			ai := &AssignmentInfo{Id: a.Id, CmId: a.CmId, Name: a.Name, CourseCode: c.Code, CourseName: c.Name, CourseId: c.Id, DueDate: t}
+			ai := &AssignmentInfo{Id: a.Id, CmId: a.CmId, Name: a.Name, CourseCode: c.Code, CourseName: c.Name, CourseId: c.Id, DueDate: t,
+				CutoffDate: a.CutoffDate, AllowSubmissionsFromDate: m.unixTime(a.AllowSubmissionsFromDate), GradingDueDate: m.unixTime(a.GradingDueDate)}
 			assignments = append(assignments, ai)
 		}
 	}
@@ -1674,6 +1801,96 @@ func (m *MoodleApi) GetAssignmentsWithCourseId(courseIds []int) ([]*AssignmentIn
 	return assignments[:], nil
 }
 
+// GetAssignments fetches the assignments in each course, replacing the
+// Assignments of each Course. Assignment weights are read from the course
+// gradebook, which requires one additional call per course.
+func (m *MoodleApi) GetAssignments(courses []Course) error {
+	if len(courses) == 0 {
+		return nil
+	}
+	params := url.Values{
+		"moodlewssettingraw":        {"true"},
+		"includenotenrolledcourses": {"1"},
+	}
+	for i, c := range courses {
+		params.Set(fmt.Sprintf("courseids[%d]", i), fmt.Sprint(c.MoodleId))
+	}
+	body, err := m.call("mod_assign_get_assignments", params)
+	if err != nil {
+		return err
+	}
+
+	type AssignInfo struct {
+		Id           int64  `json:"id"`
+		CmId         CmID   `json:"cmid"`
+		Name         string `json:"name"`
+		Intro        string `json:"intro"`
+		DueDate      int64  `json:"duedate"`
+		TimeModified int64  `json:"timemodified"`
+	}
+
+	type CourseAssign struct {
+		Id          CourseID     `json:"id"`
+		Assignments []AssignInfo `json:"assignments"`
+	}
+
+	type Result struct {
+		Courses []CourseAssign `json:"courses"`
+	}
+
+	var results Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	for i := range courses {
+		course := &courses[i]
+		course.Assignments = nil
+		for _, c := range results.Courses {
+			if c.Id != course.MoodleId {
+				continue
+			}
+			for _, a := range c.Assignments {
+				assignment := &Assignment{MoodleId: a.Id, CmId: a.CmId, Name: a.Name, Description: a.Intro, Type: "assign"}
+				if a.DueDate != 0 {
+					t := m.unix(a.DueDate)
+					assignment.Due = &t
+				}
+				if a.TimeModified != 0 {
+					t := m.unix(a.TimeModified)
+					assignment.Updated = &t
+				}
+				course.Assignments = append(course.Assignments, assignment)
+			}
+		}
+		if len(course.Assignments) == 0 {
+			continue
+		}
+
+		// Weights are the same for every person in the gradebook
+		gradebook, err := m.GetCourseGradebook(course.MoodleId)
+		if err != nil {
+			return err
+		}
+		if len(gradebook) == 0 {
+			continue
+		}
+		for _, item := range gradebook[0].Item {
+			if item.ItemModule != "assign" {
+				continue
+			}
+			for _, a := range course.Assignments {
+				if a.MoodleId == item.ItemInstance {
+					a.Weight = item.WeightRaw
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 type QuizResponse struct {
 	Quizzes []*QuizInfo `json:"quizzes"`
 	//Warnings    []ForumDiscussion `json:"warnings"`
@@ -1681,8 +1898,8 @@ type QuizResponse struct {
 
 type QuizInfo struct {
 	Id                    int64      `json:"id"`
-	CourseModuleId        int64      `json:"coursemodule"`
-	CourseId              int64      `json:"course"`
+	CourseModuleId        CmID       `json:"coursemodule"`
+	CourseId              CourseID   `json:"course"`
 	Name                  string     `json:"name"`
 	Intro                 string     `json:"intro"`
 	IntroFormat           int64      `json:"introformat"`
@@ -1737,35 +1954,34 @@ func (q *QuizInfo) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (m *MoodleApi) GetQuizzesWithCourseId(courseIds []int) ([]*QuizInfo, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true", m.base, m.token, "mod_quiz_get_quizzes_by_courses")
+func (m *MoodleApi) GetQuizzesWithCourseId(courseIds []CourseID) ([]*QuizInfo, error) {
+	params := url.Values{
+		"moodlewssettingraw": {"true"},
+	}
 	for i, c := range courseIds {
-		url = fmt.Sprintf("%s&courseids%%5B%d%%5D=%d", url, i, c)
+		params.Set(fmt.Sprintf("courseids[%d]", i), fmt.Sprint(c))
 	}
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	body, err := m.call("mod_quiz_get_quizzes_by_courses", params)
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	var results QuizResponse
 
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
+	for _, q := range results.Quizzes {
+		m.inLocation(q.TimeOpen, q.TimeClose, q.Created, q.Modified)
+	}
 
 	return results.Quizzes[:], nil
 }
 
 type ForumInfo struct {
 	Id               int64      `json:"id"`
-	CmId             int64      `json:"cmid"`
-	CourseId         int64      `json:"courseid"`
+	CmId             CmID       `json:"cmid"`
+	CourseId         CourseID   `json:"courseid"`
 	Scale            int64      `json:"scale"`
 	Grade            int64      `json:"grade"`
 	GradeForumNotify int64      `json:"grade_forum_notify"`
@@ -1777,35 +1993,31 @@ type ForumInfo struct {
 	CutoffDate       *time.Time `json:"cutoffdate"`
 }
 
-func (m *MoodleApi) GetForumsWithCourseId(courseIds []int) ([]*ForumInfo, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true", m.base, m.token, "mod_forum_get_forums_by_courses")
+func (m *MoodleApi) GetForumsWithCourseId(courseIds []CourseID) ([]*ForumInfo, error) {
+	params := url.Values{
+		"moodlewssettingraw": {"true"},
+	}
 	for i, c := range courseIds {
-		url = fmt.Sprintf("%s&courseids%%5B%d%%5D=%d", url, i, c)
+		params.Set(fmt.Sprintf("courseids[%d]", i), fmt.Sprint(c))
 	}
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	body, err := m.call("mod_forum_get_forums_by_courses", params)
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type ForumResult struct {
-		Id               int64  `json:"id"`
-		CourseId         int64  `json:"course"`
-		CmId             int64  `json:"cmid"`
-		Name             string `json:"name"`
-		DueDate          int64  `json:"duedate"`
-		CutoffDate       int64  `json:"cutoffdate"`
-		GradeForum       int64  `json:"grade_forum"`
-		GradeForumNotify int64  `json:"grade_forum_notify"`
-		Assessed         int64  `json:"assessed"`
-		Scale            int64  `json:"scale"`
-		NumDiscussions   int64  `json:"numdiscussions"`
-		Type             string `json:"type"`
+		Id               int64    `json:"id"`
+		CourseId         CourseID `json:"course"`
+		CmId             CmID     `json:"cmid"`
+		Name             string   `json:"name"`
+		DueDate          int64    `json:"duedate"`
+		CutoffDate       int64    `json:"cutoffdate"`
+		GradeForum       int64    `json:"grade_forum"`
+		GradeForumNotify int64    `json:"grade_forum_notify"`
+		Assessed         int64    `json:"assessed"`
+		Scale            int64    `json:"scale"`
+		NumDiscussions   int64    `json:"numdiscussions"`
+		Type             string   `json:"type"`
 	}
 
 	var results []ForumResult
@@ -1818,12 +2030,12 @@ func (m *MoodleApi) GetForumsWithCourseId(courseIds []int) ([]*ForumInfo, error)
 	for _, forum := range results {
 		var dueDate *time.Time
 		if forum.DueDate != 0 {
-			tt := time.Unix(forum.DueDate, 0)
+			tt := m.unix(forum.DueDate)
 			dueDate = &tt
 		}
 		var cutoffDate *time.Time
 		if forum.CutoffDate != 0 {
-			tt := time.Unix(forum.CutoffDate, 0)
+			tt := m.unix(forum.CutoffDate)
 			cutoffDate = &tt
 		}
 		ai := &ForumInfo{
@@ -1853,8 +2065,8 @@ type ForumDiscussionResponse struct {
 type ForumDiscussion struct {
 	Id                     int64      `json:"id"`
 	Name                   string     `json:"name"`
-	UserId                 int64      `json:"userid"`
-	GroupId                int64      `json:"groupid"`
+	UserId                 UserID     `json:"userid"`
+	GroupId                GroupID    `json:"groupid"`
 	TimeModified           *time.Time `json:"timemodified"`
 	UserModified           *time.Time `json:"usermodified"`
 	TimeStart              *time.Time `json:"timestart"`
@@ -1923,22 +2135,45 @@ func (u *ForumDiscussion) UnmarshalJSON(data []byte) error {
 }
 
 func (m *MoodleApi) GetForumsDiscussions(forumId int) ([]*ForumDiscussion, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&forumid=%d", m.base, m.token, "mod_forum_get_forum_discussions", forumId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
-	if err != nil {
+	return m.forumDiscussions(url.Values{
+		"moodlewssettingraw": {"true"},
+		"forumid":            {fmt.Sprint(forumId)},
+	})
+}
+
+// GetForumDiscussionsForGroup fetches the discussions of one group in a
+// forum using separate or visible groups, such as the discussions of a
+// tutor's group for export. Moodle also returns discussions posted to all
+// participants. Requires moodle 3.7 or later.
+func (m *MoodleApi) GetForumDiscussionsForGroup(forumId int, groupId GroupID) ([]ForumDiscussion, error) {
+	results, err := m.forumDiscussions(url.Values{
+		"moodlewssettingraw": {"true"},
+		"forumid":            {fmt.Sprint(forumId)},
+		"groupid":            {fmt.Sprint(groupId)},
+	})
+	if err != nil || len(results) == 0 {
 		return nil, err
 	}
+	discussions := make([]ForumDiscussion, 0, len(results))
+	for _, i := range results {
+		discussions = append(discussions, *i)
+	}
+	return discussions, nil
+}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
+func (m *MoodleApi) forumDiscussions(params url.Values) ([]*ForumDiscussion, error) {
+	body, err := m.call("mod_forum_get_forum_discussions", params)
+	if err != nil {
+		return nil, err
 	}
 
 	var results ForumDiscussionResponse
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
+	for _, d := range results.Discussions {
+		m.inLocation(d.TimeModified, d.UserModified, d.TimeStart, d.TimeEnd, d.Created, d.Modified)
+	}
 
 	return results.Discussions[:], nil
 }
@@ -1950,28 +2185,73 @@ type AssignmentRecord struct {
 
 type GradeRecord struct {
 	Id            int64   `json:"id"`
-	UserId        int64   `json:"userid"`
+	UserId        UserID  `json:"userid"`
 	AttemptNumber int64   `json:"attemptnumber"`
 	TimeCreated   int64   `json:"timecreated"`
 	TimeModified  int64   `json:"timemodified"`
-	Grader        int64   `json:"grade"`
+	Grader        int64   `json:"grader"`
 	Grade         float64 `json:"grade"`
 }
 
-func (m *MoodleApi) GetAssignmentGrades(ids ...int64) (*[]AssignmentRecord, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true", m.base, m.token, "mod_assign_get_grades")
-	for i, c := range ids {
-		url = fmt.Sprintf("%s&assignmentids%%5B%d%%5D=%d", url, i, c)
+// UnmarshalJSON reads the grade, which moodle returns as a string such as
+// "70.00", or "-1.00" if the person has not been graded.
+func (g *GradeRecord) UnmarshalJSON(data []byte) error {
+	type Alias GradeRecord
+	aux := &struct {
+		Grade json.RawMessage `json:"grade"`
+		*Alias
+	}{
+		Alias: (*Alias)(g),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	g.Grade = 0
+	if grade := strings.Trim(string(aux.Grade), `"`); grade != "" && grade != "null" {
+		f, err := strconv.ParseFloat(grade, 64)
+		if err != nil {
+			return err
+		}
+		g.Grade = f
 	}
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
+	return nil
+}
 
+// GetAssignmentGrades fetches the grades for assignments.
+//
+// Deprecated: use GetAssignmentGradeRecords, which returns a plain slice.
+func (m *MoodleApi) GetAssignmentGrades(ids ...int64) (*[]AssignmentRecord, error) {
+	records, err := m.GetAssignmentGradeRecords(ids...)
 	if err != nil {
 		return nil, err
 	}
+	if records == nil {
+		records = []AssignmentRecord{}
+	}
+	return &records, nil
+}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
+// GetAssignmentGradeRecords fetches the grades for assignments. Returns nil
+// if there are no grades.
+func (m *MoodleApi) GetAssignmentGradeRecords(ids ...int64) ([]AssignmentRecord, error) {
+	return m.getAssignmentGrades(ids, 0)
+}
+
+// getAssignmentGrades fetches the grades of assignments modified at or after
+// since, a unix time
+func (m *MoodleApi) getAssignmentGrades(ids []int64, since int64) ([]AssignmentRecord, error) {
+	params := url.Values{
+		"moodlewssettingraw": {"true"},
+	}
+	if since > 0 {
+		params.Set("since", fmt.Sprint(since))
+	}
+	for i, c := range ids {
+		params.Set(fmt.Sprintf("assignmentids[%d]", i), fmt.Sprint(c))
+	}
+	body, err := m.call("mod_assign_get_grades", params)
+	if err != nil {
+		return nil, err
 	}
 
 	type Result struct {
@@ -1983,34 +2263,36 @@ func (m *MoodleApi) GetAssignmentGrades(ids ...int64) (*[]AssignmentRecord, erro
 	if err := json.Unmarshal([]byte(body), &results); err != nil {
 		return nil, errors.New("Server returned unexpected response. " + err.Error())
 	}
+	if len(results.Assignments) == 0 {
+		return nil, nil
+	}
 
-	return &results.Assignments, nil
+	return results.Assignments, nil
 }
 
 type AssignmentSubmission struct {
 	Id            int64      `json:"id"`
 	SubmissionId  int64      `json:"submissionid"`
-	UserId        int64      `json:"userid"`
+	UserId        UserID     `json:"userid"`
 	Status        string     `json:"status"`
 	GradingStatus string     `json:"gradingstatus"`
 	Extension     *time.Time `json:"extensiondate"`
 	TimeCreated   *time.Time `json:"timecreated"`
 	TimeModified  *time.Time `json:"timemodified"`
+
+	// Plagiarism holds similarity reports, see SetPlagiarismFunction
+	Plagiarism []PlagiarismResult `json:"plagiarism,omitempty"`
 }
 
 func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentSubmission, error) {
-	url := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&assignmentids[0]=%d", m.base, m.token, "mod_assign_get_submissions", assignmentId)
-	m.log.Debug("Fetch: %s", url)
-	body, _, _, err := m.fetch.GetUrl(url)
-
+	body, err := m.call("mod_assign_get_submissions", url.Values{
+		"moodlewssettingraw": {"true"},
+		"assignmentids[0]":   {fmt.Sprint(assignmentId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type Plugin struct {
 		Type string `json:"type"`
 		Name string `json:"name"`
@@ -2018,7 +2300,7 @@ func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentS
 
 	type AssignSub struct {
 		Id            int64    `json:"id"`
-		UserId        int64    `json:"userid"`
+		UserId        UserID   `json:"userid"`
 		Status        string   `json:"status"`
 		GradingStatus string   `json:"gradingstatus"`
 		TimeCreated   int64    `json:"timecreated"`
@@ -2047,11 +2329,11 @@ func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentS
 			var timeCreated *time.Time
 			var timeModified *time.Time
 			if i.TimeCreated != 0 {
-				tt := time.Unix(i.TimeCreated, 0)
+				tt := m.unix(i.TimeCreated)
 				timeCreated = &tt
 			}
 			if i.TimeModified != 0 {
-				tt := time.Unix(i.TimeModified, 0)
+				tt := m.unix(i.TimeModified)
 				timeModified = &tt
 			}
 			assignments = append(assignments, &AssignmentSubmission{
@@ -2067,22 +2349,18 @@ func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentS
 		}
 	}
 
-	url2 := fmt.Sprintf("%swebservice/rest/server.php?wstoken=%s&wsfunction=%s&moodlewsrestformat=json&moodlewssettingraw=true&assignmentids[0]=%d", m.base, m.token, "mod_assign_get_user_flags", assignmentId)
-	m.log.Debug("Fetch: %s", url2)
-	body, _, _, err = m.fetch.GetUrl(url2)
-
+	body, err = m.call("mod_assign_get_user_flags", url.Values{
+		"moodlewssettingraw": {"true"},
+		"assignmentids[0]":   {fmt.Sprint(assignmentId)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(body, "{\"exception\":\"") {
-		return nil, errors.New(body)
-	}
-
 	type Flag struct {
-		Id        int64 `json:"id"`
-		UserId    int64 `json:"userid"`
-		Extension int64 `json:"extensionduedate"`
+		Id        int64  `json:"id"`
+		UserId    UserID `json:"userid"`
+		Extension int64  `json:"extensionduedate"`
 	}
 
 	type AssignFlag struct {
@@ -2107,7 +2385,7 @@ func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentS
 				continue
 			}
 			var t *time.Time
-			tt := time.Unix(k.Extension, 0)
+			tt := m.unix(k.Extension)
 			t = &tt
 			found := false
 			for _, a := range assignments {
@@ -2122,6 +2400,16 @@ func (m *MoodleApi) GetAssignmentSubmissions(assignmentId int64) ([]*AssignmentS
 		}
 	}
 
+	if m.plagiarismFunction != "" {
+		reports, err := m.GetPlagiarismResults(assignmentId)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assignments {
+			a.Plagiarism = reports[a.UserId]
+		}
+	}
+
 	return assignments[:], nil
 }
 
@@ -2137,3 +2425,38 @@ func GetAttendance() error {
 func (m *MoodleApi) SetUrlFetcher(fetch LookupUrl) {
 	m.fetch = fetch
 }
+
+// SetUserAgent sets the User-Agent header sent to the moodle server. By
+// default DefaultUserAgent is sent.
+func (m *MoodleApi) SetUserAgent(userAgent string) {
+	m.userAgent = userAgent
+}
+
+// SetHeader sets a header sent with every request to moodle, for example a
+// header required by a proxy in front of the moodle server. An empty value
+// removes the header.
+func (m *MoodleApi) SetHeader(name, value string) {
+	if m.headers == nil {
+		m.headers = http.Header{}
+	}
+	if value == "" {
+		m.headers.Del(name)
+	} else {
+		m.headers.Set(name, value)
+	}
+}
+
+// WithHeader returns a copy of the api that sends an additional header with
+// each request, leaving the original unchanged. Use it to add a header to
+// some calls, such as a tracing header for the calls made for one request.
+//
+//	api.WithHeader("X-Trace-Id", traceId).GetPersonByMoodleId(id)
+func (m *MoodleApi) WithHeader(name, value string) *MoodleApi {
+	c := *m
+	c.headers = http.Header{}
+	for k, v := range m.headers {
+		c.headers[k] = v
+	}
+	c.headers.Set(name, value)
+	return &c
+}
@@ -0,0 +1,1296 @@
+// Code generated by internal/mockgen from api.go. DO NOT EDIT.
+
+// Package moodlemock provides a mock of moodle.Api, so that code using
+// the moodle api can be tested without making network calls.
+package moodlemock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/zaddok/moodle"
+)
+
+// Api is a mock moodle.Api. Each method calls the function field of the
+// same name with a Func suffix, or returns an error if it is not set. The
+// name of each method called is recorded in Calls.
+type Api struct {
+	GetPersonByUsernameFunc          func(string) (*moodle.Person, error)
+	GetPersonByMoodleIdFunc          func(moodle.UserID) (*moodle.Person, error)
+	GetPersonByEmailFunc             func(string) (*moodle.Person, error)
+	GetPeopleByUsernamesFunc         func([]string) (map[string]moodle.Person, error)
+	GetPeopleByEmailsFunc            func([]string) (map[string]moodle.Person, error)
+	FindPeopleByNameFunc             func(string, string) ([]moodle.Person, error)
+	FindPeopleByAttributeFunc        func(string, string) ([]moodle.Person, error)
+	FindPeopleFunc                   func(string, string, *moodle.PeopleSearchOptions) ([]moodle.Person, error)
+	GetAccessReportFunc              func([]moodle.UserID, *moodle.AccessReportOptions) (*moodle.AccessReport, error)
+	GetCourseAccessReportFunc        func(moodle.CourseID, *moodle.AccessReportOptions) (*moodle.AccessReport, error)
+	GetCohortAccessReportFunc        func(int64, *moodle.AccessReportOptions) (*moodle.AccessReport, error)
+	AddUserFunc                      func(string, string, string, string, string) (moodle.UserID, error)
+	AddUsersFunc                     func([]moodle.NewUser) ([]moodle.AddUserResult, error)
+	UpdateUsersFunc                  func([]moodle.UserUpdate) ([]moodle.UpdateUserResult, error)
+	SuspendUserFunc                  func(moodle.UserID) error
+	ChangeUserEmailFunc              func(moodle.UserID, string, bool) error
+	RenameUsernameFunc               func(moodle.UserID, string) error
+	UnsuspendUserFunc                func(moodle.UserID) error
+	UpdateUserFunc                   func(moodle.UserID, string, string, string, string, string) error
+	SetUserAttributeFunc             func(moodle.UserID, string, string) error
+	SetUserCustomFieldFunc           func(moodle.UserID, string, string) error
+	SetUserCustomFieldsFunc          func(moodle.UserID, map[string]string) error
+	SetCustomFieldsForUsersFunc      func(map[moodle.UserID]map[string]string) error
+	ResetPasswordFunc                func(moodle.UserID, string) error
+	ResetPasswordWithEmailFunc       func(string) error
+	SetProfilePictureFunc            func(moodle.UserID, io.Reader) error
+	GetProfilePictureFunc            func(moodle.UserID, moodle.PictureSize) (io.ReadCloser, error)
+	RemoveProfilePictureFunc         func(moodle.UserID) error
+	GetPersonLocationFunc            func(moodle.UserID) (*time.Location, error)
+	GetUserDevicesFunc               func(moodle.UserID) ([]moodle.UserDevice, error)
+	GetCoursesFunc                   func(string) ([]moodle.Course, error)
+	GetCourseByFieldFunc             func(string, string) (*moodle.CourseRecord, error)
+	GetCourseByShortNameFunc         func(string) (*moodle.CourseRecord, error)
+	SearchCoursesByCustomFieldFunc   func(string, string) ([]moodle.CourseRecord, error)
+	GetPersonCourseListFunc          func(moodle.UserID) ([]moodle.Course, error)
+	GetCategoriesFunc                func(int64, bool) ([]moodle.CourseCategory, error)
+	GetCoursesInCategoryFunc         func(int64, bool) ([]moodle.Course, error)
+	GetStarredCoursesFunc            func() ([]moodle.Course, error)
+	SetCourseStarredFunc             func(moodle.CourseID, bool) error
+	UpdateCourseFunc                 func(moodle.CourseUpdate) error
+	DuplicateCourseFunc              func(moodle.CourseID, moodle.DuplicateCourseOptions) (moodle.CourseID, error)
+	DeleteCourseFunc                 func(moodle.CourseID) error
+	GetRecentItemsFunc               func(int) ([]moodle.RecentItem, error)
+	GetCourseRolesFunc               func(moodle.CourseID) ([]moodle.CoursePerson, error)
+	GetCourseEnrolmentCountFunc      func(moodle.CourseID) (int, error)
+	SearchCourseUsersFunc            func(moodle.CourseID, string) ([]moodle.CoursePerson, error)
+	GetRolesForCoursesFunc           func([]moodle.CourseID, int) (map[moodle.CourseID][]moodle.CoursePerson, error)
+	StreamCourseRolesFunc            func(moodle.CourseID, func(moodle.CoursePerson) error) error
+	SetRoleFunc                      func(moodle.UserID, moodle.RoleID, moodle.CourseID) error
+	GetUsersWithCapabilityFunc       func(moodle.CourseID, string) ([]moodle.UserID, error)
+	HasCapabilityFunc                func(moodle.CourseID, moodle.UserID, string) (bool, error)
+	RequireCapabilityFunc            func(moodle.CourseID, string) error
+	UnsetRoleFunc                    func(moodle.UserID, moodle.RoleID, moodle.CourseID) error
+	GetCourseModuleFunc              func(moodle.CmID) (*moodle.CourseModule, error)
+	GetCourseModulesFunc             func(moodle.CourseID) ([]moodle.CourseModule, error)
+	GetCourseModulesByTypeFunc       func(moodle.CourseID, string) ([]moodle.CourseModule, error)
+	GetCourseContentsFunc            func(moodle.CourseID) ([]moodle.CourseSection, error)
+	GetCourseModulesByIdsFunc        func([]moodle.CmID, int) (map[moodle.CmID]*moodle.CourseModule, error)
+	ResolveAssignmentIdFunc          func(moodle.CmID) (int64, error)
+	ResolveCmIdFunc                  func(int64) (moodle.CmID, error)
+	GetAssignmentCmIdsFunc           func([]moodle.CourseID) (map[int64]moodle.CmID, error)
+	SetAssignmentDueDateFunc         func(int64, time.Time, time.Time) error
+	ShiftAssignmentDatesFunc         func([]moodle.CourseID, time.Duration) (int, error)
+	SetQuizDatesFunc                 func(int64, time.Time, time.Time) error
+	ShiftQuizDatesFunc               func([]moodle.CourseID, time.Duration) (int, error)
+	IsModuleAvailableToFunc          func(moodle.CmID, moodle.UserID) (bool, error)
+	SetModuleAvailabilityFunc        func(moodle.CmID, *moodle.Restriction) error
+	SetCourseModuleVisibilityFunc    func(moodle.CmID, bool) error
+	DeleteCourseModuleFunc           func(moodle.CmID) error
+	GetCourseGroupsFunc              func(moodle.CourseID) ([]moodle.CourseGroup, error)
+	GetPersonCourseGroupsFunc        func(moodle.CourseID, moodle.UserID) ([]moodle.CourseGroup, error)
+	GetCourseGroupingsFunc           func(moodle.CourseID) ([]moodle.CourseGrouping, error)
+	GetPersonCourseGroupingsFunc     func(moodle.CourseID, moodle.UserID) ([]int64, error)
+	GetCourseGroupByNameFunc         func(moodle.CourseID, string) (*moodle.CourseGroup, error)
+	GetCourseGroupByIdNumberFunc     func(moodle.CourseID, string) (*moodle.CourseGroup, error)
+	GetGroupMembersFunc              func(moodle.CourseID, moodle.GroupID) ([]moodle.CoursePerson, error)
+	MessageCourseGroupFunc           func(moodle.CourseID, string, string) error
+	EmailCourseGroupFunc             func(moodle.CourseID, string, moodle.EmailTemplate, interface{}) error
+	AddGroupToCourseFunc             func(moodle.CourseID, string, string) (moodle.GroupID, error)
+	AddGroupToCourseWithIdNumberFunc func(moodle.CourseID, string, string, string) (moodle.GroupID, error)
+	UpdateCourseGroupFunc            func(moodle.CourseGroup) error
+	AddPersonToCourseGroupFunc       func(moodle.UserID, moodle.GroupID) error
+	RemovePersonFromCourseGroupFunc  func(moodle.UserID, moodle.GroupID) error
+	CreateCohortFunc                 func(string, string, string) (int64, error)
+	GetCohortsFunc                   func(...int64) ([]moodle.Cohort, error)
+	GetCohortMembersFunc             func(int64) ([]moodle.UserID, error)
+	AddCohortMembersFunc             func(int64, []moodle.UserID) error
+	RemoveCohortMembersFunc          func(int64, []moodle.UserID) error
+	GetCourseGradebookFunc           func(moodle.CourseID) ([]moodle.GradebookEntry, error)
+	GetAssignmentGradeRecordsFunc    func(...int64) ([]moodle.AssignmentRecord, error)
+	GetGradingBacklogFunc            func([]moodle.CourseID) ([]moodle.GradingBacklog, error)
+	GetAssignmentStatsFunc           func(int64) (*moodle.AssignmentStats, error)
+	GetActivitiesCompletionFunc      func(moodle.CourseID, moodle.UserID) (map[moodle.CmID]moodle.CompletionState, error)
+	GetItemRatingsFunc               func(moodle.RatingArea, int64) ([]moodle.Rating, error)
+	AddRatingFunc                    func(moodle.RatingArea, int64, moodle.UserID, int64, moodle.RatingAggregation) (*moodle.RatingResult, error)
+	GetCompetencyFrameworksFunc      func() ([]moodle.CompetencyFramework, error)
+	GetCompetenciesFunc              func(int64) ([]moodle.Competency, error)
+	GetCourseCompetenciesFunc        func(moodle.CourseID) ([]moodle.Competency, error)
+	GetUserCompetencyInCourseFunc    func(moodle.UserID, int64, moodle.CourseID) (*moodle.UserCompetency, error)
+	GetInsightsFunc                  func(moodle.UserID) ([]moodle.Insight, error)
+	GetAssignmentsForCoursesFunc     func([]moodle.CourseID) ([]moodle.AssignmentInfo, error)
+	GetQuizzesForCoursesFunc         func([]moodle.CourseID) ([]moodle.QuizInfo, error)
+	GetUserQuizAttemptsFunc          func(int64, moodle.UserID) ([]moodle.QuizAttempt, error)
+	GetQuizAttemptReviewFunc         func(int64) (*moodle.QuizAttemptReview, error)
+	GetForumsForCoursesFunc          func([]moodle.CourseID) ([]moodle.ForumInfo, error)
+	GetForumDiscussionsFunc          func(int) ([]moodle.ForumDiscussion, error)
+	GetForumDiscussionsForGroupFunc  func(int, moodle.GroupID) ([]moodle.ForumDiscussion, error)
+	GetSubmissionsForAssignmentFunc  func(int64) ([]moodle.AssignmentSubmission, error)
+	GetPlagiarismResultsFunc         func(int64) (map[moodle.UserID][]moodle.PlagiarismResult, error)
+	GetAnnotatedFeedbackPdfFunc      func(int64, moodle.UserID) (*moodle.MoodleFile, error)
+	DownloadAnnotatedFeedbackPdfFunc func(int64, moodle.UserID) (*moodle.MoodleFile, []byte, error)
+	DownloadFileFunc                 func(string) ([]byte, error)
+	OpenFileFunc                     func(string) (io.ReadCloser, error)
+	WriteSubmissionBundleFunc        func(int64, io.Writer) (int, error)
+	NotifyFeedbackByEmailFunc        func(moodle.EmailTemplate) func(moodle.FeedbackReady) error
+	GetSubmissionCommentsFunc        func(moodle.CmID, int64) ([]moodle.Comment, error)
+	AddSubmissionCommentFunc         func(moodle.CmID, int64, string) (*moodle.Comment, error)
+	SetAssessmentExtensionDateFunc   func(moodle.UserID, int64, time.Time) error
+	GetSiteInfoFunc                  func() (string, string, string, int64, error)
+	GetSiteInfoStructFunc            func() (*moodle.SiteInfo, error)
+	PingFunc                         func(context.Context, ...string) (*moodle.HealthStatus, error)
+	ValidateTokenFunc                func() (*moodle.SiteInfo, error)
+	CallFunc                         func(context.Context, string, interface{}, interface{}) error
+	GetPasswordPolicyFunc            func() (*moodle.PasswordPolicy, error)
+	GetPublicConfigFunc              func() (*moodle.PublicConfig, error)
+	GetMobileConfigFunc              func(string) (map[string]string, error)
+	GetCustomReportsFunc             func() ([]moodle.CustomReport, error)
+	GetCustomReportFunc              func(int64) (*moodle.ReportData, error)
+	GetCustomReportPageFunc          func(int64, int, int) (*moodle.ReportData, error)
+
+	mutex sync.Mutex
+	Calls []string
+}
+
+var _ moodle.Api = (*Api)(nil)
+
+func (m *Api) called(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Calls = append(m.Calls, name)
+}
+
+func notImplemented(name string) error {
+	return errors.New("moodlemock: " + name + "Func is not set")
+}
+
+func (m *Api) GetPersonByUsername(username string) (*moodle.Person, error) {
+	m.called("GetPersonByUsername")
+	if m.GetPersonByUsernameFunc == nil {
+		var r0 *moodle.Person
+		return r0, notImplemented("GetPersonByUsername")
+	}
+	return m.GetPersonByUsernameFunc(username)
+}
+
+func (m *Api) GetPersonByMoodleId(id moodle.UserID) (*moodle.Person, error) {
+	m.called("GetPersonByMoodleId")
+	if m.GetPersonByMoodleIdFunc == nil {
+		var r0 *moodle.Person
+		return r0, notImplemented("GetPersonByMoodleId")
+	}
+	return m.GetPersonByMoodleIdFunc(id)
+}
+
+func (m *Api) GetPersonByEmail(email string) (*moodle.Person, error) {
+	m.called("GetPersonByEmail")
+	if m.GetPersonByEmailFunc == nil {
+		var r0 *moodle.Person
+		return r0, notImplemented("GetPersonByEmail")
+	}
+	return m.GetPersonByEmailFunc(email)
+}
+
+func (m *Api) GetPeopleByUsernames(usernames []string) (map[string]moodle.Person, error) {
+	m.called("GetPeopleByUsernames")
+	if m.GetPeopleByUsernamesFunc == nil {
+		var r0 map[string]moodle.Person
+		return r0, notImplemented("GetPeopleByUsernames")
+	}
+	return m.GetPeopleByUsernamesFunc(usernames)
+}
+
+func (m *Api) GetPeopleByEmails(emails []string) (map[string]moodle.Person, error) {
+	m.called("GetPeopleByEmails")
+	if m.GetPeopleByEmailsFunc == nil {
+		var r0 map[string]moodle.Person
+		return r0, notImplemented("GetPeopleByEmails")
+	}
+	return m.GetPeopleByEmailsFunc(emails)
+}
+
+func (m *Api) FindPeopleByName(firstname string, lastname string) ([]moodle.Person, error) {
+	m.called("FindPeopleByName")
+	if m.FindPeopleByNameFunc == nil {
+		var r0 []moodle.Person
+		return r0, notImplemented("FindPeopleByName")
+	}
+	return m.FindPeopleByNameFunc(firstname, lastname)
+}
+
+func (m *Api) FindPeopleByAttribute(attribute string, value string) ([]moodle.Person, error) {
+	m.called("FindPeopleByAttribute")
+	if m.FindPeopleByAttributeFunc == nil {
+		var r0 []moodle.Person
+		return r0, notImplemented("FindPeopleByAttribute")
+	}
+	return m.FindPeopleByAttributeFunc(attribute, value)
+}
+
+func (m *Api) FindPeople(attribute string, value string, options *moodle.PeopleSearchOptions) ([]moodle.Person, error) {
+	m.called("FindPeople")
+	if m.FindPeopleFunc == nil {
+		var r0 []moodle.Person
+		return r0, notImplemented("FindPeople")
+	}
+	return m.FindPeopleFunc(attribute, value, options)
+}
+
+func (m *Api) GetAccessReport(userIds []moodle.UserID, options *moodle.AccessReportOptions) (*moodle.AccessReport, error) {
+	m.called("GetAccessReport")
+	if m.GetAccessReportFunc == nil {
+		var r0 *moodle.AccessReport
+		return r0, notImplemented("GetAccessReport")
+	}
+	return m.GetAccessReportFunc(userIds, options)
+}
+
+func (m *Api) GetCourseAccessReport(courseId moodle.CourseID, options *moodle.AccessReportOptions) (*moodle.AccessReport, error) {
+	m.called("GetCourseAccessReport")
+	if m.GetCourseAccessReportFunc == nil {
+		var r0 *moodle.AccessReport
+		return r0, notImplemented("GetCourseAccessReport")
+	}
+	return m.GetCourseAccessReportFunc(courseId, options)
+}
+
+func (m *Api) GetCohortAccessReport(cohortId int64, options *moodle.AccessReportOptions) (*moodle.AccessReport, error) {
+	m.called("GetCohortAccessReport")
+	if m.GetCohortAccessReportFunc == nil {
+		var r0 *moodle.AccessReport
+		return r0, notImplemented("GetCohortAccessReport")
+	}
+	return m.GetCohortAccessReportFunc(cohortId, options)
+}
+
+func (m *Api) AddUser(firstName string, lastName string, email string, username string, password string) (moodle.UserID, error) {
+	m.called("AddUser")
+	if m.AddUserFunc == nil {
+		var r0 moodle.UserID
+		return r0, notImplemented("AddUser")
+	}
+	return m.AddUserFunc(firstName, lastName, email, username, password)
+}
+
+func (m *Api) AddUsers(users []moodle.NewUser) ([]moodle.AddUserResult, error) {
+	m.called("AddUsers")
+	if m.AddUsersFunc == nil {
+		var r0 []moodle.AddUserResult
+		return r0, notImplemented("AddUsers")
+	}
+	return m.AddUsersFunc(users)
+}
+
+func (m *Api) UpdateUsers(users []moodle.UserUpdate) ([]moodle.UpdateUserResult, error) {
+	m.called("UpdateUsers")
+	if m.UpdateUsersFunc == nil {
+		var r0 []moodle.UpdateUserResult
+		return r0, notImplemented("UpdateUsers")
+	}
+	return m.UpdateUsersFunc(users)
+}
+
+func (m *Api) SuspendUser(id moodle.UserID) error {
+	m.called("SuspendUser")
+	if m.SuspendUserFunc == nil {
+		return notImplemented("SuspendUser")
+	}
+	return m.SuspendUserFunc(id)
+}
+
+func (m *Api) ChangeUserEmail(userId moodle.UserID, newEmail string, notify bool) error {
+	m.called("ChangeUserEmail")
+	if m.ChangeUserEmailFunc == nil {
+		return notImplemented("ChangeUserEmail")
+	}
+	return m.ChangeUserEmailFunc(userId, newEmail, notify)
+}
+
+func (m *Api) RenameUsername(userId moodle.UserID, newUsername string) error {
+	m.called("RenameUsername")
+	if m.RenameUsernameFunc == nil {
+		return notImplemented("RenameUsername")
+	}
+	return m.RenameUsernameFunc(userId, newUsername)
+}
+
+func (m *Api) UnsuspendUser(id moodle.UserID) error {
+	m.called("UnsuspendUser")
+	if m.UnsuspendUserFunc == nil {
+		return notImplemented("UnsuspendUser")
+	}
+	return m.UnsuspendUserFunc(id)
+}
+
+func (m *Api) UpdateUser(id moodle.UserID, firstName string, lastName string, email string, username string, password string) error {
+	m.called("UpdateUser")
+	if m.UpdateUserFunc == nil {
+		return notImplemented("UpdateUser")
+	}
+	return m.UpdateUserFunc(id, firstName, lastName, email, username, password)
+}
+
+func (m *Api) SetUserAttribute(personId moodle.UserID, attribute string, value string) error {
+	m.called("SetUserAttribute")
+	if m.SetUserAttributeFunc == nil {
+		return notImplemented("SetUserAttribute")
+	}
+	return m.SetUserAttributeFunc(personId, attribute, value)
+}
+
+func (m *Api) SetUserCustomField(personId moodle.UserID, attribute string, value string) error {
+	m.called("SetUserCustomField")
+	if m.SetUserCustomFieldFunc == nil {
+		return notImplemented("SetUserCustomField")
+	}
+	return m.SetUserCustomFieldFunc(personId, attribute, value)
+}
+
+func (m *Api) SetUserCustomFields(personId moodle.UserID, fields map[string]string) error {
+	m.called("SetUserCustomFields")
+	if m.SetUserCustomFieldsFunc == nil {
+		return notImplemented("SetUserCustomFields")
+	}
+	return m.SetUserCustomFieldsFunc(personId, fields)
+}
+
+func (m *Api) SetCustomFieldsForUsers(fields map[moodle.UserID]map[string]string) error {
+	m.called("SetCustomFieldsForUsers")
+	if m.SetCustomFieldsForUsersFunc == nil {
+		return notImplemented("SetCustomFieldsForUsers")
+	}
+	return m.SetCustomFieldsForUsersFunc(fields)
+}
+
+func (m *Api) ResetPassword(moodleId moodle.UserID, password string) error {
+	m.called("ResetPassword")
+	if m.ResetPasswordFunc == nil {
+		return notImplemented("ResetPassword")
+	}
+	return m.ResetPasswordFunc(moodleId, password)
+}
+
+func (m *Api) ResetPasswordWithEmail(email string) error {
+	m.called("ResetPasswordWithEmail")
+	if m.ResetPasswordWithEmailFunc == nil {
+		return notImplemented("ResetPasswordWithEmail")
+	}
+	return m.ResetPasswordWithEmailFunc(email)
+}
+
+func (m *Api) SetProfilePicture(userMoodleId moodle.UserID, r io.Reader) error {
+	m.called("SetProfilePicture")
+	if m.SetProfilePictureFunc == nil {
+		return notImplemented("SetProfilePicture")
+	}
+	return m.SetProfilePictureFunc(userMoodleId, r)
+}
+
+func (m *Api) GetProfilePicture(userId moodle.UserID, size moodle.PictureSize) (io.ReadCloser, error) {
+	m.called("GetProfilePicture")
+	if m.GetProfilePictureFunc == nil {
+		var r0 io.ReadCloser
+		return r0, notImplemented("GetProfilePicture")
+	}
+	return m.GetProfilePictureFunc(userId, size)
+}
+
+func (m *Api) RemoveProfilePicture(userId moodle.UserID) error {
+	m.called("RemoveProfilePicture")
+	if m.RemoveProfilePictureFunc == nil {
+		return notImplemented("RemoveProfilePicture")
+	}
+	return m.RemoveProfilePictureFunc(userId)
+}
+
+func (m *Api) GetPersonLocation(userId moodle.UserID) (*time.Location, error) {
+	m.called("GetPersonLocation")
+	if m.GetPersonLocationFunc == nil {
+		var r0 *time.Location
+		return r0, notImplemented("GetPersonLocation")
+	}
+	return m.GetPersonLocationFunc(userId)
+}
+
+func (m *Api) GetUserDevices(userId moodle.UserID) ([]moodle.UserDevice, error) {
+	m.called("GetUserDevices")
+	if m.GetUserDevicesFunc == nil {
+		var r0 []moodle.UserDevice
+		return r0, notImplemented("GetUserDevices")
+	}
+	return m.GetUserDevicesFunc(userId)
+}
+
+func (m *Api) GetCourses(value string) ([]moodle.Course, error) {
+	m.called("GetCourses")
+	if m.GetCoursesFunc == nil {
+		var r0 []moodle.Course
+		return r0, notImplemented("GetCourses")
+	}
+	return m.GetCoursesFunc(value)
+}
+
+func (m *Api) GetCourseByField(field string, value string) (*moodle.CourseRecord, error) {
+	m.called("GetCourseByField")
+	if m.GetCourseByFieldFunc == nil {
+		var r0 *moodle.CourseRecord
+		return r0, notImplemented("GetCourseByField")
+	}
+	return m.GetCourseByFieldFunc(field, value)
+}
+
+func (m *Api) GetCourseByShortName(shortName string) (*moodle.CourseRecord, error) {
+	m.called("GetCourseByShortName")
+	if m.GetCourseByShortNameFunc == nil {
+		var r0 *moodle.CourseRecord
+		return r0, notImplemented("GetCourseByShortName")
+	}
+	return m.GetCourseByShortNameFunc(shortName)
+}
+
+func (m *Api) SearchCoursesByCustomField(shortname string, value string) ([]moodle.CourseRecord, error) {
+	m.called("SearchCoursesByCustomField")
+	if m.SearchCoursesByCustomFieldFunc == nil {
+		var r0 []moodle.CourseRecord
+		return r0, notImplemented("SearchCoursesByCustomField")
+	}
+	return m.SearchCoursesByCustomFieldFunc(shortname, value)
+}
+
+func (m *Api) GetPersonCourseList(userId moodle.UserID) ([]moodle.Course, error) {
+	m.called("GetPersonCourseList")
+	if m.GetPersonCourseListFunc == nil {
+		var r0 []moodle.Course
+		return r0, notImplemented("GetPersonCourseList")
+	}
+	return m.GetPersonCourseListFunc(userId)
+}
+
+func (m *Api) GetCategories(categoryId int64, recursive bool) ([]moodle.CourseCategory, error) {
+	m.called("GetCategories")
+	if m.GetCategoriesFunc == nil {
+		var r0 []moodle.CourseCategory
+		return r0, notImplemented("GetCategories")
+	}
+	return m.GetCategoriesFunc(categoryId, recursive)
+}
+
+func (m *Api) GetCoursesInCategory(categoryId int64, recursive bool) ([]moodle.Course, error) {
+	m.called("GetCoursesInCategory")
+	if m.GetCoursesInCategoryFunc == nil {
+		var r0 []moodle.Course
+		return r0, notImplemented("GetCoursesInCategory")
+	}
+	return m.GetCoursesInCategoryFunc(categoryId, recursive)
+}
+
+func (m *Api) GetStarredCourses() ([]moodle.Course, error) {
+	m.called("GetStarredCourses")
+	if m.GetStarredCoursesFunc == nil {
+		var r0 []moodle.Course
+		return r0, notImplemented("GetStarredCourses")
+	}
+	return m.GetStarredCoursesFunc()
+}
+
+func (m *Api) SetCourseStarred(courseId moodle.CourseID, starred bool) error {
+	m.called("SetCourseStarred")
+	if m.SetCourseStarredFunc == nil {
+		return notImplemented("SetCourseStarred")
+	}
+	return m.SetCourseStarredFunc(courseId, starred)
+}
+
+func (m *Api) UpdateCourse(update moodle.CourseUpdate) error {
+	m.called("UpdateCourse")
+	if m.UpdateCourseFunc == nil {
+		return notImplemented("UpdateCourse")
+	}
+	return m.UpdateCourseFunc(update)
+}
+
+func (m *Api) DuplicateCourse(courseId moodle.CourseID, options moodle.DuplicateCourseOptions) (moodle.CourseID, error) {
+	m.called("DuplicateCourse")
+	if m.DuplicateCourseFunc == nil {
+		var r0 moodle.CourseID
+		return r0, notImplemented("DuplicateCourse")
+	}
+	return m.DuplicateCourseFunc(courseId, options)
+}
+
+func (m *Api) DeleteCourse(courseId moodle.CourseID) error {
+	m.called("DeleteCourse")
+	if m.DeleteCourseFunc == nil {
+		return notImplemented("DeleteCourse")
+	}
+	return m.DeleteCourseFunc(courseId)
+}
+
+func (m *Api) GetRecentItems(limit int) ([]moodle.RecentItem, error) {
+	m.called("GetRecentItems")
+	if m.GetRecentItemsFunc == nil {
+		var r0 []moodle.RecentItem
+		return r0, notImplemented("GetRecentItems")
+	}
+	return m.GetRecentItemsFunc(limit)
+}
+
+func (m *Api) GetCourseRoles(courseId moodle.CourseID) ([]moodle.CoursePerson, error) {
+	m.called("GetCourseRoles")
+	if m.GetCourseRolesFunc == nil {
+		var r0 []moodle.CoursePerson
+		return r0, notImplemented("GetCourseRoles")
+	}
+	return m.GetCourseRolesFunc(courseId)
+}
+
+func (m *Api) GetCourseEnrolmentCount(courseId moodle.CourseID) (int, error) {
+	m.called("GetCourseEnrolmentCount")
+	if m.GetCourseEnrolmentCountFunc == nil {
+		var r0 int
+		return r0, notImplemented("GetCourseEnrolmentCount")
+	}
+	return m.GetCourseEnrolmentCountFunc(courseId)
+}
+
+func (m *Api) SearchCourseUsers(courseId moodle.CourseID, query string) ([]moodle.CoursePerson, error) {
+	m.called("SearchCourseUsers")
+	if m.SearchCourseUsersFunc == nil {
+		var r0 []moodle.CoursePerson
+		return r0, notImplemented("SearchCourseUsers")
+	}
+	return m.SearchCourseUsersFunc(courseId, query)
+}
+
+func (m *Api) GetRolesForCourses(courseIds []moodle.CourseID, concurrency int) (map[moodle.CourseID][]moodle.CoursePerson, error) {
+	m.called("GetRolesForCourses")
+	if m.GetRolesForCoursesFunc == nil {
+		var r0 map[moodle.CourseID][]moodle.CoursePerson
+		return r0, notImplemented("GetRolesForCourses")
+	}
+	return m.GetRolesForCoursesFunc(courseIds, concurrency)
+}
+
+func (m *Api) StreamCourseRoles(courseId moodle.CourseID, fn func(moodle.CoursePerson) error) error {
+	m.called("StreamCourseRoles")
+	if m.StreamCourseRolesFunc == nil {
+		return notImplemented("StreamCourseRoles")
+	}
+	return m.StreamCourseRolesFunc(courseId, fn)
+}
+
+func (m *Api) SetRole(personId moodle.UserID, roleId moodle.RoleID, courseId moodle.CourseID) error {
+	m.called("SetRole")
+	if m.SetRoleFunc == nil {
+		return notImplemented("SetRole")
+	}
+	return m.SetRoleFunc(personId, roleId, courseId)
+}
+
+func (m *Api) GetUsersWithCapability(courseId moodle.CourseID, capability string) ([]moodle.UserID, error) {
+	m.called("GetUsersWithCapability")
+	if m.GetUsersWithCapabilityFunc == nil {
+		var r0 []moodle.UserID
+		return r0, notImplemented("GetUsersWithCapability")
+	}
+	return m.GetUsersWithCapabilityFunc(courseId, capability)
+}
+
+func (m *Api) HasCapability(courseId moodle.CourseID, userId moodle.UserID, capability string) (bool, error) {
+	m.called("HasCapability")
+	if m.HasCapabilityFunc == nil {
+		var r0 bool
+		return r0, notImplemented("HasCapability")
+	}
+	return m.HasCapabilityFunc(courseId, userId, capability)
+}
+
+func (m *Api) RequireCapability(courseId moodle.CourseID, capability string) error {
+	m.called("RequireCapability")
+	if m.RequireCapabilityFunc == nil {
+		return notImplemented("RequireCapability")
+	}
+	return m.RequireCapabilityFunc(courseId, capability)
+}
+
+func (m *Api) UnsetRole(personId moodle.UserID, roleId moodle.RoleID, courseId moodle.CourseID) error {
+	m.called("UnsetRole")
+	if m.UnsetRoleFunc == nil {
+		return notImplemented("UnsetRole")
+	}
+	return m.UnsetRoleFunc(personId, roleId, courseId)
+}
+
+func (m *Api) GetCourseModule(cmid moodle.CmID) (*moodle.CourseModule, error) {
+	m.called("GetCourseModule")
+	if m.GetCourseModuleFunc == nil {
+		var r0 *moodle.CourseModule
+		return r0, notImplemented("GetCourseModule")
+	}
+	return m.GetCourseModuleFunc(cmid)
+}
+
+func (m *Api) GetCourseModules(courseId moodle.CourseID) ([]moodle.CourseModule, error) {
+	m.called("GetCourseModules")
+	if m.GetCourseModulesFunc == nil {
+		var r0 []moodle.CourseModule
+		return r0, notImplemented("GetCourseModules")
+	}
+	return m.GetCourseModulesFunc(courseId)
+}
+
+func (m *Api) GetCourseModulesByType(courseId moodle.CourseID, modname string) ([]moodle.CourseModule, error) {
+	m.called("GetCourseModulesByType")
+	if m.GetCourseModulesByTypeFunc == nil {
+		var r0 []moodle.CourseModule
+		return r0, notImplemented("GetCourseModulesByType")
+	}
+	return m.GetCourseModulesByTypeFunc(courseId, modname)
+}
+
+func (m *Api) GetCourseContents(courseId moodle.CourseID) ([]moodle.CourseSection, error) {
+	m.called("GetCourseContents")
+	if m.GetCourseContentsFunc == nil {
+		var r0 []moodle.CourseSection
+		return r0, notImplemented("GetCourseContents")
+	}
+	return m.GetCourseContentsFunc(courseId)
+}
+
+func (m *Api) GetCourseModulesByIds(cmids []moodle.CmID, concurrency int) (map[moodle.CmID]*moodle.CourseModule, error) {
+	m.called("GetCourseModulesByIds")
+	if m.GetCourseModulesByIdsFunc == nil {
+		var r0 map[moodle.CmID]*moodle.CourseModule
+		return r0, notImplemented("GetCourseModulesByIds")
+	}
+	return m.GetCourseModulesByIdsFunc(cmids, concurrency)
+}
+
+func (m *Api) ResolveAssignmentId(cmid moodle.CmID) (int64, error) {
+	m.called("ResolveAssignmentId")
+	if m.ResolveAssignmentIdFunc == nil {
+		var r0 int64
+		return r0, notImplemented("ResolveAssignmentId")
+	}
+	return m.ResolveAssignmentIdFunc(cmid)
+}
+
+func (m *Api) ResolveCmId(assignmentId int64) (moodle.CmID, error) {
+	m.called("ResolveCmId")
+	if m.ResolveCmIdFunc == nil {
+		var r0 moodle.CmID
+		return r0, notImplemented("ResolveCmId")
+	}
+	return m.ResolveCmIdFunc(assignmentId)
+}
+
+func (m *Api) GetAssignmentCmIds(courseIds []moodle.CourseID) (map[int64]moodle.CmID, error) {
+	m.called("GetAssignmentCmIds")
+	if m.GetAssignmentCmIdsFunc == nil {
+		var r0 map[int64]moodle.CmID
+		return r0, notImplemented("GetAssignmentCmIds")
+	}
+	return m.GetAssignmentCmIdsFunc(courseIds)
+}
+
+func (m *Api) SetAssignmentDueDate(assignmentId int64, due time.Time, cutoff time.Time) error {
+	m.called("SetAssignmentDueDate")
+	if m.SetAssignmentDueDateFunc == nil {
+		return notImplemented("SetAssignmentDueDate")
+	}
+	return m.SetAssignmentDueDateFunc(assignmentId, due, cutoff)
+}
+
+func (m *Api) ShiftAssignmentDates(courseIds []moodle.CourseID, offset time.Duration) (int, error) {
+	m.called("ShiftAssignmentDates")
+	if m.ShiftAssignmentDatesFunc == nil {
+		var r0 int
+		return r0, notImplemented("ShiftAssignmentDates")
+	}
+	return m.ShiftAssignmentDatesFunc(courseIds, offset)
+}
+
+func (m *Api) SetQuizDates(quizId int64, open time.Time, close time.Time) error {
+	m.called("SetQuizDates")
+	if m.SetQuizDatesFunc == nil {
+		return notImplemented("SetQuizDates")
+	}
+	return m.SetQuizDatesFunc(quizId, open, close)
+}
+
+func (m *Api) ShiftQuizDates(courseIds []moodle.CourseID, offset time.Duration) (int, error) {
+	m.called("ShiftQuizDates")
+	if m.ShiftQuizDatesFunc == nil {
+		var r0 int
+		return r0, notImplemented("ShiftQuizDates")
+	}
+	return m.ShiftQuizDatesFunc(courseIds, offset)
+}
+
+func (m *Api) IsModuleAvailableTo(cmid moodle.CmID, userId moodle.UserID) (bool, error) {
+	m.called("IsModuleAvailableTo")
+	if m.IsModuleAvailableToFunc == nil {
+		var r0 bool
+		return r0, notImplemented("IsModuleAvailableTo")
+	}
+	return m.IsModuleAvailableToFunc(cmid, userId)
+}
+
+func (m *Api) SetModuleAvailability(cmid moodle.CmID, r *moodle.Restriction) error {
+	m.called("SetModuleAvailability")
+	if m.SetModuleAvailabilityFunc == nil {
+		return notImplemented("SetModuleAvailability")
+	}
+	return m.SetModuleAvailabilityFunc(cmid, r)
+}
+
+func (m *Api) SetCourseModuleVisibility(cmid moodle.CmID, visible bool) error {
+	m.called("SetCourseModuleVisibility")
+	if m.SetCourseModuleVisibilityFunc == nil {
+		return notImplemented("SetCourseModuleVisibility")
+	}
+	return m.SetCourseModuleVisibilityFunc(cmid, visible)
+}
+
+func (m *Api) DeleteCourseModule(cmid moodle.CmID) error {
+	m.called("DeleteCourseModule")
+	if m.DeleteCourseModuleFunc == nil {
+		return notImplemented("DeleteCourseModule")
+	}
+	return m.DeleteCourseModuleFunc(cmid)
+}
+
+func (m *Api) GetCourseGroups(courseId moodle.CourseID) ([]moodle.CourseGroup, error) {
+	m.called("GetCourseGroups")
+	if m.GetCourseGroupsFunc == nil {
+		var r0 []moodle.CourseGroup
+		return r0, notImplemented("GetCourseGroups")
+	}
+	return m.GetCourseGroupsFunc(courseId)
+}
+
+func (m *Api) GetPersonCourseGroups(courseId moodle.CourseID, userId moodle.UserID) ([]moodle.CourseGroup, error) {
+	m.called("GetPersonCourseGroups")
+	if m.GetPersonCourseGroupsFunc == nil {
+		var r0 []moodle.CourseGroup
+		return r0, notImplemented("GetPersonCourseGroups")
+	}
+	return m.GetPersonCourseGroupsFunc(courseId, userId)
+}
+
+func (m *Api) GetCourseGroupings(courseId moodle.CourseID) ([]moodle.CourseGrouping, error) {
+	m.called("GetCourseGroupings")
+	if m.GetCourseGroupingsFunc == nil {
+		var r0 []moodle.CourseGrouping
+		return r0, notImplemented("GetCourseGroupings")
+	}
+	return m.GetCourseGroupingsFunc(courseId)
+}
+
+func (m *Api) GetPersonCourseGroupings(courseId moodle.CourseID, userId moodle.UserID) ([]int64, error) {
+	m.called("GetPersonCourseGroupings")
+	if m.GetPersonCourseGroupingsFunc == nil {
+		var r0 []int64
+		return r0, notImplemented("GetPersonCourseGroupings")
+	}
+	return m.GetPersonCourseGroupingsFunc(courseId, userId)
+}
+
+func (m *Api) GetCourseGroupByName(courseId moodle.CourseID, name string) (*moodle.CourseGroup, error) {
+	m.called("GetCourseGroupByName")
+	if m.GetCourseGroupByNameFunc == nil {
+		var r0 *moodle.CourseGroup
+		return r0, notImplemented("GetCourseGroupByName")
+	}
+	return m.GetCourseGroupByNameFunc(courseId, name)
+}
+
+func (m *Api) GetCourseGroupByIdNumber(courseId moodle.CourseID, idNumber string) (*moodle.CourseGroup, error) {
+	m.called("GetCourseGroupByIdNumber")
+	if m.GetCourseGroupByIdNumberFunc == nil {
+		var r0 *moodle.CourseGroup
+		return r0, notImplemented("GetCourseGroupByIdNumber")
+	}
+	return m.GetCourseGroupByIdNumberFunc(courseId, idNumber)
+}
+
+func (m *Api) GetGroupMembers(courseId moodle.CourseID, groupId moodle.GroupID) ([]moodle.CoursePerson, error) {
+	m.called("GetGroupMembers")
+	if m.GetGroupMembersFunc == nil {
+		var r0 []moodle.CoursePerson
+		return r0, notImplemented("GetGroupMembers")
+	}
+	return m.GetGroupMembersFunc(courseId, groupId)
+}
+
+func (m *Api) MessageCourseGroup(courseId moodle.CourseID, groupName string, text string) error {
+	m.called("MessageCourseGroup")
+	if m.MessageCourseGroupFunc == nil {
+		return notImplemented("MessageCourseGroup")
+	}
+	return m.MessageCourseGroupFunc(courseId, groupName, text)
+}
+
+func (m *Api) EmailCourseGroup(courseId moodle.CourseID, groupName string, t moodle.EmailTemplate, data interface{}) error {
+	m.called("EmailCourseGroup")
+	if m.EmailCourseGroupFunc == nil {
+		return notImplemented("EmailCourseGroup")
+	}
+	return m.EmailCourseGroupFunc(courseId, groupName, t, data)
+}
+
+func (m *Api) AddGroupToCourse(courseId moodle.CourseID, groupName string, groupDescription string) (moodle.GroupID, error) {
+	m.called("AddGroupToCourse")
+	if m.AddGroupToCourseFunc == nil {
+		var r0 moodle.GroupID
+		return r0, notImplemented("AddGroupToCourse")
+	}
+	return m.AddGroupToCourseFunc(courseId, groupName, groupDescription)
+}
+
+func (m *Api) AddGroupToCourseWithIdNumber(courseId moodle.CourseID, groupName string, groupDescription string, idNumber string) (moodle.GroupID, error) {
+	m.called("AddGroupToCourseWithIdNumber")
+	if m.AddGroupToCourseWithIdNumberFunc == nil {
+		var r0 moodle.GroupID
+		return r0, notImplemented("AddGroupToCourseWithIdNumber")
+	}
+	return m.AddGroupToCourseWithIdNumberFunc(courseId, groupName, groupDescription, idNumber)
+}
+
+func (m *Api) UpdateCourseGroup(group moodle.CourseGroup) error {
+	m.called("UpdateCourseGroup")
+	if m.UpdateCourseGroupFunc == nil {
+		return notImplemented("UpdateCourseGroup")
+	}
+	return m.UpdateCourseGroupFunc(group)
+}
+
+func (m *Api) AddPersonToCourseGroup(personId moodle.UserID, groupId moodle.GroupID) error {
+	m.called("AddPersonToCourseGroup")
+	if m.AddPersonToCourseGroupFunc == nil {
+		return notImplemented("AddPersonToCourseGroup")
+	}
+	return m.AddPersonToCourseGroupFunc(personId, groupId)
+}
+
+func (m *Api) RemovePersonFromCourseGroup(personId moodle.UserID, groupId moodle.GroupID) error {
+	m.called("RemovePersonFromCourseGroup")
+	if m.RemovePersonFromCourseGroupFunc == nil {
+		return notImplemented("RemovePersonFromCourseGroup")
+	}
+	return m.RemovePersonFromCourseGroupFunc(personId, groupId)
+}
+
+func (m *Api) CreateCohort(name string, idNumber string, description string) (int64, error) {
+	m.called("CreateCohort")
+	if m.CreateCohortFunc == nil {
+		var r0 int64
+		return r0, notImplemented("CreateCohort")
+	}
+	return m.CreateCohortFunc(name, idNumber, description)
+}
+
+func (m *Api) GetCohorts(ids ...int64) ([]moodle.Cohort, error) {
+	m.called("GetCohorts")
+	if m.GetCohortsFunc == nil {
+		var r0 []moodle.Cohort
+		return r0, notImplemented("GetCohorts")
+	}
+	return m.GetCohortsFunc(ids...)
+}
+
+func (m *Api) GetCohortMembers(cohortId int64) ([]moodle.UserID, error) {
+	m.called("GetCohortMembers")
+	if m.GetCohortMembersFunc == nil {
+		var r0 []moodle.UserID
+		return r0, notImplemented("GetCohortMembers")
+	}
+	return m.GetCohortMembersFunc(cohortId)
+}
+
+func (m *Api) AddCohortMembers(cohortId int64, userIds []moodle.UserID) error {
+	m.called("AddCohortMembers")
+	if m.AddCohortMembersFunc == nil {
+		return notImplemented("AddCohortMembers")
+	}
+	return m.AddCohortMembersFunc(cohortId, userIds)
+}
+
+func (m *Api) RemoveCohortMembers(cohortId int64, userIds []moodle.UserID) error {
+	m.called("RemoveCohortMembers")
+	if m.RemoveCohortMembersFunc == nil {
+		return notImplemented("RemoveCohortMembers")
+	}
+	return m.RemoveCohortMembersFunc(cohortId, userIds)
+}
+
+func (m *Api) GetCourseGradebook(courseId moodle.CourseID) ([]moodle.GradebookEntry, error) {
+	m.called("GetCourseGradebook")
+	if m.GetCourseGradebookFunc == nil {
+		var r0 []moodle.GradebookEntry
+		return r0, notImplemented("GetCourseGradebook")
+	}
+	return m.GetCourseGradebookFunc(courseId)
+}
+
+func (m *Api) GetAssignmentGradeRecords(ids ...int64) ([]moodle.AssignmentRecord, error) {
+	m.called("GetAssignmentGradeRecords")
+	if m.GetAssignmentGradeRecordsFunc == nil {
+		var r0 []moodle.AssignmentRecord
+		return r0, notImplemented("GetAssignmentGradeRecords")
+	}
+	return m.GetAssignmentGradeRecordsFunc(ids...)
+}
+
+func (m *Api) GetGradingBacklog(courseIds []moodle.CourseID) ([]moodle.GradingBacklog, error) {
+	m.called("GetGradingBacklog")
+	if m.GetGradingBacklogFunc == nil {
+		var r0 []moodle.GradingBacklog
+		return r0, notImplemented("GetGradingBacklog")
+	}
+	return m.GetGradingBacklogFunc(courseIds)
+}
+
+func (m *Api) GetAssignmentStats(assignmentId int64) (*moodle.AssignmentStats, error) {
+	m.called("GetAssignmentStats")
+	if m.GetAssignmentStatsFunc == nil {
+		var r0 *moodle.AssignmentStats
+		return r0, notImplemented("GetAssignmentStats")
+	}
+	return m.GetAssignmentStatsFunc(assignmentId)
+}
+
+func (m *Api) GetActivitiesCompletion(courseId moodle.CourseID, userId moodle.UserID) (map[moodle.CmID]moodle.CompletionState, error) {
+	m.called("GetActivitiesCompletion")
+	if m.GetActivitiesCompletionFunc == nil {
+		var r0 map[moodle.CmID]moodle.CompletionState
+		return r0, notImplemented("GetActivitiesCompletion")
+	}
+	return m.GetActivitiesCompletionFunc(courseId, userId)
+}
+
+func (m *Api) GetItemRatings(area moodle.RatingArea, itemId int64) ([]moodle.Rating, error) {
+	m.called("GetItemRatings")
+	if m.GetItemRatingsFunc == nil {
+		var r0 []moodle.Rating
+		return r0, notImplemented("GetItemRatings")
+	}
+	return m.GetItemRatingsFunc(area, itemId)
+}
+
+func (m *Api) AddRating(area moodle.RatingArea, itemId int64, ratedUserId moodle.UserID, rating int64, aggregation moodle.RatingAggregation) (*moodle.RatingResult, error) {
+	m.called("AddRating")
+	if m.AddRatingFunc == nil {
+		var r0 *moodle.RatingResult
+		return r0, notImplemented("AddRating")
+	}
+	return m.AddRatingFunc(area, itemId, ratedUserId, rating, aggregation)
+}
+
+func (m *Api) GetCompetencyFrameworks() ([]moodle.CompetencyFramework, error) {
+	m.called("GetCompetencyFrameworks")
+	if m.GetCompetencyFrameworksFunc == nil {
+		var r0 []moodle.CompetencyFramework
+		return r0, notImplemented("GetCompetencyFrameworks")
+	}
+	return m.GetCompetencyFrameworksFunc()
+}
+
+func (m *Api) GetCompetencies(frameworkId int64) ([]moodle.Competency, error) {
+	m.called("GetCompetencies")
+	if m.GetCompetenciesFunc == nil {
+		var r0 []moodle.Competency
+		return r0, notImplemented("GetCompetencies")
+	}
+	return m.GetCompetenciesFunc(frameworkId)
+}
+
+func (m *Api) GetCourseCompetencies(courseId moodle.CourseID) ([]moodle.Competency, error) {
+	m.called("GetCourseCompetencies")
+	if m.GetCourseCompetenciesFunc == nil {
+		var r0 []moodle.Competency
+		return r0, notImplemented("GetCourseCompetencies")
+	}
+	return m.GetCourseCompetenciesFunc(courseId)
+}
+
+func (m *Api) GetUserCompetencyInCourse(userId moodle.UserID, competencyId int64, courseId moodle.CourseID) (*moodle.UserCompetency, error) {
+	m.called("GetUserCompetencyInCourse")
+	if m.GetUserCompetencyInCourseFunc == nil {
+		var r0 *moodle.UserCompetency
+		return r0, notImplemented("GetUserCompetencyInCourse")
+	}
+	return m.GetUserCompetencyInCourseFunc(userId, competencyId, courseId)
+}
+
+func (m *Api) GetInsights(userId moodle.UserID) ([]moodle.Insight, error) {
+	m.called("GetInsights")
+	if m.GetInsightsFunc == nil {
+		var r0 []moodle.Insight
+		return r0, notImplemented("GetInsights")
+	}
+	return m.GetInsightsFunc(userId)
+}
+
+func (m *Api) GetAssignmentsForCourses(courseIds []moodle.CourseID) ([]moodle.AssignmentInfo, error) {
+	m.called("GetAssignmentsForCourses")
+	if m.GetAssignmentsForCoursesFunc == nil {
+		var r0 []moodle.AssignmentInfo
+		return r0, notImplemented("GetAssignmentsForCourses")
+	}
+	return m.GetAssignmentsForCoursesFunc(courseIds)
+}
+
+func (m *Api) GetQuizzesForCourses(courseIds []moodle.CourseID) ([]moodle.QuizInfo, error) {
+	m.called("GetQuizzesForCourses")
+	if m.GetQuizzesForCoursesFunc == nil {
+		var r0 []moodle.QuizInfo
+		return r0, notImplemented("GetQuizzesForCourses")
+	}
+	return m.GetQuizzesForCoursesFunc(courseIds)
+}
+
+func (m *Api) GetUserQuizAttempts(quizId int64, userId moodle.UserID) ([]moodle.QuizAttempt, error) {
+	m.called("GetUserQuizAttempts")
+	if m.GetUserQuizAttemptsFunc == nil {
+		var r0 []moodle.QuizAttempt
+		return r0, notImplemented("GetUserQuizAttempts")
+	}
+	return m.GetUserQuizAttemptsFunc(quizId, userId)
+}
+
+func (m *Api) GetQuizAttemptReview(attemptId int64) (*moodle.QuizAttemptReview, error) {
+	m.called("GetQuizAttemptReview")
+	if m.GetQuizAttemptReviewFunc == nil {
+		var r0 *moodle.QuizAttemptReview
+		return r0, notImplemented("GetQuizAttemptReview")
+	}
+	return m.GetQuizAttemptReviewFunc(attemptId)
+}
+
+func (m *Api) GetForumsForCourses(courseIds []moodle.CourseID) ([]moodle.ForumInfo, error) {
+	m.called("GetForumsForCourses")
+	if m.GetForumsForCoursesFunc == nil {
+		var r0 []moodle.ForumInfo
+		return r0, notImplemented("GetForumsForCourses")
+	}
+	return m.GetForumsForCoursesFunc(courseIds)
+}
+
+func (m *Api) GetForumDiscussions(forumId int) ([]moodle.ForumDiscussion, error) {
+	m.called("GetForumDiscussions")
+	if m.GetForumDiscussionsFunc == nil {
+		var r0 []moodle.ForumDiscussion
+		return r0, notImplemented("GetForumDiscussions")
+	}
+	return m.GetForumDiscussionsFunc(forumId)
+}
+
+func (m *Api) GetForumDiscussionsForGroup(forumId int, groupId moodle.GroupID) ([]moodle.ForumDiscussion, error) {
+	m.called("GetForumDiscussionsForGroup")
+	if m.GetForumDiscussionsForGroupFunc == nil {
+		var r0 []moodle.ForumDiscussion
+		return r0, notImplemented("GetForumDiscussionsForGroup")
+	}
+	return m.GetForumDiscussionsForGroupFunc(forumId, groupId)
+}
+
+func (m *Api) GetSubmissionsForAssignment(assignmentId int64) ([]moodle.AssignmentSubmission, error) {
+	m.called("GetSubmissionsForAssignment")
+	if m.GetSubmissionsForAssignmentFunc == nil {
+		var r0 []moodle.AssignmentSubmission
+		return r0, notImplemented("GetSubmissionsForAssignment")
+	}
+	return m.GetSubmissionsForAssignmentFunc(assignmentId)
+}
+
+func (m *Api) GetPlagiarismResults(assignmentId int64) (map[moodle.UserID][]moodle.PlagiarismResult, error) {
+	m.called("GetPlagiarismResults")
+	if m.GetPlagiarismResultsFunc == nil {
+		var r0 map[moodle.UserID][]moodle.PlagiarismResult
+		return r0, notImplemented("GetPlagiarismResults")
+	}
+	return m.GetPlagiarismResultsFunc(assignmentId)
+}
+
+func (m *Api) GetAnnotatedFeedbackPdf(assignmentId int64, userId moodle.UserID) (*moodle.MoodleFile, error) {
+	m.called("GetAnnotatedFeedbackPdf")
+	if m.GetAnnotatedFeedbackPdfFunc == nil {
+		var r0 *moodle.MoodleFile
+		return r0, notImplemented("GetAnnotatedFeedbackPdf")
+	}
+	return m.GetAnnotatedFeedbackPdfFunc(assignmentId, userId)
+}
+
+func (m *Api) DownloadAnnotatedFeedbackPdf(assignmentId int64, userId moodle.UserID) (*moodle.MoodleFile, []byte, error) {
+	m.called("DownloadAnnotatedFeedbackPdf")
+	if m.DownloadAnnotatedFeedbackPdfFunc == nil {
+		var r0 *moodle.MoodleFile
+		var r1 []byte
+		return r0, r1, notImplemented("DownloadAnnotatedFeedbackPdf")
+	}
+	return m.DownloadAnnotatedFeedbackPdfFunc(assignmentId, userId)
+}
+
+func (m *Api) DownloadFile(fileUrl string) ([]byte, error) {
+	m.called("DownloadFile")
+	if m.DownloadFileFunc == nil {
+		var r0 []byte
+		return r0, notImplemented("DownloadFile")
+	}
+	return m.DownloadFileFunc(fileUrl)
+}
+
+func (m *Api) OpenFile(fileUrl string) (io.ReadCloser, error) {
+	m.called("OpenFile")
+	if m.OpenFileFunc == nil {
+		var r0 io.ReadCloser
+		return r0, notImplemented("OpenFile")
+	}
+	return m.OpenFileFunc(fileUrl)
+}
+
+func (m *Api) WriteSubmissionBundle(assignmentId int64, w io.Writer) (int, error) {
+	m.called("WriteSubmissionBundle")
+	if m.WriteSubmissionBundleFunc == nil {
+		var r0 int
+		return r0, notImplemented("WriteSubmissionBundle")
+	}
+	return m.WriteSubmissionBundleFunc(assignmentId, w)
+}
+
+func (m *Api) NotifyFeedbackByEmail(t moodle.EmailTemplate) func(moodle.FeedbackReady) error {
+	m.called("NotifyFeedbackByEmail")
+	if m.NotifyFeedbackByEmailFunc == nil {
+		var r0 func(moodle.FeedbackReady) error
+		return r0
+	}
+	return m.NotifyFeedbackByEmailFunc(t)
+}
+
+func (m *Api) GetSubmissionComments(cmid moodle.CmID, submissionId int64) ([]moodle.Comment, error) {
+	m.called("GetSubmissionComments")
+	if m.GetSubmissionCommentsFunc == nil {
+		var r0 []moodle.Comment
+		return r0, notImplemented("GetSubmissionComments")
+	}
+	return m.GetSubmissionCommentsFunc(cmid, submissionId)
+}
+
+func (m *Api) AddSubmissionComment(cmid moodle.CmID, submissionId int64, content string) (*moodle.Comment, error) {
+	m.called("AddSubmissionComment")
+	if m.AddSubmissionCommentFunc == nil {
+		var r0 *moodle.Comment
+		return r0, notImplemented("AddSubmissionComment")
+	}
+	return m.AddSubmissionCommentFunc(cmid, submissionId, content)
+}
+
+func (m *Api) SetAssessmentExtensionDate(userId moodle.UserID, assessmentId int64, newDueDate time.Time) error {
+	m.called("SetAssessmentExtensionDate")
+	if m.SetAssessmentExtensionDateFunc == nil {
+		return notImplemented("SetAssessmentExtensionDate")
+	}
+	return m.SetAssessmentExtensionDateFunc(userId, assessmentId, newDueDate)
+}
+
+func (m *Api) GetSiteInfo() (string, string, string, int64, error) {
+	m.called("GetSiteInfo")
+	if m.GetSiteInfoFunc == nil {
+		var r0 string
+		var r1 string
+		var r2 string
+		var r3 int64
+		return r0, r1, r2, r3, notImplemented("GetSiteInfo")
+	}
+	return m.GetSiteInfoFunc()
+}
+
+func (m *Api) GetSiteInfoStruct() (*moodle.SiteInfo, error) {
+	m.called("GetSiteInfoStruct")
+	if m.GetSiteInfoStructFunc == nil {
+		var r0 *moodle.SiteInfo
+		return r0, notImplemented("GetSiteInfoStruct")
+	}
+	return m.GetSiteInfoStructFunc()
+}
+
+func (m *Api) Ping(ctx context.Context, required ...string) (*moodle.HealthStatus, error) {
+	m.called("Ping")
+	if m.PingFunc == nil {
+		var r0 *moodle.HealthStatus
+		return r0, notImplemented("Ping")
+	}
+	return m.PingFunc(ctx, required...)
+}
+
+func (m *Api) ValidateToken() (*moodle.SiteInfo, error) {
+	m.called("ValidateToken")
+	if m.ValidateTokenFunc == nil {
+		var r0 *moodle.SiteInfo
+		return r0, notImplemented("ValidateToken")
+	}
+	return m.ValidateTokenFunc()
+}
+
+func (m *Api) Call(ctx context.Context, function string, params interface{}, result interface{}) error {
+	m.called("Call")
+	if m.CallFunc == nil {
+		return notImplemented("Call")
+	}
+	return m.CallFunc(ctx, function, params, result)
+}
+
+func (m *Api) GetPasswordPolicy() (*moodle.PasswordPolicy, error) {
+	m.called("GetPasswordPolicy")
+	if m.GetPasswordPolicyFunc == nil {
+		var r0 *moodle.PasswordPolicy
+		return r0, notImplemented("GetPasswordPolicy")
+	}
+	return m.GetPasswordPolicyFunc()
+}
+
+func (m *Api) GetPublicConfig() (*moodle.PublicConfig, error) {
+	m.called("GetPublicConfig")
+	if m.GetPublicConfigFunc == nil {
+		var r0 *moodle.PublicConfig
+		return r0, notImplemented("GetPublicConfig")
+	}
+	return m.GetPublicConfigFunc()
+}
+
+func (m *Api) GetMobileConfig(section string) (map[string]string, error) {
+	m.called("GetMobileConfig")
+	if m.GetMobileConfigFunc == nil {
+		var r0 map[string]string
+		return r0, notImplemented("GetMobileConfig")
+	}
+	return m.GetMobileConfigFunc(section)
+}
+
+func (m *Api) GetCustomReports() ([]moodle.CustomReport, error) {
+	m.called("GetCustomReports")
+	if m.GetCustomReportsFunc == nil {
+		var r0 []moodle.CustomReport
+		return r0, notImplemented("GetCustomReports")
+	}
+	return m.GetCustomReportsFunc()
+}
+
+func (m *Api) GetCustomReport(reportId int64) (*moodle.ReportData, error) {
+	m.called("GetCustomReport")
+	if m.GetCustomReportFunc == nil {
+		var r0 *moodle.ReportData
+		return r0, notImplemented("GetCustomReport")
+	}
+	return m.GetCustomReportFunc(reportId)
+}
+
+func (m *Api) GetCustomReportPage(reportId int64, page int, perPage int) (*moodle.ReportData, error) {
+	m.called("GetCustomReportPage")
+	if m.GetCustomReportPageFunc == nil {
+		var r0 *moodle.ReportData
+		return r0, notImplemented("GetCustomReportPage")
+	}
+	return m.GetCustomReportPageFunc(reportId, page, perPage)
+}
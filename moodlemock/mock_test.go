@@ -0,0 +1,39 @@
+package moodlemock
+
+import (
+	"testing"
+
+	"github.com/zaddok/moodle"
+)
+
+// enrolled is an example of application code that depends on an interface
+func enrolled(api moodle.CourseApi, courseId moodle.CourseID) (int, error) {
+	people, err := api.GetCourseRoles(courseId)
+	return len(people), err
+}
+
+func TestMock(t *testing.T) {
+
+	mock := &Api{
+		GetCourseRolesFunc: func(courseId moodle.CourseID) ([]moodle.CoursePerson, error) {
+			return []moodle.CoursePerson{{Id: 1}, {Id: 2}}, nil
+		},
+		GetAssignmentGradeRecordsFunc: func(ids ...int64) ([]moodle.AssignmentRecord, error) {
+			return make([]moodle.AssignmentRecord, len(ids)), nil
+		},
+	}
+
+	n, err := enrolled(mock, 3)
+	if err != nil || n != 2 {
+		t.Errorf("Expected two people, found %d %v", n, err)
+	}
+	if records, _ := mock.GetAssignmentGradeRecords(1, 2, 3); len(records) != 3 {
+		t.Errorf("Expected variadic arguments to be passed on, found %d records", len(records))
+	}
+	if _, err := mock.GetPersonByUsername("jsmith"); err == nil {
+		t.Errorf("Expected an unset function to return an error")
+	}
+	if len(mock.Calls) != 3 || mock.Calls[0] != "GetCourseRoles" || mock.Calls[2] != "GetPersonByUsername" {
+		t.Errorf("Unexpected calls: %v", mock.Calls)
+	}
+}
@@ -0,0 +1,386 @@
+// Package moodletest starts a disposable moodle site in docker for
+// integration tests. The site is created from the Bitnami moodle and mariadb
+// images, web services are enabled, a token is created for the admin user,
+// and fixture users and courses are added.
+//
+//	site, err := moodletest.Start(nil)
+//	if err != nil {
+//		...
+//	}
+//	defer site.Close()
+//	api := moodle.NewMoodleApi(site.URL, site.Token)
+//
+// The docker command line is used rather than the docker api so that no
+// dependencies are needed. The first start downloads the images and
+// installs moodle, which can take several minutes.
+package moodletest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ErrDockerUnavailable is returned by Start when the docker command is not
+// installed or the docker daemon is not running.
+var ErrDockerUnavailable = errors.New("docker is not available")
+
+// DefaultFunctions are the web service functions used by the moodle
+// package, enabled on the test service unless Options.Functions is set.
+var DefaultFunctions = []string{
+	"block_recentlyaccesseditems_get_recent_items",
+	"core_auth_get_signup_settings",
+	"core_completion_get_activities_completion_status",
+	"core_cohort_add_cohort_members",
+	"core_cohort_create_cohorts",
+	"core_cohort_delete_cohort_members",
+	"core_cohort_get_cohort_members",
+	"core_cohort_get_cohorts",
+	"core_comment_add_comments",
+	"core_comment_get_comments",
+	"core_competency_list_competencies",
+	"core_competency_list_competency_frameworks",
+	"core_competency_list_course_competencies",
+	"core_course_delete_courses",
+	"core_course_delete_modules",
+	"core_course_duplicate_course",
+	"core_course_edit_module",
+	"core_course_get_categories",
+	"core_course_get_contents",
+	"core_course_get_course_module",
+	"core_course_get_course_module_by_instance",
+	"core_course_get_courses_by_field",
+	"core_course_get_enrolled_courses_by_timeline_classification",
+	"core_course_search_courses",
+	"core_course_set_favourite_courses",
+	"core_course_update_courses",
+	"core_enrol_get_enrolled_users",
+	"core_enrol_get_enrolled_users_with_capability",
+	"core_enrol_get_users_courses",
+	"core_enrol_search_users",
+	"core_files_upload",
+	"core_group_add_group_members",
+	"core_group_create_groups",
+	"core_group_delete_group_members",
+	"core_group_get_course_groupings",
+	"core_group_get_course_groups",
+	"core_group_get_course_user_groups",
+	"core_group_get_groupings",
+	"core_group_update_groups",
+	"core_message_send_instant_messages",
+	"core_rating_add_rating",
+	"core_rating_get_item_ratings",
+	"core_reportbuilder_list_reports",
+	"core_reportbuilder_retrieve_report",
+	"core_user_create_users",
+	"core_user_get_user_devices",
+	"core_user_get_users",
+	"core_user_get_users_by_field",
+	"core_user_update_picture",
+	"core_user_update_users",
+	"core_webservice_get_site_info",
+	"enrol_manual_enrol_users",
+	"enrol_manual_unenrol_users",
+	"gradereport_user_get_grade_items",
+	"message_popup_get_popup_notifications",
+	"mod_assign_get_assignments",
+	"mod_assign_get_grades",
+	"mod_assign_get_submission_status",
+	"mod_assign_get_submissions",
+	"mod_assign_get_user_flags",
+	"mod_assign_list_participants",
+	"mod_assign_set_user_flags",
+	"mod_forum_get_forum_discussions",
+	"mod_forum_get_forums_by_courses",
+	"mod_quiz_get_attempt_review",
+	"mod_quiz_get_quizzes_by_courses",
+	"mod_quiz_get_user_attempts",
+	"tool_lp_data_for_user_competency_summary_in_course",
+	"tool_mobile_get_config",
+	"tool_mobile_get_public_config",
+	"tool_policy_get_user_acceptances",
+}
+
+// DefaultUsers and DefaultCourses are added to the site unless
+// Options.Users or Options.Courses are set.
+var (
+	DefaultUsers = []User{
+		{Username: "student1", Password: "Student-1", FirstName: "Sam", LastName: "Student", Email: "student1@example.com"},
+		{Username: "student2", Password: "Student-2", FirstName: "Alex", LastName: "Student", Email: "student2@example.com"},
+		{Username: "teacher1", Password: "Teacher-1", FirstName: "Terry", LastName: "Teacher", Email: "teacher1@example.com"},
+	}
+	DefaultCourses = []Course{
+		{ShortName: "LIBRARY", FullName: "Library", Students: []string{"student1", "student2"}, Teachers: []string{"teacher1"}},
+		{ShortName: "HIST101", FullName: "History", Students: []string{"student1"}, Teachers: []string{"teacher1"}},
+	}
+)
+
+// Options configures the test site. A nil Options uses the defaults.
+type Options struct {
+	// MoodleImage and DatabaseImage default to bitnami/moodle:4.1 and
+	// bitnami/mariadb:10.6
+	MoodleImage   string
+	DatabaseImage string
+
+	// AdminPassword is the password of the "admin" account. Defaults to
+	// "Admin-1234".
+	AdminPassword string
+
+	// Functions are enabled on the web service. Defaults to
+	// DefaultFunctions.
+	Functions []string
+
+	Users   []User
+	Courses []Course
+
+	// StartTimeout limits how long to wait for moodle to install. Defaults
+	// to ten minutes.
+	StartTimeout time.Duration
+}
+
+// User is a fixture account. Id is set once the account is created.
+type User struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstname"`
+	LastName  string `json:"lastname"`
+	Email     string `json:"email"`
+	Id        int64  `json:"-"`
+}
+
+// Course is a fixture course. Students and Teachers list the usernames
+// enrolled in the course. Id is set once the course is created.
+type Course struct {
+	ShortName string   `json:"shortname"`
+	FullName  string   `json:"fullname"`
+	Students  []string `json:"students"`
+	Teachers  []string `json:"teachers"`
+	Id        int64    `json:"-"`
+}
+
+// Site is a running test site
+type Site struct {
+	// URL is the address of the site, ending with a slash
+	URL string
+
+	// Token is a web service token for the admin user
+	Token string
+
+	Users   []User
+	Courses []Course
+
+	network    string
+	containers []string
+}
+
+// User returns the fixture account with a username
+func (s *Site) User(username string) *User {
+	for i := range s.Users {
+		if s.Users[i].Username == username {
+			return &s.Users[i]
+		}
+	}
+	return nil
+}
+
+// Course returns the fixture course with a short name
+func (s *Site) Course(shortName string) *Course {
+	for i := range s.Courses {
+		if s.Courses[i].ShortName == shortName {
+			return &s.Courses[i]
+		}
+	}
+	return nil
+}
+
+// Require starts a site for a test, skipping the test if docker is not
+// available. The caller must Close the site.
+func Require(t testing.TB, opts *Options) *Site {
+	t.Helper()
+	site, err := Start(opts)
+	if errors.Is(err, ErrDockerUnavailable) {
+		t.Skip("Skipping integration test: " + err.Error())
+	}
+	if err != nil {
+		t.Fatalf("Failed to start moodle: %v", err)
+	}
+	return site
+}
+
+// Start creates and starts a test site. If starting fails any containers
+// created are removed.
+func Start(opts *Options) (*Site, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MoodleImage == "" {
+		o.MoodleImage = "bitnami/moodle:4.1"
+	}
+	if o.DatabaseImage == "" {
+		o.DatabaseImage = "bitnami/mariadb:10.6"
+	}
+	if o.AdminPassword == "" {
+		o.AdminPassword = "Admin-1234"
+	}
+	if o.Functions == nil {
+		o.Functions = DefaultFunctions
+	}
+	if o.Users == nil {
+		o.Users = append([]User{}, DefaultUsers...)
+	}
+	if o.Courses == nil {
+		o.Courses = append([]Course{}, DefaultCourses...)
+	}
+	if o.StartTimeout == 0 {
+		o.StartTimeout = 10 * time.Minute
+	}
+
+	if _, err := docker("version", "--format", "{{.Server.Version}}"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDockerUnavailable, err)
+	}
+
+	site := &Site{Users: o.Users, Courses: o.Courses}
+	if err := site.start(&o); err != nil {
+		site.Close()
+		return nil, err
+	}
+	return site, nil
+}
+
+func (s *Site) start(o *Options) error {
+	name := fmt.Sprintf("moodletest-%d-%d", os.Getpid(), time.Now().UnixNano())
+
+	var err error
+	if s.network, err = docker("network", "create", name); err != nil {
+		return err
+	}
+
+	db, err := docker("run", "-d", "--network", s.network, "--network-alias", "mariadb",
+		"-e", "ALLOW_EMPTY_PASSWORD=yes",
+		"-e", "MARIADB_USER=bn_moodle",
+		"-e", "MARIADB_DATABASE=bitnami_moodle",
+		"-e", "MARIADB_CHARACTER_SET=utf8mb4",
+		"-e", "MARIADB_COLLATE=utf8mb4_unicode_ci",
+		o.DatabaseImage)
+	if err != nil {
+		return err
+	}
+	s.containers = append(s.containers, db)
+
+	moodle, err := docker("run", "-d", "--network", s.network, "-p", "127.0.0.1::8080",
+		"-e", "ALLOW_EMPTY_PASSWORD=yes",
+		"-e", "MOODLE_DATABASE_HOST=mariadb",
+		"-e", "MOODLE_DATABASE_PORT_NUMBER=3306",
+		"-e", "MOODLE_DATABASE_USER=bn_moodle",
+		"-e", "MOODLE_DATABASE_NAME=bitnami_moodle",
+		"-e", "MOODLE_USERNAME=admin",
+		"-e", "MOODLE_PASSWORD="+o.AdminPassword,
+		"-e", "MOODLE_SKIP_BOOTSTRAP=no",
+		o.MoodleImage)
+	if err != nil {
+		return err
+	}
+	s.containers = append(s.containers, moodle)
+
+	port, err := docker("port", moodle, "8080/tcp")
+	if err != nil {
+		return err
+	}
+	s.URL = "http://" + strings.TrimSpace(strings.Split(port, "\n")[0]) + "/"
+
+	if err := waitForSite(s.URL, o.StartTimeout); err != nil {
+		logs, _ := docker("logs", "--tail", "50", moodle)
+		return fmt.Errorf("%v\n%s", err, logs)
+	}
+
+	return s.setup(moodle, o)
+}
+
+// setup enables web services and adds the fixtures, by running a php script
+// inside the moodle container.
+func (s *Site) setup(container string, o *Options) error {
+	config, err := json.Marshal(map[string]interface{}{
+		"functions": o.Functions,
+		"users":     s.Users,
+		"courses":   s.Courses,
+	})
+	if err != nil {
+		return err
+	}
+	out, err := docker("exec", "-e", "MOODLETEST_CONFIG="+string(config), container, "php", "-r", setupScript)
+	if err != nil {
+		return err
+	}
+
+	type Result struct {
+		Token   string           `json:"token"`
+		Users   map[string]int64 `json:"users"`
+		Courses map[string]int64 `json:"courses"`
+	}
+	var result Result
+	// Moodle may print notices before the result
+	if i := strings.LastIndex(out, "\n{"); i >= 0 {
+		out = out[i+1:]
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return errors.New("Setup script returned unexpected output: " + out)
+	}
+	s.Token = result.Token
+	for i := range s.Users {
+		s.Users[i].Id = result.Users[s.Users[i].Username]
+	}
+	for i := range s.Courses {
+		s.Courses[i].Id = result.Courses[s.Courses[i].ShortName]
+	}
+	return nil
+}
+
+// Close stops and removes the containers and network of the site
+func (s *Site) Close() error {
+	var failed error
+	for i := len(s.containers) - 1; i >= 0; i-- {
+		if _, err := docker("rm", "-f", "-v", s.containers[i]); err != nil {
+			failed = err
+		}
+	}
+	s.containers = nil
+	if s.network != "" {
+		if _, err := docker("network", "rm", s.network); err != nil {
+			failed = err
+		}
+		s.network = ""
+	}
+	return failed
+}
+
+// waitForSite polls the login page until moodle has been installed
+func waitForSite(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if response, err := client.Get(url + "login/index.php"); err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return errors.New("Moodle did not start within " + timeout.String())
+}
+
+// docker runs a docker command and returns its trimmed output
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s failed: %v %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,80 @@
+package moodletest
+
+// setupScript is run with php inside the moodle container. It reads its
+// configuration from MOODLETEST_CONFIG and prints the token and the ids of
+// the fixtures as json. Existing fixtures are reused, so the script can be
+// run more than once.
+const setupScript = `
+define('CLI_SCRIPT', true);
+foreach (['/bitnami/moodle/config.php', '/opt/bitnami/moodle/config.php'] as $file) {
+    if (file_exists($file)) {
+        require($file);
+        break;
+    }
+}
+require_once($CFG->dirroot . '/user/lib.php');
+require_once($CFG->dirroot . '/course/lib.php');
+require_once($CFG->dirroot . '/lib/externallib.php');
+require_once($CFG->dirroot . '/lib/enrollib.php');
+
+$config = json_decode(getenv('MOODLETEST_CONFIG'));
+$admin = get_admin();
+\core\session\manager::set_user($admin);
+
+set_config('enablewebservices', 1);
+set_config('webserviceprotocols', 'rest');
+
+$service = $DB->get_record('external_services', ['shortname' => 'moodletest']);
+if (!$service) {
+    $service = (object)[
+        'name' => 'moodletest',
+        'shortname' => 'moodletest',
+        'enabled' => 1,
+        'restrictedusers' => 0,
+        'downloadfiles' => 1,
+        'uploadfiles' => 1,
+        'timecreated' => time(),
+    ];
+    $service->id = $DB->insert_record('external_services', $service);
+}
+foreach ($config->functions as $function) {
+    if ($DB->record_exists('external_functions', ['name' => $function]) &&
+        !$DB->record_exists('external_services_functions', ['externalserviceid' => $service->id, 'functionname' => $function])) {
+        $DB->insert_record('external_services_functions', (object)['externalserviceid' => $service->id, 'functionname' => $function]);
+    }
+}
+
+$result = ['token' => external_generate_token(EXTERNAL_TOKEN_PERMANENT, $service->id, $admin->id, context_system::instance()), 'users' => [], 'courses' => []];
+
+foreach ($config->users as $u) {
+    $user = $DB->get_record('user', ['username' => $u->username]);
+    $result['users'][$u->username] = $user ? (int)$user->id : (int)user_create_user((object)[
+        'username' => $u->username,
+        'password' => $u->password,
+        'firstname' => $u->firstname,
+        'lastname' => $u->lastname,
+        'email' => $u->email,
+        'auth' => 'manual',
+        'confirmed' => 1,
+        'mnethostid' => $CFG->mnet_localhost_id,
+    ]);
+}
+
+$manual = enrol_get_plugin('manual');
+$roles = ['students' => $DB->get_field('role', 'id', ['shortname' => 'student']), 'teachers' => $DB->get_field('role', 'id', ['shortname' => 'editingteacher'])];
+foreach ($config->courses as $c) {
+    $course = $DB->get_record('course', ['shortname' => $c->shortname]);
+    if (!$course) {
+        $course = create_course((object)['shortname' => $c->shortname, 'fullname' => $c->fullname, 'category' => core_course_category::get_default()->id]);
+    }
+    $instance = $DB->get_record('enrol', ['courseid' => $course->id, 'enrol' => 'manual']);
+    foreach ($roles as $list => $roleid) {
+        foreach ((array)$c->$list as $username) {
+            $manual->enrol_user($instance, $result['users'][$username], $roleid);
+        }
+    }
+    $result['courses'][$c->shortname] = (int)$course->id;
+}
+
+echo "\n" . json_encode($result) . "\n";
+`
@@ -0,0 +1,305 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrQueued is returned by a call that changes data when moodle could not be
+// reached and the call was added to the mutation queue. See SetMutationQueue.
+var ErrQueued = errors.New("queued until moodle is reachable")
+
+// IdempotencyKeyHeader is sent with each call that changes data while a
+// mutation queue is in use. A call that reached moodle but whose response
+// was lost is replayed with the same key, so that a proxy in front of moodle
+// can discard the duplicate.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Mutation is a call that changes data, held until it can be sent to moodle
+type Mutation struct {
+	// Id is the idempotency key of the call
+	Id       string
+	Function string
+	Params   url.Values
+	Queued   time.Time
+}
+
+// MutationFailure records a queued call that moodle rejected when it was
+// replayed, for example because the record it changes no longer exists.
+type MutationFailure struct {
+	Mutation Mutation
+	Err      error
+}
+
+// MutationStore holds queued calls in the order they were made. Parameters
+// are stored as sent to moodle, excluding the token, so a persistent store
+// may hold passwords and other personal details.
+type MutationStore interface {
+	Append(m Mutation) error
+	Pending() ([]Mutation, error)
+	Remove(id string) error
+}
+
+// SetMutationQueue enables the mutation queue. When moodle can not be
+// reached, calls that change data are added to the store and return an
+// error wrapping ErrQueued instead of failing. Queued calls are replayed in
+// order by ReplayMutations, and before any further call that changes data,
+// so that changes are never applied out of order. Nil disables the queue.
+//
+// The queue does not hold the token of each call, so copies made by
+// WithToken do not queue calls, and fail as usual when moodle is
+// unreachable.
+//
+//	api.SetMutationQueue(moodle.NewFileMutationStore("/var/lib/sync/moodle-queue.json"))
+//	...
+//	if err := api.SetRole(personId, roleId, courseId); errors.Is(err, moodle.ErrQueued) {
+//		// Will be sent when moodle is reachable
+//	}
+func (m *MoodleApi) SetMutationQueue(store MutationStore) {
+	if store == nil {
+		m.mutations = nil
+		return
+	}
+	m.mutations = &mutationQueue{store: store}
+}
+
+// mutationQueue holds the store, and ensures one goroutine replays at a time
+type mutationQueue struct {
+	mu    sync.Mutex
+	store MutationStore
+}
+
+// ReplayMutations sends queued calls to moodle in the order they were made.
+// Replay stops if moodle is still unreachable, returning an error wrapping
+// ErrQueued. Calls rejected by moodle are removed from the queue and
+// returned as failures.
+func (m *MoodleApi) ReplayMutations() (int, []MutationFailure, error) {
+	if m.mutations == nil {
+		return 0, nil, nil
+	}
+	m.mutations.mu.Lock()
+	defer m.mutations.mu.Unlock()
+	return m.replay()
+}
+
+// replay sends queued calls, the queue must be locked
+func (m *MoodleApi) replay() (int, []MutationFailure, error) {
+	pending, err := m.mutations.store.Pending()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sent := 0
+	var failures []MutationFailure
+	for _, mutation := range pending {
+		_, err := m.sendWithRetry(mutation.Function, copyValues(mutation.Params), http.Header{IdempotencyKeyHeader: {mutation.Id}})
+		if unreachable(err) {
+			return sent, failures, wrapError("Moodle is unreachable, "+errorMessage(err), ErrQueued)
+		}
+		if err != nil {
+			failures = append(failures, MutationFailure{Mutation: mutation, Err: err})
+		} else {
+			sent++
+		}
+		if err := m.mutations.store.Remove(mutation.Id); err != nil {
+			return sent, failures, err
+		}
+		m.info("Replayed queued call to %s from %s", mutation.Function, mutation.Queued.Format(time.RFC3339))
+	}
+	return sent, failures, nil
+}
+
+// sendOrQueue sends a call that changes data, queueing it if moodle can not
+// be reached or earlier calls are still queued. Calls that change data are
+// sent one at a time so that they reach moodle in order.
+func (m *MoodleApi) sendOrQueue(function string, params url.Values) (string, error) {
+	m.mutations.mu.Lock()
+	defer m.mutations.mu.Unlock()
+
+	mutation := Mutation{Id: newRequestId(), Function: function, Params: copyValues(params), Queued: time.Now()}
+
+	pending, err := m.mutations.store.Pending()
+	if err != nil {
+		return "", err
+	}
+	if len(pending) > 0 {
+		_, failures, err := m.replay()
+		for _, f := range failures {
+			m.logError("Queued call to %s failed: %v", f.Mutation.Function, f.Err)
+		}
+		if err != nil {
+			return "", m.enqueue(mutation, err)
+		}
+	}
+
+	body, err := m.sendWithRetry(function, params, http.Header{IdempotencyKeyHeader: {mutation.Id}})
+	if unreachable(err) {
+		return "", m.enqueue(mutation, err)
+	}
+	return body, err
+}
+
+func (m *MoodleApi) enqueue(mutation Mutation, cause error) error {
+	if err := m.mutations.store.Append(mutation); err != nil {
+		return err
+	}
+	m.warn("Queued call to %s: %s", mutation.Function, errorMessage(cause))
+	if errors.Is(cause, ErrQueued) {
+		return cause
+	}
+	return wrapError("Moodle is unreachable, "+errorMessage(cause), ErrQueued)
+}
+
+// unreachable reports whether a call failed without reaching moodle
+func unreachable(err error) bool {
+	var merr *MoodleError
+	if !errors.As(err, &merr) || merr.Exception != "" {
+		return false
+	}
+	switch merr.StatusCode {
+	case 0, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// copyValues copies parameters, leaving out those added by send
+func copyValues(params url.Values) url.Values {
+	c := url.Values{}
+	for k, v := range params {
+		switch k {
+		case "wstoken", "wsfunction", "moodlewsrestformat":
+			continue
+		}
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// MemoryMutationStore is a MutationStore held in memory. Queued calls are
+// lost if the program exits.
+type MemoryMutationStore struct {
+	mu        sync.Mutex
+	mutations []Mutation
+}
+
+func (s *MemoryMutationStore) Append(m Mutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mutations = append(s.mutations, m)
+	return nil
+}
+
+func (s *MemoryMutationStore) Pending() ([]Mutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mutation(nil), s.mutations...), nil
+}
+
+func (s *MemoryMutationStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.mutations {
+		if m.Id == id {
+			s.mutations = append(s.mutations[:i], s.mutations[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// FileMutationStore is a MutationStore saved as a json file, so that queued
+// calls survive a restart. The file is replaced on each change.
+type FileMutationStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileMutationStore returns a store saved to path. The file is created
+// when the first call is queued.
+func NewFileMutationStore(path string) *FileMutationStore {
+	return &FileMutationStore{path: path}
+}
+
+func (s *FileMutationStore) Append(m Mutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutations, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.write(append(mutations, m))
+}
+
+func (s *FileMutationStore) Pending() ([]Mutation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *FileMutationStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mutations, err := s.read()
+	if err != nil {
+		return err
+	}
+	for i, m := range mutations {
+		if m.Id == id {
+			return s.write(append(mutations[:i], mutations[i+1:]...))
+		}
+	}
+	return nil
+}
+
+func (s *FileMutationStore) read() ([]Mutation, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var mutations []Mutation
+	if err := json.Unmarshal(data, &mutations); err != nil {
+		return nil, errors.New("Mutation queue " + s.path + " is corrupt. " + err.Error())
+	}
+	return mutations, nil
+}
+
+// write replaces the file using a rename, so that a crash while writing
+// does not lose the queue
+func (s *FileMutationStore) write(mutations []Mutation) error {
+	data, err := json.Marshal(mutations)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
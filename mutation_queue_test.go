@@ -0,0 +1,174 @@
+package moodle
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// unreachableLookupUrl fails every request while down is set
+type unreachableLookupUrl struct {
+	*testLookupUrl
+	down bool
+}
+
+func (u *unreachableLookupUrl) Do(method, l string, form url.Values, header http.Header) (string, int, string, error) {
+	if u.down {
+		return "", 0, "", errors.New("dial tcp: connection refused")
+	}
+	return u.testLookupUrl.Do(method, l, form, header)
+}
+
+func TestMutationQueue(t *testing.T) {
+
+	fetch := &unreachableLookupUrl{testLookupUrl: newTestLookupUrl(map[string]string{
+		"core_user_update_users": "",
+	}), down: true}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	dir, err := ioutil.TempDir("", "moodle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileMutationStore(filepath.Join(dir, "queue.json"))
+	api.SetMutationQueue(store)
+
+	if err := api.SetUserAttribute(7, "city", "Perth"); !errors.Is(err, ErrQueued) {
+		t.Fatalf("Expected call to be queued, found %v", err)
+	}
+	if err := api.SetUserAttribute(7, "city", "Darwin"); !errors.Is(err, ErrQueued) {
+		t.Fatalf("Expected call to be queued behind the first, found %v", err)
+	}
+
+	pending, _ := store.Pending()
+	if len(pending) != 2 || pending[0].Params.Get("users[0][city]") != "Perth" || pending[0].Params.Get("wstoken") != "" {
+		t.Fatalf("Unexpected queue: %+v", pending)
+	}
+
+	// A new store reads the queue saved by the first
+	api.SetMutationQueue(NewFileMutationStore(filepath.Join(dir, "queue.json")))
+	fetch.down = false
+	sent, failures, err := api.ReplayMutations()
+	if err != nil || sent != 2 || len(failures) != 0 {
+		t.Fatalf("Expected two calls to be replayed, found %d %v %v", sent, failures, err)
+	}
+	if len(fetch.requests) != 2 {
+		t.Fatalf("Expected two requests, found %d", len(fetch.requests))
+	}
+	if fetch.requests[0].Get("users[0][city]") != "Perth" || fetch.requests[1].Get("users[0][city]") != "Darwin" {
+		t.Errorf("Expected calls to be replayed in order, found %v", fetch.requests)
+	}
+	if fetch.headers[0].Get(IdempotencyKeyHeader) != pending[0].Id {
+		t.Errorf("Expected the idempotency key to be sent, found %v", fetch.headers[1])
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected the queue to be empty, found %+v", pending)
+	}
+}
+
+func TestMutationQueueOrder(t *testing.T) {
+
+	fetch := &unreachableLookupUrl{testLookupUrl: newTestLookupUrl(map[string]string{
+		"core_user_update_users": "",
+	}), down: true}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	store := &MemoryMutationStore{}
+	api.SetMutationQueue(store)
+
+	api.SetUserAttribute(7, "city", "Perth")
+	fetch.down = false
+
+	// The queued call is sent before the new one
+	if err := api.SetUserAttribute(7, "city", "Darwin"); err != nil {
+		t.Fatalf("SetUserAttribute failed: %v", err)
+	}
+	if len(fetch.requests) != 2 || fetch.requests[0].Get("users[0][city]") != "Perth" || fetch.requests[1].Get("users[0][city]") != "Darwin" {
+		t.Errorf("Expected queued call to be sent first, found %v", fetch.requests)
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected the queue to be empty, found %+v", pending)
+	}
+}
+
+func TestMutationQueueReadOnly(t *testing.T) {
+
+	fetch := &unreachableLookupUrl{testLookupUrl: newTestLookupUrl(map[string]string{
+		"core_user_update_users":                     "",
+		"core_competency_list_competency_frameworks": `[{"id":3,"shortname":"Nursing"}]`,
+		"local_reports_list_overdue":                 `[]`,
+	}), down: true}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	store := &MemoryMutationStore{}
+	api.SetMutationQueue(store)
+
+	if _, err := api.GetCompetencyFrameworks(); err == nil || errors.Is(err, ErrQueued) {
+		t.Errorf("Expected a read to fail rather than be queued while moodle is unreachable, found %v", err)
+	}
+	api.SetReadOnlyFunctions("local_reports_list_overdue")
+	if err := api.Call(nil, "local_reports_list_overdue", nil, nil); err == nil || errors.Is(err, ErrQueued) {
+		t.Errorf("Expected a function marked read only not to be queued, found %v", err)
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Fatalf("Expected reads not to be queued, found %+v", pending)
+	}
+
+	// Reads are sent while calls that change data are still queued
+	api.SetUserAttribute(7, "city", "Perth")
+	fetch.down = false
+	frameworks, err := api.GetCompetencyFrameworks()
+	if err != nil || len(frameworks) != 1 {
+		t.Fatalf("Expected frameworks to be read, found %v %v", frameworks, err)
+	}
+	if pending, _ := store.Pending(); len(pending) != 1 || len(fetch.requests) != 1 {
+		t.Errorf("Expected a read not to replay the queue, found %d queued and %d sent", len(pending), len(fetch.requests))
+	}
+}
+
+func TestMutationQueueWithToken(t *testing.T) {
+
+	fetch := &unreachableLookupUrl{testLookupUrl: newTestLookupUrl(map[string]string{
+		"core_user_update_users": "",
+	}), down: true}
+	api := NewMoodleApi("https://moodle.example.com/", "service")
+	api.SetUrlFetcher(fetch)
+	store := &MemoryMutationStore{}
+	api.SetMutationQueue(store)
+
+	err := api.WithToken("student").SetUserAttribute(7, "city", "Perth")
+	if err == nil || errors.Is(err, ErrQueued) {
+		t.Errorf("Expected a call made with another token to fail rather than be queued, found %v", err)
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("Expected nothing to be queued, found %+v", pending)
+	}
+}
+
+func TestMutationQueueRetry(t *testing.T) {
+
+	fetch := &failingLookupUrl{
+		testLookupUrl: newTestLookupUrl(map[string]string{"core_user_update_users": ""}),
+		failures: map[string][]string{
+			"core_user_update_users": {`{"exception":"moodle_exception","errorcode":"locktimeout","message":"Unable to obtain lock"}`},
+		},
+	}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	api.SetMutationQueue(&MemoryMutationStore{})
+	api.SetRetryPolicy(&RetryPolicy{Delay: time.Millisecond})
+
+	if err := api.SetUserAttribute(7, "city", "Perth"); err != nil {
+		t.Fatalf("Expected the call to be retried, found %v", err)
+	}
+	if len(fetch.requests) != 2 || fetch.headers[0].Get(IdempotencyKeyHeader) != fetch.headers[1].Get(IdempotencyKeyHeader) {
+		t.Errorf("Expected the call to be repeated with the same idempotency key, found %d calls", len(fetch.requests))
+	}
+}
@@ -0,0 +1,50 @@
+package moodle
+
+import "sync"
+
+// forEachParallel calls fn with each index from 0 to n-1, using up to
+// concurrency goroutines, or one if concurrency is less than one. Once fn
+// returns an error no further calls are started, and the first error is
+// returned after the calls in progress finish.
+func forEachParallel(n, concurrency int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var first error
+	var once sync.Once
+	done := make(chan struct{})
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				select {
+				case <-done:
+					continue
+				default:
+				}
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						first = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+queue:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-done:
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,56 @@
+package moodle
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachParallel(t *testing.T) {
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	seen := make([]bool, 20)
+	err := forEachParallel(len(seen), 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		seen[i] = true
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if most > 3 {
+		t.Errorf("Expected at most 3 calls at once, found %d", most)
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("Expected index %d to be called", i)
+		}
+	}
+
+	failed := errors.New("failed")
+	calls := 0
+	err = forEachParallel(20, 1, func(i int) error {
+		calls++
+		if i == 2 {
+			return failed
+		}
+		return nil
+	})
+	if err != failed {
+		t.Errorf("Expected the first error to be returned, found %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected no calls after the error, found %d calls", calls)
+	}
+}
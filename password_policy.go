@@ -0,0 +1,301 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy holds the password rules configured in moodle under
+// Site administration > Security > Site security settings. A zero value
+// rule is not enforced.
+//
+// The remaining fields control how RandomPasswordForPolicy generates
+// passwords. The zero values generate ten characters, excluding ambiguous
+// characters, with a hyphen after every fifth character.
+type PasswordPolicy struct {
+	MinLength               int
+	MinDigits               int
+	MinLower                int
+	MinUpper                int
+	MinNonAlphanumeric      int
+	MaxConsecutiveIdentical int
+
+	// Length is the number of generated characters, not counting
+	// separators. Longer passwords are generated if MinLength requires it.
+	Length int
+
+	// Separator is inserted every SeparatorEvery characters to make
+	// passwords easier to read. Defaults to "-" every 5 characters.
+	Separator      string
+	SeparatorEvery int
+	NoSeparator    bool
+
+	// Symbols are the ASCII characters used when MinNonAlphanumeric
+	// requires more non-alphanumeric characters than the separators
+	// provide. Defaults to "#*!".
+	Symbols string
+
+	// Exclude lists characters that are never generated. Ambiguous
+	// characters (AmbiguousCharacters) are also excluded unless
+	// AllowAmbiguous is set.
+	Exclude        string
+	AllowAmbiguous bool
+}
+
+// AmbiguousCharacters are easily confused when a password is read from an
+// email or printed letter.
+const AmbiguousCharacters = "IOlo01"
+
+// DefaultPasswordPolicy matches the policy of a new moodle installation.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:          8,
+	MinDigits:          1,
+	MinLower:           1,
+	MinUpper:           1,
+	MinNonAlphanumeric: 1,
+}
+
+// Validate checks a password against the policy, returning an error that
+// describes the first rule the password does not satisfy.
+func (p *PasswordPolicy) Validate(password string) error {
+	var length, digits, lower, upper, other, consecutive int
+	var last rune
+	for _, c := range password {
+		length++
+		switch {
+		case unicode.IsDigit(c):
+			digits++
+		case unicode.IsLower(c):
+			lower++
+		case unicode.IsUpper(c):
+			upper++
+		default:
+			other++
+		}
+		if c == last {
+			consecutive++
+		} else {
+			consecutive = 1
+		}
+		last = c
+		if p.MaxConsecutiveIdentical > 0 && consecutive > p.MaxConsecutiveIdentical {
+			return errors.New(fmt.Sprintf("Password must not have more than %d consecutive identical characters", p.MaxConsecutiveIdentical))
+		}
+	}
+
+	if length < p.MinLength {
+		return errors.New(fmt.Sprintf("Password must have at least %d characters", p.MinLength))
+	}
+	if digits < p.MinDigits {
+		return errors.New(fmt.Sprintf("Password must have at least %d digit(s)", p.MinDigits))
+	}
+	if lower < p.MinLower {
+		return errors.New(fmt.Sprintf("Password must have at least %d lower case letter(s)", p.MinLower))
+	}
+	if upper < p.MinUpper {
+		return errors.New(fmt.Sprintf("Password must have at least %d upper case letter(s)", p.MinUpper))
+	}
+	if other < p.MinNonAlphanumeric {
+		return errors.New(fmt.Sprintf("Password must have at least %d non-alphanumeric character(s)", p.MinNonAlphanumeric))
+	}
+	return nil
+}
+
+// RandomPasswordForPolicy generates a random password that satisfies the
+// policy. Adjacent characters are never repeated or incrementing. A nil
+// policy uses DefaultPasswordPolicy. Returns an error if the policy can not be
+// satisfied, for example when the symbols are all alphanumeric.
+func RandomPasswordForPolicy(policy *PasswordPolicy) (string, error) {
+	if policy == nil {
+		policy = &DefaultPasswordPolicy
+	}
+	random := rand.New(NewCryptoSeededSource())
+
+	separator := policy.Separator
+	if separator == "" {
+		separator = "-"
+	}
+	every := policy.SeparatorEvery
+	if every <= 0 {
+		every = 5
+	}
+	if policy.NoSeparator {
+		separator = ""
+	}
+	symbols := policy.Symbols
+	if symbols == "" {
+		symbols = "#*!"
+	}
+
+	upper := policy.characters("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	lower := policy.characters("abcdefghijklmnopqrstuvwxyz")
+	digits := policy.characters("0123456789")
+	symbols = policy.characters(symbols)
+	all := upper + lower + digits
+
+	// Only non-alphanumeric separators count towards MinNonAlphanumeric
+	separatorSymbols := 0
+	for _, c := range separator {
+		if !unicode.IsDigit(c) && !unicode.IsLower(c) && !unicode.IsUpper(c) {
+			separatorSymbols++
+		}
+	}
+
+	size := policy.Length
+	if size <= 0 {
+		size = 10
+	}
+	for attempt := 0; attempt < maxPasswordAttempts; attempt++ {
+		// Grow the password if the policy can not be met at this size
+		if attempt > 0 && attempt%10 == 0 {
+			size++
+		}
+
+		separators := 0
+		if separator != "" {
+			separators = (size - 1) / every
+		}
+		separatorLength := separators * utf8.RuneCountInString(separator)
+		extraSymbols := policy.MinNonAlphanumeric - separators*separatorSymbols
+		if extraSymbols < 0 {
+			extraSymbols = 0
+		}
+		if policy.MinDigits+policy.MinLower+policy.MinUpper+extraSymbols > size ||
+			size+separatorLength < policy.MinLength {
+			size++
+			continue
+		}
+
+		var password []byte
+		for i := 0; i < policy.MinDigits; i++ {
+			password = append(password, digits[random.Intn(len(digits))])
+		}
+		for i := 0; i < policy.MinLower; i++ {
+			password = append(password, lower[random.Intn(len(lower))])
+		}
+		for i := 0; i < policy.MinUpper; i++ {
+			password = append(password, upper[random.Intn(len(upper))])
+		}
+		for i := 0; i < extraSymbols; i++ {
+			password = append(password, symbols[random.Intn(len(symbols))])
+		}
+		for len(password) < size {
+			password = append(password, all[random.Intn(len(all))])
+		}
+		random.Shuffle(len(password), func(i, j int) { password[i], password[j] = password[j], password[i] })
+
+		if !passwordIsReadable(password) {
+			continue
+		}
+
+		var s strings.Builder
+		for i, c := range password {
+			if i > 0 && i%every == 0 {
+				s.WriteString(separator)
+			}
+			s.WriteByte(c)
+		}
+		if policy.Validate(s.String()) == nil {
+			return s.String(), nil
+		}
+	}
+	return "", errors.New("Unable to generate a password that satisfies the password policy")
+}
+
+// maxPasswordAttempts limits the passwords RandomPasswordForPolicy generates
+// before giving up on a policy that can not be satisfied
+const maxPasswordAttempts = 1000
+
+// characters removes the excluded characters from a set of characters. If
+// every character would be removed the set is returned unchanged.
+func (p *PasswordPolicy) characters(set string) string {
+	exclude := p.Exclude
+	if !p.AllowAmbiguous {
+		exclude = exclude + AmbiguousCharacters
+	}
+	var s strings.Builder
+	for _, c := range set {
+		if !strings.ContainsRune(exclude, c) {
+			s.WriteRune(c)
+		}
+	}
+	if s.Len() == 0 {
+		return set
+	}
+	return s.String()
+}
+
+// passwordIsReadable checks there is no series of repeated or incrementing
+// characters in a password.
+func passwordIsReadable(password []byte) bool {
+	for i := 1; i < len(password); i++ {
+		if password[i] == password[i-1] || password[i] == password[i-1]+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// SetPasswordPolicy sets the policy used to check passwords passed to
+// ResetPassword, and to generate passwords in ResetPasswordWithEmail. A
+// policy fetched with GetPasswordPolicy may be adjusted to change how
+// passwords are generated before it is set.
+func (m *MoodleApi) SetPasswordPolicy(policy *PasswordPolicy) {
+	m.passwordPolicy = policy
+}
+
+var passwordPolicyRules = []struct {
+	pattern *regexp.Regexp
+	set     func(p *PasswordPolicy, n int)
+}{
+	{regexp.MustCompile(`at least (\d+) characters`), func(p *PasswordPolicy, n int) { p.MinLength = n }},
+	{regexp.MustCompile(`at least (\d+) digit`), func(p *PasswordPolicy, n int) { p.MinDigits = n }},
+	{regexp.MustCompile(`at least (\d+) lower case`), func(p *PasswordPolicy, n int) { p.MinLower = n }},
+	{regexp.MustCompile(`at least (\d+) upper case`), func(p *PasswordPolicy, n int) { p.MinUpper = n }},
+	{regexp.MustCompile(`at least (\d+) non-alphanumeric`), func(p *PasswordPolicy, n int) { p.MinNonAlphanumeric = n }},
+}
+
+// GetPasswordPolicy fetches the password policy from moodle. Moodle only
+// publishes the policy through "core_auth_get_signup_settings", as a
+// sentence such as "The password must have at least 8 characters, at least
+// 1 digit(s)...", so this requires email based self registration to be
+// enabled and the site language to be English. Returns an empty policy if
+// the site does not enforce a password policy.
+func (m *MoodleApi) GetPasswordPolicy() (*PasswordPolicy, error) {
+	body, err := m.call("core_auth_get_signup_settings", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		PasswordPolicy string `json:"passwordpolicy"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return parsePasswordPolicy(result.PasswordPolicy), nil
+}
+
+func parsePasswordPolicy(description string) *PasswordPolicy {
+	policy := &PasswordPolicy{}
+	description = strings.ToLower(description)
+	for _, rule := range passwordPolicyRules {
+		if match := rule.pattern.FindStringSubmatch(description); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			rule.set(policy, n)
+		}
+	}
+	return policy
+}
@@ -0,0 +1,132 @@
+package moodle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"Abcde-fgh1", true},
+		{"Ab1-", false},
+		{"abcde-fgh1", false},
+		{"ABCDE-FGH1", false},
+		{"Abcde-fghi", false},
+		{"Abcdefghi1", false},
+	}
+	for _, test := range tests {
+		err := DefaultPasswordPolicy.Validate(test.password)
+		if test.valid && err != nil {
+			t.Errorf("Expected %q to be valid, found: %v", test.password, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %q to be invalid", test.password)
+		}
+	}
+
+	strict := &PasswordPolicy{MaxConsecutiveIdentical: 2}
+	if strict.Validate("aab") != nil || strict.Validate("aaab") == nil {
+		t.Errorf("Expected consecutive identical characters to be limited to two")
+	}
+
+	for i := 0; i < 50; i++ {
+		if p := RandomPassword(); DefaultPasswordPolicy.Validate(p) != nil {
+			t.Errorf("Random password %q does not match the default policy", p)
+		}
+	}
+	for _, policy := range []*PasswordPolicy{{MinLength: 24, MinDigits: 3, MinNonAlphanumeric: 3}, {MaxConsecutiveIdentical: 1}} {
+		for i := 0; i < 50; i++ {
+			p, err := RandomPasswordForPolicy(policy)
+			if err != nil {
+				t.Fatalf("Failed to generate a password: %v", err)
+			}
+			if err := policy.Validate(p); err != nil {
+				t.Errorf("Random password %q does not match policy: %v", p, err)
+			}
+		}
+	}
+}
+
+func TestRandomPasswordOptions(t *testing.T) {
+
+	p := RandomPassword()
+	if len(p) != 11 || p[5] != '-' {
+		t.Errorf("Expected default password to be ten characters split by a hyphen, found %q", p)
+	}
+
+	policy := &PasswordPolicy{MinLength: 16, MinDigits: 2, MinUpper: 2, MinNonAlphanumeric: 3, NoSeparator: true, Symbols: "@", Exclude: "ABCDEFG", AllowAmbiguous: true}
+	for i := 0; i < 50; i++ {
+		p, err := RandomPasswordForPolicy(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate a password: %v", err)
+		}
+		if len(p) < 16 {
+			t.Errorf("Expected at least 16 characters, found %q", p)
+		}
+		if strings.ContainsAny(p, "ABCDEFG-") {
+			t.Errorf("Expected excluded characters and separators to be omitted, found %q", p)
+		}
+		if strings.Count(p, "@") < 3 {
+			t.Errorf("Expected three symbols, found %q", p)
+		}
+	}
+
+	policy = &PasswordPolicy{Length: 12, Separator: ".", SeparatorEvery: 4}
+	for i := 0; i < 50; i++ {
+		p, err := RandomPasswordForPolicy(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate a password: %v", err)
+		}
+		if len(p) != 14 || p[4] != '.' || p[9] != '.' {
+			t.Errorf("Expected twelve characters in groups of four, found %q", p)
+		}
+		if strings.ContainsAny(p, AmbiguousCharacters) {
+			t.Errorf("Expected ambiguous characters to be excluded, found %q", p)
+		}
+	}
+}
+
+func TestRandomPasswordUnsatisfiablePolicy(t *testing.T) {
+
+	policy := &PasswordPolicy{MinNonAlphanumeric: 2, Separator: "x"}
+	for i := 0; i < 50; i++ {
+		p, err := RandomPasswordForPolicy(policy)
+		if err != nil {
+			t.Fatalf("Failed to generate a password: %v", err)
+		}
+		if err := policy.Validate(p); err != nil {
+			t.Errorf("Expected symbols to be added when the separator is alphanumeric, found %q: %v", p, err)
+		}
+	}
+
+	policy = &PasswordPolicy{MinNonAlphanumeric: 1, Symbols: "ab", NoSeparator: true}
+	if p, err := RandomPasswordForPolicy(policy); err == nil {
+		t.Errorf("Expected an error for a policy that can not be satisfied, found %q", p)
+	}
+}
+
+func TestGetPasswordPolicy(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_auth_get_signup_settings": `{"namefields":["firstname","lastname"],"passwordpolicy":"The password must have at least 12 characters, at least 2 digit(s), at least 1 lower case letter(s), at least 1 upper case letter(s), at least 1 non-alphanumeric character(s) such as *, -, or #","warnings":[]}`,
+	}))
+
+	policy, err := api.GetPasswordPolicy()
+	if err != nil {
+		t.Fatalf("GetPasswordPolicy failed: %v", err)
+	}
+	expected := PasswordPolicy{MinLength: 12, MinDigits: 2, MinLower: 1, MinUpper: 1, MinNonAlphanumeric: 1}
+	if *policy != expected {
+		t.Errorf("Expected policy %+v, found %+v", expected, *policy)
+	}
+
+	api.SetPasswordPolicy(policy)
+	if err := api.ResetPassword(1, "Short-1"); err == nil {
+		t.Errorf("Expected ResetPassword to reject a password that does not match the policy")
+	}
+}
@@ -0,0 +1,125 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// peopleByFieldBatchSize is the number of values looked up by each call
+const peopleByFieldBatchSize = 100
+
+// GetPeopleByUsernames fetches the accounts with the usernames, using one
+// call for every hundred usernames. The map is keyed by the usernames as
+// requested, usernames without an account are omitted.
+func (m *MoodleApi) GetPeopleByUsernames(usernames []string) (map[string]Person, error) {
+	people, err := m.getPeopleByField("username", usernames)
+	if err != nil {
+		return nil, err
+	}
+	return peopleByValue(usernames, people, func(p *Person) string { return p.Username }), nil
+}
+
+// GetPeopleByEmails fetches the accounts with the email addresses, using one
+// call for every hundred addresses. The map is keyed by the addresses as
+// requested, addresses without an account are omitted. If several accounts
+// share an address the first is returned.
+func (m *MoodleApi) GetPeopleByEmails(emails []string) (map[string]Person, error) {
+	people, err := m.getPeopleByField("email", emails)
+	if err != nil {
+		return nil, err
+	}
+	return peopleByValue(emails, people, func(p *Person) string { return p.Email }), nil
+}
+
+// peopleByValue matches people to the requested values, ignoring case as
+// moodle does
+func peopleByValue(values []string, people []Person, field func(*Person) string) map[string]Person {
+	found := make(map[string]Person)
+	for i := range people {
+		key := strings.ToLower(field(&people[i]))
+		if _, ok := found[key]; !ok {
+			found[key] = people[i]
+		}
+	}
+	matches := make(map[string]Person)
+	for _, v := range values {
+		if p, ok := found[strings.ToLower(strings.TrimSpace(v))]; ok {
+			matches[v] = p
+		}
+	}
+	return matches
+}
+
+// getPeopleByField fetches the accounts with any of the values of a field,
+// such as "id", "username" or "email", 100 values at a time
+func (m *MoodleApi) getPeopleByField(field string, values []string) ([]Person, error) {
+	type Result struct {
+		Id                   UserID        `json:"id"`
+		Username             string        `json:"username"`
+		FirstName            string        `json:"firstname"`
+		LastName             string        `json:"lastname"`
+		Email                string        `json:"email"`
+		ProfileImageUrl      string        `json:"profileimageurl,omitempty"`
+		ProfileImageUrlSmall string        `json:"profileimageurlsmall,omitempty"`
+		Lang                 string        `json:"lang"`
+		Auth                 string        `json:"auth"`
+		Suspended            bool          `json:"suspended"`
+		Confirmed            *bool         `json:"confirmed"`
+		FirstAccess          int64         `json:"firstaccess"`
+		LastAccess           int64         `json:"lastaccess"`
+		CustomFields         []CustomField `json:"customfields"`
+	}
+
+	var people []Person
+	for start := 0; start < len(values); start += peopleByFieldBatchSize {
+		end := start + peopleByFieldBatchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		params := url.Values{"field": {field}}
+		for i, v := range values[start:end] {
+			params.Set(fmt.Sprintf("values[%d]", i), strings.TrimSpace(v))
+		}
+		body, err := m.call("core_user_get_users_by_field", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []Result
+		if err := json.Unmarshal([]byte(body), &results); err != nil {
+			return nil, errors.New("Server returned unexpected response. " + err.Error())
+		}
+
+		raw := m.rawItems(body, "")
+		for n, r := range results {
+			if strings.Index(r.ProfileImageUrl, "gravatar") > 0 {
+				r.ProfileImageUrl = ""
+				r.ProfileImageUrlSmall = ""
+			}
+			p := Person{
+				MoodleId:             r.Id,
+				Username:             r.Username,
+				FirstName:            r.FirstName,
+				LastName:             r.LastName,
+				Email:                r.Email,
+				ProfileImageUrl:      r.ProfileImageUrl,
+				ProfileImageUrlSmall: r.ProfileImageUrlSmall,
+				Lang:                 r.Lang,
+				Auth:                 r.Auth,
+				Suspended:            r.Suspended,
+				Unconfirmed:          unconfirmed(r.Confirmed),
+				FirstAccess:          m.unixTime(r.FirstAccess),
+				LastAccess:           m.unixTime(r.LastAccess),
+				Raw:                  rawItem(raw, n),
+			}
+			for _, c := range r.CustomFields {
+				p.CustomField = append(p.CustomField, CustomField{Name: c.Name, Value: c.Value})
+			}
+			people = append(people, p)
+		}
+	}
+	return people, nil
+}
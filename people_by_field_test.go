@@ -0,0 +1,46 @@
+package moodle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetPeopleByUsernames(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_users_by_field": `[{"id":7,"username":"ann","firstname":"Ann","lastname":"Lee","email":"Ann@example.com","suspended":false},{"id":8,"username":"bo","firstname":"Bo","lastname":"Chan","email":"bo@example.com","suspended":true}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	people, err := api.GetPeopleByUsernames([]string{"Ann", "bo", "missing"})
+	if err != nil {
+		t.Fatalf("GetPeopleByUsernames failed: %v", err)
+	}
+	if len(people) != 2 || people["Ann"].MoodleId != 7 || !people["bo"].Suspended {
+		t.Errorf("Expected people keyed by the requested usernames, found %+v", people)
+	}
+	if _, ok := people["missing"]; ok {
+		t.Errorf("Expected unknown usernames to be omitted")
+	}
+	if r := fetch.last(); r.Get("field") != "username" || r.Get("values[2]") != "missing" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	people, err = api.GetPeopleByEmails([]string{"ann@example.com"})
+	if err != nil || people["ann@example.com"].MoodleId != 7 {
+		t.Errorf("Expected email addresses to match ignoring case, found %+v %v", people, err)
+	}
+
+	fetch.requests = nil
+	var usernames []string
+	for i := 0; i < 250; i++ {
+		usernames = append(usernames, fmt.Sprintf("user%d", i))
+	}
+	if _, err := api.GetPeopleByUsernames(usernames); err != nil {
+		t.Fatalf("GetPeopleByUsernames failed: %v", err)
+	}
+	if len(fetch.requests) != 3 {
+		t.Errorf("Expected three calls for 250 usernames, found %d", len(fetch.requests))
+	}
+}
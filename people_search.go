@@ -0,0 +1,58 @@
+package moodle
+
+// PeopleSearchOptions filter the accounts returned by FindPeople. Moodle
+// never returns deleted accounts, so they are always excluded.
+type PeopleSearchOptions struct {
+	// ExcludeSuspended returns only accounts that can sign in
+	ExcludeSuspended bool
+
+	// OnlySuspended returns only suspended accounts
+	OnlySuspended bool
+
+	// ExcludeUnconfirmed leaves out self registered accounts whose email
+	// address has not been confirmed
+	ExcludeUnconfirmed bool
+}
+
+// FindPeople fetches moodle accounts that have a specific field value, as
+// FindPeopleByAttribute does, keeping only the accounts allowed by the
+// options. For example, to list only active accounts:
+//
+//	api.FindPeople("email", "jane@example.com", &PeopleSearchOptions{ExcludeSuspended: true})
+//
+// Returns nil if no accounts match.
+func (m *MoodleApi) FindPeople(attribute, value string, options *PeopleSearchOptions) ([]Person, error) {
+	people, err := m.FindPeopleByAttribute(attribute, value)
+	if err != nil || options == nil {
+		return people, err
+	}
+
+	var matches []Person
+	for _, p := range people {
+		if options.allows(&p) {
+			matches = append(matches, p)
+		}
+	}
+	return matches, nil
+}
+
+// allows reports whether a person passes the filters of the options
+func (o *PeopleSearchOptions) allows(p *Person) bool {
+	if o.ExcludeSuspended && p.Suspended {
+		return false
+	}
+	if o.OnlySuspended && !p.Suspended {
+		return false
+	}
+	if o.ExcludeUnconfirmed && p.Unconfirmed {
+		return false
+	}
+	return true
+}
+
+// unconfirmed interprets the confirmed flag of an account. Moodle only
+// includes the flag for callers allowed to update accounts, so a missing
+// flag is treated as confirmed.
+func unconfirmed(confirmed *bool) bool {
+	return confirmed != nil && !*confirmed
+}
@@ -0,0 +1,47 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestFindPeople(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_users": `{"users":[` +
+			`{"id":7,"username":"ann","firstname":"Ann","lastname":"Lee","suspended":false,"confirmed":true},` +
+			`{"id":8,"username":"bo","firstname":"Bo","lastname":"Lee","suspended":true,"confirmed":true},` +
+			`{"id":9,"username":"cy","firstname":"Cy","lastname":"Lee","suspended":false,"confirmed":false},` +
+			`{"id":10,"username":"di","firstname":"Di","lastname":"Lee","suspended":false}]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	ids := func(people []Person) []UserID {
+		var ids []UserID
+		for _, p := range people {
+			ids = append(ids, p.MoodleId)
+		}
+		return ids
+	}
+
+	people, err := api.FindPeople("lastname", "Lee", nil)
+	if err != nil {
+		t.Fatalf("FindPeople failed: %v", err)
+	}
+	if len(people) != 4 || !people[1].Suspended || !people[2].Unconfirmed || people[3].Unconfirmed {
+		t.Errorf("Expected suspended and confirmed flags to be parsed, found %+v", people)
+	}
+	if r := fetch.last(); r.Get("criteria[0][key]") != "lastname" || r.Get("criteria[0][value]") != "Lee" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	people, _ = api.FindPeople("lastname", "Lee", &PeopleSearchOptions{ExcludeSuspended: true, ExcludeUnconfirmed: true})
+	if found := ids(people); len(found) != 2 || found[0] != 7 || found[1] != 10 {
+		t.Errorf("Expected only active confirmed accounts, found %v", found)
+	}
+
+	people, _ = api.FindPeople("lastname", "Lee", &PeopleSearchOptions{OnlySuspended: true})
+	if found := ids(people); len(found) != 1 || found[0] != 8 {
+		t.Errorf("Expected only suspended accounts, found %v", found)
+	}
+}
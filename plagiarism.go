@@ -0,0 +1,69 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// PlagiarismResult is the similarity report of a plagiarism plugin, such
+// as Turnitin or Ouriginal, for one file or online text of a submission.
+type PlagiarismResult struct {
+	FileName string `json:"filename"`
+
+	// Score is the similarity as a percentage, nil until the report is ready
+	Score *float64 `json:"score"`
+
+	// Status is the state of the report as described by the plugin, such
+	// as "queued", "pending", "complete" or "error"
+	Status    string `json:"status"`
+	ReportUrl string `json:"reporturl"`
+}
+
+// SetPlagiarismFunction sets the web service function used to read
+// similarity reports. When set, GetAssignmentSubmissions and
+// GetSubmissionsForAssignment include the reports of each submission.
+//
+// Plagiarism plugins do not share a web service, so this must be provided by
+// the plugin or a local plugin. The function is called with the parameter
+// "assignmentid" and must return a list of reports:
+//
+//	[{"userid":7,"filename":"essay.docx","score":12.5,"status":"complete","reporturl":"https://..."}]
+func (m *MoodleApi) SetPlagiarismFunction(function string) {
+	m.plagiarismFunction = function
+	m.SetReadOnlyFunctions(function)
+}
+
+// GetPlagiarismResults fetches the similarity reports of the submissions to
+// an assignment, keyed by the person who made the submission. Requires
+// SetPlagiarismFunction.
+func (m *MoodleApi) GetPlagiarismResults(assignmentId int64) (map[UserID][]PlagiarismResult, error) {
+	if m.plagiarismFunction == "" {
+		return nil, errors.New("Reading plagiarism results requires a web service function, see SetPlagiarismFunction")
+	}
+
+	body, err := m.call(m.plagiarismFunction, url.Values{
+		"assignmentid": {fmt.Sprint(assignmentId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		UserId UserID `json:"userid"`
+		PlagiarismResult
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	reports := make(map[UserID][]PlagiarismResult)
+	for _, r := range results {
+		reports[r.UserId] = append(reports[r.UserId], r.PlagiarismResult)
+	}
+	return reports, nil
+}
@@ -0,0 +1,40 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestPlagiarismResults(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_get_submissions":   `{"assignments":[{"assignmentid":12,"submissions":[{"id":100,"userid":7,"status":"submitted","gradingstatus":"notgraded","timecreated":1600000000,"timemodified":1600000100},{"id":101,"userid":8,"status":"submitted","gradingstatus":"notgraded"}]}],"warnings":[]}`,
+		"mod_assign_get_user_flags":    `{"assignments":[],"warnings":[]}`,
+		"local_plagiarism_get_reports": `[{"userid":7,"filename":"essay.docx","score":12.5,"status":"complete","reporturl":"https://turnitin.example.com/report/1"},{"userid":8,"filename":"essay.pdf","status":"pending"}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if _, err := api.GetPlagiarismResults(12); err == nil {
+		t.Errorf("Expected an error when no plagiarism function is set")
+	}
+
+	api.SetPlagiarismFunction("local_plagiarism_get_reports")
+	submissions, err := api.GetSubmissionsForAssignment(12)
+	if err != nil {
+		t.Fatalf("GetSubmissionsForAssignment failed: %v", err)
+	}
+	if len(submissions) != 2 {
+		t.Fatalf("Expected two submissions, found %+v", submissions)
+	}
+	p := submissions[0].Plagiarism
+	if len(p) != 1 || p[0].Score == nil || *p[0].Score != 12.5 || p[0].ReportUrl == "" {
+		t.Errorf("Unexpected plagiarism results: %+v", p)
+	}
+	p = submissions[1].Plagiarism
+	if len(p) != 1 || p[0].Score != nil || p[0].Status != "pending" {
+		t.Errorf("Expected a pending report without a score, found %+v", p)
+	}
+	if q := fetch.last(); q.Get("assignmentid") != "12" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}
@@ -0,0 +1,54 @@
+package moodle
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// PictureSize selects the size of a profile picture
+type PictureSize string
+
+const (
+	PictureSmall  PictureSize = "f2" // 35 pixels
+	PictureMedium PictureSize = "f1" // 100 pixels
+	PictureLarge  PictureSize = "f3" // 512 pixels
+)
+
+// GetProfilePicture opens the profile picture of a person at the requested
+// size. The caller must close the picture. Returns an error wrapping
+// ErrNotFound if the person has not uploaded a picture.
+func (m *MoodleApi) GetProfilePicture(userId UserID, size PictureSize) (io.ReadCloser, error) {
+	person, err := m.GetPersonByMoodleId(userId)
+	if err != nil {
+		return nil, err
+	}
+	if person == nil {
+		return nil, wrapError(fmt.Sprintf("Moodle account %d does not exist", userId), ErrNotFound)
+	}
+
+	// People without a picture are given a url to the default image of the
+	// theme, which is not served by pluginfile.php
+	if !strings.Contains(person.ProfileImageUrl, "/pluginfile.php/") {
+		return nil, wrapError(fmt.Sprintf("Moodle account %d has no profile picture", userId), ErrNotFound)
+	}
+
+	return m.OpenFile(pictureUrl(person.ProfileImageUrl, size))
+}
+
+// pictureUrl changes the size of a profile picture url, such as
+// https://moodle.example.com/pluginfile.php/5/user/icon/boost/f1?rev=123
+func pictureUrl(imageUrl string, size PictureSize) string {
+	if size == "" {
+		return imageUrl
+	}
+	u, err := url.Parse(imageUrl)
+	if err != nil {
+		return imageUrl
+	}
+	if i := strings.LastIndex(u.Path, "/"); i >= 0 {
+		u.Path = u.Path[:i+1] + string(size)
+	}
+	return u.String()
+}
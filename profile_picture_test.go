@@ -0,0 +1,92 @@
+package moodle
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetProfilePicture(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_get_users_by_field":                     `[{"id":7,"username":"sam","firstname":"Sam","lastname":"Student","profileimageurl":"https://moodle.example.com/pluginfile.php/30/user/icon/boost/f1?rev=123","profileimageurlsmall":"https://moodle.example.com/pluginfile.php/30/user/icon/boost/f2?rev=123"}]`,
+		"/webservice/pluginfile.php/30/user/icon/boost/f3": "JPEG",
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "secret")
+	api.SetUrlFetcher(fetch)
+
+	r, err := api.GetProfilePicture(7, PictureLarge)
+	if err != nil {
+		t.Fatalf("GetProfilePicture failed: %v", err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(data) != "JPEG" {
+		t.Errorf("Unexpected picture: %q", data)
+	}
+	if u := fetch.urls[len(fetch.urls)-1]; u != "https://moodle.example.com/webservice/pluginfile.php/30/user/icon/boost/f3?rev=123" {
+		t.Errorf("Unexpected url: %s", u)
+	}
+	if q := fetch.last(); q.Get("token") != "secret" {
+		t.Errorf("Expected the token to be sent, found %v", q)
+	}
+
+	fetch.responses["core_user_get_users_by_field"] = `[{"id":8,"username":"alex","profileimageurl":"https://moodle.example.com/theme/image.php/boost/core/1600000000/u/f1"}]`
+	if _, err := api.GetProfilePicture(8, PictureSmall); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for the default picture, found %v", err)
+	}
+}
+
+func TestGetProfilePictureOverHttp(t *testing.T) {
+
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00 \n\xff\xd9")
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/webservice/rest/server.php":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"id":7,"username":"sam","profileimageurl":"` + server.URL + `/pluginfile.php/30/user/icon/boost/f1?rev=123"}]`))
+		case "/webservice/pluginfile.php/30/user/icon/boost/f3":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(jpeg)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "secret")
+	r, err := api.GetProfilePicture(7, PictureLarge)
+	if err != nil {
+		t.Fatalf("GetProfilePicture failed: %v", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("Expected the picture unchanged, found %q %v", data, err)
+	}
+}
+
+func TestRemoveProfilePicture(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_update_picture": `{"success":true,"profileimageurl":"https://moodle.example.com/theme/image.php/boost/core/1600000000/u/f1","warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.RemoveProfilePicture(7); err != nil {
+		t.Fatalf("RemoveProfilePicture failed: %v", err)
+	}
+	if q := fetch.last(); q.Get("delete") != "1" || q.Get("userid") != "7" || q.Get("draftitemid") != "0" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	fetch.responses["core_user_update_picture"] = `{"success":false,"warnings":[]}`
+	if err := api.RemoveProfilePicture(7); err == nil {
+		t.Errorf("Expected an error when moodle does not remove the picture")
+	}
+}
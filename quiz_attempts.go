@@ -0,0 +1,209 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuizAttempt is one attempt by a person at a quiz
+type QuizAttempt struct {
+	Id       int64
+	QuizId   int64
+	UserId   UserID
+	Attempt  int64
+	State    string
+	Preview  bool
+	Started  time.Time
+	Finished *time.Time
+	Modified time.Time
+
+	// SumGrades is the total mark, nil if the attempt has not been graded
+	SumGrades *float64
+}
+
+// QuizAttemptReview holds the questions of a finished attempt as shown on
+// the review page, including the history of each response.
+type QuizAttemptReview struct {
+	Attempt   QuizAttempt
+	Grade     string
+	Questions []QuestionAttempt
+}
+
+// QuestionAttempt is the state of one question in an attempt. Html is the
+// question as rendered by moodle.
+type QuestionAttempt struct {
+	Slot    int64
+	Number  string
+	Type    string
+	State   string
+	Status  string
+	Mark    string
+	MaxMark float64
+	Html    string
+
+	// Steps are read from the response history of the question, only
+	// included when the review options of the quiz, or the permissions of
+	// the token's user, allow the history to be shown.
+	Steps []QuestionStep
+}
+
+// QuestionStep is one row of the response history of a question. Time is
+// formatted by moodle in the language and timezone of the token's user.
+type QuestionStep struct {
+	Step   int
+	Time   string
+	Action string
+	State  string
+	Marks  string
+}
+
+type quizAttemptResult struct {
+	Id         int64    `json:"id"`
+	Quiz       int64    `json:"quiz"`
+	UserId     UserID   `json:"userid"`
+	Attempt    int64    `json:"attempt"`
+	State      string   `json:"state"`
+	Preview    int      `json:"preview"`
+	TimeStart  int64    `json:"timestart"`
+	TimeFinish int64    `json:"timefinish"`
+	Modified   int64    `json:"timemodified"`
+	SumGrades  *float64 `json:"sumgrades"`
+}
+
+func (m *MoodleApi) quizAttempt(a quizAttemptResult) QuizAttempt {
+	return QuizAttempt{
+		Id:        a.Id,
+		QuizId:    a.Quiz,
+		UserId:    a.UserId,
+		Attempt:   a.Attempt,
+		State:     a.State,
+		Preview:   a.Preview != 0,
+		Started:   m.unix(a.TimeStart),
+		Finished:  m.unixTime(a.TimeFinish),
+		Modified:  m.unix(a.Modified),
+		SumGrades: a.SumGrades,
+	}
+}
+
+// GetUserQuizAttempts lists every attempt a person has made at a quiz,
+// including unfinished attempts and previews.
+func (m *MoodleApi) GetUserQuizAttempts(quizId int64, userId UserID) ([]QuizAttempt, error) {
+	body, err := m.call("mod_quiz_get_user_attempts", url.Values{
+		"quizid":          {fmt.Sprint(quizId)},
+		"userid":          {fmt.Sprint(userId)},
+		"status":          {"all"},
+		"includepreviews": {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Attempts []quizAttemptResult `json:"attempts"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var attempts []QuizAttempt
+	for _, a := range result.Attempts {
+		attempts = append(attempts, m.quizAttempt(a))
+	}
+	return attempts, nil
+}
+
+// GetQuizAttemptReview fetches every question of a finished attempt with
+// the steps recorded for each response, for example to investigate when
+// answers were changed during an exam.
+func (m *MoodleApi) GetQuizAttemptReview(attemptId int64) (*QuizAttemptReview, error) {
+	body, err := m.call("mod_quiz_get_attempt_review", url.Values{
+		"attemptid": {fmt.Sprint(attemptId)},
+		"page":      {"-1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Question struct {
+		Slot    int64   `json:"slot"`
+		Number  string  `json:"number"`
+		Type    string  `json:"type"`
+		State   string  `json:"state"`
+		Status  string  `json:"status"`
+		Mark    string  `json:"mark"`
+		MaxMark float64 `json:"maxmark"`
+		Html    string  `json:"html"`
+	}
+	type Result struct {
+		Grade     string            `json:"grade"`
+		Attempt   quizAttemptResult `json:"attempt"`
+		Questions []Question        `json:"questions"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	review := &QuizAttemptReview{Attempt: m.quizAttempt(result.Attempt), Grade: result.Grade}
+	for _, q := range result.Questions {
+		review.Questions = append(review.Questions, QuestionAttempt{
+			Slot:    q.Slot,
+			Number:  q.Number,
+			Type:    q.Type,
+			State:   q.State,
+			Status:  q.Status,
+			Mark:    q.Mark,
+			MaxMark: q.MaxMark,
+			Html:    q.Html,
+			Steps:   parseResponseHistory(q.Html),
+		})
+	}
+	return review, nil
+}
+
+var (
+	responseHistoryPattern = regexp.MustCompile(`(?s)class="responsehistoryheader.*?<tbody>(.*?)</tbody>`)
+	rowPattern             = regexp.MustCompile(`(?s)<tr[^>]*>(.*?)</tr>`)
+	cellPattern            = regexp.MustCompile(`(?s)<td[^>]*>(.*?)</td>`)
+	tagPattern             = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// parseResponseHistory reads the response history table moodle includes in
+// the html of a reviewed question. The columns are step, time, action,
+// state and, if marks are shown, marks.
+func parseResponseHistory(questionHtml string) []QuestionStep {
+	table := responseHistoryPattern.FindStringSubmatch(questionHtml)
+	if table == nil {
+		return nil
+	}
+	var steps []QuestionStep
+	for _, row := range rowPattern.FindAllStringSubmatch(table[1], -1) {
+		var cells []string
+		for _, cell := range cellPattern.FindAllStringSubmatch(row[1], -1) {
+			text := html.UnescapeString(tagPattern.ReplaceAllString(cell[1], ""))
+			cells = append(cells, strings.TrimSpace(text))
+		}
+		if len(cells) < 4 {
+			continue
+		}
+		step := QuestionStep{Time: cells[1], Action: cells[2], State: cells[3]}
+		step.Step, _ = strconv.Atoi(cells[0])
+		if len(cells) > 4 {
+			step.Marks = cells[4]
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
@@ -0,0 +1,55 @@
+package moodle
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testResponseHistory = `<div class="que multichoice"><div class="formulation">What is 2+2?</div>` +
+	`<div class="history"><h4>Response history</h4><div class="responsehistoryheader"><table class="generaltable generalbox">` +
+	`<thead><tr><th>Step</th><th>Time</th><th>Action</th><th>State</th><th>Marks</th></tr></thead><tbody>` +
+	`<tr class=""><td class="cell c0">1</td><td class="cell c1">15/09/20, 10:30:12</td><td class="cell c2">Started</td><td class="cell c3">Not yet answered</td><td class="cell c4"></td></tr>` +
+	`<tr class=""><td class="cell c0">2</td><td class="cell c1">15/09/20, 10:31:40</td><td class="cell c2">Saved: 3</td><td class="cell c3">Answer saved</td><td class="cell c4"></td></tr>` +
+	`<tr class="current"><td class="cell c0">3</td><td class="cell c1">15/09/20, 10:35:02</td><td class="cell c2">Saved: 4 &amp; submitted</td><td class="cell c3">Correct</td><td class="cell c4">1.00</td></tr>` +
+	`</tbody></table></div></div></div>`
+
+func TestQuizAttemptReview(t *testing.T) {
+
+	questionHtml, _ := json.Marshal(testResponseHistory)
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_quiz_get_user_attempts":  `{"attempts":[{"id":40,"quiz":6,"userid":7,"attempt":1,"state":"finished","preview":0,"timestart":1600165812,"timefinish":1600166102,"timemodified":1600166102,"sumgrades":1.0}],"warnings":[]}`,
+		"mod_quiz_get_attempt_review": `{"grade":"10.00","attempt":{"id":40,"quiz":6,"userid":7,"attempt":1,"state":"finished","timestart":1600165812,"timefinish":1600166102,"sumgrades":1.0},"questions":[{"slot":1,"type":"multichoice","number":"1","state":"gradedright","status":"Correct","mark":"1.00","maxmark":1,"html":` + string(questionHtml) + `}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	attempts, err := api.GetUserQuizAttempts(6, 7)
+	if err != nil {
+		t.Fatalf("GetUserQuizAttempts failed: %v", err)
+	}
+	if len(attempts) != 1 || attempts[0].Id != 40 || attempts[0].Finished == nil || *attempts[0].SumGrades != 1 {
+		t.Errorf("Unexpected attempts: %+v", attempts)
+	}
+
+	review, err := api.GetQuizAttemptReview(40)
+	if err != nil {
+		t.Fatalf("GetQuizAttemptReview failed: %v", err)
+	}
+	if review.Grade != "10.00" || len(review.Questions) != 1 {
+		t.Fatalf("Unexpected review: %+v", review)
+	}
+	steps := review.Questions[0].Steps
+	if len(steps) != 3 {
+		t.Fatalf("Expected three steps, found %+v", steps)
+	}
+	if steps[1].Step != 2 || steps[1].Action != "Saved: 3" || steps[1].State != "Answer saved" {
+		t.Errorf("Unexpected step: %+v", steps[1])
+	}
+	if steps[2].Action != "Saved: 4 & submitted" || steps[2].Marks != "1.00" || steps[2].Time != "15/09/20, 10:35:02" {
+		t.Errorf("Unexpected step: %+v", steps[2])
+	}
+
+	if steps := parseResponseHistory(`<div class="que">No history</div>`); steps != nil {
+		t.Errorf("Expected no steps without a response history, found %+v", steps)
+	}
+}
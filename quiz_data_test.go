@@ -11,7 +11,7 @@ func TestGetQuizzesWithCourseId(t *testing.T) {
 	api.SetLogger(&PrintMoodleLogger{})
 
 	fmt.Println("Check for Quizzes")
-	quizzes, err := api.GetQuizzesWithCourseId([]int{36})
+	quizzes, err := api.GetQuizzesWithCourseId([]CourseID{36})
 	if err != nil {
 		t.Errorf("API call failed: %s", err)
 		return
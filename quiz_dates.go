@@ -0,0 +1,65 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SetQuizDatesFunction sets the web service function used by SetQuizDates.
+// Moodle does not include a web service to change when a quiz opens and
+// closes, so this must be provided by a local plugin. The function is called
+// with the parameters "quizid", "timeopen" and "timeclose", the dates being
+// unix times, or 0 to remove the date.
+func (m *MoodleApi) SetQuizDatesFunction(function string) {
+	m.quizDatesFunction = function
+}
+
+// SetQuizDates changes when a quiz opens and closes for everyone. A zero time
+// removes the date. Requires SetQuizDatesFunction.
+func (m *MoodleApi) SetQuizDates(quizId int64, open, close time.Time) error {
+	if m.quizDatesFunction == "" {
+		return errors.New("Setting quiz dates requires a web service function, see SetQuizDatesFunction")
+	}
+
+	_, err := m.call(m.quizDatesFunction, url.Values{
+		"quizid":    {fmt.Sprint(quizId)},
+		"timeopen":  {fmt.Sprint(unixOrZero(open))},
+		"timeclose": {fmt.Sprint(unixOrZero(close))},
+	})
+	return err
+}
+
+// ShiftQuizDates moves the open and close dates of every quiz in the courses
+// by offset. Dates that are not set are left unset. Returns the number of
+// quizzes updated, stopping at the first failure.
+func (m *MoodleApi) ShiftQuizDates(courseIds []CourseID, offset time.Duration) (int, error) {
+	quizzes, err := m.GetQuizzesWithCourseId(courseIds)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, q := range quizzes {
+		open := shiftDate(q.TimeOpen, offset)
+		close := shiftDate(q.TimeClose, offset)
+		if open.IsZero() && close.IsZero() {
+			continue
+		}
+		if err := m.SetQuizDates(q.Id, open, close); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// shiftDate moves a date by offset, returning a zero time if the date is not
+// set. Quiz dates that are not set are read as the unix epoch.
+func shiftDate(t *time.Time, offset time.Duration) time.Time {
+	if t == nil || t.Unix() == 0 {
+		return time.Time{}
+	}
+	return t.Add(offset)
+}
@@ -0,0 +1,40 @@
+package moodle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetQuizDates(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"local_dates_set_quiz_dates":      `null`,
+		"mod_quiz_get_quizzes_by_courses": `{"quizzes":[{"id":4,"coursemodule":110,"course":3,"timeopen":1600000000,"timeclose":0},{"id":5,"coursemodule":111,"course":3,"timeopen":0,"timeclose":0}],"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	open := time.Unix(1600000000, 0)
+	if err := api.SetQuizDates(4, open, time.Time{}); err == nil {
+		t.Errorf("Expected an error when no dates function has been set")
+	}
+
+	api.SetQuizDatesFunction("local_dates_set_quiz_dates")
+	if err := api.SetQuizDates(4, open, open.Add(time.Hour)); err != nil {
+		t.Fatalf("SetQuizDates failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("quizid") != "4" || r.Get("timeopen") != "1600000000" || r.Get("timeclose") != "1600003600" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	n, err := api.ShiftQuizDates([]CourseID{3}, -24*time.Hour)
+	if err != nil {
+		t.Fatalf("ShiftQuizDates failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected quizzes without dates to be skipped, found %d updated", n)
+	}
+	if r := fetch.last(); r.Get("quizid") != "4" || r.Get("timeopen") != "1599913600" || r.Get("timeclose") != "0" {
+		t.Errorf("Expected open date to move back a day, found %v", r)
+	}
+}
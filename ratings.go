@@ -0,0 +1,147 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RatingArea identifies the items that can be rated in an activity, such as
+// the posts of a forum. Use ForumRatings, GlossaryRatings or DataRatings,
+// or set the fields for another module that supports ratings.
+type RatingArea struct {
+	// Component is the frankenstyle name of the module, such as "mod_forum"
+	Component string
+
+	// Area is the rating area within the module, such as "post"
+	Area string
+
+	// CmId is the course module holding the items
+	CmId CmID
+
+	// ScaleId is the scale of the activity. Positive values are a maximum
+	// point grade, negative values are the id of a custom scale.
+	ScaleId int64
+}
+
+// ForumRatings is the rating area of the posts in a forum
+func ForumRatings(cmid CmID, scaleId int64) RatingArea {
+	return RatingArea{Component: "mod_forum", Area: "post", CmId: cmid, ScaleId: scaleId}
+}
+
+// GlossaryRatings is the rating area of the entries in a glossary
+func GlossaryRatings(cmid CmID, scaleId int64) RatingArea {
+	return RatingArea{Component: "mod_glossary", Area: "entry", CmId: cmid, ScaleId: scaleId}
+}
+
+// DataRatings is the rating area of the entries in a database activity
+func DataRatings(cmid CmID, scaleId int64) RatingArea {
+	return RatingArea{Component: "mod_data", Area: "entry", CmId: cmid, ScaleId: scaleId}
+}
+
+// RatingAggregation is how the ratings of an item are combined
+type RatingAggregation int
+
+const (
+	RatingAverage RatingAggregation = 1
+	RatingCount   RatingAggregation = 2
+	RatingMaximum RatingAggregation = 3
+	RatingMinimum RatingAggregation = 4
+	RatingSum     RatingAggregation = 5
+)
+
+// Rating is a rating given to an item by one person. Rating holds the
+// value as moodle displays it, which is the scale item name for custom
+// scales.
+type Rating struct {
+	Id           int64
+	UserId       UserID
+	UserFullName string
+	Rating       string
+	Modified     time.Time
+}
+
+// RatingResult is the aggregate rating of an item after a rating is added
+type RatingResult struct {
+	ItemId    int64
+	Aggregate string
+	Count     int64
+}
+
+func (a *RatingArea) params(itemId int64) url.Values {
+	return url.Values{
+		"contextlevel": {"module"},
+		"instanceid":   {fmt.Sprint(a.CmId)},
+		"component":    {a.Component},
+		"ratingarea":   {a.Area},
+		"itemid":       {fmt.Sprint(itemId)},
+		"scaleid":      {fmt.Sprint(a.ScaleId)},
+	}
+}
+
+// GetItemRatings lists the ratings given to an item, such as a forum post
+func (m *MoodleApi) GetItemRatings(area RatingArea, itemId int64) ([]Rating, error) {
+	params := area.params(itemId)
+	params.Set("sort", "timemodified")
+	body, err := m.call("core_rating_get_item_ratings", params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Id           int64  `json:"id"`
+		UserId       UserID `json:"userid"`
+		UserFullName string `json:"userfullname"`
+		Rating       string `json:"rating"`
+		TimeModified int64  `json:"timemodified"`
+	}
+	type Results struct {
+		Ratings []Result `json:"ratings"`
+	}
+
+	var results Results
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var ratings []Rating
+	for _, r := range results.Ratings {
+		ratings = append(ratings, Rating{Id: r.Id, UserId: r.UserId, UserFullName: r.UserFullName, Rating: r.Rating, Modified: m.unix(r.TimeModified)})
+	}
+	return ratings, nil
+}
+
+// AddRating rates an item on behalf of the web service user. The rated user
+// is the author of the item, and the aggregation must match the setting of
+// the activity.
+func (m *MoodleApi) AddRating(area RatingArea, itemId int64, ratedUserId UserID, rating int64, aggregation RatingAggregation) (*RatingResult, error) {
+	params := area.params(itemId)
+	params.Set("rating", fmt.Sprint(rating))
+	params.Set("rateduserid", fmt.Sprint(ratedUserId))
+	params.Set("aggregation", fmt.Sprint(int(aggregation)))
+	body, err := m.call("core_rating_add_rating", params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Success   bool   `json:"success"`
+		Aggregate string `json:"aggregate"`
+		Count     int64  `json:"count"`
+		ItemId    int64  `json:"itemid"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if !result.Success {
+		return nil, wrapError("Rating was not added", ErrPermissionDenied)
+	}
+
+	return &RatingResult{ItemId: result.ItemId, Aggregate: result.Aggregate, Count: result.Count}, nil
+}
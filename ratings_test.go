@@ -0,0 +1,40 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestRatings(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_rating_get_item_ratings": `{"ratings":[{"id":1,"userid":7,"userfullname":"Jane Smith","rating":"Good","timemodified":1600000000}],"warnings":[]}`,
+		"core_rating_add_rating":       `{"success":true,"aggregate":"4.5","count":2,"itemid":55,"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	area := ForumRatings(100, -2)
+	ratings, err := api.GetItemRatings(area, 55)
+	if err != nil {
+		t.Fatalf("GetItemRatings failed: %v", err)
+	}
+	if len(ratings) != 1 || ratings[0].UserId != 7 || ratings[0].Rating != "Good" || ratings[0].Modified.Unix() != 1600000000 {
+		t.Errorf("Unexpected ratings: %+v", ratings)
+	}
+	q := fetch.last()
+	if q.Get("component") != "mod_forum" || q.Get("ratingarea") != "post" || q.Get("instanceid") != "100" || q.Get("scaleid") != "-2" || q.Get("contextlevel") != "module" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	result, err := api.AddRating(GlossaryRatings(101, 5), 55, 8, 4, RatingAverage)
+	if err != nil {
+		t.Fatalf("AddRating failed: %v", err)
+	}
+	if result.Aggregate != "4.5" || result.Count != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	q = fetch.last()
+	if q.Get("component") != "mod_glossary" || q.Get("rateduserid") != "8" || q.Get("rating") != "4" || q.Get("aggregation") != "1" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}
@@ -0,0 +1,65 @@
+package moodle
+
+import (
+	"encoding/json"
+)
+
+// SetKeepRawJSON keeps the json moodle returns for each Person, CoursePerson
+// and GradebookItem in its Raw field. This allows fields that are not yet
+// part of these types, such as fields added by newer moodle versions, to be
+// read. Disabled by default, as it doubles the memory used by results.
+func (m *MoodleApi) SetKeepRawJSON(keep bool) {
+	m.keepRaw = keep
+}
+
+// rawItems returns the json of each item in a list returned by moodle, or nil
+// if raw json is not being kept. An empty key means the response is the list,
+// otherwise the list is read from the named field of the response.
+func (m *MoodleApi) rawItems(body string, key string) []json.RawMessage {
+	if !m.keepRaw {
+		return nil
+	}
+	var items []json.RawMessage
+	if key == "" {
+		json.Unmarshal([]byte(body), &items)
+		return items
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return nil
+	}
+	json.Unmarshal(fields[key], &items)
+	return items
+}
+
+// rawItem returns the json of an item, if it is available
+func rawItem(items []json.RawMessage, n int) json.RawMessage {
+	if n < len(items) {
+		return items[n]
+	}
+	return nil
+}
+
+// rawGradebookItems copies the json of each grade item into the gradebook
+func (m *MoodleApi) rawGradebookItems(body string, entries []GradebookEntry) {
+	if !m.keepRaw {
+		return
+	}
+	type Entry struct {
+		Items []json.RawMessage `json:"gradeitems"`
+	}
+	var results struct {
+		Usergrades []Entry `json:"usergrades"`
+	}
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return
+	}
+	for n := range entries {
+		if n >= len(results.Usergrades) {
+			return
+		}
+		for i := range entries[n].Item {
+			entries[n].Item[i].Raw = rawItem(results.Usergrades[n].Items, i)
+		}
+	}
+}
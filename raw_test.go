@@ -0,0 +1,53 @@
+package moodle
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestKeepRawJSON(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_user_get_users_by_field":     `[{"id":7,"username":"jsmith","firstname":"Jane","lastname":"Smith","trackforums":1}]`,
+		"core_enrol_get_enrolled_users":    `[{"id":7,"username":"jsmith","interests":"chess"}]`,
+		"gradereport_user_get_grade_items": `{"usergrades":[{"userid":7,"gradeitems":[{"id":1,"itemname":"Essay","locked":true}]}]}`,
+	}))
+
+	p, err := api.GetPersonByUsername("jsmith")
+	if err != nil {
+		t.Fatalf("GetPersonByUsername failed: %v", err)
+	}
+	if p.Raw != nil {
+		t.Errorf("Expected raw json to be omitted by default")
+	}
+
+	api.SetKeepRawJSON(true)
+
+	p, err = api.GetPersonByUsername("jsmith")
+	if err != nil {
+		t.Fatalf("GetPersonByUsername failed: %v", err)
+	}
+	var fields struct {
+		TrackForums int `json:"trackforums"`
+	}
+	if err := json.Unmarshal(p.Raw, &fields); err != nil || fields.TrackForums != 1 {
+		t.Errorf("Expected raw json to include trackforums, found %s", p.Raw)
+	}
+
+	people, err := api.GetCourseRoles(3)
+	if err != nil {
+		t.Fatalf("GetCourseRoles failed: %v", err)
+	}
+	if len(people) != 1 || string(people[0].Raw) != `{"id":7,"username":"jsmith","interests":"chess"}` {
+		t.Errorf("Unexpected raw json for course person: %+v", people)
+	}
+
+	gradebook, err := api.GetCourseGradebook(3)
+	if err != nil {
+		t.Fatalf("GetCourseGradebook failed: %v", err)
+	}
+	if len(gradebook) != 1 || len(gradebook[0].Item) != 1 || string(gradebook[0].Item[0].Raw) != `{"id":1,"itemname":"Essay","locked":true}` {
+		t.Errorf("Unexpected raw json for grade item: %+v", gradebook)
+	}
+}
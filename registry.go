@@ -0,0 +1,119 @@
+package moodle
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry holds a MoodleApi for each moodle site of an institution, such
+// as one per campus or tenant, configured with the same logger, metrics,
+// rate limit and url fetcher.
+//
+//	r := moodle.NewRegistry(&moodle.RegistryOptions{Logger: logger, RateLimit: 100 * time.Millisecond})
+//	r.Add("north", "https://north.example.com/", northToken)
+//	r.Add("south", "https://south.example.com/", southToken)
+//	...
+//	api, err := r.Get("north")
+type Registry struct {
+	options RegistryOptions
+
+	mu    sync.RWMutex
+	sites map[string]*MoodleApi
+}
+
+// RegistryOptions configures every api added to a Registry. Zero values
+// use the defaults of NewMoodleApi.
+type RegistryOptions struct {
+	Logger  MoodleLogger
+	Metrics Metrics
+
+	// RateLimit is the minimum time between requests to each site. Sites
+	// are limited separately, a slow site does not delay the others.
+	RateLimit time.Duration
+
+	// Fetcher is shared by every site, so that connections are pooled
+	Fetcher LookupUrl
+
+	UserAgent string
+}
+
+// NewRegistry returns an empty registry. Options may be nil.
+func NewRegistry(options *RegistryOptions) *Registry {
+	r := &Registry{sites: make(map[string]*MoodleApi)}
+	if options != nil {
+		r.options = *options
+	}
+	return r
+}
+
+// Add creates an api for a site and adds it to the registry under name,
+// replacing any site already using the name. The returned api may be
+// configured further, for example with SetMailer or SetCredentials.
+func (r *Registry) Add(name, base, token string) *MoodleApi {
+	m := NewMoodleApi(base, token)
+	m.name = name
+	if r.options.Logger != nil {
+		m.SetLogger(r.options.Logger)
+	}
+	if r.options.Fetcher != nil {
+		m.SetUrlFetcher(r.options.Fetcher)
+	}
+	if r.options.UserAgent != "" {
+		m.SetUserAgent(r.options.UserAgent)
+	}
+	m.SetMetrics(r.options.Metrics)
+	m.SetRateLimit(r.options.RateLimit)
+
+	r.mu.Lock()
+	r.sites[name] = m
+	r.mu.Unlock()
+	return m
+}
+
+// Get returns the api for a site, or an error wrapping ErrNotFound if no
+// site has the name.
+func (r *Registry) Get(name string) (*MoodleApi, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.sites[name]
+	if !ok {
+		return nil, wrapError("No moodle site named "+name, ErrNotFound)
+	}
+	return m, nil
+}
+
+// Remove removes a site from the registry
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	delete(r.sites, name)
+	r.mu.Unlock()
+}
+
+// Names lists the sites in the registry in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.sites))
+	for name := range r.sites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Each calls fn for each site in alphabetical order, stopping at the
+// first error.
+func (r *Registry) Each(fn func(name string, m *MoodleApi) error) error {
+	for _, name := range r.Names() {
+		m, err := r.Get(name)
+		if err != nil {
+			// Removed while iterating
+			continue
+		}
+		if err := fn(name, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
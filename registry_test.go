@@ -0,0 +1,66 @@
+package moodle
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (t *testMetrics) ObserveCall(site, function string, elapsed time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, site+" "+function)
+}
+
+func TestRegistry(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"sitename":"Example","firstname":"Admin","lastname":"User","userid":2}`,
+	})
+	metrics := &testMetrics{}
+	r := NewRegistry(&RegistryOptions{Fetcher: fetch, Metrics: metrics, RateLimit: 20 * time.Millisecond})
+	r.Add("south", "https://south.example.com", "token2")
+	r.Add("north", "https://north.example.com", "token1")
+
+	if names := r.Names(); strings.Join(names, ",") != "north,south" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+	if _, err := r.Get("east"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown site, found %v", err)
+	}
+
+	start := time.Now()
+	err := r.Each(func(name string, m *MoodleApi) error {
+		for i := 0; i < 2; i++ {
+			if _, _, _, _, err := m.GetSiteInfo(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected calls to each site to be rate limited, took %s", elapsed)
+	}
+
+	if strings.Join(metrics.calls, ",") != "north core_webservice_get_site_info,north core_webservice_get_site_info,south core_webservice_get_site_info,south core_webservice_get_site_info" {
+		t.Errorf("Unexpected metrics: %v", metrics.calls)
+	}
+	if len(fetch.urls) != 4 || !strings.HasPrefix(fetch.urls[0], "https://north.example.com/") || !strings.HasPrefix(fetch.urls[3], "https://south.example.com/") {
+		t.Errorf("Expected calls to use the url of each site, found %v", fetch.urls)
+	}
+
+	r.Remove("north")
+	if _, err := r.Get("north"); err == nil {
+		t.Errorf("Expected removed site to be unknown")
+	}
+}
@@ -0,0 +1,137 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// CustomReport is a report created with the report builder of moodle 4.x
+type CustomReport struct {
+	Id         int64
+	Name       string
+	Source     string
+	SourceName string
+	Created    time.Time
+	Modified   time.Time
+}
+
+// ReportData holds the rows of a custom report. Each row has a column for
+// each header, formatted by moodle as it would appear on screen.
+type ReportData struct {
+	Headers []string
+	Rows    [][]string
+
+	// TotalRows is the number of rows in the report, which is more than
+	// len(Rows) if only one page was fetched
+	TotalRows int64
+}
+
+// reportPageSize is the number of rows fetched in each call when all
+// rows of a report are requested
+const reportPageSize = 500
+
+// GetCustomReports lists the custom reports the token's user may view.
+// Requires moodle 4.1 or later.
+func (m *MoodleApi) GetCustomReports() ([]CustomReport, error) {
+	var reports []CustomReport
+	for page := 0; ; page++ {
+		body, err := m.call("core_reportbuilder_list_reports", url.Values{
+			"page":    {fmt.Sprint(page)},
+			"perpage": {fmt.Sprint(reportPageSize)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		type Report struct {
+			Id           int64  `json:"id"`
+			Name         string `json:"name"`
+			Source       string `json:"source"`
+			SourceName   string `json:"sourcename"`
+			TimeCreated  int64  `json:"timecreated"`
+			TimeModified int64  `json:"timemodified"`
+		}
+		type Result struct {
+			Reports []Report `json:"reports"`
+		}
+
+		var result Result
+
+		if err := json.Unmarshal([]byte(body), &result); err != nil {
+			return nil, errors.New("Server returned unexpected response. " + err.Error())
+		}
+
+		for _, r := range result.Reports {
+			reports = append(reports, CustomReport{
+				Id:         r.Id,
+				Name:       r.Name,
+				Source:     r.Source,
+				SourceName: r.SourceName,
+				Created:    m.unix(r.TimeCreated),
+				Modified:   m.unix(r.TimeModified),
+			})
+		}
+		if len(result.Reports) < reportPageSize {
+			return reports, nil
+		}
+	}
+}
+
+// GetCustomReportPage fetches one page of a custom report. Pages are
+// numbered from zero.
+func (m *MoodleApi) GetCustomReportPage(reportId int64, page, perPage int) (*ReportData, error) {
+	body, err := m.call("core_reportbuilder_retrieve_report", url.Values{
+		"reportid": {fmt.Sprint(reportId)},
+		"page":     {fmt.Sprint(page)},
+		"perpage":  {fmt.Sprint(perPage)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Row struct {
+		Columns []string `json:"columns"`
+	}
+	type Data struct {
+		Headers       []string `json:"headers"`
+		Rows          []Row    `json:"rows"`
+		TotalRowCount int64    `json:"totalrowcount"`
+	}
+	type Result struct {
+		Data Data `json:"data"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	data := &ReportData{Headers: result.Data.Headers, TotalRows: result.Data.TotalRowCount}
+	for _, r := range result.Data.Rows {
+		data.Rows = append(data.Rows, r.Columns)
+	}
+	return data, nil
+}
+
+// GetCustomReport fetches every row of a custom report, one page at a time
+func (m *MoodleApi) GetCustomReport(reportId int64) (*ReportData, error) {
+	data, err := m.GetCustomReportPage(reportId, 0, reportPageSize)
+	if err != nil {
+		return nil, err
+	}
+	for page := 1; int64(len(data.Rows)) < data.TotalRows; page++ {
+		next, err := m.GetCustomReportPage(reportId, page, reportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(next.Rows) == 0 {
+			break
+		}
+		data.Rows = append(data.Rows, next.Rows...)
+	}
+	return data, nil
+}
@@ -0,0 +1,50 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// pagedLookupUrl returns a different canned response for each page
+type pagedLookupUrl struct {
+	*testLookupUrl
+	pages map[string]string
+}
+
+func (p *pagedLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	p.responses["core_reportbuilder_retrieve_report"] = p.pages[form.Get("page")]
+	return p.testLookupUrl.Do(method, u, form, header)
+}
+
+func TestCustomReports(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_reportbuilder_list_reports": `{"reports":[{"id":4,"name":"Enrolled students","source":"core_user\\reportbuilder\\datasource\\users","sourcename":"Users","timecreated":1600000000,"timemodified":1600000100}],"warnings":[]}`,
+	}))
+
+	reports, err := api.GetCustomReports()
+	if err != nil {
+		t.Fatalf("GetCustomReports failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Name != "Enrolled students" || reports[0].SourceName != "Users" || reports[0].Created.Unix() != 1600000000 {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+
+	fetch := &pagedLookupUrl{newTestLookupUrl(map[string]string{}), map[string]string{
+		"0": `{"details":{"id":4},"data":{"headers":["Name","Email"],"rows":[{"columns":["Jane","jane@example.com"]},{"columns":["John","john@example.com"]}],"totalrowcount":3},"warnings":[]}`,
+		"1": `{"details":{"id":4},"data":{"headers":["Name","Email"],"rows":[{"columns":["Sam","sam@example.com"]}],"totalrowcount":3},"warnings":[]}`,
+	}}
+	api.SetUrlFetcher(fetch)
+	data, err := api.GetCustomReport(4)
+	if err != nil {
+		t.Fatalf("GetCustomReport failed: %v", err)
+	}
+	if len(data.Headers) != 2 || len(data.Rows) != 3 || data.Rows[2][0] != "Sam" || data.TotalRows != 3 {
+		t.Errorf("Unexpected report data: %+v", data)
+	}
+	if len(fetch.requests) != 2 || fetch.last().Get("page") != "1" || fetch.last().Get("reportid") != "4" {
+		t.Errorf("Expected two pages to be fetched, found %v", fetch.requests)
+	}
+}
@@ -0,0 +1,230 @@
+package moodle
+
+import (
+	"strings"
+	"time"
+)
+
+// Restriction holds the availability rules of a course module, as stored by
+// moodle in the availability json of the module.
+//
+//	{"op":"&","c":[{"type":"group","id":191},{"type":"date","d":">=","t":1541682000}],"showc":[true,true]}
+type Restriction struct {
+	OP    string         `json:"op"`
+	C     []RestrictionC `json:"c"`
+	Show  bool           `json:"show"`
+	ShowC []bool         `json:"showc"`
+}
+
+// RestrictionC is a single availability condition. Which fields are used
+// depends on the Type of condition:
+//
+//	group, grouping: Id
+//	date:            D (">=" available from, "<" available until) and T
+//	profile:         Sf (standard field) or Cf (custom field), Op and V
+//	grade:           Id (grade item), Min and Max (percentages)
+//	completion:      Cm and E (expected CompletionState)
+//
+// A condition without a Type is a nested group of conditions, combined
+// using Op in the same way as a Restriction.
+//
+//	{"op":"|","c":[{"type":"group","id":191},{"op":"&","c":[...]}]}
+type RestrictionC struct {
+	Type string   `json:"type"`
+	Id   int64    `json:"id"`
+	D    string   `json:"d"`
+	T    int64    `json:"t"`
+	Sf   string   `json:"sf,omitempty"`
+	Cf   string   `json:"cf,omitempty"`
+	Op   string   `json:"op,omitempty"`
+	V    string   `json:"v,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+	Cm   CmID     `json:"cm,omitempty"`
+	E    int      `json:"e,omitempty"`
+
+	C []RestrictionC `json:"c,omitempty"`
+}
+
+// CompletionState is the completion status of an activity for a person
+type CompletionState int
+
+const (
+	CompletionIncomplete   CompletionState = 0
+	CompletionComplete     CompletionState = 1
+	CompletionCompletePass CompletionState = 2
+	CompletionCompleteFail CompletionState = 3
+)
+
+// RestrictionContext holds what is known about a person when evaluating
+// restrictions. Conditions that need information that has not been supplied,
+// such as a grade condition when Grades is nil, are treated as not met.
+type RestrictionContext struct {
+	Groups []CourseGroup
+
+	// Groupings are the ids of the groupings the person belongs to, see
+	// GroupingsOf
+	Groupings []int64
+	Person    *Person
+
+	// Grades maps a grade item id to the person's grade as a percentage
+	Grades map[int64]float64
+
+	// Completion maps a course module to the person's completion state
+	Completion map[CmID]CompletionState
+
+	// Now is the time used for date conditions. Defaults to the current time.
+	Now time.Time
+}
+
+// IsRestricted reports whether a person in the groups is prevented from
+// accessing the module. Only group and date conditions can be met, use
+// IsRestrictedForGroupings when the module is restricted to a grouping, and
+// IsRestrictedFor to evaluate profile, grade and completion conditions.
+func (r *Restriction) IsRestricted(groups []CourseGroup) bool {
+	return r.IsRestrictedFor(&RestrictionContext{Groups: groups})
+}
+
+// IsRestrictedForGroupings is like IsRestricted, also meeting grouping
+// conditions for groupings that contain one of the groups. The groupings of a
+// course are listed by GetCourseGroupings.
+func (r *Restriction) IsRestrictedForGroupings(groups []CourseGroup, groupings []CourseGrouping) bool {
+	return r.IsRestrictedFor(&RestrictionContext{Groups: groups, Groupings: GroupingsOf(groups, groupings)})
+}
+
+// IsRestrictedFor reports whether the person described by the context is
+// prevented from accessing the module.
+func (r *Restriction) IsRestrictedFor(ctx *RestrictionContext) bool {
+	return !available(r.OP, r.C, ctx)
+}
+
+// available evaluates a group of conditions
+func available(op string, conditions []RestrictionC, ctx *RestrictionContext) bool {
+	if len(conditions) == 0 {
+		return true
+	}
+
+	// Negated operators negate each condition. "!&" must not match all
+	// conditions, "!|" must not match any condition.
+	not := op == "!&" || op == "!|"
+	all := op == "&" || op == "!|"
+
+	for _, c := range conditions {
+		met := c.met(ctx) != not
+		if all && !met {
+			return false
+		}
+		if !all && met {
+			return true
+		}
+	}
+	return all
+}
+
+// met reports whether the condition is satisfied. Unknown condition types
+// are treated as met.
+func (c *RestrictionC) met(ctx *RestrictionContext) bool {
+	switch c.Type {
+	case "":
+		return available(c.Op, c.C, ctx)
+	case "group":
+		for _, g := range ctx.Groups {
+			if GroupID(c.Id) == g.Id {
+				return true
+			}
+		}
+		return false
+	case "grouping":
+		for _, g := range ctx.Groupings {
+			if c.Id == g {
+				return true
+			}
+		}
+		return false
+	case "date":
+		now := ctx.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if c.D == "<" {
+			return now.Unix() < c.T
+		}
+		return now.Unix() >= c.T
+	case "profile":
+		if ctx.Person == nil {
+			return false
+		}
+		return profileConditionMet(c.Op, c.profileValue(ctx.Person), c.V)
+	case "grade":
+		grade, ok := ctx.Grades[c.Id]
+		if !ok {
+			return false
+		}
+		if c.Min != nil && grade < *c.Min {
+			return false
+		}
+		if c.Max != nil && grade >= *c.Max {
+			return false
+		}
+		return true
+	case "completion":
+		state, ok := ctx.Completion[c.Cm]
+		if !ok {
+			state = CompletionIncomplete
+		}
+		switch CompletionState(c.E) {
+		case CompletionComplete:
+			return state == CompletionComplete || state == CompletionCompletePass
+		case CompletionIncomplete:
+			return state == CompletionIncomplete || state == CompletionCompleteFail
+		default:
+			return state == CompletionState(c.E)
+		}
+	default:
+		return true
+	}
+}
+
+// profileValue returns the value of the profile field tested by a condition
+func (c *RestrictionC) profileValue(p *Person) string {
+	if c.Cf != "" {
+		return p.Field(c.Cf)
+	}
+	switch c.Sf {
+	case "firstname":
+		return p.FirstName
+	case "lastname":
+		return p.LastName
+	case "email":
+		return p.Email
+	case "username":
+		return p.Username
+	case "lang":
+		return p.Lang
+	}
+	return ""
+}
+
+// profileConditionMet compares a profile value the same way moodle does,
+// ignoring case and surrounding white space.
+func profileConditionMet(op, value, expected string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	switch op {
+	case "isequalto":
+		return value == expected
+	case "contains":
+		return strings.Contains(value, expected)
+	case "doesnotcontain":
+		return !strings.Contains(value, expected)
+	case "startswith":
+		return strings.HasPrefix(value, expected)
+	case "endswith":
+		return strings.HasSuffix(value, expected)
+	case "isempty":
+		return value == ""
+	case "isnotempty":
+		return value != ""
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package moodle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Restrictions can be built in code and applied to course modules, for
+// example to open a module to one group during an intensive:
+//
+//	r := moodle.AllOf(
+//		moodle.GroupCondition(191),
+//		moodle.DateFrom(start),
+//		moodle.DateUntil(end),
+//	)
+//	err := api.SetModuleAvailability(cmid, r)
+
+// GroupCondition requires membership of a group
+func GroupCondition(id GroupID) RestrictionC {
+	return RestrictionC{Type: "group", Id: int64(id)}
+}
+
+// GroupingCondition requires membership of a group in a grouping
+func GroupingCondition(id int64) RestrictionC {
+	return RestrictionC{Type: "grouping", Id: id}
+}
+
+// DateFrom makes a module available from a time
+func DateFrom(t time.Time) RestrictionC {
+	return RestrictionC{Type: "date", D: ">=", T: t.Unix()}
+}
+
+// DateUntil makes a module available until a time
+func DateUntil(t time.Time) RestrictionC {
+	return RestrictionC{Type: "date", D: "<", T: t.Unix()}
+}
+
+// AllOf returns a restriction that requires every condition to be met. The
+// module is shown, greyed out, to people who do not meet the conditions.
+func AllOf(conditions ...RestrictionC) *Restriction {
+	r := &Restriction{OP: "&", C: conditions}
+	for range conditions {
+		r.ShowC = append(r.ShowC, true)
+	}
+	return r
+}
+
+// AnyOf returns a restriction that requires at least one condition to be
+// met. The module is shown, greyed out, to people who do not meet the
+// conditions.
+func AnyOf(conditions ...RestrictionC) *Restriction {
+	return &Restriction{OP: "|", C: conditions, Show: true}
+}
+
+// Condition converts a restriction into a condition, so that it may be
+// nested within another restriction.
+func (r *Restriction) Condition() RestrictionC {
+	return RestrictionC{Op: r.OP, C: r.C}
+}
+
+// JSON returns the restriction as availability json
+func (r *Restriction) JSON() (string, error) {
+	data, err := marshalUnescaped(r)
+	return string(data), err
+}
+
+// marshalUnescaped encodes json without escaping the <, > and & characters
+// used by availability operators.
+func marshalUnescaped(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(b.Bytes()), nil
+}
+
+// MarshalJSON writes the restriction in the form moodle expects. "&" and "!|"
+// restrictions have a show flag for each condition, "|" and "!&"
+// restrictions have a single show flag.
+func (r Restriction) MarshalJSON() ([]byte, error) {
+	v := map[string]interface{}{
+		"op": r.OP,
+		"c":  r.conditions(),
+	}
+	if r.OP == "&" || r.OP == "!|" {
+		showc := r.ShowC
+		for len(showc) < len(r.C) {
+			showc = append(showc, true)
+		}
+		v["showc"] = showc
+	} else {
+		v["show"] = r.Show
+	}
+	return marshalUnescaped(v)
+}
+
+func (r Restriction) conditions() []RestrictionC {
+	if r.C == nil {
+		return []RestrictionC{}
+	}
+	return r.C
+}
+
+// MarshalJSON writes only the fields used by the type of condition
+func (c RestrictionC) MarshalJSON() ([]byte, error) {
+	v := map[string]interface{}{}
+	switch c.Type {
+	case "":
+		v["op"] = c.Op
+		v["c"] = Restriction{C: c.C}.conditions()
+	case "group", "grouping":
+		v["type"] = c.Type
+		if c.Id != 0 {
+			v["id"] = c.Id
+		}
+	case "date":
+		v["type"] = c.Type
+		v["d"] = c.D
+		v["t"] = c.T
+	case "profile":
+		v["type"] = c.Type
+		if c.Cf != "" {
+			v["cf"] = c.Cf
+		} else {
+			v["sf"] = c.Sf
+		}
+		v["op"] = c.Op
+		if c.Op != "isempty" && c.Op != "isnotempty" {
+			v["v"] = c.V
+		}
+	case "grade":
+		v["type"] = c.Type
+		v["id"] = c.Id
+		if c.Min != nil {
+			v["min"] = *c.Min
+		}
+		if c.Max != nil {
+			v["max"] = *c.Max
+		}
+	case "completion":
+		v["type"] = c.Type
+		v["cm"] = c.Cm
+		v["e"] = c.E
+	default:
+		// Unknown conditions are written with every field so they survive
+		// being read and written back.
+		type plain RestrictionC
+		return marshalUnescaped(plain(c))
+	}
+	return marshalUnescaped(v)
+}
+
+// SetAvailabilityFunction sets the web service function used by
+// SetModuleAvailability. Moodle does not include a web service to change the
+// availability of a module, so this must be provided by a local plugin. The
+// function is called with the parameters "cmid" and "availability", the
+// latter being the availability json, or blank to remove all restrictions.
+func (m *MoodleApi) SetAvailabilityFunction(function string) {
+	m.availabilityFunction = function
+}
+
+// SetModuleAvailability replaces the restrictions on a course module. A nil
+// restriction removes all restrictions. Requires SetAvailabilityFunction.
+func (m *MoodleApi) SetModuleAvailability(cmid CmID, r *Restriction) error {
+	if m.availabilityFunction == "" {
+		return errors.New("Setting module availability requires a web service function, see SetAvailabilityFunction")
+	}
+
+	availability := ""
+	if r != nil {
+		var err error
+		if availability, err = r.JSON(); err != nil {
+			return err
+		}
+	}
+
+	_, err := m.call(m.availabilityFunction, url.Values{
+		"cmid":         {fmt.Sprint(cmid)},
+		"availability": {availability},
+	})
+	return err
+}
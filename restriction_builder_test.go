@@ -0,0 +1,56 @@
+package moodle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRestrictionBuilder(t *testing.T) {
+
+	start := time.Unix(1541682000, 0)
+	end := time.Unix(1542286800, 0)
+	r := AllOf(
+		GroupCondition(191),
+		AnyOf(DateFrom(start), DateUntil(end)).Condition(),
+	)
+
+	data, err := r.JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	expected := `{"c":[{"id":191,"type":"group"},{"c":[{"d":">=","t":1541682000,"type":"date"},{"d":"<","t":1542286800,"type":"date"}],"op":"|"}],"op":"&","showc":[true,true]}`
+	if data != expected {
+		t.Errorf("Unexpected availability json:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	var parsed Restriction
+	if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	ctx := &RestrictionContext{Groups: []CourseGroup{{Id: 191}}, Now: start}
+	if parsed.IsRestrictedFor(ctx) {
+		t.Errorf("Expected parsed restriction to allow a group member")
+	}
+}
+
+func TestSetModuleAvailability(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"local_availability_set": `null`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.SetModuleAvailability(5, AllOf(GroupCondition(10))); err == nil {
+		t.Errorf("Expected an error when no availability function is set")
+	}
+
+	api.SetAvailabilityFunction("local_availability_set")
+	if err := api.SetModuleAvailability(5, AnyOf(GroupCondition(10))); err != nil {
+		t.Fatalf("SetModuleAvailability failed: %v", err)
+	}
+	if fetch.last().Get("cmid") != "5" || fetch.last().Get("availability") != `{"c":[{"id":10,"type":"group"}],"op":"|","show":true}` {
+		t.Errorf("Unexpected request %v", fetch.last())
+	}
+}
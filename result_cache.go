@@ -0,0 +1,133 @@
+package moodle
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultCache stores the responses of calls that only read data, so that
+// repeated calls, such as GetCourseRoles for the same course while
+// generating a report, are answered without contacting moodle.
+// Implementations must be safe for concurrent use.
+type ResultCache interface {
+	// Get returns a response that has not expired
+	Get(key string) (string, bool)
+
+	// Set stores a response until the ttl has passed
+	Set(key string, body string, ttl time.Duration)
+
+	// Delete removes every response with a key starting with prefix
+	Delete(prefix string)
+}
+
+// MemoryResultCache is a ResultCache held in memory. Expired responses are
+// removed when they are next read.
+type MemoryResultCache struct {
+	mu      sync.Mutex
+	entries map[string]resultEntry
+	now     func() time.Time
+}
+
+type resultEntry struct {
+	body    string
+	expires time.Time
+}
+
+func NewMemoryResultCache() *MemoryResultCache {
+	return &MemoryResultCache{entries: make(map[string]resultEntry), now: time.Now}
+}
+
+func (c *MemoryResultCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return e.body, true
+}
+
+func (c *MemoryResultCache) Set(key string, body string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = resultEntry{body: body, expires: c.now().Add(ttl)}
+}
+
+func (c *MemoryResultCache) Delete(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// resultCache holds the settings of SetResultCache
+type resultCache struct {
+	cache ResultCache
+	ttl   time.Duration
+
+	mu   sync.RWMutex
+	ttls map[string]time.Duration
+}
+
+// SetResultCache caches the responses of functions that only read data,
+// such as core_course_search_courses, core_group_get_course_groups and
+// core_enrol_get_enrolled_users, for ttl. Use SetCacheTTL to change how long
+// a particular function is cached, and InvalidateCache when data is known to
+// have changed. Changes made through the api do not invalidate the cache. A
+// nil cache disables caching.
+//
+// Responses are held separately for each site and user, so one cache may be
+// shared by several apis. Responses are not cached for custom Credentials,
+// whose user is not known.
+func (m *MoodleApi) SetResultCache(cache ResultCache, ttl time.Duration) {
+	if cache == nil {
+		m.results = nil
+		return
+	}
+	m.results = &resultCache{cache: cache, ttl: ttl, ttls: make(map[string]time.Duration)}
+}
+
+// SetCacheTTL sets how long responses to a web service function are cached,
+// overriding the ttl given to SetResultCache. A ttl of zero or less stops
+// the function being cached.
+func (m *MoodleApi) SetCacheTTL(function string, ttl time.Duration) {
+	if m.results == nil {
+		return
+	}
+	m.results.mu.Lock()
+	m.results.ttls[function] = ttl
+	m.results.mu.Unlock()
+}
+
+// InvalidateCache removes the cached responses of the web service functions,
+// or every cached response if no function is given.
+func (m *MoodleApi) InvalidateCache(functions ...string) {
+	if m.results == nil {
+		return
+	}
+	if len(functions) == 0 {
+		m.results.cache.Delete("")
+	}
+	for _, f := range functions {
+		m.results.cache.Delete(f + "?")
+	}
+}
+
+// cacheTTL returns how long the responses of a function that only reads
+// data are cached, zero if they are not
+func (r *resultCache) cacheTTL(function string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if ttl, ok := r.ttls[function]; ok {
+		return ttl
+	}
+	return r.ttl
+}
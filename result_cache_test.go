@@ -0,0 +1,115 @@
+package moodle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultCache(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_group_get_course_groups":  `[{"id":10,"name":"Audit"}]`,
+		"core_enrol_get_enrolled_users": `[{"id":5,"firstname":"Ann"}]`,
+		"core_group_create_groups":      `[{"id":11,"name":"Tax"}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	cache := NewMemoryResultCache()
+	now := time.Unix(1600000000, 0)
+	cache.now = func() time.Time { return now }
+	api.SetResultCache(cache, time.Minute)
+	api.SetCacheTTL("core_enrol_get_enrolled_users", 0)
+
+	for i := 0; i < 3; i++ {
+		if groups, err := api.GetCourseGroups(3); err != nil || len(groups) != 1 {
+			t.Fatalf("GetCourseGroups failed: %v %v", groups, err)
+		}
+	}
+	if len(fetch.requests) != 1 {
+		t.Errorf("Expected repeated calls to be answered from the cache, found %d requests", len(fetch.requests))
+	}
+	api.GetCourseGroups(4)
+	if len(fetch.requests) != 2 {
+		t.Errorf("Expected a different course to be fetched, found %d requests", len(fetch.requests))
+	}
+
+	api.GetCourseRoles(3)
+	api.GetCourseRoles(3)
+	if len(fetch.requests) != 4 {
+		t.Errorf("Expected function with no ttl not to be cached, found %d requests", len(fetch.requests))
+	}
+	api.AddGroupToCourse(3, "Tax", "")
+	api.AddGroupToCourse(3, "Tax", "")
+	if len(fetch.requests) != 6 {
+		t.Errorf("Expected changes not to be cached, found %d requests", len(fetch.requests))
+	}
+
+	other := api.WithToken("other")
+	other.GetCourseGroups(3)
+	if len(fetch.requests) != 7 {
+		t.Errorf("Expected responses not to be shared between tokens, found %d requests", len(fetch.requests))
+	}
+
+	api.InvalidateCache("core_group_get_course_groups")
+	api.GetCourseGroups(3)
+	other.GetCourseGroups(3)
+	if len(fetch.requests) != 9 {
+		t.Errorf("Expected invalidated responses to be fetched, found %d requests", len(fetch.requests))
+	}
+
+	now = now.Add(2 * time.Minute)
+	api.GetCourseGroups(3)
+	if len(fetch.requests) != 10 {
+		t.Errorf("Expected expired response to be fetched, found %d requests", len(fetch.requests))
+	}
+
+	api.InvalidateCache()
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected every response to be removed, found %d", len(cache.entries))
+	}
+}
+
+func TestResultCacheScope(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_group_get_course_groups": `[{"id":10,"name":"Audit"}]`,
+	})
+	cache := NewMemoryResultCache()
+
+	// Sites sharing a cache do not share responses
+	site1 := NewMoodleApi("https://one.example.com/", "token")
+	site1.SetUrlFetcher(fetch)
+	site1.SetResultCache(cache, time.Minute)
+	site2 := NewMoodleApi("https://two.example.com/", "token")
+	site2.SetUrlFetcher(fetch)
+	site2.SetResultCache(cache, time.Minute)
+	site1.GetCourseGroups(3)
+	site2.GetCourseGroups(3)
+	if len(fetch.requests) != 2 {
+		t.Errorf("Expected each site to be called, found %d requests", len(fetch.requests))
+	}
+
+	// Nor do people authenticated with bearer tokens
+	bearer := func(token string) *MoodleApi {
+		api := NewMoodleApi("https://one.example.com/", "")
+		api.SetUrlFetcher(fetch)
+		api.SetCredentials(NewBearerCredentials(func() (string, time.Time, error) {
+			return token, time.Time{}, nil
+		}, ""))
+		api.SetResultCache(cache, time.Minute)
+		return api
+	}
+	ann, bo := bearer("ann"), bearer("bo")
+	fetch.requests = nil
+	for i := 0; i < 3; i++ {
+		ann.GetCourseGroups(3)
+		bo.GetCourseGroups(3)
+	}
+
+	// The first call of each person is made before the token is known, so
+	// is not cached
+	if len(fetch.requests) != 4 {
+		t.Errorf("Expected two requests for each person, found %d requests", len(fetch.requests))
+	}
+}
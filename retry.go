@@ -0,0 +1,76 @@
+package moodle
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how failed calls are repeated. Calls that read data
+// are repeated when the failure is retryable, see MoodleError.Retryable.
+// Calls that change data are only repeated when moodle raised a retryable
+// exception, as a call that timed out may have been applied.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is made, including the
+	// first. Defaults to 3.
+	MaxAttempts int
+
+	// Delay is the wait before the first retry, doubled for each further
+	// retry up to MaxDelay. Defaults to 500 milliseconds and 10 seconds.
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	// Retryable overrides the classification of errors, for example to
+	// retry additional moodle error codes.
+	Retryable func(err error) bool
+}
+
+// SetRetryPolicy enables retrying failed calls. A nil policy disables
+// retries, which is the default.
+func (m *MoodleApi) SetRetryPolicy(policy *RetryPolicy) {
+	m.retry = policy
+}
+
+// retryable reports whether a failed call should be repeated. Calls that
+// change data are only repeated if moodle reported an exception, so the
+// change was not made.
+func (p *RetryPolicy) retryable(readOnly bool, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var merr *MoodleError
+	if !errors.As(err, &merr) || !merr.Retryable() {
+		return false
+	}
+	return readOnly || merr.Exception != ""
+}
+
+// delay returns the wait before a retry, the first retry being attempt 1
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := durationOr(p.Delay, 500*time.Millisecond)
+	max := durationOr(p.MaxDelay, 10*time.Second)
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// sendWithRetry sends a call, repeating it as allowed by the retry policy
+func (m *MoodleApi) sendWithRetry(function string, params url.Values, extra http.Header) (string, error) {
+	body, err := m.send(function, params, extra)
+	if m.retry == nil {
+		return body, err
+	}
+	attempts := intOr(m.retry.MaxAttempts, 3)
+	for attempt := 1; attempt < attempts && err != nil && m.retry.retryable(m.readOnly(function), err); attempt++ {
+		d := m.retry.delay(attempt)
+		m.info("Retrying call to %s in %s: %v", function, d, err)
+		time.Sleep(d)
+		body, err = m.send(function, params, extra)
+	}
+	return body, err
+}
@@ -0,0 +1,71 @@
+package moodle
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// failingLookupUrl fails the first calls to each function with a response
+type failingLookupUrl struct {
+	*testLookupUrl
+	failures map[string][]string
+}
+
+func (f *failingLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	function := form.Get("wsfunction")
+	if pending := f.failures[function]; len(pending) > 0 {
+		f.failures[function] = pending[1:]
+		f.testLookupUrl.Do(method, u, form, header)
+		return pending[0], 200, "application/json", nil
+	}
+	return f.testLookupUrl.Do(method, u, form, header)
+}
+
+func TestRetryPolicy(t *testing.T) {
+
+	lock := `{"exception":"moodle_exception","errorcode":"locktimeout","message":"Unable to obtain a lock"}`
+	invalid := `{"exception":"invalid_parameter_exception","errorcode":"invalidparameter","message":"Invalid parameter value detected"}`
+	fetch := &failingLookupUrl{newTestLookupUrl(map[string]string{
+		"core_group_get_course_groups":  `[{"id":10,"name":"Audit"}]`,
+		"core_enrol_get_enrolled_users": `[]`,
+		"core_group_create_groups":      `[{"id":11,"name":"Tax"}]`,
+	}), map[string][]string{
+		"core_group_get_course_groups":  {lock, lock},
+		"core_enrol_get_enrolled_users": {invalid},
+		"core_group_create_groups":      {lock, lock, lock},
+	}}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if _, err := api.GetCourseGroups(3); err == nil {
+		t.Fatalf("Expected an error without a retry policy")
+	}
+	fetch.failures["core_group_get_course_groups"] = []string{lock, lock}
+	fetch.requests = nil
+
+	api.SetRetryPolicy(&RetryPolicy{Delay: time.Millisecond})
+	groups, err := api.GetCourseGroups(3)
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("Expected lock timeouts to be retried, found %v %v", groups, err)
+	}
+	if len(fetch.requests) != 3 {
+		t.Errorf("Expected three attempts, found %d", len(fetch.requests))
+	}
+
+	fetch.requests = nil
+	if _, err := api.GetCourseRoles(3); err == nil || len(fetch.requests) != 1 {
+		t.Errorf("Expected permanent errors not to be retried, found %d attempts", len(fetch.requests))
+	}
+
+	fetch.requests = nil
+	if _, err := api.AddGroupToCourse(3, "Tax", ""); err == nil || len(fetch.requests) != 3 {
+		t.Errorf("Expected retries to stop after three attempts, found %d attempts: %v", len(fetch.requests), err)
+	}
+
+	p := &RetryPolicy{Delay: time.Second, MaxDelay: 3 * time.Second}
+	if p.delay(1) != time.Second || p.delay(2) != 2*time.Second || p.delay(3) != 3*time.Second {
+		t.Errorf("Expected delay to double up to the maximum, found %s %s %s", p.delay(1), p.delay(2), p.delay(3))
+	}
+}
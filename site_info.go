@@ -0,0 +1,132 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+// SiteInfo describes a moodle site and the user the token belongs to
+type SiteInfo struct {
+	SiteName  string
+	SiteUrl   string
+	Username  string
+	FirstName string
+	LastName  string
+	FullName  string
+	UserId    UserID
+	Lang      string
+	IsAdmin   bool
+
+	// Release is the human readable version, such as "4.1.2 (Build: 20230313)"
+	Release string
+	// Version is the version number, such as "2022112802"
+	Version string
+
+	// Functions lists the web service functions available to the token
+	Functions []string
+
+	// AdvancedFeatures maps features such as "enablecompletion" to 1 if
+	// they are enabled
+	AdvancedFeatures map[string]int
+
+	// MobileService reports whether the mobile app web service is enabled.
+	// It is nil if tool_mobile_get_public_config is not available to the
+	// token.
+	MobileService *bool
+}
+
+// HasFunction reports whether a web service function is available
+func (s *SiteInfo) HasFunction(function string) bool {
+	for _, f := range s.Functions {
+		if f == function {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSiteInfoStruct fetches details of the site and the token's user. If
+// tool_mobile_get_public_config is available a second call is made to read
+// whether the mobile service is enabled.
+func (m *MoodleApi) GetSiteInfoStruct() (*SiteInfo, error) {
+	info, err := m.getSiteInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.HasFunction("tool_mobile_get_public_config") {
+		if config, err := m.GetPublicConfig(); err == nil {
+			enabled := config.EnableMobileWebService != 0
+			info.MobileService = &enabled
+		}
+	}
+	return info, nil
+}
+
+// getSiteInfo calls core_webservice_get_site_info. Missing fields are left
+// empty.
+func (m *MoodleApi) getSiteInfo() (*SiteInfo, error) {
+	return m.readSiteInfo(m.call)
+}
+
+// readSiteInfo makes the core_webservice_get_site_info call using call
+func (m *MoodleApi) readSiteInfo(call func(string, url.Values) (string, error)) (*SiteInfo, error) {
+	body, err := call("core_webservice_get_site_info", url.Values{
+		"moodlewssettingraw": {"true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Function struct {
+		Name string `json:"name"`
+	}
+	type Feature struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+	type Result struct {
+		SiteName         string     `json:"sitename"`
+		SiteUrl          string     `json:"siteurl"`
+		Username         string     `json:"username"`
+		FirstName        string     `json:"firstname"`
+		LastName         string     `json:"lastname"`
+		FullName         string     `json:"fullname"`
+		UserId           UserID     `json:"userid"`
+		Lang             string     `json:"lang"`
+		IsAdmin          bool       `json:"userissiteadmin"`
+		Release          string     `json:"release"`
+		Version          string     `json:"version"`
+		Functions        []Function `json:"functions"`
+		AdvancedFeatures []Feature  `json:"advancedfeatures"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	info := &SiteInfo{
+		SiteName:         result.SiteName,
+		SiteUrl:          result.SiteUrl,
+		Username:         result.Username,
+		FirstName:        result.FirstName,
+		LastName:         result.LastName,
+		FullName:         result.FullName,
+		UserId:           result.UserId,
+		Lang:             result.Lang,
+		IsAdmin:          result.IsAdmin,
+		Release:          result.Release,
+		Version:          result.Version,
+		AdvancedFeatures: make(map[string]int),
+	}
+	for _, f := range result.Functions {
+		info.Functions = append(info.Functions, f.Name)
+	}
+	for _, f := range result.AdvancedFeatures {
+		info.AdvancedFeatures[f.Name] = f.Value
+	}
+	return info, nil
+}
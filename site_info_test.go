@@ -0,0 +1,40 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestGetSiteInfoStruct(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"sitename":"Example","siteurl":"https://moodle.example.com","username":"ws","firstname":"Web","lastname":"Service","fullname":"Web Service","userid":2,"lang":"en","userissiteadmin":true,"release":"4.1.2 (Build: 20230313)","version":"2022112802",` +
+			`"functions":[{"name":"core_user_get_users","version":"4.1"},{"name":"tool_mobile_get_public_config","version":"4.1"}],"advancedfeatures":[{"name":"enablecompletion","value":1},{"name":"usetags","value":0}]}`,
+		"tool_mobile_get_public_config": `{"wwwroot":"https://moodle.example.com","enablemobilewebservice":1,"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	info, err := api.GetSiteInfoStruct()
+	if err != nil {
+		t.Fatalf("GetSiteInfoStruct failed: %v", err)
+	}
+	if info.SiteName != "Example" || info.UserId != 2 || info.Version != "2022112802" || !info.IsAdmin {
+		t.Errorf("Unexpected site info: %+v", info)
+	}
+	if !info.HasFunction("core_user_get_users") || info.HasFunction("core_user_create_users") {
+		t.Errorf("Unexpected functions: %v", info.Functions)
+	}
+	if info.AdvancedFeatures["enablecompletion"] != 1 || info.AdvancedFeatures["usetags"] != 0 {
+		t.Errorf("Unexpected features: %v", info.AdvancedFeatures)
+	}
+	if info.MobileService == nil || !*info.MobileService {
+		t.Errorf("Expected the mobile service to be enabled")
+	}
+
+	// Missing fields must not cause a panic
+	fetch.responses["core_webservice_get_site_info"] = `{"sitename":"Example"}`
+	name, first, _, userId, err := api.GetSiteInfo()
+	if err != nil || name != "Example" || first != "" || userId != 0 {
+		t.Errorf("Unexpected site info: %q %q %d %v", name, first, userId, err)
+	}
+}
@@ -0,0 +1,134 @@
+package moodle
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Mailer delivers an email message. SmtpMailer is the default implementation,
+// alternatives may be supplied using SetMailer.
+type Mailer interface {
+	Send(msg *EmailMessage) error
+}
+
+// SmtpSecurity selects how a connection to the smtp server is secured.
+type SmtpSecurity int
+
+const (
+	// SmtpImplicitTLS connects using TLS from the very beginning, as on
+	// port 465. This is the default, whatever the port.
+	SmtpImplicitTLS SmtpSecurity = iota
+	// SmtpStartTLS connects in plain text then upgrades the connection
+	// using the STARTTLS command (port 587). Sending fails if the server
+	// does not offer STARTTLS.
+	SmtpStartTLS
+)
+
+// SmtpMailer sends email through an smtp server. Server certificates are
+// verified unless InsecureSkipVerify is explicitly set.
+type SmtpMailer struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Security SmtpSecurity
+
+	// InsecureSkipVerify disables verification of the server certificate.
+	// Only use this for test servers with self signed certificates.
+	InsecureSkipVerify bool
+
+	// Timeout applies to establishing the connection. Defaults to 16 seconds.
+	Timeout time.Duration
+}
+
+func (s *SmtpMailer) tlsConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: s.InsecureSkipVerify,
+		ServerName:         s.Host,
+	}
+}
+
+// Send delivers the message to the recipient
+func (s *SmtpMailer) Send(msg *EmailMessage) error {
+	if s.Host == "" || s.Port == 0 {
+		return errors.New("Sending email requires smtp host and port to be specified.")
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 16 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	address := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+
+	var conn net.Conn
+	if s.Security == SmtpImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, s.tlsConfig())
+		if err != nil {
+			return errors.New(fmt.Sprintf("tls.Dial(\"%s\") failed: %v", address, err))
+		}
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+		if err != nil {
+			return errors.New(fmt.Sprintf("net.Dial(\"%s\") failed: %v", address, err))
+		}
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return errors.New(fmt.Sprintf("SMTP.NewClient() failed: %v", err))
+	}
+	defer c.Close()
+
+	if s.Security == SmtpStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New(fmt.Sprintf("SMTP server %s does not support STARTTLS", address))
+		}
+		if err = c.StartTLS(s.tlsConfig()); err != nil {
+			return errors.New(fmt.Sprintf("SMTP.StartTLS() failed: %v", err))
+		}
+	}
+
+	if s.User != "" && s.Password != "" {
+		auth := smtp.PlainAuth("", s.User, s.Password, s.Host)
+		if err = c.Auth(auth); err != nil {
+			return errors.New(fmt.Sprintf("SMTP.Auth() failed: %v", err))
+		}
+	}
+
+	if err = c.Mail(msg.FromEmail); err != nil {
+		return errors.New(fmt.Sprintf("SMTP.Mail() failed: %v", err))
+	}
+
+	if err = c.Rcpt(msg.ToEmail); err != nil {
+		return errors.New(fmt.Sprintf("SMTP.Rcpt() failed: %v", err))
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.New(fmt.Sprintf("SMTP.Data() failed: %v", err))
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return errors.New(fmt.Sprintf("SMTP.Close() failed: %v", err))
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,31 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestSmtpSecurity(t *testing.T) {
+
+	s := &SmtpMailer{Host: "smtp.example.com", Port: 587}
+	if s.Security != SmtpImplicitTLS {
+		t.Errorf("Implicit TLS should be the default on any port")
+	}
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetSmtpSettings("smtp.example.com", 25, "user", "password", "Admin", "admin@example.com")
+	if mailer, ok := api.mailer.(*SmtpMailer); !ok || mailer.Security != SmtpImplicitTLS {
+		t.Errorf("SetSmtpSettings should use implicit TLS, found %+v", api.mailer)
+	}
+
+	if s.tlsConfig().InsecureSkipVerify {
+		t.Errorf("Certificate verification should be enabled by default")
+	}
+	if s.tlsConfig().ServerName != "smtp.example.com" {
+		t.Errorf("TLS server name should match the smtp host")
+	}
+
+	var m Mailer = s
+	if err := m.Send(&EmailMessage{}); err == nil {
+		t.Errorf("Send() should fail for an empty message")
+	}
+}
@@ -0,0 +1,142 @@
+package moodle
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StreamLookupUrl is implemented by a LookupUrl that can return a response
+// body without reading it into memory, so that large responses can be
+// decoded as they arrive.
+type StreamLookupUrl interface {
+	DoStream(method, url string, form url.Values, header http.Header) (io.ReadCloser, int, error)
+}
+
+// StreamCourseRoles calls fn for each person in a course as they are read
+// from moodle, rather than holding every person in memory as
+// GetCourseRoles does. If fn returns an error no further people are read
+// and the error is returned.
+func (m *MoodleApi) StreamCourseRoles(courseId CourseID, fn func(CoursePerson) error) error {
+	r, err := m.stream("core_enrol_get_enrolled_users", url.Values{
+		"moodlewssettingraw": {"true"},
+		"courseid":           {fmt.Sprint(courseId)},
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return decodeList(r, func(d *json.Decoder) error {
+		var p CoursePerson
+		if m.keepRaw {
+			var raw json.RawMessage
+			if err := d.Decode(&raw); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return err
+			}
+			p.Raw = raw
+		} else if err := d.Decode(&p); err != nil {
+			return err
+		}
+		return fn(p)
+	})
+}
+
+// decodeList reads a json array, calling item to decode each element
+func decodeList(r io.Reader, item func(*json.Decoder) error) error {
+	d := json.NewDecoder(r)
+	if t, err := d.Token(); err != nil || t != json.Delim('[') {
+		return errors.New("Server returned unexpected response. Expected a list")
+	}
+	for d.More() {
+		if err := item(d); err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				return errors.New("Server returned unexpected response. " + err.Error())
+			}
+			if _, ok := err.(*json.SyntaxError); ok {
+				return errors.New("Server returned unexpected response. " + err.Error())
+			}
+			return err
+		}
+	}
+	if _, err := d.Token(); err != nil {
+		return errors.New("Server returned unexpected response. " + err.Error())
+	}
+	return nil
+}
+
+// stream invokes a moodle web service function in the same way as call,
+// returning the response body unread. If the LookupUrl can not stream, the
+// body is read by call.
+func (m *MoodleApi) stream(function string, params url.Values) (io.ReadCloser, error) {
+	f, ok := m.fetch.(StreamLookupUrl)
+	if !ok {
+		body, err := m.call(function, params)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(strings.NewReader(body)), nil
+	}
+
+	params.Set("wsfunction", function)
+	params.Set("moodlewsrestformat", "json")
+	id := newRequestId()
+
+	for attempt := 0; ; attempt++ {
+		header := m.header()
+		header.Set(RequestIdHeader, id)
+		if err := m.credentials.Apply(params, header); err != nil {
+			return nil, err
+		}
+
+		l := m.base + "webservice/rest/server.php?wsfunction=" + url.QueryEscape(function)
+		m.debug("[%s] Fetch: %s %s", id, l, params.Encode())
+		if m.limiter != nil {
+			m.limiter.wait()
+		}
+		r, status, err := f.DoStream("POST", l, params, header)
+
+		if status == http.StatusUnauthorized && attempt == 0 {
+			if c, ok := m.credentials.(RefreshableCredentials); ok {
+				m.info("[%s] Call to %s was unauthorised, refreshing token and retrying", id, function)
+				if r != nil {
+					r.Close()
+				}
+				c.Invalidate()
+				continue
+			}
+		}
+		if err != nil {
+			return nil, m.transportError(id, function, l, status, err)
+		}
+
+		// Exceptions are small, so are read in full
+		b := bufio.NewReader(r)
+		exception := "{\"exception\":\""
+		if peek, _ := b.Peek(len(exception)); string(peek) == exception {
+			data, _ := ioutil.ReadAll(b)
+			r.Close()
+			body := strings.TrimSpace(string(data))
+			if attempt == 0 && m.refreshInvalidToken(id, function, body) {
+				continue
+			}
+			return nil, m.exceptionError(id, function, l, status, body)
+		}
+		m.debug("[%s] Response streamed", id)
+		return bufferedReadCloser{b, r}, nil
+	}
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
@@ -0,0 +1,80 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamCourseRoles(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("courseid") == "404" {
+			w.Write([]byte(`{"exception":"moodle_exception","errorcode":"invalidcourseid","message":"Course not found"}`))
+			return
+		}
+		w.Write([]byte("["))
+		for i := 1; i <= 1000; i++ {
+			if i > 1 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"id":%d,"username":"user%d","roles":[{"roleid":5,"shortname":"student"}]}`, i, i)
+		}
+		w.Write([]byte("]"))
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "token")
+
+	count := 0
+	err := api.StreamCourseRoles(3, func(p CoursePerson) error {
+		count++
+		if p.Username != fmt.Sprintf("user%d", p.Id) {
+			t.Errorf("Unexpected person: %+v", p)
+		}
+		return nil
+	})
+	if err != nil || count != 1000 {
+		t.Errorf("Expected 1000 people, found %d %v", count, err)
+	}
+
+	stop := errors.New("stop")
+	count = 0
+	err = api.StreamCourseRoles(3, func(p CoursePerson) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 10 {
+		t.Errorf("Expected the callback error to stop reading, found %d %v", count, err)
+	}
+
+	err = api.StreamCourseRoles(404, func(p CoursePerson) error { return nil })
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, found %v", err)
+	}
+}
+
+func TestStreamCourseRolesWithoutStreaming(t *testing.T) {
+
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(newTestLookupUrl(map[string]string{
+		"core_enrol_get_enrolled_users": `[{"id":7,"username":"jsmith"},{"id":8,"username":"akim"}]`,
+	}))
+	api.SetKeepRawJSON(true)
+
+	var people []CoursePerson
+	err := api.StreamCourseRoles(3, func(p CoursePerson) error {
+		people = append(people, p)
+		return nil
+	})
+	if err != nil || len(people) != 2 || !strings.Contains(string(people[1].Raw), "akim") {
+		t.Errorf("Unexpected people: %+v %v", people, err)
+	}
+}
@@ -0,0 +1,155 @@
+package moodle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// WriteSubmissionBundle downloads the files submitted to an assignment and
+// writes them to w as a zip file, with a folder for each student named after
+// their id number, as requested for external moderation. Students without an
+// id number are foldered by their moodle id. Only submitted files are
+// included, drafts are skipped. Returns the number of files written.
+func (m *MoodleApi) WriteSubmissionBundle(assignmentId int64, w io.Writer) (int, error) {
+	participants, err := m.getAssignParticipants(assignmentId)
+	if err != nil {
+		return 0, err
+	}
+	files, err := m.getSubmissionFiles(assignmentId)
+	if err != nil {
+		return 0, err
+	}
+
+	z := zip.NewWriter(w)
+	count := 0
+	for _, p := range participants {
+		folder := bundleFolderName(p.IdNumber)
+		if folder == "" {
+			folder = fmt.Sprint(p.Id)
+		}
+		for _, f := range files[p.Id] {
+			header := &zip.FileHeader{
+				Name:     path.Join(folder, f.FilePath, f.FileName),
+				Method:   zip.Deflate,
+				Modified: f.Modified,
+			}
+			entry, err := z.CreateHeader(header)
+			if err != nil {
+				return count, err
+			}
+			r, err := m.OpenFile(f.Url)
+			if err != nil {
+				return count, err
+			}
+			_, err = io.Copy(entry, r)
+			r.Close()
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, z.Close()
+}
+
+// bundleFolderName removes characters that can not be used in a folder name
+func bundleFolderName(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name), " .")
+}
+
+type assignParticipant struct {
+	Id       UserID `json:"id"`
+	FullName string `json:"fullname"`
+	IdNumber string `json:"idnumber"`
+}
+
+// getAssignParticipants lists the students who may submit to an assignment
+func (m *MoodleApi) getAssignParticipants(assignmentId int64) ([]assignParticipant, error) {
+	body, err := m.call("mod_assign_list_participants", url.Values{
+		"assignid": {fmt.Sprint(assignmentId)},
+		"groupid":  {"0"},
+		"filter":   {""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []assignParticipant
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	return results, nil
+}
+
+// getSubmissionFiles lists the files in each submitted submission to an
+// assignment, keyed by the student who submitted it
+func (m *MoodleApi) getSubmissionFiles(assignmentId int64) (map[UserID][]MoodleFile, error) {
+	body, err := m.call("mod_assign_get_submissions", url.Values{
+		"moodlewssettingraw": {"true"},
+		"assignmentids[0]":   {fmt.Sprint(assignmentId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type File struct {
+		MoodleFile
+		TimeModified int64 `json:"timemodified"`
+	}
+	type FileArea struct {
+		Area  string `json:"area"`
+		Files []File `json:"files"`
+	}
+	type Plugin struct {
+		Type      string     `json:"type"`
+		FileAreas []FileArea `json:"fileareas"`
+	}
+	type Submission struct {
+		UserId  UserID   `json:"userid"`
+		Status  string   `json:"status"`
+		Plugins []Plugin `json:"plugins"`
+	}
+	type Assign struct {
+		Submissions []Submission `json:"submissions"`
+	}
+	type Result struct {
+		Assignments []Assign `json:"assignments"`
+	}
+
+	var results Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	files := make(map[UserID][]MoodleFile)
+	for _, a := range results.Assignments {
+		for _, s := range a.Submissions {
+			if s.Status != "submitted" {
+				continue
+			}
+			for _, p := range s.Plugins {
+				for _, area := range p.FileAreas {
+					for _, f := range area.Files {
+						file := f.MoodleFile
+						file.Modified = m.unix(f.TimeModified)
+						files[s.UserId] = append(files[s.UserId], file)
+					}
+				}
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,105 @@
+package moodle
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteSubmissionBundle(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"mod_assign_list_participants": `[{"id":7,"fullname":"Ann Lee","idnumber":"S1001"},{"id":8,"fullname":"Bo Chan","idnumber":""},{"id":9,"fullname":"Cy Dale","idnumber":"S1003"}]`,
+		"mod_assign_get_submissions": `{"assignments":[{"assignmentid":12,"submissions":[` +
+			`{"id":1,"userid":7,"status":"submitted","plugins":[{"type":"file","fileareas":[{"area":"submission_files","files":[{"filename":"essay.docx","filepath":"/","filesize":4,"fileurl":"https://moodle.example.com/pluginfile.php/55/assignsubmission_file/submission_files/1/essay.docx","timemodified":1600000000}]}]}]},` +
+			`{"id":2,"userid":8,"status":"submitted","plugins":[{"type":"file","fileareas":[{"area":"submission_files","files":[{"filename":"notes.txt","filepath":"/drafts/","filesize":5,"fileurl":"https://moodle.example.com/pluginfile.php/55/assignsubmission_file/submission_files/2/drafts/notes.txt","timemodified":1600000000}]}]}]},` +
+			`{"id":3,"userid":9,"status":"draft","plugins":[{"type":"file","fileareas":[{"area":"submission_files","files":[{"filename":"draft.docx","filepath":"/","fileurl":"https://moodle.example.com/pluginfile.php/55/assignsubmission_file/submission_files/3/draft.docx"}]}]}]}` +
+			`]}],"warnings":[]}`,
+		"/webservice/pluginfile.php/55/assignsubmission_file/submission_files/1/essay.docx":       "abcd",
+		"/webservice/pluginfile.php/55/assignsubmission_file/submission_files/2/drafts/notes.txt": "notes",
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "secret")
+	api.SetUrlFetcher(fetch)
+
+	var buf bytes.Buffer
+	count, err := api.WriteSubmissionBundle(12, &buf)
+	if err != nil {
+		t.Fatalf("WriteSubmissionBundle failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected two files, found %d", count)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Expected a valid zip file: %v", err)
+	}
+	expected := map[string]string{"S1001/essay.docx": "abcd", "8/drafts/notes.txt": "notes"}
+	if len(z.File) != len(expected) {
+		t.Errorf("Expected %d files, found %d", len(expected), len(z.File))
+	}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := ioutil.ReadAll(r)
+		r.Close()
+		if string(data) != expected[f.Name] {
+			t.Errorf("Unexpected contents of %s: %q", f.Name, data)
+		}
+	}
+
+	if bundleFolderName(`a/b:c `) != "a_b_c" {
+		t.Errorf("Expected unsafe characters to be replaced, found %q", bundleFolderName(`a/b:c `))
+	}
+}
+
+func TestWriteSubmissionBundleOverHttp(t *testing.T) {
+
+	pdf := []byte("%PDF-1.4\n\x00\xff\xfe\n")
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/webservice/rest/server.php":
+			w.Header().Set("Content-Type", "application/json")
+			switch r.FormValue("wsfunction") {
+			case "mod_assign_list_participants":
+				w.Write([]byte(`[{"id":7,"fullname":"Ann Lee","idnumber":"S1001"}]`))
+			case "mod_assign_get_submissions":
+				w.Write([]byte(`{"assignments":[{"assignmentid":12,"submissions":[{"id":1,"userid":7,"status":"submitted","plugins":[{"type":"file","fileareas":[{"area":"submission_files","files":[` +
+					`{"filename":"essay.pdf","filepath":"/","filesize":14,"fileurl":"` + server.URL + `/pluginfile.php/55/assignsubmission_file/submission_files/1/essay.pdf","timemodified":1600000000}]}]}]}]}],"warnings":[]}`))
+			}
+		case "/webservice/pluginfile.php/55/assignsubmission_file/submission_files/1/essay.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	api := NewMoodleApi(server.URL, "secret")
+	var buf bytes.Buffer
+	count, err := api.WriteSubmissionBundle(12, &buf)
+	if err != nil || count != 1 {
+		t.Fatalf("Expected one file to be written, found %d %v", count, err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(z.File) != 1 || z.File[0].Name != "S1001/essay.pdf" {
+		t.Fatalf("Expected a zip holding S1001/essay.pdf: %v", err)
+	}
+	r, err := z.File[0].Open()
+	if err != nil {
+		t.Fatalf("Failed to open essay.pdf: %v", err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(data, pdf) {
+		t.Errorf("Expected the file unchanged, found %q", data)
+	}
+}
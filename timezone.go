@@ -0,0 +1,124 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Moodle stores times as unix timestamps and displays them in the timezone of
+// the site, or of the person viewing them. Times returned by the api are in
+// the location set with SetLocation, which defaults to the local timezone of
+// the server running this code.
+
+// SetLocation sets the timezone of times returned by the api. Use the site
+// timezone so that dates such as "due at midnight" appear as they do in
+// moodle.
+func (m *MoodleApi) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+	m.location = loc
+}
+
+// Location returns the timezone of times returned by the api
+func (m *MoodleApi) Location() *time.Location {
+	return m.location
+}
+
+// Time converts a moodle timestamp into a time in the api location. Returns
+// nil for a zero timestamp, which moodle uses to mean not set.
+func (m *MoodleApi) Time(t int64) *time.Time {
+	return m.unixTime(t)
+}
+
+// Date returns the time at the start of a day in the api location, for
+// example to set a due date of midnight in the site timezone.
+func (m *MoodleApi) Date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, m.location)
+}
+
+// GetPersonLocation returns the timezone of a person. People using the
+// site default timezone ("99" in moodle) are given the api location.
+func (m *MoodleApi) GetPersonLocation(userId UserID) (*time.Location, error) {
+	body, err := m.call("core_user_get_users_by_field", url.Values{
+		"field":     {"id"},
+		"values[0]": {fmt.Sprint(userId)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Timezone string `json:"timezone"`
+	}
+
+	var results []Result
+
+	if err := json.Unmarshal([]byte(body), &results); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if len(results) == 0 {
+		return nil, wrapError("Moodle account not found", ErrNotFound)
+	}
+
+	return m.parseTimezone(results[0].Timezone)
+}
+
+// LoadTimezone sets the api location to the timezone of the web service
+// user. Moodle does not publish the site timezone to web services, so set the
+// timezone of the web service user to the site timezone. If the web service
+// user uses the site default timezone the location is not changed.
+func (m *MoodleApi) LoadTimezone() error {
+	_, _, _, userId, err := m.GetSiteInfo()
+	if err != nil {
+		return err
+	}
+	loc, err := m.GetPersonLocation(UserID(userId))
+	if err != nil {
+		return err
+	}
+	m.location = loc
+	return nil
+}
+
+// parseTimezone converts a moodle timezone setting into a location
+func (m *MoodleApi) parseTimezone(timezone string) (*time.Location, error) {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" || timezone == "99" {
+		return m.location, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.New("Unknown timezone " + timezone + ". " + err.Error())
+	}
+	return loc, nil
+}
+
+// unix converts a moodle timestamp into a time in the api location
+func (m *MoodleApi) unix(t int64) time.Time {
+	return time.Unix(t, 0).In(m.location)
+}
+
+// unixTime converts a moodle timestamp, where zero means not set, into a
+// time in the api location.
+func (m *MoodleApi) unixTime(t int64) *time.Time {
+	if t == 0 {
+		return nil
+	}
+	tt := m.unix(t)
+	return &tt
+}
+
+// inLocation moves times that have already been converted into the api
+// location.
+func (m *MoodleApi) inLocation(times ...*time.Time) {
+	for _, t := range times {
+		if t != nil {
+			*t = t.In(m.location)
+		}
+	}
+}
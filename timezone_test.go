@@ -0,0 +1,50 @@
+package moodle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimezone(t *testing.T) {
+
+	melbourne, err := time.LoadLocation("Australia/Melbourne")
+	if err != nil {
+		t.Skipf("Timezone data not available: %v", err)
+	}
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_webservice_get_site_info": `{"sitename":"Test","firstname":"Admin","lastname":"User","userid":2}`,
+		"core_user_get_users_by_field":  `[{"id":2,"timezone":"Australia/Melbourne"}]`,
+		"core_course_get_course_module": `{"cm":{"id":100,"course":3,"visible":1,"added":1600000000}}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.LoadTimezone(); err != nil {
+		t.Fatalf("LoadTimezone failed: %v", err)
+	}
+	if api.Location().String() != melbourne.String() {
+		t.Errorf("Expected location to be Australia/Melbourne, found %v", api.Location())
+	}
+
+	cm, err := api.GetCourseModule(100)
+	if err != nil {
+		t.Fatalf("GetCourseModule failed: %v", err)
+	}
+	if cm.Added.Location().String() != melbourne.String() || cm.Added.Unix() != 1600000000 {
+		t.Errorf("Expected time in Australia/Melbourne, found %v", cm.Added)
+	}
+
+	if d := api.Date(2020, time.March, 1); d.Hour() != 0 || d.Location() != api.Location() {
+		t.Errorf("Expected midnight in the api location, found %v", d)
+	}
+	if api.Time(0) != nil {
+		t.Errorf("Expected a zero timestamp to be nil")
+	}
+
+	fetch.responses["core_user_get_users_by_field"] = `[{"id":5,"timezone":"99"}]`
+	loc, err := api.GetPersonLocation(5)
+	if err != nil || loc != api.Location() {
+		t.Errorf("Expected the site default timezone to use the api location, found %v %v", loc, err)
+	}
+}
@@ -0,0 +1,134 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WithToken returns a copy of the api that authenticates using a different
+// web service token. Use this to perform individual calls as a specific user,
+// for example with a token obtained from GetUserToken, while continuing to
+// use the original api for service account calls.
+//
+//	token, err := api.GetUserToken("student1", password, "moodle_mobile_app")
+//	...
+//	err = api.WithToken(token).SetRole(...)
+func (m *MoodleApi) WithToken(token string) *MoodleApi {
+	c := *m
+	c.credentials = TokenCredentials(token)
+	if c.inflight != nil {
+		// Calls made with different tokens may see different data
+		c.inflight = &flightGroup{}
+	}
+	// Queued calls are replayed with the credentials of the api that
+	// replays them, so calls made with another token are never queued
+	c.mutations = nil
+	return &c
+}
+
+// GetUserToken requests a web service token for a user from login/token.php.
+// The service is the short name of an enabled external service, such as
+// "moodle_mobile_app".
+func (m *MoodleApi) GetUserToken(username, password, service string) (string, error) {
+	l := m.base + "login/token.php"
+	m.debug("Fetch: %s", l)
+
+	body, _, _, err := m.fetch.Do("POST", l, url.Values{
+		"username": {username},
+		"password": {password},
+		"service":  {service},
+	}, m.header())
+	if err != nil {
+		return "", err
+	}
+	m.debug("Response: %s", body)
+
+	type Result struct {
+		Token     string `json:"token"`
+		Error     string `json:"error"`
+		ErrorCode string `json:"errorcode"`
+	}
+
+	var result Result
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return "", errors.New("Server returned unexpected response. " + err.Error())
+	}
+	if result.Error != "" {
+		return "", wrapError(strings.TrimSpace(result.Error), errorCodes[result.ErrorCode])
+	}
+	if result.Token == "" {
+		return "", errors.New("Server returned unexpected response: " + body)
+	}
+
+	return result.Token, nil
+}
+
+// ValidateToken checks the token by calling core_webservice_get_site_info,
+// returning the site and the user the token belongs to. If moodle rejects
+// the token the error wraps ErrInvalidToken. The result cache is not used, so
+// that a revoked token is reported.
+func (m *MoodleApi) ValidateToken() (*SiteInfo, error) {
+	return m.readSiteInfo(m.callUncached)
+}
+
+// LoginCredentials authenticate using a web service token obtained by
+// signing in to login/token.php. If moodle reports the token is invalid, for
+// example after it has been rotated or revoked, a new token is obtained and
+// the call is retried once. Use SetLoginCredentials to create them.
+type LoginCredentials struct {
+	username string
+	login    func() (string, error)
+
+	mu    sync.Mutex
+	token string
+}
+
+// SetLoginCredentials authenticates using a token obtained with the
+// username and password of the web service user, see LoginCredentials. The
+// service is the short name of an external service the user may access.
+func (m *MoodleApi) SetLoginCredentials(username, password, service string) {
+	m.SetCredentials(&LoginCredentials{username: username, login: func() (string, error) {
+		return m.GetUserToken(username, password, service)
+	}})
+}
+
+// Apply adds the wstoken parameter, signing in if there is no token
+func (c *LoginCredentials) Apply(params url.Values, header http.Header) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		token, err := c.login()
+		if err != nil {
+			return err
+		}
+		c.token = token
+	}
+	params.Set("wstoken", c.token)
+	return nil
+}
+
+// Invalidate discards the token so that the next request signs in again
+func (c *LoginCredentials) Invalidate() {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+}
+
+// refreshInvalidToken invalidates refreshable credentials when moodle
+// reports the token is invalid, returning true if the call should be retried.
+func (m *MoodleApi) refreshInvalidToken(id, function, body string) bool {
+	if readException(body).ErrorCode != "invalidtoken" {
+		return false
+	}
+	r, ok := m.credentials.(RefreshableCredentials)
+	if !ok {
+		return false
+	}
+	m.info("[%s] Call to %s used an invalid token, refreshing token and retrying", id, function)
+	r.Invalidate()
+	return true
+}
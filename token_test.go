@@ -0,0 +1,98 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWithToken(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"/login/token.php":             `{"token":"usertoken","privatetoken":null}`,
+		"core_group_add_group_members": `null`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "servicetoken")
+	api.SetUrlFetcher(fetch)
+
+	token, err := api.GetUserToken("student", "secret", "moodle_mobile_app")
+	if err != nil {
+		t.Fatalf("GetUserToken() failed: %v", err)
+	}
+	if token != "usertoken" {
+		t.Errorf("Expected usertoken, not %s", token)
+	}
+
+	if err := api.WithToken(token).AddPersonToCourseGroup(1, 2); err != nil {
+		t.Fatalf("AddPersonToCourseGroup() failed: %v", err)
+	}
+	if fetch.last().Get("wstoken") != "usertoken" {
+		t.Errorf("Call should use the overridden token, not %s", fetch.last().Get("wstoken"))
+	}
+
+	if err := api.AddPersonToCourseGroup(1, 2); err != nil {
+		t.Fatalf("AddPersonToCourseGroup() failed: %v", err)
+	}
+	if fetch.last().Get("wstoken") != "servicetoken" {
+		t.Errorf("Original api should continue to use the service token, not %s", fetch.last().Get("wstoken"))
+	}
+
+	fetch.responses["/login/token.php"] = `{"error":"Invalid login, please try again","errorcode":"invalidlogin"}`
+	if _, err := api.GetUserToken("student", "wrong", "moodle_mobile_app"); err == nil {
+		t.Errorf("GetUserToken() should fail for an invalid login")
+	}
+}
+
+// rotatingLookupUrl issues a new token at each sign in, and rejects every
+// token except the latest
+type rotatingLookupUrl struct {
+	*testLookupUrl
+	issued int
+}
+
+func (r *rotatingLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	r.testLookupUrl.Do(method, u, form, header)
+	if strings.HasSuffix(u, "login/token.php") {
+		r.issued++
+		return fmt.Sprintf(`{"token":"token%d"}`, r.issued), 200, "application/json", nil
+	}
+	if form.Get("wstoken") != fmt.Sprintf("token%d", r.issued) {
+		return `{"exception":"moodle_exception","errorcode":"invalidtoken","message":"Invalid token - token not found"}`, 200, "application/json", nil
+	}
+	return `{"sitename":"Test","username":"ws","userid":5,"functions":[]}`, 200, "application/json", nil
+}
+
+func TestLoginCredentials(t *testing.T) {
+
+	fetch := &rotatingLookupUrl{testLookupUrl: newTestLookupUrl(nil)}
+	api := NewMoodleApi("https://moodle.example.com/", "expired")
+	api.SetUrlFetcher(fetch)
+
+	if _, err := api.ValidateToken(); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected the static token to be rejected, found %v", err)
+	}
+
+	api.SetLoginCredentials("ws", "secret", "integration")
+	info, err := api.ValidateToken()
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.UserId != 5 || fetch.issued != 1 {
+		t.Errorf("Expected to sign in once, found %+v after %d sign ins", info, fetch.issued)
+	}
+	if fetch.requests[len(fetch.requests)-2].Get("username") != "ws" {
+		t.Errorf("Expected sign in with the username, found %v", fetch.requests[len(fetch.requests)-2])
+	}
+
+	// Another client rotates the token
+	fetch.issued++
+	if _, err := api.ValidateToken(); err != nil {
+		t.Fatalf("Expected a new token to be obtained, found %v", err)
+	}
+	if fetch.last().Get("wstoken") != "token3" {
+		t.Errorf("Expected the call to be retried with the new token, found %v", fetch.last())
+	}
+}
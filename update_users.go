@@ -0,0 +1,217 @@
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// UserUpdate describes changes to an account made by UpdateUsers. Blank
+// fields, and a nil Suspended, are left unchanged. Custom fields not listed
+// in CustomFields are left unchanged.
+type UserUpdate struct {
+	Id           UserID
+	FirstName    string
+	LastName     string
+	Email        string
+	Username     string
+	Password     string
+	Auth         string
+	IdNumber     string
+	Suspended    *bool
+	CustomFields map[string]string
+}
+
+// UpdateUserResult is the outcome of updating one account. Err is nil if
+// the account was updated.
+type UpdateUserResult struct {
+	Id  UserID
+	Err error
+}
+
+// updateUsersBatchSize is the number of accounts updated by each call
+const updateUsersBatchSize = 100
+
+// UpdateUsers updates many accounts using one call for every hundred
+// accounts. Results are returned in the same order as users. Moodle reports
+// some failures, such as a username that is already taken, as warnings
+// about the account, and others by rejecting the whole call, in which case
+// the accounts of that call are updated one at a time so that only the
+// invalid accounts fail. The error is nil only if every account was updated.
+func (m *MoodleApi) UpdateUsers(users []UserUpdate) ([]UpdateUserResult, error) {
+	results := make([]UpdateUserResult, len(users))
+	var batch []int
+	for i, u := range users {
+		results[i].Id = u.Id
+		if u.Email != "" && strings.Index(u.Email, "@") < 0 {
+			results[i].Err = errors.New("Invalid email address")
+			continue
+		}
+		batch = append(batch, i)
+		if len(batch) == updateUsersBatchSize {
+			m.updateUsersBatch(users, batch, results)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		m.updateUsersBatch(users, batch, results)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, errors.New(fmt.Sprintf("%d of %d accounts could not be updated", failed, len(users)))
+	}
+	return results, nil
+}
+
+// SuspendUser suspends an account, preventing the person from signing in.
+// Moodle also ends any sessions the person has open.
+func (m *MoodleApi) SuspendUser(id UserID) error {
+	return m.setSuspended(id, true)
+}
+
+// UnsuspendUser allows a suspended account to sign in again
+func (m *MoodleApi) UnsuspendUser(id UserID) error {
+	return m.setSuspended(id, false)
+}
+
+func (m *MoodleApi) setSuspended(id UserID, suspended bool) error {
+	warnings, err := m.updateUsers([]UserUpdate{{Id: id, Suspended: &suspended}}, []int{0})
+	if err != nil {
+		return err
+	}
+	return warnings[id]
+}
+
+// RenameUsername changes the username of an account, returning an error
+// wrapping ErrUsernameTaken if another account already has the username.
+// Usernames are stored by moodle in lower case.
+func (m *MoodleApi) RenameUsername(userId UserID, newUsername string) error {
+	newUsername = strings.ToLower(strings.TrimSpace(newUsername))
+	if _, err := validateUserAttribute("username", newUsername); err != nil {
+		return err
+	}
+
+	// A cached lookup may predate the account that now has the username
+	m.InvalidateCache("core_user_get_users_by_field")
+	p, err := m.GetPersonByUsername(newUsername)
+	if err != nil && !errors.Is(err, ErrMultipleMatches) {
+		return err
+	}
+	if err != nil || (p != nil && p.MoodleId != userId) {
+		return wrapError(fmt.Sprintf("Username %q is already in use", newUsername), ErrUsernameTaken)
+	}
+	if p != nil {
+		return nil
+	}
+
+	warnings, err := m.updateUsers([]UserUpdate{{Id: userId, Username: newUsername}}, []int{0})
+	if err != nil {
+		return err
+	}
+	return warnings[userId]
+}
+
+// updateUsersBatch updates the users at the indexes, recording the outcome
+// in results
+func (m *MoodleApi) updateUsersBatch(users []UserUpdate, indexes []int, results []UpdateUserResult) {
+	warnings, err := m.updateUsers(users, indexes)
+	if err != nil {
+		var merr *MoodleError
+		if len(indexes) > 1 && errors.As(err, &merr) && merr.Exception != "" {
+			for _, i := range indexes {
+				m.updateUsersBatch(users, []int{i}, results)
+			}
+			return
+		}
+		for _, i := range indexes {
+			results[i].Err = err
+		}
+		return
+	}
+	for _, i := range indexes {
+		results[i].Err = warnings[users[i].Id]
+	}
+}
+
+// updateUsers calls core_user_update_users, returning the warning reported
+// for each account that was not updated
+func (m *MoodleApi) updateUsers(users []UserUpdate, indexes []int) (map[UserID]error, error) {
+	params := url.Values{}
+	for n, i := range indexes {
+		u := users[i]
+		prefix := fmt.Sprintf("users[%d]", n)
+		params.Set(prefix+"[id]", fmt.Sprint(u.Id))
+		for field, value := range map[string]string{
+			"firstname": u.FirstName,
+			"lastname":  u.LastName,
+			"email":     u.Email,
+			"username":  u.Username,
+			"password":  u.Password,
+			"auth":      u.Auth,
+			"idnumber":  u.IdNumber,
+		} {
+			if value != "" {
+				params.Set(prefix+"["+field+"]", value)
+			}
+		}
+		if u.Suspended != nil {
+			suspended := "0"
+			if *u.Suspended {
+				suspended = "1"
+			}
+			params.Set(prefix+"[suspended]", suspended)
+		}
+		names := make([]string, 0, len(u.CustomFields))
+		for name := range u.CustomFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for f, name := range names {
+			params.Set(fmt.Sprintf("%s[customfields][%d][type]", prefix, f), name)
+			params.Set(fmt.Sprintf("%s[customfields][%d][value]", prefix, f), u.CustomFields[name])
+		}
+	}
+
+	body, err := m.call("core_user_update_users", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older versions of moodle return nothing
+	if body = strings.TrimSpace(body); body == "" || body == "null" {
+		return nil, nil
+	}
+
+	type Warning struct {
+		Item        string `json:"item"`
+		ItemId      UserID `json:"itemid"`
+		WarningCode string `json:"warningcode"`
+		Message     string `json:"message"`
+	}
+	type Result struct {
+		Warnings []Warning `json:"warnings"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	warnings := make(map[UserID]error)
+	for _, w := range result.Warnings {
+		if w.Item == "user" {
+			warnings[w.ItemId] = wrapError(w.Message, errorCodes[w.WarningCode])
+		}
+	}
+	return warnings, nil
+}
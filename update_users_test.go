@@ -0,0 +1,154 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// updateUsersLookupUrl rejects any call updating account 13, and warns that
+// the username of account 12 is taken
+type updateUsersLookupUrl struct {
+	*testLookupUrl
+}
+
+func (c *updateUsersLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	c.testLookupUrl.Do(method, u, form, header)
+	warnings := ""
+	for i := 0; form.Get(fmt.Sprintf("users[%d][id]", i)) != ""; i++ {
+		switch form.Get(fmt.Sprintf("users[%d][id]", i)) {
+		case "13":
+			return `{"exception":"invalid_parameter_exception","errorcode":"invalidparameter","message":"Invalid parameter value detected"}`, 200, "application/json", nil
+		case "12":
+			warnings = `{"item":"user","itemid":12,"warningcode":"usernameexists","message":"Username already exists: taken"}`
+		}
+	}
+	return `{"warnings":[` + warnings + `]}`, 200, "application/json", nil
+}
+
+func TestUpdateUsers(t *testing.T) {
+
+	fetch := &updateUsersLookupUrl{testLookupUrl: newTestLookupUrl(nil)}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	suspended := true
+	results, err := api.UpdateUsers([]UserUpdate{
+		{Id: 10, Email: "ten@example.com", Suspended: &suspended, CustomFields: map[string]string{"campus": "North"}},
+		{Id: 11, FirstName: "Eleven"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateUsers failed: %v %+v", err, results)
+	}
+	r := fetch.last()
+	if r.Get("users[0][email]") != "ten@example.com" || r.Get("users[0][suspended]") != "1" || r.Get("users[0][customfields][0][value]") != "North" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if _, ok := r["users[0][firstname]"]; ok {
+		t.Errorf("Expected blank fields to be left unchanged, found %v", r)
+	}
+
+	fetch.requests = nil
+	results, err = api.UpdateUsers([]UserUpdate{
+		{Id: 11, FirstName: "Eleven"},
+		{Id: 12, Username: "taken"},
+		{Id: 13, FirstName: "Thirteen"},
+		{Id: 14, Email: "none"},
+	})
+	if err == nil || err.Error() != "3 of 4 accounts could not be updated" {
+		t.Errorf("Expected failures to be reported, found %v", err)
+	}
+	if results[0].Err != nil || results[1].Err == nil || !errors.Is(results[1].Err, ErrUsernameTaken) || results[2].Err == nil || results[3].Err == nil {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if len(fetch.requests) != 4 {
+		t.Errorf("Expected the rejected call to be repeated for each account, found %d calls", len(fetch.requests))
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"core_user_update_users":       `null`,
+		"core_user_get_users_by_field": `[{"id":10,"username":"ten","firstname":"Ten","lastname":"Person","email":"ten@example.com","suspended":true}]`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.SuspendUser(10); err != nil {
+		t.Fatalf("SuspendUser failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("users[0][id]") != "10" || r.Get("users[0][suspended]") != "1" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+	if err := api.UnsuspendUser(10); err != nil {
+		t.Fatalf("UnsuspendUser failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("users[0][suspended]") != "0" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	fetch.responses["core_user_update_users"] = `{"warnings":[{"item":"user","itemid":10,"warningcode":"invalidparameter","message":"You cannot suspend this account"}]}`
+	if err := api.SuspendUser(10); err == nil || err.Error() != "You cannot suspend this account" {
+		t.Errorf("Expected the warning to be returned, found %v", err)
+	}
+
+	p, err := api.GetPersonByMoodleId(10)
+	if err != nil || p == nil || !p.Suspended {
+		t.Errorf("Expected the person to be suspended, found %+v %v", p, err)
+	}
+	p, err = api.GetPersonByUsername("ten")
+	if err != nil || p == nil || !p.Suspended {
+		t.Errorf("Expected the person to be suspended, found %+v %v", p, err)
+	}
+}
+
+// renameLookupUrl has an account 20 with the username "taken", and moodle
+// warns that the username of account 12 is taken
+type renameLookupUrl struct {
+	*updateUsersLookupUrl
+}
+
+func (c *renameLookupUrl) Do(method, u string, form url.Values, header http.Header) (string, int, string, error) {
+	if form.Get("wsfunction") == "core_user_get_users_by_field" {
+		c.testLookupUrl.Do(method, u, form, header)
+		if form.Get("values[0]") == "taken" {
+			return `[{"id":20,"username":"taken","firstname":"Twenty","lastname":"Person"}]`, 200, "application/json", nil
+		}
+		return `[]`, 200, "application/json", nil
+	}
+	return c.updateUsersLookupUrl.Do(method, u, form, header)
+}
+
+func TestRenameUsername(t *testing.T) {
+
+	fetch := &renameLookupUrl{&updateUsersLookupUrl{testLookupUrl: newTestLookupUrl(nil)}}
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	if err := api.RenameUsername(10, " New.Name "); err != nil {
+		t.Fatalf("RenameUsername failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("users[0][id]") != "10" || r.Get("users[0][username]") != "new.name" {
+		t.Errorf("Unexpected parameters: %v", r)
+	}
+
+	fetch.requests = nil
+	if err := api.RenameUsername(10, "taken"); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Expected a collision to be reported, found %v", err)
+	}
+	if len(fetch.requests) != 1 {
+		t.Errorf("Expected the account not to be updated, found %d calls", len(fetch.requests))
+	}
+	if err := api.RenameUsername(20, "taken"); err != nil || len(fetch.requests) != 2 {
+		t.Errorf("Expected renaming an account to its own username to do nothing, found %v", err)
+	}
+	if err := api.RenameUsername(12, "raced"); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Expected a collision reported by moodle to be returned, found %v", err)
+	}
+	if err := api.RenameUsername(10, " "); err == nil {
+		t.Errorf("Expected a blank username to be rejected")
+	}
+}
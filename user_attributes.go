@@ -0,0 +1,93 @@
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// attributeKind is the type of value moodle accepts for a user attribute
+type attributeKind int
+
+const (
+	attributeText attributeKind = iota
+	attributeBool
+	attributeInt
+)
+
+// userAttributes are the fields of a person that core_user_update_users
+// accepts, and the type of value of each. Custom profile fields are set
+// with SetUserCustomField.
+var userAttributes = map[string]attributeKind{
+	"username":          attributeText,
+	"auth":              attributeText,
+	"suspended":         attributeBool,
+	"password":          attributeText,
+	"firstname":         attributeText,
+	"lastname":          attributeText,
+	"email":             attributeText,
+	"maildisplay":       attributeInt,
+	"city":              attributeText,
+	"country":           attributeText,
+	"timezone":          attributeText,
+	"description":       attributeText,
+	"userpicture":       attributeInt,
+	"firstnamephonetic": attributeText,
+	"lastnamephonetic":  attributeText,
+	"middlename":        attributeText,
+	"alternatename":     attributeText,
+	"interests":         attributeText,
+	"idnumber":          attributeText,
+	"institution":       attributeText,
+	"department":        attributeText,
+	"phone1":            attributeText,
+	"phone2":            attributeText,
+	"address":           attributeText,
+	"lang":              attributeText,
+	"calendartype":      attributeText,
+	"theme":             attributeText,
+	"mailformat":        attributeInt,
+}
+
+// validateUserAttribute checks that moodle accepts an attribute and value,
+// returning the value as it should be sent. Booleans may be given as
+// "true" or "false" and are sent as 1 or 0.
+func validateUserAttribute(attribute, value string) (string, error) {
+	kind, ok := userAttributes[attribute]
+	if !ok {
+		if attribute == "customfields" || strings.HasPrefix(attribute, "profile_field_") {
+			return "", errors.New(fmt.Sprintf("Custom profile fields can not be set as attribute %q, use SetUserCustomField", attribute))
+		}
+		return "", errors.New(fmt.Sprintf("Unknown user attribute %q", attribute))
+	}
+
+	switch kind {
+	case attributeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("User attribute %q must be true or false, found %q", attribute, value))
+		}
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+	case attributeInt:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("User attribute %q must be a number, found %q", attribute, value))
+		}
+		if (attribute == "maildisplay" && (n < 0 || n > 2)) || (attribute == "mailformat" && (n < 0 || n > 1)) {
+			return "", errors.New(fmt.Sprintf("User attribute %q can not be %d", attribute, n))
+		}
+		return strconv.Itoa(n), nil
+	}
+
+	if attribute == "email" && strings.Index(value, "@") < 0 {
+		return "", errors.New("Invalid email address")
+	}
+	if (attribute == "username" || attribute == "firstname" || attribute == "lastname" || attribute == "email") && strings.TrimSpace(value) == "" {
+		return "", errors.New(fmt.Sprintf("User attribute %q can not be blank", attribute))
+	}
+	return value, nil
+}
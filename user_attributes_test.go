@@ -0,0 +1,47 @@
+package moodle
+
+import (
+	"testing"
+)
+
+func TestSetUserAttributeValidation(t *testing.T) {
+
+	tests := []struct {
+		attribute, value, sent string
+		valid                  bool
+	}{
+		{"city", "Perth", "Perth", true},
+		{"suspended", "true", "1", true},
+		{"suspended", "0", "0", true},
+		{"suspended", "yes", "", false},
+		{"maildisplay", " 2", "2", true},
+		{"maildisplay", "3", "", false},
+		{"mailformat", "html", "", false},
+		{"email", "nobody", "", false},
+		{"firstname", " ", "", false},
+		{"favouritecolour", "blue", "", false},
+		{"profile_field_studentid", "S1", "", false},
+	}
+	for _, test := range tests {
+		sent, err := validateUserAttribute(test.attribute, test.value)
+		if test.valid && (err != nil || sent != test.sent) {
+			t.Errorf("Expected %s=%q to be sent as %q, found %q %v", test.attribute, test.value, test.sent, sent, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %s=%q to be rejected", test.attribute, test.value)
+		}
+	}
+
+	fetch := newTestLookupUrl(map[string]string{"core_user_update_users": ``})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+	if err := api.SetUserAttribute(7, "suspended", "false"); err != nil {
+		t.Fatalf("SetUserAttribute failed: %v", err)
+	}
+	if r := fetch.last(); r.Get("users[0][suspended]") != "0" {
+		t.Errorf("Expected the boolean to be sent as 0, found %v", r)
+	}
+	if err := api.SetUserAttribute(7, "favouritecolour", "blue"); err == nil || len(fetch.requests) != 1 {
+		t.Errorf("Expected an unknown attribute to be rejected without calling moodle, found %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package moodle
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"math/rand"
 	"time"
 )
@@ -25,3 +27,12 @@ func RandomString(size int) string {
 	}
 	return string(bytes)
 }
+
+// newRequestId returns a random id used to identify a call in log output
+func newRequestId() string {
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		return RandomString(16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,227 @@
+//go:build workplace
+// +build workplace
+
+package moodle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// WorkplaceFunctions names the web service functions of Moodle Workplace.
+// Workplace does not publish documentation for its web services, the
+// defaults match Workplace 4.1. Change them if a release renames a function,
+// and pass renamed functions that only read data to SetReadOnlyFunctions.
+var WorkplaceFunctions = struct {
+	Tenants               string
+	AllocateTenant        string
+	Programs              string
+	ProgramUsers          string
+	AllocateProgram       string
+	Certifications        string
+	CertificationUsers    string
+	AllocateCertification string
+}{
+	Tenants:               "tool_tenant_get_tenants",
+	AllocateTenant:        "tool_tenant_allocate_users",
+	Programs:              "tool_program_get_programs",
+	ProgramUsers:          "tool_program_get_program_users",
+	AllocateProgram:       "tool_program_allocate_users",
+	Certifications:        "tool_certification_get_certifications",
+	CertificationUsers:    "tool_certification_get_certification_users",
+	AllocateCertification: "tool_certification_allocate_users",
+}
+
+// Tenant is an organisation sharing a Moodle Workplace site
+type Tenant struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	SiteName  string `json:"sitename"`
+	IsDefault bool   `json:"isdefault"`
+	Archived  bool   `json:"archived"`
+}
+
+// Program is a sequence of courses that people are allocated to
+type Program struct {
+	Id       int64
+	Name     string
+	IdNumber string
+	Archived bool
+}
+
+// Certification is a program that must be completed again when it expires
+type Certification struct {
+	Id       int64
+	Name     string
+	IdNumber string
+	Archived bool
+}
+
+// Allocation records that a person has been allocated to a program or
+// certification, and their progress.
+type Allocation struct {
+	UserId    UserID
+	Allocated time.Time
+	Completed *time.Time
+
+	// Expires is when a certification must be completed again, nil for
+	// programs
+	Expires *time.Time
+}
+
+type workplaceItem struct {
+	Id       int64  `json:"id"`
+	FullName string `json:"fullname"`
+	IdNumber string `json:"idnumber"`
+	Archived bool   `json:"archived"`
+}
+
+// GetTenants lists the tenants of a Moodle Workplace site
+func (m *MoodleApi) GetTenants() ([]Tenant, error) {
+	body, err := m.call(WorkplaceFunctions.Tenants, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Tenants []Tenant `json:"tenants"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	return result.Tenants, nil
+}
+
+// AllocateUsersToTenant moves people into a tenant
+func (m *MoodleApi) AllocateUsersToTenant(tenantId int64, userIds ...UserID) error {
+	params := url.Values{}
+	for i, userId := range userIds {
+		params.Set(fmt.Sprintf("users[%d][userid]", i), fmt.Sprint(userId))
+		params.Set(fmt.Sprintf("users[%d][tenantid]", i), fmt.Sprint(tenantId))
+	}
+	_, err := m.call(WorkplaceFunctions.AllocateTenant, params)
+	return err
+}
+
+// GetPrograms lists the programs of a Moodle Workplace site
+func (m *MoodleApi) GetPrograms() ([]Program, error) {
+	items, err := m.workplaceItems(WorkplaceFunctions.Programs, "programs")
+	if err != nil {
+		return nil, err
+	}
+	var programs []Program
+	for _, i := range items {
+		programs = append(programs, Program{Id: i.Id, Name: i.FullName, IdNumber: i.IdNumber, Archived: i.Archived})
+	}
+	return programs, nil
+}
+
+// GetProgramAllocations lists the people allocated to a program
+func (m *MoodleApi) GetProgramAllocations(programId int64) ([]Allocation, error) {
+	return m.workplaceAllocations(WorkplaceFunctions.ProgramUsers, "programid", programId)
+}
+
+// AllocateUsersToProgram allocates people to a program
+func (m *MoodleApi) AllocateUsersToProgram(programId int64, userIds ...UserID) error {
+	return m.workplaceAllocate(WorkplaceFunctions.AllocateProgram, "programid", programId, userIds)
+}
+
+// GetCertifications lists the certifications of a Moodle Workplace site
+func (m *MoodleApi) GetCertifications() ([]Certification, error) {
+	items, err := m.workplaceItems(WorkplaceFunctions.Certifications, "certifications")
+	if err != nil {
+		return nil, err
+	}
+	var certifications []Certification
+	for _, i := range items {
+		certifications = append(certifications, Certification{Id: i.Id, Name: i.FullName, IdNumber: i.IdNumber, Archived: i.Archived})
+	}
+	return certifications, nil
+}
+
+// GetCertificationAllocations lists the people allocated to a certification
+func (m *MoodleApi) GetCertificationAllocations(certificationId int64) ([]Allocation, error) {
+	return m.workplaceAllocations(WorkplaceFunctions.CertificationUsers, "certificationid", certificationId)
+}
+
+// AllocateUsersToCertification allocates people to a certification
+func (m *MoodleApi) AllocateUsersToCertification(certificationId int64, userIds ...UserID) error {
+	return m.workplaceAllocate(WorkplaceFunctions.AllocateCertification, "certificationid", certificationId, userIds)
+}
+
+// workplaceItems reads a list of programs or certifications
+func (m *MoodleApi) workplaceItems(function, key string) ([]workplaceItem, error) {
+	body, err := m.call(function, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+	var items []workplaceItem
+	if raw, ok := result[key]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, errors.New("Server returned unexpected response. " + err.Error())
+		}
+	}
+	return items, nil
+}
+
+// workplaceAllocations reads the people allocated to a program or
+// certification
+func (m *MoodleApi) workplaceAllocations(function, idName string, id int64) ([]Allocation, error) {
+	body, err := m.call(function, url.Values{
+		idName: {fmt.Sprint(id)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type User struct {
+		UserId        UserID `json:"userid"`
+		TimeAllocated int64  `json:"timeallocated"`
+		TimeCompleted int64  `json:"timecompleted"`
+		TimeExpires   int64  `json:"timeexpires"`
+	}
+	type Result struct {
+		Users []User `json:"users"`
+	}
+
+	var result Result
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, errors.New("Server returned unexpected response. " + err.Error())
+	}
+
+	var allocations []Allocation
+	for _, u := range result.Users {
+		allocations = append(allocations, Allocation{
+			UserId:    u.UserId,
+			Allocated: m.unix(u.TimeAllocated),
+			Completed: m.unixTime(u.TimeCompleted),
+			Expires:   m.unixTime(u.TimeExpires),
+		})
+	}
+	return allocations, nil
+}
+
+// workplaceAllocate allocates people to a program or certification
+func (m *MoodleApi) workplaceAllocate(function, idName string, id int64, userIds []UserID) error {
+	params := url.Values{
+		idName: {fmt.Sprint(id)},
+	}
+	for i, userId := range userIds {
+		params.Set(fmt.Sprintf("userids[%d]", i), fmt.Sprint(userId))
+	}
+	_, err := m.call(function, params)
+	return err
+}
@@ -0,0 +1,54 @@
+//go:build workplace
+// +build workplace
+
+package moodle
+
+import (
+	"testing"
+)
+
+func TestWorkplace(t *testing.T) {
+
+	fetch := newTestLookupUrl(map[string]string{
+		"tool_tenant_get_tenants":                    `{"tenants":[{"id":1,"name":"Head office","isdefault":true},{"id":2,"name":"Retail","archived":false}],"warnings":[]}`,
+		"tool_program_get_programs":                  `{"programs":[{"id":5,"fullname":"Induction","idnumber":"IND","archived":false}],"warnings":[]}`,
+		"tool_certification_get_certification_users": `{"users":[{"userid":7,"timeallocated":1600000000,"timecompleted":1600100000,"timeexpires":1631636000}],"warnings":[]}`,
+		"tool_program_allocate_users":                `{"warnings":[]}`,
+	})
+	api := NewMoodleApi("https://moodle.example.com/", "token")
+	api.SetUrlFetcher(fetch)
+
+	tenants, err := api.GetTenants()
+	if err != nil {
+		t.Fatalf("GetTenants failed: %v", err)
+	}
+	if len(tenants) != 2 || !tenants[0].IsDefault || tenants[1].Name != "Retail" {
+		t.Errorf("Unexpected tenants: %+v", tenants)
+	}
+
+	programs, err := api.GetPrograms()
+	if err != nil {
+		t.Fatalf("GetPrograms failed: %v", err)
+	}
+	if len(programs) != 1 || programs[0].Name != "Induction" || programs[0].IdNumber != "IND" {
+		t.Errorf("Unexpected programs: %+v", programs)
+	}
+
+	allocations, err := api.GetCertificationAllocations(3)
+	if err != nil {
+		t.Fatalf("GetCertificationAllocations failed: %v", err)
+	}
+	if len(allocations) != 1 || allocations[0].UserId != 7 || allocations[0].Completed == nil || allocations[0].Expires.Unix() != 1631636000 {
+		t.Errorf("Unexpected allocations: %+v", allocations)
+	}
+	if q := fetch.last(); q.Get("certificationid") != "3" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+
+	if err := api.AllocateUsersToProgram(5, 7, 8); err != nil {
+		t.Fatalf("AllocateUsersToProgram failed: %v", err)
+	}
+	if q := fetch.last(); q.Get("programid") != "5" || q.Get("userids[1]") != "8" {
+		t.Errorf("Unexpected parameters: %v", q)
+	}
+}